@@ -91,6 +91,10 @@ type ElasticSearch struct {
 	RequestTimeout     int      `json:"requestTimeout"`
 	EnableSearchAfter  bool     `json:"enableSearchAfter"`
 	TrackTotalHits     bool     `json:"trackTotalHits"`
+	MaxExports         int      `json:"maxExports"`
+	CursorSecret       string   `json:"cursorSecret"`
+	CursorTTL          int      `json:"cursorTTL"`
+	RelatedFields      []string `json:"relatedFields"`
 	IndexTypes         []string
 }
 
@@ -257,6 +261,8 @@ func setDefaults() {
 	viper.SetDefault("ElasticSearch.Replicas", 0)
 	viper.SetDefault("ElasticSearch.RequestTimeout", 15)
 	viper.SetDefault("ElasticSearch.TrackTotalHits", true)
+	viper.SetDefault("ElasticSearch.MaxExports", 2)
+	viper.SetDefault("ElasticSearch.CursorTTL", 10)
 	viper.SetDefault("ElasticSearch.IndexTypes", []string{"v2"})
 
 	// logging
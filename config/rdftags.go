@@ -36,6 +36,7 @@ type RDFTag struct {
 	DateRange        []string `json:"dateRange"`
 	Integer          []string `json:"integer"`
 	IntegerRange     []string `json:"integerRange"`
+	Phonetic         []string `json:"phonetic"`
 }
 
 // RDFTagMap contains all the URIs that trigger indexing labels
@@ -68,6 +69,7 @@ func NewRDFTagMap(c *RawConfig) *RDFTagMap {
 		tagPair{"objectID", c.RDFTag.ObjectID},
 		tagPair{"creator", c.RDFTag.Creator},
 		tagPair{"dateRange", c.RDFTag.DateRange},
+		tagPair{"phonetic", c.RDFTag.Phonetic},
 	}
 	tagMap := make(map[string][]string)
 	for _, pair := range pairs {
@@ -51,6 +51,8 @@ type Description struct {
 	NrItems    int            `json:"nrItems,omitempty"`
 	NrHits     int            `json:"nrHits"`
 	Item       []*DataItem    `json:"item,omitempty"`
+	// Highlights contains the keyword-in-context snippets of the search hits
+	Highlights []*fragments.ResourceEntryHighlight `json:"highlights,omitempty"`
 }
 
 // SectionInfo holds meta information about each section so that it could
@@ -1092,6 +1094,8 @@ func GetDescriptionCount(spec, query string) (int, error) {
 	}
 
 	if descriptionIndex != nil {
+		defer descriptionIndex.Close()
+
 		searhHits, searchErr := descriptionIndex.SearchWithString(query)
 		if searchErr != nil && !errors.Is(searchErr, memory.ErrSearchNoMatch) {
 			c.Config.Logger.Error().Err(searchErr).
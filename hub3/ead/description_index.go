@@ -15,13 +15,14 @@
 package ead
 
 import (
-	"bytes"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"path"
+	"sync"
 
 	"github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
 	"github.com/delving/hub3/ikuzo/service/x/search"
 	"github.com/delving/hub3/ikuzo/storage/x/memory"
 )
@@ -30,9 +31,13 @@ var (
 	ErrNoDescriptionIndex = errors.New("no index created for EAD description")
 )
 
+// migrateMu serializes the migration of legacy gob indexes to segments.
+var migrateMu sync.Mutex
+
 const (
 	startHighlightTag    = "em"
 	hightlightStyleClass = "dhcl"
+	snippetSearchLabel   = "description"
 )
 
 type DescriptionIndex struct {
@@ -66,18 +71,25 @@ func (di *DescriptionIndex) Write() error {
 		return err
 	}
 
-	var buf bytes.Buffer
-
-	err = di.ti.Encode(&buf)
+	// the segment is replaced atomically, because open DescriptionIndexes
+	// memory map the current segment
+	err = di.ti.WriteSegmentFile(getSegmentPath(di.spec))
 	if err != nil {
 		return err
 	}
 
-	return ioutil.WriteFile(
-		getIndexPath(di.spec),
-		buf.Bytes(),
-		os.ModePerm,
-	)
+	// the legacy gob index is superseded by the segment
+	err = os.Remove(getIndexPath(di.spec))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Close releases the memory mapped segment of the DescriptionIndex.
+func (di *DescriptionIndex) Close() error {
+	return di.ti.Close()
 }
 
 func (di *DescriptionIndex) Search(qt *search.QueryTerm) (*search.Matches, error) {
@@ -136,18 +148,74 @@ func (di *DescriptionIndex) HighlightMatches(hits *search.Matches, items []*Data
 	return matches
 }
 
-func GetDescriptionIndex(spec string) (*DescriptionIndex, error) {
-	indexPath := getIndexPath(spec)
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-		return nil, ErrNoDescriptionIndex
+// Explain returns the parsed query and the TextIndex score explanation per
+// DataItem. The hit identifier is the order of the DataItem. The scores are
+// an approximation, see memory.ScoreApproximation.
+func (di *DescriptionIndex) Explain(query string) (*search.Explanation, error) {
+	e, err := search.NewExplanation(query)
+	if err != nil || e.QueryTerm() == nil {
+		return e, err
 	}
 
-	r, err := os.Open(indexPath)
-	if err != nil {
+	e.Approximation = memory.ScoreApproximation
+
+	hits, err := di.ti.Explain(e.QueryTerm())
+	if err != nil && !errors.Is(err, memory.ErrSearchNoMatch) {
 		return nil, err
 	}
 
-	ti, err := memory.DecodeTextIndex(r)
+	e.Hits = hits
+
+	return e, nil
+}
+
+// Snippets returns the best keyword-in-context fragments for the hits in the
+// DataItems in the same format as the ElasticSearch highlights.
+// Snippets must be called before the items are highlighted.
+func (di *DescriptionIndex) Snippets(hits *search.Matches, items []*DataItem, cfg *search.SnippetConfig) []*fragments.ResourceEntryHighlight {
+	if cfg == nil {
+		cfg = search.NewSnippetConfig()
+	}
+
+	found := []search.Fragment{}
+
+	for _, item := range items {
+		if !hits.HasDocID(int(item.Order)) {
+			continue
+		}
+
+		tok := search.NewTokenizer()
+		ts := tok.ParseString(item.Text, int(item.Order))
+
+		found = append(found, ts.Fragments(hits.Vectors(), cfg, startHighlightTag, hightlightStyleClass)...)
+	}
+
+	if len(found) == 0 {
+		return []*fragments.ResourceEntryHighlight{}
+	}
+
+	hl := &fragments.ResourceEntryHighlight{SearchLabel: snippetSearchLabel}
+
+	for _, fragment := range search.TopFragments(found, cfg.NumberOfFragments) {
+		hl.MarkDown = append(hl.MarkDown, fragment.Text)
+	}
+
+	return []*fragments.ResourceEntryHighlight{hl}
+}
+
+// GetDescriptionIndex opens the DescriptionIndex for spec. Legacy gob indexes
+// are migrated to the segment format on first use.
+// The DescriptionIndex must be closed after use.
+func GetDescriptionIndex(spec string) (*DescriptionIndex, error) {
+	segmentPath := getSegmentPath(spec)
+
+	if _, err := os.Stat(segmentPath); os.IsNotExist(err) {
+		if err := migrateDescriptionIndex(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	ti, err := memory.OpenTextIndex(segmentPath)
 	if err != nil {
 		return nil, err
 	}
@@ -158,10 +226,42 @@ func GetDescriptionIndex(spec string) (*DescriptionIndex, error) {
 	return di, nil
 }
 
+// migrateDescriptionIndex migrates the legacy gob index of spec to a segment.
+// The segment is checked again under the lock, because a concurrent request
+// may have migrated it already.
+func migrateDescriptionIndex(spec string) error {
+	migrateMu.Lock()
+	defer migrateMu.Unlock()
+
+	segmentPath := getSegmentPath(spec)
+	if _, err := os.Stat(segmentPath); err == nil {
+		return nil
+	}
+
+	indexPath := getIndexPath(spec)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return ErrNoDescriptionIndex
+	}
+
+	if err := memory.MigrateTextIndex(indexPath, segmentPath); err != nil {
+		return fmt.Errorf("unable to migrate description index for %s; %w", spec, err)
+	}
+
+	if err := os.Remove(indexPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
 func getIndexPath(spec string) string {
 	return path.Join(GetDataPath(spec), "description_index.gob")
 }
 
+func getSegmentPath(spec string) string {
+	return path.Join(GetDataPath(spec), "description_index.seg")
+}
+
 func getDescriptionPath(spec string) string {
 	return path.Join(GetDataPath(spec), "description.gob")
 }
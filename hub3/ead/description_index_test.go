@@ -0,0 +1,104 @@
+// Copyright 2017 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ead
+
+import (
+	"testing"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func TestDescriptionIndex_Snippets(t *testing.T) {
+	is := is.New(t)
+
+	desc := &Description{
+		Item: []*DataItem{
+			{Order: 1, Text: "Archief van de familie Jansen te Leiden."},
+			{Order: 2, Text: "Stukken betreffende de verkoop van een huis aan de Breestraat."},
+			{Order: 3, Text: "Brieven van Pieter Jansen aan zijn broer Jan Jansen te Delft."},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		cfg   *search.SnippetConfig
+		want  []*fragments.ResourceEntryHighlight
+	}{
+		{
+			"no hits",
+			"rotterdam",
+			nil,
+			[]*fragments.ResourceEntryHighlight{},
+		},
+		{
+			"hits across items",
+			"jansen",
+			search.NewSnippetConfig(search.SetFragmentSize(30)),
+			[]*fragments.ResourceEntryHighlight{
+				{
+					SearchLabel: "description",
+					MarkDown: []string{
+						"de familie <em class=\"dhcl\">Jansen</em> te Leiden.",
+						"van Pieter <em class=\"dhcl\">Jansen</em> aan zijn broer Jan <em class=\"dhcl\">Jansen</em> te Delft.",
+					},
+				},
+			},
+		},
+		{
+			"best fragment only",
+			"jansen OR brieven",
+			search.NewSnippetConfig(search.SetFragmentSize(30), search.SetNumberOfFragments(1)),
+			[]*fragments.ResourceEntryHighlight{
+				{
+					SearchLabel: "description",
+					MarkDown: []string{
+						"<em class=\"dhcl\">Brieven</em> van Pieter <em class=\"dhcl\">Jansen</em> aan",
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			di := NewDescriptionIndex("test")
+
+			err := di.CreateFrom(desc)
+			is.NoErr(err)
+
+			qp, err := search.NewQueryParser()
+			is.NoErr(err)
+
+			qt, err := qp.Parse(tt.query)
+			is.NoErr(err)
+
+			hits, err := di.Search(qt)
+			if err != nil {
+				hits = search.NewMatches()
+			}
+
+			got := di.Snippets(hits, desc.Item, tt.cfg)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("DescriptionIndex.Snippets() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
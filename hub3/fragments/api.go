@@ -25,8 +25,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	proto "github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 	elastic "github.com/olivere/elastic/v7"
@@ -39,6 +42,7 @@ const (
 	qfIDKey        = "qf.id"
 	qfDateRangeKey = "qf.dateRange"
 	responseSize   = int32(16)
+	phoneticSuffix = "~ph"
 )
 
 // DefaultSearchRequest takes an Config Objects and sets the defaults
@@ -196,7 +200,27 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 				sr.ResponseFormatType = ResponseFormatType_LDJSON
 			case "bulkaction":
 				sr.ResponseFormatType = ResponseFormatType_BULKACTION
+			case "geojson":
+				sr.ResponseFormatType = ResponseFormatType_GEOJSON
+			case "kml":
+				sr.ResponseFormatType = ResponseFormatType_KML
+			case "geocluster":
+				sr.ResponseFormatType = ResponseFormatType_GEOCLUSTER
+			case "xml":
+				sr.ResponseFormatType = ResponseFormatType_XML
+			case "jsonp":
+				sr.ResponseFormatType = ResponseFormatType_JSONP
 			}
+		case "bbox":
+			err := sr.AddBoundingBox(params.Get(p))
+			if err != nil {
+				return sr, err
+			}
+		case "pt":
+			sr.LatLong = params.Get(p)
+			sr.GeoType = GeoType_GEOFILT
+		case "d":
+			sr.Distance = params.Get(p)
 		case "rows":
 			size, err := strconv.Atoi(params.Get(p))
 			if err != nil {
@@ -225,6 +249,8 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 			default:
 				sr.ItemFormat = ItemFormatType_SUMMARY
 			}
+		case "lang":
+			sr.Lang = BaseLanguage(params.Get(p))
 		case "sortBy":
 			sr.SortBy = params.Get(p)
 		case "sortAsc":
@@ -358,6 +384,10 @@ func NewSearchRequest(params url.Values) (*SearchRequest, error) {
 		}
 	}
 
+	if strings.EqualFold(params.Get("phonetic"), "true") {
+		sr.Query = markPhonetic(sr.Query)
+	}
+
 	if sr.Tree != nil && sr.GetResponseSize() != int32(1) && sr.Page != 0 {
 		rows := params.Get("rows")
 		if rows == "" {
@@ -670,7 +700,13 @@ func (sr *SearchRequest) ElasticQuery() (elastic.Query, error) {
 			}
 			rawQuery = strings.Join(all, " ")
 		}
-		if rawQuery != "" {
+		if strings.Contains(rawQuery, phoneticSuffix) {
+			pq, err := phoneticQuery(rawQuery)
+			if err != nil {
+				return query, err
+			}
+			query = query.Must(pq)
+		} else if rawQuery != "" {
 			qs := elastic.NewQueryStringQuery(escapeRawQuery(rawQuery))
 			qs.DefaultOperator("and")
 
@@ -678,10 +714,23 @@ func (sr *SearchRequest) ElasticQuery() (elastic.Query, error) {
 				Field("full_text").
 				MinimumShouldMatch(c.Config.ElasticSearch.MinimumShouldMatch)
 			query = query.Must(qs)
+
+			if IsIndexLanguage(sr.GetLang()) {
+				query = query.Should(languageQuery(rawQuery, sr.GetLang()))
+			}
 		}
 
 	}
 
+	geoQuery, err := sr.geoQuery()
+	if err != nil {
+		return query, err
+	}
+
+	if geoQuery != nil {
+		query = query.Must(geoQuery)
+	}
+
 	if strings.HasPrefix(sr.GetSortBy(), "random") {
 		randomFunc := elastic.NewRandomFunction()
 
@@ -824,6 +873,53 @@ func (sr *SearchRequest) ElasticQuery() (elastic.Query, error) {
 	return query, nil
 }
 
+// markPhonetic adds the phonetic modifier to all the plain terms in the query.
+// Phrases, operators, field queries and terms with modifiers are left as is.
+func markPhonetic(query string) string {
+	parts := []string{}
+
+	var inPhrase bool
+
+	for _, part := range strings.Fields(query) {
+		quotes := strings.Count(part, "\"")
+
+		switch {
+		case inPhrase, quotes != 0:
+			if quotes%2 != 0 {
+				inPhrase = !inPhrase
+			}
+		case part == "AND", part == "OR", part == "NOT":
+		case strings.ContainsAny(part, ":~^*?()[]{}+-\\/"):
+		default:
+			part += phoneticSuffix
+		}
+
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// phoneticQuery parses the query with the search.QueryParser, so the phonetic
+// terms keep their place in the boolean structure of the query. The phonetic
+// terms match exactly in the full-text field or by their phonetic key in the
+// resource entries.
+func phoneticQuery(rawQuery string) (elastic.Query, error) {
+	qp, err := search.NewQueryParser(search.SetDefaultOperator(search.AndOperator))
+	if err != nil {
+		return nil, err
+	}
+
+	qt, err := qp.Parse(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	qb := elasticsearch.NewQueryBuilder(elasticsearch.QueryField{Field: "full_text"})
+
+	return qb.NewElasticQuery(qt), nil
+}
+
 // isAdvancedSearch checks if the query contains Lucene QueryString
 // advanced search query syntax.
 func isAdvancedSearch(query string) bool {
@@ -863,12 +959,18 @@ func (sr *SearchRequest) Aggregations(fub *FacetURIBuilder) (map[string]elastic.
 }
 
 // CreateAggregationBySearchLabel creates Elastic aggregations for the nested fragment resources
+// When the SearchRequest has a preferred language the languages of the facet
+// values are aggregated as well.
 func (sr *SearchRequest) CreateAggregationBySearchLabel(path string, facet *FacetField, fub *FacetURIBuilder) (elastic.Aggregation, error) {
-	return CreateAggregationBySearchLabel(path, facet, sr.FacetAndBoolType, fub)
+	return createAggregationBySearchLabel(path, facet, sr.FacetAndBoolType, fub, sr.GetLang())
 }
 
 // CreateAggregationBySearchLabel creates Elastic aggregations for the nested fragment resources
 func CreateAggregationBySearchLabel(path string, facet *FacetField, facetAndBoolType bool, fub *FacetURIBuilder) (elastic.Aggregation, error) {
+	return createAggregationBySearchLabel(path, facet, facetAndBoolType, fub, "")
+}
+
+func createAggregationBySearchLabel(path string, facet *FacetField, facetAndBoolType bool, fub *FacetURIBuilder, lang string) (elastic.Aggregation, error) {
 	nestedPath := fmt.Sprintf("%s.searchLabel", path)
 	fieldTermQuery := elastic.NewTermQuery(nestedPath, facet.GetField())
 
@@ -887,6 +989,14 @@ func CreateAggregationBySearchLabel(path string, facet *FacetField, facetAndBool
 		labelAgg = labelAgg.OrderByCount(facet.GetAsc())
 	}
 
+	if lang != "" && !facet.GetById() {
+		langAgg := elastic.NewTermsAggregation().
+			Field(fmt.Sprintf("%s.@language", path)).
+			Missing(missingLanguage)
+
+		labelAgg = labelAgg.SubAggregation("lang", langAgg)
+	}
+
 	// Add Filters as nested path
 	facetFilters, err := fub.CreateFacetFilterQuery(facet.GetField(), facetAndBoolType)
 	if err != nil {
@@ -1030,6 +1140,13 @@ func (sr *SearchRequest) DecodeSearchAfter() ([]interface{}, error) {
 
 // ElasticSearchService creates the elastic SearchService for execution
 func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.SearchService, *FacetURIBuilder, error) {
+	return sr.CursorSearchService(ec, nil, 0)
+}
+
+// CursorSearchService creates the elastic SearchService that continues after the Cursor.
+// When the cursor holds a point-in-time the search is run against the point-in-time
+// instead of the index and the point-in-time is extended by keepAlive.
+func (sr *SearchRequest) CursorSearchService(ec *elastic.Client, cur *Cursor, keepAlive time.Duration) (*elastic.SearchService, *FacetURIBuilder, error) {
 	idSort := elastic.NewFieldSort("meta.hubID")
 	var fieldSort *elastic.FieldSort
 
@@ -1070,12 +1187,21 @@ func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.Sear
 		}
 	}
 
+	ss := elastic.NewSearchSource()
+
 	s := ec.Search().
-		Index(c.Config.ElasticSearch.GetIndexName()).
+		SearchSource(ss).
 		TrackTotalHits(c.Config.ElasticSearch.TrackTotalHits).
-		Preference(sr.GetSessionID()).
 		Size(int(sr.GetResponseSize()))
 
+	if cur.HasPIT() {
+		// a point-in-time search must not specify the index or preference
+		s = s.Source(&pointInTimeSource{source: ss, id: cur.PIT, keepAlive: KeepAlive(keepAlive)})
+	} else {
+		s = s.Index(c.Config.ElasticSearch.GetIndexName()).
+			Preference(sr.GetSessionID())
+	}
+
 	// This section is used to return the tree page section of 250 nodes starting the current.
 	if sr.Tree != nil && sr.Tree.IsPaging && !sr.Tree.IsSearch {
 		s = s.SortBy(fieldSort)
@@ -1088,7 +1214,11 @@ func (sr *SearchRequest) ElasticSearchService(ec *elastic.Client) (*elastic.Sear
 	} else {
 		// This section is used to get the search hit with size 1.
 		s = s.SortBy(fieldSort, idSort)
-		if len(sr.SearchAfter) != 0 && sr.CollapseOn == "" {
+
+		switch {
+		case cur != nil && len(cur.SearchAfter) != 0:
+			s = s.SearchAfter(cur.SearchAfter...)
+		case len(sr.SearchAfter) != 0 && sr.CollapseOn == "":
 			sa, err := sr.DecodeSearchAfter()
 			if err != nil {
 				return nil, nil, err
@@ -1665,12 +1795,18 @@ func getKeyAsString(raw json.RawMessage) string {
 }
 
 // DecodeFacets decodes the elastic aggregations in the SearchResult to fragments.QueryFacets
+// When the SearchRequest has a preferred language, facet values in other
+// languages are left out if values in the preferred language are present.
 func (sr SearchRequest) DecodeFacets(res *elastic.SearchResult, fb *FacetURIBuilder) ([]*QueryFacet, error) {
-	return DecodeFacets(res, fb)
+	return decodeFacets(res, fb, sr.GetLang())
 }
 
 // DecodeFacets decodes the elastic aggregations in the SearchResult to fragments.QueryFacets
 func DecodeFacets(res *elastic.SearchResult, fb *FacetURIBuilder) ([]*QueryFacet, error) {
+	return decodeFacets(res, fb, "")
+}
+
+func decodeFacets(res *elastic.SearchResult, fb *FacetURIBuilder, lang string) ([]*QueryFacet, error) {
 	if res == nil || res.TotalHits() == 0 {
 		return nil, nil
 	}
@@ -1705,8 +1841,14 @@ func DecodeFacets(res *elastic.SearchResult, fb *FacetURIBuilder) ([]*QueryFacet
 					if ok {
 						valid = true
 						qf.OtherDocs = value.SumOfOtherDocCount
+						languages := map[string][]string{}
 						for _, b := range value.Buckets {
 							key := KeyAsString(b)
+							if langAgg, ok := b.Terms("lang"); ok {
+								for _, lb := range langAgg.Buckets {
+									languages[key] = append(languages[key], bucketLanguage(lb))
+								}
+							}
 							url, isSelected := fb.CreateFacetFilterURI(qf.Field, key)
 
 							if isSelected && !qf.IsSelected {
@@ -1721,6 +1863,7 @@ func DecodeFacets(res *elastic.SearchResult, fb *FacetURIBuilder) ([]*QueryFacet
 							}
 							qf.Links = append(qf.Links, fl)
 						}
+						qf.Links = preferLanguageLinks(qf.Links, languages, lang)
 					}
 					histogram, ok := inner.Histogram("histogram")
 					if ok {
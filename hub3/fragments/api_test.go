@@ -506,6 +506,88 @@ func Test_isAdvancedSearch(t *testing.T) {
 	}
 }
 
+func Test_markPhonetic(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"single term", "Jansen", "Jansen~ph"},
+		{"multiple terms", "Jansen Meijer", "Jansen~ph Meijer~ph"},
+		{"operators", "Jansen AND Meijer", "Jansen~ph AND Meijer~ph"},
+		{"phrase", `"Jan Jansen" Meijer`, `"Jan Jansen" Meijer~ph`},
+		{"modifiers", "Jansen~1 Meijer^2 Jans* Smit~ph", "Jansen~1 Meijer^2 Jans* Smit~ph"},
+		{"field query", "meta.spec:civil Jansen", "meta.spec:civil Jansen~ph"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markPhonetic(tt.query); got != tt.want {
+				t.Errorf("markPhonetic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_phoneticQuery(t *testing.T) {
+	phonetic := func(term, key string) string {
+		return `{"bool":{"should":[{"match":{"full_text":{"boost":2,"query":"` + term + `"}}},` +
+			`{"nested":{"path":"resources.entries","query":{"term":{"resources.entries.phonetic":"` + key + `"}}}}]}}`
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			"and",
+			"Jansen~ph Meyer~ph",
+			`{"bool":{"must":[` + phonetic("jansen", "JANSEN") + `,` + phonetic("meyer", "MYER") + `]}}`,
+		},
+		{
+			"or",
+			"Jansen~ph OR Meyer~ph",
+			`{"bool":{"should":[` + phonetic("jansen", "JANSEN") + `,` + phonetic("meyer", "MYER") + `]}}`,
+		},
+		{
+			"not",
+			"NOT Jansen~ph",
+			`{"bool":{"must_not":` + phonetic("jansen", "JANSEN") + `}}`,
+		},
+		{
+			"phrase",
+			`"Jan Jansen" Meyer~ph`,
+			`{"bool":{"must":[{"match_phrase":{"full_text":{"query":"jan jansen"}}},` + phonetic("meyer", "MYER") + `]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := phoneticQuery(tt.query)
+			if err != nil {
+				t.Fatalf("phoneticQuery() error = %v", err)
+			}
+
+			src, err := q.Source()
+			if err != nil {
+				t.Fatalf("unable to get query source; %v", err)
+			}
+
+			got, err := json.Marshal(src)
+			if err != nil {
+				t.Fatalf("unable to marshal query source; %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("phoneticQuery() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestNewDateRangeFilter(t *testing.T) {
 	type args struct {
 		filter string
@@ -0,0 +1,258 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	proto "github.com/golang/protobuf/proto"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor is malformed or its signature does not match.
+	ErrInvalidCursor = errors.New("invalid search cursor")
+	// ErrCursorExpired is returned when a cursor or its point-in-time is no longer valid.
+	// Clients must restart the search without a cursor.
+	ErrCursorExpired = errors.New("search cursor has expired; restart the search without a cursor")
+)
+
+// Cursor is the state needed to fetch the next page of a deep-paging search.
+// It is handed to clients as an opaque signed token, see CursorCodec.
+type Cursor struct {
+	// Request is the protobuf encoded SearchRequest the cursor belongs to.
+	Request []byte `json:"r"`
+	// SearchAfter are the ElasticSearch sort values of the last hit of the previous page.
+	SearchAfter []interface{} `json:"sa,omitempty"`
+	// PIT is the optional ElasticSearch point-in-time id.
+	PIT string `json:"pit,omitempty"`
+	// Expires is the unix timestamp after which the cursor is rejected.
+	Expires int64 `json:"exp"`
+}
+
+// NewCursor creates a Cursor for the SearchRequest that continues after the searchAfter sort values.
+func NewCursor(sr *SearchRequest, searchAfter []interface{}, pit string, expires time.Time) (*Cursor, error) {
+	copySr, err := sr.DeepCopy()
+	if err != nil {
+		return nil, err
+	}
+
+	// the search_after values are kept in the cursor itself
+	copySr.SearchAfter = nil
+	copySr.Paging = false
+
+	b, err := proto.Marshal(copySr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal search request for cursor; %w", err)
+	}
+
+	return &Cursor{
+		Request:     b,
+		SearchAfter: searchAfter,
+		PIT:         pit,
+		Expires:     expires.Unix(),
+	}, nil
+}
+
+// NextCursor returns the Cursor for the page that follows the hits in the SearchResult.
+// When there are no more pages nil is returned.
+func (sr *SearchRequest) NextCursor(res *elastic.SearchResult, pit string, expires time.Time) (*Cursor, error) {
+	if sr.Tree != nil || res == nil || res.Hits == nil || len(res.Hits.Hits) == 0 {
+		return nil, nil
+	}
+
+	if len(res.Hits.Hits) < int(sr.GetResponseSize()) {
+		return nil, nil
+	}
+
+	last := res.Hits.Hits[len(res.Hits.Hits)-1]
+	if len(last.Sort) == 0 {
+		return nil, nil
+	}
+
+	next, err := newSearchRequestScrollPage(sr, ScrollNext, res.TotalHits())
+	if err != nil {
+		return nil, err
+	}
+
+	total := res.Hits.TotalHits
+	if total != nil && total.Relation == "eq" && int64(next.GetStart()) >= total.Value {
+		return nil, nil
+	}
+
+	return NewCursor(next, last.Sort, pit, expires)
+}
+
+// SearchRequest returns the SearchRequest stored in the cursor.
+func (cur *Cursor) SearchRequest() (*SearchRequest, error) {
+	sr := &SearchRequest{}
+	if err := proto.Unmarshal(cur.Request, sr); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal cursor search request; %w", ErrInvalidCursor)
+	}
+
+	return sr, nil
+}
+
+// HasPIT returns true when the cursor is bound to an ElasticSearch point-in-time.
+func (cur *Cursor) HasPIT() bool {
+	return cur != nil && cur.PIT != ""
+}
+
+// CursorCodec signs and verifies cursor tokens.
+type CursorCodec struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewCursorCodec returns a CursorCodec that signs cursors with secret.
+// When the secret is empty a random secret is generated. Cursors signed
+// with a random secret are only valid for the lifetime of the process.
+func NewCursorCodec(secret string) (*CursorCodec, error) {
+	key := []byte(secret)
+
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("unable to generate cursor secret; %w", err)
+		}
+	}
+
+	return &CursorCodec{secret: key, now: time.Now}, nil
+}
+
+// Encode returns the signed opaque token for the cursor.
+func (cc *CursorCodec) Encode(cur *Cursor) (string, error) {
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal cursor; %w", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(b)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cc.sign(payload)), nil
+}
+
+// Decode verifies the token and returns the Cursor.
+// ErrInvalidCursor is returned for tampered or malformed tokens and
+// ErrCursorExpired when the cursor has expired.
+func (cc *CursorCodec) Decode(token string) (*Cursor, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, cc.sign(parts[0])) {
+		return nil, ErrInvalidCursor
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// UseNumber makes sure long sort values do not lose precision
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var cur Cursor
+	if err := dec.Decode(&cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if cc.now().Unix() > cur.Expires {
+		return nil, ErrCursorExpired
+	}
+
+	return &cur, nil
+}
+
+func (cc *CursorCodec) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, cc.secret)
+	_, _ = mac.Write([]byte(payload))
+
+	return mac.Sum(nil)
+}
+
+// KeepAlive returns the duration in the ElasticSearch time unit format.
+func KeepAlive(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
+
+// OpenPointInTime opens an ElasticSearch point-in-time for the index and returns its id.
+func OpenPointInTime(ctx context.Context, ec *elastic.Client, index string, keepAlive time.Duration) (string, error) {
+	res, err := ec.PerformRequest(ctx, elastic.PerformRequestOptions{
+		Method: "POST",
+		Path:   fmt.Sprintf("/%s/_pit", url.PathEscape(index)),
+		Params: url.Values{"keep_alive": []string{KeepAlive(keepAlive)}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to open point-in-time for %s; %w", index, err)
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.Unmarshal(res.Body, &pit); err != nil {
+		return "", fmt.Errorf("unable to decode point-in-time response; %w", err)
+	}
+
+	if pit.ID == "" {
+		return "", fmt.Errorf("empty point-in-time id returned for %s", index)
+	}
+
+	return pit.ID, nil
+}
+
+// pointInTimeSource adds the point-in-time to the request body of the SearchSource.
+// The SearchSource is only serialized when the request is executed, so changes
+// made to it after the pointInTimeSource is created are included.
+type pointInTimeSource struct {
+	source    *elastic.SearchSource
+	id        string
+	keepAlive string
+}
+
+// MarshalJSON implements json.Marshaler.
+func (pit *pointInTimeSource) MarshalJSON() ([]byte, error) {
+	src, err := pit.source.Source()
+	if err != nil {
+		return nil, err
+	}
+
+	body, ok := src.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected search source type %T", src)
+	}
+
+	body["pit"] = map[string]interface{}{
+		"id":         pit.id,
+		"keep_alive": pit.keepAlive,
+	}
+
+	return json.Marshal(body)
+}
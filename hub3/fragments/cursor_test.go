@@ -0,0 +1,187 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func TestCursorCodec(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	codec, err := NewCursorCodec("secret")
+	if err != nil {
+		t.Fatalf("NewCursorCodec() error = %v", err)
+	}
+
+	codec.now = func() time.Time { return now }
+
+	other, err := NewCursorCodec("other")
+	if err != nil {
+		t.Fatalf("NewCursorCodec() error = %v", err)
+	}
+
+	sr := &SearchRequest{Query: "rembrandt", ResponseSize: 16, Start: 16}
+
+	cur, err := NewCursor(sr, []interface{}{1.5, json.Number("9007199254740993"), "hub_1"}, "pit-1", now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("NewCursor() error = %v", err)
+	}
+
+	token, err := codec.Encode(cur)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expired, err := codec.Encode(&Cursor{Request: cur.Request, Expires: now.Add(-time.Second).Unix()})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	forged, err := other.Encode(cur)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid", token, nil},
+		{"expired", expired, ErrCursorExpired},
+		{"wrong secret", forged, ErrInvalidCursor},
+		{"tampered", "x" + token, ErrInvalidCursor},
+		{"malformed", "not-a-cursor", ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := codec.Decode(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			// sort values are decoded as json.Number to keep the precision of long values
+			want := *cur
+			want.SearchAfter = []interface{}{json.Number("1.5"), json.Number("9007199254740993"), "hub_1"}
+
+			if diff := cmp.Diff(&want, got); diff != "" {
+				t.Errorf("Decode() mismatch (-want +got):\n%s", diff)
+			}
+
+			gotSr, err := got.SearchRequest()
+			if err != nil {
+				t.Fatalf("SearchRequest() error = %v", err)
+			}
+
+			if gotSr.GetQuery() != sr.GetQuery() || gotSr.GetStart() != sr.GetStart() {
+				t.Errorf("SearchRequest() = %v, want %v", gotSr, sr)
+			}
+		})
+	}
+}
+
+func TestSearchRequest_NextCursor(t *testing.T) {
+	hits := func(n int, total int64, relation string) *elastic.SearchResult {
+		res := &elastic.SearchResult{
+			Hits: &elastic.SearchHits{TotalHits: &elastic.TotalHits{Value: total, Relation: relation}},
+		}
+
+		for i := 0; i < n; i++ {
+			res.Hits.Hits = append(res.Hits.Hits, &elastic.SearchHit{Sort: []interface{}{float64(i), "hub"}})
+		}
+
+		return res
+	}
+
+	tests := []struct {
+		name      string
+		res       *elastic.SearchResult
+		wantNil   bool
+		wantStart int32
+	}{
+		{"next page", hits(2, 10, "eq"), false, 2},
+		{"last page", hits(2, 2, "eq"), true, 0},
+		{"partial page", hits(1, 10, "eq"), true, 0},
+		{"lower bound total", hits(2, 2, "gte"), false, 2},
+		{"no hits", hits(0, 0, "eq"), true, 0},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &SearchRequest{ResponseSize: 2, SearchAfter: []byte("old")}
+
+			got, err := sr.NextCursor(tt.res, "pit-1", time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("NextCursor() error = %v", err)
+			}
+
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("NextCursor() = %v, wantNil %v", got, tt.wantNil)
+			}
+
+			if got == nil {
+				return
+			}
+
+			if diff := cmp.Diff([]interface{}{float64(1), "hub"}, got.SearchAfter); diff != "" {
+				t.Errorf("NextCursor() SearchAfter mismatch (-want +got):\n%s", diff)
+			}
+
+			next, err := got.SearchRequest()
+			if err != nil {
+				t.Fatalf("SearchRequest() error = %v", err)
+			}
+
+			if next.GetStart() != tt.wantStart || len(next.GetSearchAfter()) != 0 || got.PIT != "pit-1" {
+				t.Errorf("NextCursor() = %v; request %v", got, next)
+			}
+		})
+	}
+}
+
+func TestPointInTimeSource(t *testing.T) {
+	ss := elastic.NewSearchSource().Size(10)
+	pit := &pointInTimeSource{source: ss, id: "pit-1", keepAlive: KeepAlive(10 * time.Minute)}
+
+	// changes after wrapping must be part of the request body
+	ss.Query(elastic.NewMatchAllQuery())
+
+	b, err := json.Marshal(pit)
+	if err != nil {
+		t.Fatalf("MarshalJSON() error = %v", err)
+	}
+
+	want := `{"pit":{"id":"pit-1","keep_alive":"600s"},"query":{"match_all":{}},"size":10}`
+	if diff := cmp.Diff(want, strings.TrimSpace(string(b))); diff != "" {
+		t.Errorf("MarshalJSON() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	r "github.com/kiivihal/rdf2go"
+)
+
+// ExportFormat is the serialization format of a search export.
+type ExportFormat string
+
+// Supported export formats
+const (
+	ExportCSV      ExportFormat = "csv"
+	ExportJSONL    ExportFormat = "jsonl"
+	ExportNTriples ExportFormat = "ntriples"
+)
+
+const defaultExportSeparator = "; "
+
+// ContentType returns the mime-type of the export format.
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportNTriples:
+		return "application/n-triples; charset=utf-8"
+	default:
+		return "application/x-ndjson; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension of the export format.
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportCSV:
+		return "csv"
+	case ExportNTriples:
+		return "nt"
+	default:
+		return "jsonl"
+	}
+}
+
+// ExportConfig holds the options for exporting search results.
+type ExportConfig struct {
+	Format ExportFormat
+	// Fields are the searchLabels that are exported.
+	// For CSV they are the columns after the hubID.
+	Fields []string
+	// Separator joins multiple values of a field in a single CSV cell.
+	Separator string
+}
+
+// NewExportConfig creates an ExportConfig from the url parameters.
+//
+// Supported parameters are 'format' (csv, jsonl or ntriples), 'fields' as a
+// comma separated list of searchLabels and 'separator' for multi-valued CSV
+// cells.
+func NewExportConfig(params url.Values) (*ExportConfig, error) {
+	cfg := &ExportConfig{
+		Format:    ExportJSONL,
+		Separator: defaultExportSeparator,
+	}
+
+	switch format := params.Get("format"); format {
+	case "", "jsonl", "ndjson":
+	case "csv":
+		cfg.Format = ExportCSV
+	case "ntriples", "nt":
+		cfg.Format = ExportNTriples
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	for _, fields := range params["fields"] {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				cfg.Fields = append(cfg.Fields, field)
+			}
+		}
+	}
+
+	if sep, ok := params["separator"]; ok && len(sep) > 0 {
+		cfg.Separator = sep[0]
+	}
+
+	if cfg.Format == ExportCSV && len(cfg.Fields) == 0 {
+		return nil, fmt.Errorf("csv export requires the 'fields' parameter")
+	}
+
+	return cfg, nil
+}
+
+// ExportWriter streams FragmentGraphs in the configured ExportFormat.
+type ExportWriter struct {
+	cfg *ExportConfig
+	w   io.Writer
+	csv *csv.Writer
+	enc *json.Encoder
+}
+
+// NewExportWriter returns an ExportWriter. For CSV the header is written
+// immediately.
+func NewExportWriter(w io.Writer, cfg *ExportConfig) (*ExportWriter, error) {
+	ew := &ExportWriter{cfg: cfg, w: w}
+
+	switch cfg.Format {
+	case ExportCSV:
+		if len(cfg.Fields) == 0 {
+			return nil, fmt.Errorf("csv export requires at least one field")
+		}
+
+		ew.csv = csv.NewWriter(w)
+
+		header := append([]string{"hubID"}, cfg.Fields...)
+		if err := ew.csv.Write(header); err != nil {
+			return nil, fmt.Errorf("unable to write csv header; %w", err)
+		}
+	case ExportJSONL:
+		ew.enc = json.NewEncoder(w)
+	case ExportNTriples:
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", cfg.Format)
+	}
+
+	return ew, nil
+}
+
+// Write serializes a single FragmentGraph.
+func (ew *ExportWriter) Write(fg *FragmentGraph) error {
+	switch ew.cfg.Format {
+	case ExportCSV:
+		fields := fg.NewFields(nil, ew.cfg.Fields...)
+
+		row := []string{fg.Meta.GetHubID()}
+		for _, field := range ew.cfg.Fields {
+			row = append(row, strings.Join(fields[field], ew.cfg.Separator))
+		}
+
+		return ew.csv.Write(row)
+	case ExportJSONL:
+		if len(ew.cfg.Fields) != 0 {
+			fg.NewFields(nil, ew.cfg.Fields...)
+			return ew.enc.Encode(&FragmentGraph{Meta: fg.Meta, Fields: fg.Fields})
+		}
+
+		return ew.enc.Encode(fg)
+	case ExportNTriples:
+		for _, t := range fg.NewTriples() {
+			if _, err := fmt.Fprintln(ew.w, t.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (ew *ExportWriter) Flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		return ew.csv.Error()
+	}
+
+	return nil
+}
+
+// NewTriples returns the RDF triples of all the resources in the FragmentGraph.
+func (fg *FragmentGraph) NewTriples() []*r.Triple {
+	triples := []*r.Triple{}
+
+	for _, rsc := range fg.Resources {
+		subject := newSubjectTerm(rsc.ID)
+
+		for _, t := range rsc.Types {
+			triples = append(triples, r.NewTriple(subject, r.NewResource(RDFType), r.NewResource(t)))
+		}
+
+		for _, entry := range rsc.Entries {
+			object := entry.objectTerm()
+			if object == nil {
+				continue
+			}
+
+			triples = append(triples, r.NewTriple(subject, r.NewResource(entry.Predicate), object))
+		}
+	}
+
+	return triples
+}
+
+// objectTerm returns the rdf2go.Term for the object of the ResourceEntry.
+func (re *ResourceEntry) objectTerm() r.Term {
+	switch re.EntryType {
+	case resource, bnode:
+		if re.ID == "" {
+			return nil
+		}
+
+		return newSubjectTerm(re.ID)
+	}
+
+	switch {
+	case re.Value == "":
+		return nil
+	case re.Language != "":
+		return r.NewLiteralWithLanguage(re.Value, re.Language)
+	case re.DataType != "":
+		return r.NewLiteralWithDatatype(re.Value, r.NewResource(re.DataType))
+	}
+
+	return r.NewLiteral(re.Value)
+}
+
+// newSubjectTerm returns a BlankNode for '_:' prefixed identifiers and a
+// Resource otherwise.
+func newSubjectTerm(id string) r.Term {
+	if strings.HasPrefix(id, "_:") {
+		return r.NewBlankNode(strings.TrimPrefix(id, "_:"))
+	}
+
+	return r.NewResource(id)
+}
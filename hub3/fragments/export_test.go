@@ -0,0 +1,185 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func exportGraph() *FragmentGraph {
+	return &FragmentGraph{
+		Meta: &Header{HubID: "hub3_test_1", Spec: "test", OrgID: "hub3"},
+		Resources: []*FragmentResource{
+			{
+				ID:    "http://example.org/1",
+				Types: []string{"http://www.europeana.eu/schemas/edm/ProvidedCHO"},
+				Entries: []*ResourceEntry{
+					{
+						Value:       "first title",
+						Language:    "en",
+						EntryType:   literal,
+						Predicate:   "http://purl.org/dc/elements/1.1/title",
+						SearchLabel: "dc_title",
+						Order:       1,
+					},
+					{
+						Value:       "second \"title\"",
+						EntryType:   literal,
+						Predicate:   "http://purl.org/dc/elements/1.1/title",
+						SearchLabel: "dc_title",
+						Order:       2,
+					},
+					{
+						ID:          "http://example.org/creator/1",
+						EntryType:   resource,
+						Predicate:   "http://purl.org/dc/elements/1.1/creator",
+						SearchLabel: "dc_creator",
+						Order:       3,
+					},
+					{
+						ID:          "_:b0",
+						EntryType:   bnode,
+						Predicate:   "http://purl.org/dc/terms/spatial",
+						SearchLabel: "dcterms_spatial",
+						Order:       4,
+					},
+					{
+						Value:       "1900",
+						DataType:    "http://www.w3.org/2001/XMLSchema#gYear",
+						EntryType:   literal,
+						Predicate:   "http://purl.org/dc/elements/1.1/date",
+						SearchLabel: "dc_date",
+						Order:       5,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestNewExportConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  url.Values
+		want    *ExportConfig
+		wantErr bool
+	}{
+		{
+			"default",
+			url.Values{},
+			&ExportConfig{Format: ExportJSONL, Separator: defaultExportSeparator},
+			false,
+		},
+		{
+			"csv with fields",
+			url.Values{"format": {"csv"}, "fields": {"dc_title, dc_creator", "dc_date"}, "separator": {"|"}},
+			&ExportConfig{Format: ExportCSV, Fields: []string{"dc_title", "dc_creator", "dc_date"}, Separator: "|"},
+			false,
+		},
+		{
+			"ntriples",
+			url.Values{"format": {"nt"}},
+			&ExportConfig{Format: ExportNTriples, Separator: defaultExportSeparator},
+			false,
+		},
+		{
+			"csv without fields",
+			url.Values{"format": {"csv"}},
+			nil,
+			true,
+		},
+		{
+			"unknown format",
+			url.Values{"format": {"xlsx"}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewExportConfig(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewExportConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewExportConfig() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExportWriter(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *ExportConfig
+		want string
+	}{
+		{
+			"csv",
+			&ExportConfig{Format: ExportCSV, Fields: []string{"dc_title", "dc_creator", "dc_subject"}, Separator: "; "},
+			"hubID,dc_title,dc_creator,dc_subject\n" +
+				"hub3_test_1,\"first title; second \"\"title\"\"\",http://example.org/creator/1,\n",
+		},
+		{
+			"jsonl with fields",
+			&ExportConfig{Format: ExportJSONL, Fields: []string{"dc_date"}},
+			`{"meta":{"orgID":"hub3","spec":"test","hubID":"hub3_test_1"},"fields":{"dc_date":["1900"]},"protobuf":{}}` + "\n",
+		},
+		{
+			"ntriples",
+			&ExportConfig{Format: ExportNTriples},
+			"<http://example.org/1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.europeana.eu/schemas/edm/ProvidedCHO> .\n" +
+				"<http://example.org/1> <http://purl.org/dc/elements/1.1/title> \"first title\"@en .\n" +
+				"<http://example.org/1> <http://purl.org/dc/elements/1.1/title> \"second \\\"title\\\"\" .\n" +
+				"<http://example.org/1> <http://purl.org/dc/elements/1.1/creator> <http://example.org/creator/1> .\n" +
+				"<http://example.org/1> <http://purl.org/dc/terms/spatial> _:b0 .\n" +
+				"<http://example.org/1> <http://purl.org/dc/elements/1.1/date> \"1900\"^^<http://www.w3.org/2001/XMLSchema#gYear> .\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			ew, err := NewExportWriter(&buf, tt.cfg)
+			if err != nil {
+				t.Fatalf("NewExportWriter() error = %v", err)
+			}
+
+			if err := ew.Write(exportGraph()); err != nil {
+				t.Fatalf("ExportWriter.Write() error = %v", err)
+			}
+
+			if err := ew.Flush(); err != nil {
+				t.Fatalf("ExportWriter.Flush() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("ExportWriter mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
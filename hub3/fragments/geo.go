@@ -0,0 +1,483 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	elastic "github.com/olivere/elastic/v7"
+)
+
+const (
+	// GeoClusterAggName is the name of the aggregation used for GEOCLUSTER responses.
+	GeoClusterAggName = "geocluster"
+
+	latLongField         = "resources.entries.latLong"
+	defaultZoom          = 6
+	geoClusterSize       = 1000
+	geoClusterSampleSize = 3
+	geohashBase32        = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// zoomPrecision maps the maximum map zoom level to the geohash precision.
+var zoomPrecision = []struct {
+	zoom      int
+	precision int
+}{
+	{2, 1},
+	{4, 2},
+	{7, 3},
+	{10, 4},
+	{12, 5},
+	{15, 6},
+	{17, 7},
+}
+
+// GeohashPrecision returns the geohash precision for a map zoom level.
+// An empty zoom returns the precision of the default zoom level.
+func GeohashPrecision(zoom string) (int, error) {
+	level := defaultZoom
+
+	if zoom != "" {
+		var err error
+
+		level, err = strconv.Atoi(zoom)
+		if err != nil || level < 0 {
+			return 0, fmt.Errorf("invalid zoom level: %s", zoom)
+		}
+	}
+
+	for _, zp := range zoomPrecision {
+		if level <= zp.zoom {
+			return zp.precision, nil
+		}
+	}
+
+	return 8, nil
+}
+
+// AddBoundingBox sets the bounding box filter from a 'minLon,minLat,maxLon,maxLat' string.
+func (sr *SearchRequest) AddBoundingBox(bbox string) error {
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return fmt.Errorf("bbox must be formatted as minLon,minLat,maxLon,maxLat: %s", bbox)
+	}
+
+	coords := make([]float32, 0, 4)
+
+	for _, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return fmt.Errorf("invalid bbox coordinate %s; %w", part, err)
+		}
+
+		coords = append(coords, float32(f))
+	}
+
+	if coords[0] > coords[2] || coords[1] > coords[3] {
+		return fmt.Errorf("bbox minimum must be lower than the maximum: %s", bbox)
+	}
+
+	sr.MinX, sr.MinY, sr.MaxX, sr.MaxY = coords[0], coords[1], coords[2], coords[3]
+	sr.GeoType = GeoType_BBOX
+
+	return nil
+}
+
+func (sr *SearchRequest) hasBoundingBox() bool {
+	return sr.GetGeoType() == GeoType_BBOX &&
+		(sr.GetMinX() != 0 || sr.GetMinY() != 0 || sr.GetMaxX() != 0 || sr.GetMaxY() != 0)
+}
+
+// geoQuery returns the bounding box or distance filter for the SearchRequest.
+// When no geo filter is set nil is returned.
+func (sr *SearchRequest) geoQuery() (elastic.Query, error) {
+	var q elastic.Query
+
+	switch {
+	case sr.hasBoundingBox():
+		q = elastic.NewGeoBoundingBoxQuery(latLongField).
+			TopLeft(float64(sr.GetMaxY()), float64(sr.GetMinX())).
+			BottomRight(float64(sr.GetMinY()), float64(sr.GetMaxX()))
+	case sr.GetGeoType() == GeoType_GEOFILT && sr.GetLatLong() != "":
+		point, err := parseLatLong(sr.GetLatLong())
+		if err != nil {
+			return nil, err
+		}
+
+		distance := sr.GetDistance()
+		if distance == "" {
+			distance = "10km"
+		}
+
+		if _, err := strconv.ParseFloat(distance, 64); err == nil {
+			distance += "km"
+		}
+
+		q = elastic.NewGeoDistanceQuery(latLongField).
+			Point(point.Lat, point.Lon).
+			Distance(distance)
+	default:
+		return nil, nil
+	}
+
+	return elastic.NewNestedQuery("resources.entries", q), nil
+}
+
+// GeoClusterAggregation returns a geohash grid aggregation with per cluster
+// record counts, centroids and sample hits.
+func (sr *SearchRequest) GeoClusterAggregation(precision int) elastic.Aggregation {
+	sample := elastic.NewTopHitsAggregation().
+		Size(geoClusterSampleSize).
+		FetchSourceContext(elastic.NewFetchSourceContext(true).Include("meta", "resources"))
+
+	grid := elastic.NewGeoHashGridAggregation().
+		Field(latLongField).
+		Precision(precision).
+		Size(geoClusterSize).
+		SubAggregation("centroid", elastic.NewGeoCentroidAggregation().Field(latLongField)).
+		SubAggregation("docs", elastic.NewReverseNestedAggregation().SubAggregation("sample", sample))
+
+	var filter elastic.Query = elastic.NewExistsQuery(latLongField)
+
+	if sr.hasBoundingBox() {
+		filter = elastic.NewGeoBoundingBoxQuery(latLongField).
+			TopLeft(float64(sr.GetMaxY()), float64(sr.GetMinX())).
+			BottomRight(float64(sr.GetMinY()), float64(sr.GetMaxX()))
+	}
+
+	return elastic.NewNestedAggregation().
+		Path("resources.entries").
+		SubAggregation("points", elastic.NewFilterAggregation().
+			Filter(filter).
+			SubAggregation("cells", grid))
+}
+
+// GeoPoint is a WGS84 coordinate.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// parseLatLong parses a 'lat,lon' string.
+func parseLatLong(latLong string) (*GeoPoint, error) {
+	parts := strings.Split(latLong, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("latLong must be formatted as lat,lon: %s", latLong)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude %s; %w", parts[0], err)
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude %s; %w", parts[1], err)
+	}
+
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("latLong out of range: %s", latLong)
+	}
+
+	return &GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// GeoPoints returns all the unique valid coordinates of the FragmentGraph.
+func (fg *FragmentGraph) GeoPoints() []*GeoPoint {
+	points := []*GeoPoint{}
+	seen := map[string]bool{}
+
+	for _, rsc := range fg.Resources {
+		for _, entry := range rsc.Entries {
+			if entry.LatLong == "" || seen[entry.LatLong] {
+				continue
+			}
+
+			seen[entry.LatLong] = true
+
+			point, err := parseLatLong(entry.LatLong)
+			if err != nil {
+				continue
+			}
+
+			points = append(points, point)
+		}
+	}
+
+	return points
+}
+
+// FeatureCollection is a GeoJSON FeatureCollection (RFC 7946).
+type FeatureCollection struct {
+	Type     string     `json:"type"`
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a GeoJSON Feature.
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   *Geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON Point or MultiPoint geometry.
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// NewFeatureCollection creates a GeoJSON FeatureCollection from the records.
+// Records without coordinates are skipped.
+func NewFeatureCollection(records []*FragmentGraph) *FeatureCollection {
+	fc := &FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []*Feature{},
+	}
+
+	for _, rec := range records {
+		points := rec.GeoPoints()
+		if len(points) == 0 {
+			continue
+		}
+
+		summary := rec.NewResultSummary()
+
+		feature := &Feature{
+			Type: "Feature",
+			ID:   rec.Meta.GetHubID(),
+			Properties: map[string]interface{}{
+				"hubID": rec.Meta.GetHubID(),
+				"spec":  rec.Meta.GetSpec(),
+				"title": summary.GetTitle(),
+			},
+		}
+
+		if summary.GetThumbnail() != "" {
+			feature.Properties["thumbnail"] = summary.GetThumbnail()
+		}
+
+		if len(points) == 1 {
+			feature.Geometry = &Geometry{Type: "Point", Coordinates: points[0].coordinates()}
+		} else {
+			coords := [][]float64{}
+			for _, p := range points {
+				coords = append(coords, p.coordinates())
+			}
+
+			feature.Geometry = &Geometry{Type: "MultiPoint", Coordinates: coords}
+		}
+
+		fc.Features = append(fc.Features, feature)
+	}
+
+	return fc
+}
+
+// coordinates returns the GeoJSON position (longitude first).
+func (p *GeoPoint) coordinates() []float64 {
+	return []float64{p.Lon, p.Lat}
+}
+
+// kml returns the KML coordinate tuple (longitude first).
+func (p *GeoPoint) kml() string {
+	return strconv.FormatFloat(p.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lat, 'f', -1, 64)
+}
+
+// KML is the root element of a KML 2.2 document.
+type KML struct {
+	XMLName  xml.Name     `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Document *KMLDocument `xml:"Document"`
+}
+
+// KMLDocument holds the KML Placemarks.
+type KMLDocument struct {
+	Placemarks []*KMLPlacemark `xml:"Placemark"`
+}
+
+// KMLPlacemark is a KML Placemark for a single record.
+type KMLPlacemark struct {
+	ID            string            `xml:"id,attr,omitempty"`
+	Name          string            `xml:"name"`
+	Description   string            `xml:"description,omitempty"`
+	Point         *KMLPoint         `xml:"Point,omitempty"`
+	MultiGeometry *KMLMultiGeometry `xml:"MultiGeometry,omitempty"`
+}
+
+// KMLMultiGeometry groups the Points of records with multiple coordinates.
+type KMLMultiGeometry struct {
+	Points []*KMLPoint `xml:"Point"`
+}
+
+// KMLPoint is a KML Point.
+type KMLPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// NewKML creates a KML document with a Placemark for each record with coordinates.
+func NewKML(records []*FragmentGraph) *KML {
+	doc := &KMLDocument{Placemarks: []*KMLPlacemark{}}
+
+	for _, rec := range records {
+		points := rec.GeoPoints()
+		if len(points) == 0 {
+			continue
+		}
+
+		summary := rec.NewResultSummary()
+
+		pm := &KMLPlacemark{
+			ID:          rec.Meta.GetHubID(),
+			Name:        summary.GetTitle(),
+			Description: summary.GetDescription(),
+		}
+
+		if len(points) == 1 {
+			pm.Point = &KMLPoint{Coordinates: points[0].kml()}
+		} else {
+			pm.MultiGeometry = &KMLMultiGeometry{}
+			for _, p := range points {
+				pm.MultiGeometry.Points = append(pm.MultiGeometry.Points, &KMLPoint{Coordinates: p.kml()})
+			}
+		}
+
+		doc.Placemarks = append(doc.Placemarks, pm)
+	}
+
+	return &KML{Document: doc}
+}
+
+// GeoClusterResult is the response of the GEOCLUSTER format.
+type GeoClusterResult struct {
+	Pager     *ScrollPager  `json:"pager"`
+	Precision int           `json:"precision"`
+	Clusters  []*GeoCluster `json:"clusters"`
+}
+
+// GeoCluster is a geohash grid cell with the records within it.
+type GeoCluster struct {
+	GeoHash  string           `json:"geoHash"`
+	Count    int64            `json:"count"`
+	Centroid *GeoPoint        `json:"centroid,omitempty"`
+	BBox     []float64        `json:"bbox"`
+	Samples  []*FragmentGraph `json:"samples,omitempty"`
+}
+
+// DecodeGeoClusters decodes the GeoClusterAggregation from the search result.
+func DecodeGeoClusters(res *elastic.SearchResult) ([]*GeoCluster, error) {
+	clusters := []*GeoCluster{}
+
+	if res == nil || res.Aggregations == nil {
+		return clusters, nil
+	}
+
+	nested, ok := res.Aggregations.Nested(GeoClusterAggName)
+	if !ok {
+		return clusters, nil
+	}
+
+	points, ok := nested.Filter("points")
+	if !ok {
+		return clusters, nil
+	}
+
+	cells, ok := points.GeoHash("cells")
+	if !ok {
+		return clusters, nil
+	}
+
+	for _, bucket := range cells.Buckets {
+		geohash, ok := bucket.Key.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected geohash key: %#v", bucket.Key)
+		}
+
+		cluster := &GeoCluster{
+			GeoHash: geohash,
+			Count:   bucket.DocCount,
+			BBox:    geohashBBox(geohash),
+		}
+
+		if centroid, ok := bucket.GeoCentroid("centroid"); ok {
+			cluster.Centroid = &GeoPoint{Lat: centroid.Location.Latitude, Lon: centroid.Location.Longitude}
+		}
+
+		if docs, ok := bucket.ReverseNested("docs"); ok {
+			// count records instead of coordinates
+			cluster.Count = docs.DocCount
+
+			if sample, ok := docs.TopHits("sample"); ok && sample.Hits != nil {
+				for _, hit := range sample.Hits.Hits {
+					fg := &FragmentGraph{}
+					if err := json.Unmarshal(hit.Source, fg); err != nil {
+						return nil, fmt.Errorf("unable to decode sample hit; %w", err)
+					}
+
+					fg.NewResultSummary()
+					fg.Resources = nil
+
+					cluster.Samples = append(cluster.Samples, fg)
+				}
+			}
+		}
+
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// geohashBBox returns the bounding box of the geohash as [minLon, minLat, maxLon, maxLat].
+func geohashBBox(geohash string) []float64 {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	even := true
+
+	for _, c := range geohash {
+		idx := strings.IndexRune(geohashBase32, c)
+		if idx < 0 {
+			return nil
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			set := idx&(1<<uint(bit)) != 0
+
+			if even {
+				mid := (minLon + maxLon) / 2
+				if set {
+					minLon = mid
+				} else {
+					maxLon = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+
+			even = !even
+		}
+	}
+
+	return []float64{minLon, minLat, maxLon, maxLat}
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func geoGraph(hubID string, latLongs ...string) *FragmentGraph {
+	entries := []*ResourceEntry{
+		{Value: "title " + hubID, Tags: []string{"title"}, EntryType: literal},
+	}
+
+	for _, ll := range latLongs {
+		entries = append(entries, &ResourceEntry{Value: ll, LatLong: ll, EntryType: literal})
+	}
+
+	return &FragmentGraph{
+		Meta:      &Header{HubID: hubID, Spec: "geo"},
+		Resources: []*FragmentResource{{ID: "http://example.org/" + hubID, Entries: entries}},
+	}
+}
+
+func TestGeohashPrecision(t *testing.T) {
+	tests := []struct {
+		zoom    string
+		want    int
+		wantErr bool
+	}{
+		{"", 3, false},
+		{"0", 1, false},
+		{"4", 2, false},
+		{"11", 5, false},
+		{"20", 8, false},
+		{"-1", 0, true},
+		{"high", 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.zoom, func(t *testing.T) {
+			got, err := GeohashPrecision(tt.zoom)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GeohashPrecision() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("GeohashPrecision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRequest_AddBoundingBox(t *testing.T) {
+	tests := []struct {
+		name    string
+		bbox    string
+		want    []float32
+		wantErr bool
+	}{
+		{"valid", "3.3,50.7,7.2,53.6", []float32{3.3, 50.7, 7.2, 53.6}, false},
+		{"too few coordinates", "3.3,50.7,7.2", nil, true},
+		{"not a number", "3.3,50.7,7.2,north", nil, true},
+		{"min larger than max", "7.2,50.7,3.3,53.6", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &SearchRequest{}
+
+			err := sr.AddBoundingBox(tt.bbox)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddBoundingBox() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			got := []float32{sr.GetMinX(), sr.GetMinY(), sr.GetMaxX(), sr.GetMaxY()}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("AddBoundingBox() mismatch (-want +got):\n%s", diff)
+			}
+
+			if !sr.hasBoundingBox() {
+				t.Errorf("AddBoundingBox() should set the bounding box")
+			}
+		})
+	}
+}
+
+func TestNewFeatureCollection(t *testing.T) {
+	records := []*FragmentGraph{
+		geoGraph("1", "52.1,5.1"),
+		geoGraph("2"),
+		geoGraph("3", "52.1,5.1", "51.5, 4.5", "52.1,5.1", "invalid"),
+	}
+
+	got, err := json.Marshal(NewFeatureCollection(records))
+	if err != nil {
+		t.Fatalf("unable to marshal FeatureCollection: %s", err)
+	}
+
+	want := `{"type":"FeatureCollection","features":[` +
+		`{"type":"Feature","id":"1","geometry":{"type":"Point","coordinates":[5.1,52.1]},"properties":{"hubID":"1","spec":"geo","title":"title 1"}},` +
+		`{"type":"Feature","id":"3","geometry":{"type":"MultiPoint","coordinates":[[5.1,52.1],[4.5,51.5]]},"properties":{"hubID":"3","spec":"geo","title":"title 3"}}]}`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("NewFeatureCollection() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewKML(t *testing.T) {
+	records := []*FragmentGraph{
+		geoGraph("1", "52.1,5.1"),
+		geoGraph("2"),
+		geoGraph("3", "52.1,5.1", "51.5,4.5"),
+	}
+
+	got, err := xml.Marshal(NewKML(records))
+	if err != nil {
+		t.Fatalf("unable to marshal KML: %s", err)
+	}
+
+	want := `<kml xmlns="http://www.opengis.net/kml/2.2"><Document>` +
+		`<Placemark id="1"><name>title 1</name><Point><coordinates>5.1,52.1</coordinates></Point></Placemark>` +
+		`<Placemark id="3"><name>title 3</name><MultiGeometry>` +
+		`<Point><coordinates>5.1,52.1</coordinates></Point><Point><coordinates>4.5,51.5</coordinates></Point>` +
+		`</MultiGeometry></Placemark></Document></kml>`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("NewKML() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_geohashBBox(t *testing.T) {
+	tests := []struct {
+		geohash string
+		want    []float64
+	}{
+		{"", []float64{-180, -90, 180, 90}},
+		{"u", []float64{0, 45, 45, 90}},
+		{"u1", []float64{0, 50.625, 11.25, 56.25}},
+		{"a", nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.geohash, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, geohashBBox(tt.geohash)); diff != "" {
+				t.Errorf("geohashBBox() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeGeoClusters(t *testing.T) {
+	body := `{
+		"hits": {"total": {"value": 2}, "hits": []},
+		"aggregations": {
+			"geocluster": {
+				"doc_count": 3,
+				"points": {
+					"doc_count": 3,
+					"cells": {
+						"buckets": [
+							{
+								"key": "u1",
+								"doc_count": 3,
+								"centroid": {"location": {"lat": 52.0, "lon": 5.0}, "count": 3},
+								"docs": {
+									"doc_count": 2,
+									"sample": {
+										"hits": {
+											"total": {"value": 2},
+											"hits": [
+												{"_id": "1", "_source": {"meta": {"hubID": "1"}, "resources": [{"id": "r1", "entries": [{"@value": "first", "tags": ["title"]}]}]}}
+											]
+										}
+									}
+								}
+							}
+						]
+					}
+				}
+			}
+		}
+	}`
+
+	res := &elastic.SearchResult{}
+	if err := json.Unmarshal([]byte(body), res); err != nil {
+		t.Fatalf("unable to decode search result: %s", err)
+	}
+
+	got, err := DecodeGeoClusters(res)
+	if err != nil {
+		t.Fatalf("DecodeGeoClusters() error = %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("DecodeGeoClusters() got %d clusters; want 1", len(got))
+	}
+
+	cluster := got[0]
+
+	if cluster.GeoHash != "u1" || cluster.Count != 2 {
+		t.Errorf("DecodeGeoClusters() got geohash %s with count %d; want u1 with count 2", cluster.GeoHash, cluster.Count)
+	}
+
+	if diff := cmp.Diff(&GeoPoint{Lat: 52, Lon: 5}, cluster.Centroid); diff != "" {
+		t.Errorf("DecodeGeoClusters() centroid mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(cluster.Samples) != 1 || cluster.Samples[0].Summary.GetTitle() != "first" || cluster.Samples[0].Resources != nil {
+		t.Errorf("DecodeGeoClusters() unexpected samples: %#v", cluster.Samples)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"strings"
+
+	"github.com/olivere/elastic/v7"
+	"golang.org/x/text/language"
+)
+
+// languageBoost is the boost of query matches in the preferred language.
+const languageBoost = 2.0
+
+// fallbackLanguage is used for rendering when a field has neither values in
+// the preferred language nor values without a language.
+const fallbackLanguage = "en"
+
+// missingLanguage is the key of the language aggregation for values without a language.
+const missingLanguage = "_none"
+
+// indexLanguages are the languages that are indexed in a language-specific
+// field with a matching analyzer. They must be kept in sync with the 'i18n'
+// fields of the v2 ElasticSearch mapping.
+var indexLanguages = map[string]bool{
+	"nl": true,
+	"en": true,
+	"de": true,
+	"fr": true,
+}
+
+// BaseLanguage returns the lowercase primary language subtag of a language
+// tag, e.g. 'nl' for 'nl-NL'.
+func BaseLanguage(tag string) string {
+	tag = strings.TrimSpace(tag)
+	if i := strings.IndexAny(tag, "-_"); i != -1 {
+		tag = tag[:i]
+	}
+
+	return strings.ToLower(tag)
+}
+
+// IsIndexLanguage returns true when the language has a language-specific search field.
+func IsIndexLanguage(lang string) bool {
+	return indexLanguages[lang]
+}
+
+// ParseAcceptLanguage returns the first index language in order of
+// preference of an Accept-Language header. An empty string is returned when
+// the header contains no index language.
+func ParseAcceptLanguage(header string) string {
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return ""
+	}
+
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		if lang := base.String(); IsIndexLanguage(lang) {
+			return lang
+		}
+	}
+
+	return ""
+}
+
+// languageQuery returns the query that boosts matches in the language-specific
+// full text field of the preferred language.
+func languageQuery(rawQuery, lang string) elastic.Query {
+	return elastic.NewQueryStringQuery(escapeRawQuery(rawQuery)).
+		DefaultOperator("and").
+		Field("full_text_" + lang).
+		Boost(languageBoost)
+}
+
+// setI18N adds the value of literals in an index language to the
+// language-specific field of the ResourceEntry.
+func (re *ResourceEntry) setI18N() {
+	if re.EntryType != literal || re.Value == "" {
+		return
+	}
+
+	lang := BaseLanguage(re.Language)
+	if !IsIndexLanguage(lang) {
+		return
+	}
+
+	re.I18N = map[string]string{lang: re.Value}
+}
+
+// PreferLanguage keeps the literal values of a single language for each
+// multilingual field of the FragmentGraph.
+//
+// For each resource and search label the values are chosen in the order:
+// values in the preferred language and values without a language; values
+// without a language; values in the fallback language; values in the language
+// of the first value. Values of fields with a single language are always kept.
+func (fg *FragmentGraph) PreferLanguage(lang string) {
+	lang = BaseLanguage(lang)
+	if lang == "" {
+		return
+	}
+
+	for _, rsc := range fg.Resources {
+		rsc.Entries = preferLanguageEntries(rsc.Entries, lang)
+	}
+}
+
+func preferLanguageEntries(entries []*ResourceEntry, lang string) []*ResourceEntry {
+	// languages per search label; an empty language is a value without a language
+	labels := map[string]map[string]bool{}
+	first := map[string]string{}
+
+	for _, entry := range entries {
+		if entry.EntryType != literal {
+			continue
+		}
+
+		langs, ok := labels[entry.SearchLabel]
+		if !ok {
+			langs = map[string]bool{}
+			labels[entry.SearchLabel] = langs
+			first[entry.SearchLabel] = BaseLanguage(entry.Language)
+		}
+
+		langs[BaseLanguage(entry.Language)] = true
+	}
+
+	keep := map[string]string{}
+
+	for label, langs := range labels {
+		if len(langs) < 2 {
+			continue
+		}
+
+		switch {
+		case langs[lang]:
+			keep[label] = lang
+		case langs[""]:
+			keep[label] = ""
+		case langs[fallbackLanguage]:
+			keep[label] = fallbackLanguage
+		default:
+			keep[label] = first[label]
+		}
+	}
+
+	if len(keep) == 0 {
+		return entries
+	}
+
+	filtered := make([]*ResourceEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		keepLang, ok := keep[entry.SearchLabel]
+		if ok && entry.EntryType == literal {
+			entryLang := BaseLanguage(entry.Language)
+			if entryLang != keepLang && entryLang != "" {
+				continue
+			}
+		}
+
+		filtered = append(filtered, entry)
+	}
+
+	return filtered
+}
+
+// bucketLanguage returns the base language of a language aggregation bucket
+// or an empty string for values without a language.
+func bucketLanguage(b *elastic.AggregationBucketKeyItem) string {
+	key := KeyAsString(b)
+	if key == missingLanguage {
+		return ""
+	}
+
+	return BaseLanguage(key)
+}
+
+// preferLanguageLinks removes the facet links without a value in the
+// preferred language or without a language when at least one link has a
+// value in the preferred language. The languages contain the languages of
+// the values of each link; an empty string for values without a language.
+// Selected links are always kept.
+func preferLanguageLinks(links []*FacetLink, languages map[string][]string, lang string) []*FacetLink {
+	if lang == "" {
+		return links
+	}
+
+	var found bool
+
+	for _, link := range links {
+		if containsString(languages[link.Value], lang) {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return links
+	}
+
+	filtered := make([]*FacetLink, 0, len(links))
+
+	for _, link := range links {
+		langs := languages[link.Value]
+		if len(langs) == 0 || containsString(langs, lang) || containsString(langs, "") || link.IsSelected {
+			filtered = append(filtered, link)
+		}
+	}
+
+	return filtered
+}
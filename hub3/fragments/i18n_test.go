@@ -0,0 +1,250 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBaseLanguage(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"nl", "nl"},
+		{"nl-NL", "nl"},
+		{"EN_gb", "en"},
+		{" de ", "de"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := BaseLanguage(tt.tag); got != tt.want {
+			t.Errorf("BaseLanguage(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"nl-NL,nl;q=0.9,en;q=0.8", "nl"},
+		{"en;q=0.5, de", "de"},
+		{"es, fr;q=0.3", "fr"},
+		{"es", ""},
+		{"", ""},
+		{"!!invalid", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); got != tt.want {
+			t.Errorf("ParseAcceptLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResourceEntry_setI18N(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry *ResourceEntry
+		want  map[string]string
+	}{
+		{"index language", &ResourceEntry{Value: "schilderij", Language: "nl-NL", EntryType: literal}, map[string]string{"nl": "schilderij"}},
+		{"other language", &ResourceEntry{Value: "pintura", Language: "es", EntryType: literal}, nil},
+		{"no language", &ResourceEntry{Value: "painting", EntryType: literal}, nil},
+		{"resource", &ResourceEntry{ID: "http://example.org/1", Value: "label", Language: "en", EntryType: resource}, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.setI18N()
+
+			if diff := cmp.Diff(tt.want, tt.entry.I18N); diff != "" {
+				t.Errorf("ResourceEntry.setI18N() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFragmentGraph_PreferLanguage(t *testing.T) {
+	newGraph := func() *FragmentGraph {
+		return &FragmentGraph{
+			Resources: []*FragmentResource{
+				{
+					ID: "http://example.org/1",
+					Entries: []*ResourceEntry{
+						{Value: "schilderij", Language: "nl", SearchLabel: "dc_title", EntryType: literal},
+						{Value: "painting", Language: "en", SearchLabel: "dc_title", EntryType: literal},
+						{Value: "Gemälde", Language: "de", SearchLabel: "dc_title", EntryType: literal},
+						{Value: "portret", Language: "nl", SearchLabel: "dc_subject", EntryType: literal},
+						{Value: "portrait", SearchLabel: "dc_subject", EntryType: literal},
+						{Value: "olieverf", Language: "nl", SearchLabel: "dc_format", EntryType: literal},
+						{ID: "http://example.org/rembrandt", SearchLabel: "dc_creator", EntryType: resource},
+					},
+				},
+			},
+		}
+	}
+
+	values := func(fg *FragmentGraph) []string {
+		var v []string
+		for _, entry := range fg.Resources[0].Entries {
+			v = append(v, entry.SearchLabel+"="+entry.Value+entry.ID)
+		}
+
+		return v
+	}
+
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{
+			"nl",
+			[]string{
+				"dc_title=schilderij",
+				"dc_subject=portret",
+				"dc_subject=portrait",
+				"dc_format=olieverf",
+				"dc_creator=http://example.org/rembrandt",
+			},
+		},
+		{
+			"de-DE",
+			[]string{
+				"dc_title=Gemälde",
+				"dc_subject=portrait",
+				"dc_format=olieverf",
+				"dc_creator=http://example.org/rembrandt",
+			},
+		},
+		{
+			"fr",
+			[]string{
+				"dc_title=painting",
+				"dc_subject=portrait",
+				"dc_format=olieverf",
+				"dc_creator=http://example.org/rembrandt",
+			},
+		},
+		{
+			"",
+			values(newGraph()),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.lang, func(t *testing.T) {
+			fg := newGraph()
+			fg.PreferLanguage(tt.lang)
+
+			if diff := cmp.Diff(tt.want, values(fg)); diff != "" {
+				t.Errorf("FragmentGraph.PreferLanguage() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_preferLanguageLinks(t *testing.T) {
+	links := []*FacetLink{
+		{Value: "schilderij"},
+		{Value: "painting"},
+		{Value: "portrait"},
+		{Value: "Gemälde", IsSelected: true},
+	}
+
+	languages := map[string][]string{
+		"schilderij": {"nl"},
+		"painting":   {"en"},
+		"portrait":   {"en", ""},
+		"Gemälde":    {"de"},
+	}
+
+	linkValues := func(links []*FacetLink) []string {
+		var v []string
+		for _, l := range links {
+			v = append(v, l.Value)
+		}
+
+		return v
+	}
+
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{"nl", []string{"schilderij", "portrait", "Gemälde"}},
+		{"fr", []string{"schilderij", "painting", "portrait", "Gemälde"}},
+		{"", []string{"schilderij", "painting", "portrait", "Gemälde"}},
+	}
+
+	for _, tt := range tests {
+		got := linkValues(preferLanguageLinks(links, languages, tt.lang))
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("preferLanguageLinks(%q) mismatch (-want +got):\n%s", tt.lang, diff)
+		}
+	}
+}
+
+func TestSearchRequest_languageQuery(t *testing.T) {
+	sr, err := NewSearchRequest(url.Values{"query": {"schilderij"}, "lang": {"nl-NL"}})
+	if err != nil {
+		t.Fatalf("NewSearchRequest() unexpected error = %v", err)
+	}
+
+	if sr.GetLang() != "nl" {
+		t.Errorf("NewSearchRequest() lang = %q, want nl", sr.GetLang())
+	}
+
+	q, err := sr.ElasticQuery()
+	if err != nil {
+		t.Fatalf("SearchRequest.ElasticQuery() unexpected error = %v", err)
+	}
+
+	src, err := q.Source()
+	if err != nil {
+		t.Fatalf("Source() unexpected error = %v", err)
+	}
+
+	b, err := json.Marshal(src)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error = %v", err)
+	}
+
+	if !strings.Contains(string(b), `"full_text_nl"`) {
+		t.Errorf("SearchRequest.ElasticQuery() should boost full_text_nl; got %s", b)
+	}
+
+	sr.Lang = "es"
+
+	q, _ = sr.ElasticQuery()
+	src, _ = q.Source()
+	b, _ = json.Marshal(src)
+
+	if strings.Contains(string(b), `"should"`) {
+		t.Errorf("SearchRequest.ElasticQuery() should not boost languages without a search field; got %s", b)
+	}
+}
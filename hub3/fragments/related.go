@@ -0,0 +1,266 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	c "github.com/delving/hub3/config"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// Strategies used to find related records.
+const (
+	RelatedMoreLikeThis = "moreLikeThis"
+	RelatedSharedURI    = "sharedURI"
+)
+
+const (
+	defaultRelatedSize = 10
+	maxRelatedSize     = 50
+	// maxRelatedURIs limits the number of resource references in the shared-URI query.
+	maxRelatedURIs = 100
+)
+
+// DefaultRelatedFields are the search labels used for more_like_this when none are configured.
+var DefaultRelatedFields = []string{"dc_subject", "dc_creator", "dcterms_spatial", "dc_type"}
+
+// RelatedRequest is the request for records related to a record.
+type RelatedRequest struct {
+	// HubID is the record the related records are searched for.
+	HubID string
+	// Fields are the search labels used for the more_like_this query.
+	Fields []string
+	// Specs limits the related records to these datasets.
+	Specs []string
+	// OrgID limits the related records to the organization.
+	OrgID string
+	// Size is the maximum number of related records.
+	Size int
+}
+
+// NewRelatedRequest creates a RelatedRequest from the URL query parameters.
+//
+// The following parameters are supported:
+//
+// field: search label used for more_like_this; repeatable or comma separated (default: configured relatedFields)
+// dataset or spec: limit to the dataset; repeatable
+// orgID: limit to the organization (default: the configured orgID)
+// rows: the number of related records (default: 10, max: 50)
+func NewRelatedRequest(hubID string, params url.Values) (*RelatedRequest, error) {
+	rr := &RelatedRequest{
+		HubID:  hubID,
+		Fields: c.Config.ElasticSearch.RelatedFields,
+		OrgID:  c.Config.OrgID,
+		Size:   defaultRelatedSize,
+	}
+
+	if len(rr.Fields) == 0 {
+		rr.Fields = DefaultRelatedFields
+	}
+
+	if fields := splitParams(params["field"]); len(fields) != 0 {
+		rr.Fields = fields
+	}
+
+	rr.Specs = append(splitParams(params["dataset"]), splitParams(params["spec"])...)
+
+	if orgID := params.Get("orgID"); orgID != "" {
+		rr.OrgID = orgID
+	}
+
+	if rows := params.Get("rows"); rows != "" {
+		size, err := strconv.Atoi(rows)
+		if err != nil || size < 1 {
+			return nil, fmt.Errorf("invalid rows %q", rows)
+		}
+
+		rr.Size = size
+	}
+
+	if rr.Size > maxRelatedSize {
+		rr.Size = maxRelatedSize
+	}
+
+	return rr, nil
+}
+
+func splitParams(values []string) []string {
+	var out []string
+
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+
+	return out
+}
+
+// MoreLikeThisQuery returns a more_like_this query on the values of the
+// configured fields of the record. When the record has no values for the
+// fields false is returned.
+func (rr *RelatedRequest) MoreLikeThisQuery(fg *FragmentGraph) (elastic.Query, bool) {
+	values := fg.labelValues(rr.Fields...)
+
+	q := elastic.NewBoolQuery().MinimumNumberShouldMatch(1)
+
+	var found bool
+
+	for _, field := range rr.Fields {
+		like := values[field]
+		if len(like) == 0 {
+			continue
+		}
+
+		found = true
+
+		mlt := elastic.NewMoreLikeThisQuery().
+			Field("resources.entries.@value").
+			LikeText(like...).
+			MinTermFreq(1).
+			MinDocFreq(1).
+			MaxQueryTerms(25)
+
+		q = q.Should(
+			elastic.NewNestedQuery(
+				"resources.entries",
+				elastic.NewBoolQuery().
+					Must(elastic.NewTermQuery("resources.entries.searchLabel", field)).
+					Must(mlt),
+			),
+		)
+	}
+
+	return q, found
+}
+
+// SharedURIQuery returns a query for records that reference the same
+// resources as the record. When the record has no references false is returned.
+func (rr *RelatedRequest) SharedURIQuery(fg *FragmentGraph) (elastic.Query, bool) {
+	uris := fg.resourceReferences()
+	if len(uris) == 0 {
+		return nil, false
+	}
+
+	if len(uris) > maxRelatedURIs {
+		uris = uris[:maxRelatedURIs]
+	}
+
+	terms := make([]interface{}, 0, len(uris))
+	for _, uri := range uris {
+		terms = append(terms, uri)
+	}
+
+	return elastic.NewNestedQuery(
+		"resources.entries",
+		elastic.NewTermsQuery("resources.entries.@id", terms...),
+	), true
+}
+
+// SearchBody returns the ElasticSearch request body for the query with the
+// dataset and organization filters applied and the record itself excluded.
+func (rr *RelatedRequest) SearchBody(q elastic.Query) ([]byte, error) {
+	bq := elastic.NewBoolQuery().
+		Must(q).
+		MustNot(elastic.NewTermQuery("meta.hubID", rr.HubID))
+
+	if rr.OrgID != "" {
+		bq = bq.Filter(elastic.NewTermQuery(c.Config.ElasticSearch.OrgIDKey, rr.OrgID))
+	}
+
+	if len(rr.Specs) != 0 {
+		specs := make([]interface{}, 0, len(rr.Specs))
+		for _, spec := range rr.Specs {
+			specs = append(specs, spec)
+		}
+
+		bq = bq.Filter(elastic.NewTermsQuery(c.Config.ElasticSearch.SpecKey, specs...))
+	}
+
+	src, err := elastic.NewSearchSource().
+		Query(bq).
+		Size(rr.Size).
+		Source()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create related search source; %w", err)
+	}
+
+	return json.Marshal(src)
+}
+
+// labelValues returns the literal values of the resource entries per search label.
+func (fg *FragmentGraph) labelValues(labels ...string) map[string][]string {
+	wanted := map[string]bool{}
+	for _, label := range labels {
+		wanted[label] = true
+	}
+
+	values := map[string][]string{}
+	seen := map[string]bool{}
+
+	for _, rsc := range fg.Resources {
+		for _, entry := range rsc.Entries {
+			if !wanted[entry.SearchLabel] || entry.Value == "" {
+				continue
+			}
+
+			key := entry.SearchLabel + "\x00" + entry.Value
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+
+			values[entry.SearchLabel] = append(values[entry.SearchLabel], entry.Value)
+		}
+	}
+
+	return values
+}
+
+// resourceReferences returns the unique URIs of the resources the record refers to.
+// Blank nodes and the resources of the record itself are ignored.
+func (fg *FragmentGraph) resourceReferences() []string {
+	own := map[string]bool{}
+	for _, rsc := range fg.Resources {
+		own[rsc.ID] = true
+	}
+
+	var uris []string
+
+	seen := map[string]bool{}
+
+	for _, rsc := range fg.Resources {
+		for _, entry := range rsc.Entries {
+			id := entry.ID
+			if id == "" || own[id] || seen[id] || strings.HasPrefix(id, "_:") {
+				continue
+			}
+
+			seen[id] = true
+
+			uris = append(uris, id)
+		}
+	}
+
+	return uris
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	c "github.com/delving/hub3/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func relatedGraph() *FragmentGraph {
+	return &FragmentGraph{
+		Meta: &Header{HubID: "hub3_spec_1", Spec: "spec", OrgID: "hub3"},
+		Resources: []*FragmentResource{
+			{
+				ID: "http://example.org/1",
+				Entries: []*ResourceEntry{
+					{Value: "portrait", SearchLabel: "dc_subject"},
+					{Value: "portrait", SearchLabel: "dc_subject"},
+					{Value: "Rembrandt", SearchLabel: "dc_creator", ID: "http://example.org/person/rembrandt"},
+					{Value: "title", SearchLabel: "dc_title"},
+					{ID: "http://example.org/1/agg", SearchLabel: "ore_aggregation"},
+					{ID: "_:b0", SearchLabel: "dc_coverage"},
+				},
+			},
+			{ID: "http://example.org/1/agg"},
+		},
+	}
+}
+
+func TestNewRelatedRequest(t *testing.T) {
+	orgID := c.Config.OrgID
+	defer func() { c.Config.OrgID = orgID }()
+
+	c.Config.OrgID = "hub3"
+
+	tests := []struct {
+		name    string
+		params  url.Values
+		want    *RelatedRequest
+		wantErr bool
+	}{
+		{
+			"defaults",
+			url.Values{},
+			&RelatedRequest{HubID: "id", Fields: DefaultRelatedFields, OrgID: "hub3", Size: 10},
+			false,
+		},
+		{
+			"with filters",
+			url.Values{"field": {"dc_subject,dc_creator"}, "dataset": {"a"}, "spec": {"b"}, "orgID": {"other"}, "rows": {"100"}},
+			&RelatedRequest{HubID: "id", Fields: []string{"dc_subject", "dc_creator"}, Specs: []string{"a", "b"}, OrgID: "other", Size: 50},
+			false,
+		},
+		{
+			"invalid rows",
+			url.Values{"rows": {"none"}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRelatedRequest("id", tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewRelatedRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewRelatedRequest() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFragmentGraph_labelValues(t *testing.T) {
+	fg := relatedGraph()
+
+	wantValues := map[string][]string{
+		"dc_subject": {"portrait"},
+		"dc_creator": {"Rembrandt"},
+	}
+	if diff := cmp.Diff(wantValues, fg.labelValues("dc_subject", "dc_creator", "dc_type")); diff != "" {
+		t.Errorf("labelValues() mismatch (-want +got):\n%s", diff)
+	}
+
+	// blank nodes and resources of the record itself are not shared references
+	wantURIs := []string{"http://example.org/person/rembrandt"}
+	if diff := cmp.Diff(wantURIs, fg.resourceReferences()); diff != "" {
+		t.Errorf("resourceReferences() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRelatedRequest_SearchBody(t *testing.T) {
+	orgIDKey, specKey := c.Config.ElasticSearch.OrgIDKey, c.Config.ElasticSearch.SpecKey
+	defer func() { c.Config.ElasticSearch.OrgIDKey, c.Config.ElasticSearch.SpecKey = orgIDKey, specKey }()
+
+	c.Config.ElasticSearch.OrgIDKey = "meta.orgID"
+	c.Config.ElasticSearch.SpecKey = "meta.spec"
+
+	rr := &RelatedRequest{HubID: "hub3_spec_1", Fields: []string{"dc_subject", "dc_type"}, Specs: []string{"spec"}, OrgID: "hub3", Size: 5}
+	fg := relatedGraph()
+
+	q, ok := rr.SharedURIQuery(fg)
+	if !ok {
+		t.Fatal("SharedURIQuery() expected a query")
+	}
+
+	body, err := rr.SearchBody(q)
+	if err != nil {
+		t.Fatalf("SearchBody() error = %v", err)
+	}
+
+	var got interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unable to unmarshal body: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"size": float64(5),
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"meta.orgID": "hub3"}},
+					map[string]interface{}{"terms": map[string]interface{}{"meta.spec": []interface{}{"spec"}}},
+				},
+				"must": map[string]interface{}{
+					"nested": map[string]interface{}{
+						"path": "resources.entries",
+						"query": map[string]interface{}{
+							"terms": map[string]interface{}{
+								"resources.entries.@id": []interface{}{"http://example.org/person/rembrandt"},
+							},
+						},
+					},
+				},
+				"must_not": map[string]interface{}{"term": map[string]interface{}{"meta.hubID": "hub3_spec_1"}},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("SearchBody() mismatch (-want +got):\n%s", diff)
+	}
+
+	mlt, ok := rr.MoreLikeThisQuery(fg)
+	if !ok {
+		t.Fatal("MoreLikeThisQuery() expected a query")
+	}
+
+	src, err := mlt.Source()
+	if err != nil {
+		t.Fatalf("Source() error = %v", err)
+	}
+
+	// only dc_subject has values
+	should := src.(map[string]interface{})["bool"].(map[string]interface{})["should"]
+	if _, isMap := should.(map[string]interface{}); !isMap {
+		t.Errorf("MoreLikeThisQuery() expected a single should clause; got %#v", should)
+	}
+
+	if _, ok := rr.MoreLikeThisQuery(&FragmentGraph{}); ok {
+		t.Error("MoreLikeThisQuery() expected no query for a record without values")
+	}
+}
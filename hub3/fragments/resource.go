@@ -29,6 +29,7 @@ import (
 	c "github.com/delving/hub3/config"
 	"github.com/delving/hub3/hub3/index"
 	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/delving/hub3/ikuzo/service/x/search"
 	"github.com/delving/hub3/ikuzo/storage/x/memory"
 	r "github.com/kiivihal/rdf2go"
 	elastic "github.com/olivere/elastic/v7"
@@ -352,6 +353,7 @@ type FragmentGraph struct {
 	Fields     map[string][]string       `json:"fields,omitempty"`
 	Highlights []*ResourceEntryHighlight `json:"highlights,omitempty"`
 	ProtoBuf   ProtoBuf                  `json:"protobuf,omitempty"`
+	View       *DetailView               `json:"view,omitempty"`
 }
 
 func (fg *FragmentGraph) Marshal() ([]byte, error) {
@@ -415,6 +417,8 @@ type ScrollPager struct {
 	Cursor           int32  `json:"cursor"`
 	Total            int64  `json:"total"`
 	Rows             int32  `json:"rows"`
+	// NextCursor is the signed cursor for the next page of a search_after walk
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // ProtoBuf holds a protobuf encode version of the messageType.
@@ -425,16 +429,17 @@ type ProtoBuf struct {
 
 // ScrollResultV4 intermediate non-protobuf search results
 type ScrollResultV4 struct {
-	Pager      *ScrollPager       `json:"pager"`
-	Query      *Query             `json:"query"`
-	Items      []*FragmentGraph   `json:"items,omitempty"`
-	Collapsed  []*Collapsed       `json:"collapse,omitempty"`
-	Peek       map[string]int64   `json:"peek,omitempty"`
-	Facets     []*QueryFacet      `json:"facets,omitempty"`
-	TreeHeader *TreeHeader        `json:"treeHeader,omitempty"`
-	Tree       []*Tree            `json:"tree,omitempty"`
-	TreePage   map[string][]*Tree `json:"treePage,omitempty"`
-	ProtoBuf   *ProtoBuf          `json:"protobuf,omitempty"`
+	Pager      *ScrollPager        `json:"pager"`
+	Query      *Query              `json:"query"`
+	Items      []*FragmentGraph    `json:"items,omitempty"`
+	Collapsed  []*Collapsed        `json:"collapse,omitempty"`
+	Peek       map[string]int64    `json:"peek,omitempty"`
+	Facets     []*QueryFacet       `json:"facets,omitempty"`
+	TreeHeader *TreeHeader         `json:"treeHeader,omitempty"`
+	Tree       []*Tree             `json:"tree,omitempty"`
+	TreePage   map[string][]*Tree  `json:"treePage,omitempty"`
+	ProtoBuf   *ProtoBuf           `json:"protobuf,omitempty"`
+	Explain    *search.Explanation `json:"explain,omitempty"`
 }
 
 // TreeHeader contains rendering hints for the consumer of the TreeView API.
@@ -766,6 +771,8 @@ func (fe *FragmentEntry) NewResourceEntry(predicate string, level int32, rm *Res
 		re.AddTags("resolved")
 	}
 
+	re.setI18N()
+
 	switch re.DataType {
 	case "http://www.w3.org/2001/XMLSchema#integer":
 		i, err := strconv.Atoi(re.Value)
@@ -807,6 +814,8 @@ func (fe *FragmentEntry) NewResourceEntry(predicate string, level int32, rm *Res
 					}
 				case "latLong":
 					re.LatLong = re.Value
+				case "phonetic":
+					re.Phonetic = phoneticKeys(re.Value)
 				case "integer":
 					i, err := strconv.Atoi(re.Value)
 					if err != nil {
@@ -822,6 +831,25 @@ func (fe *FragmentEntry) NewResourceEntry(predicate string, level int32, rm *Res
 	return re, nil
 }
 
+// phoneticKeys returns the unique phonetic keys for each word in the value.
+func phoneticKeys(value string) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+
+	for _, word := range strings.Fields(value) {
+		key := search.PhoneticKey(word)
+		if key == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
 // CreateDateRange creates a date indexRange
 func CreateDateRange(period string) (IndexRange, error) {
 	ir := IndexRange{}
@@ -1002,6 +1030,8 @@ type ResourceEntry struct {
 	Float       float64           `json:"float,omitempty"`
 	IntRange    *IndexRange       `json:"intRange,omitempty"`
 	LatLong     string            `json:"latLong,omitempty"`
+	Phonetic    []string          `json:"phonetic,omitempty"`
+	I18N        map[string]string `json:"i18n,omitempty"`
 	Inline      *FragmentResource `json:"inline,omitempty"`
 	Order       int               `json:"order"`
 }
@@ -1358,6 +1388,12 @@ func (fg *FragmentGraph) NewResultSummary() *ResultSummary {
 	return fg.Summary
 }
 
+type hlEntry struct {
+	searchLabel string
+	docID       int
+	text        string
+}
+
 // NewFields returns a map of the triples sorted by their searchLabel
 func (fg *FragmentGraph) NewFields(tq *memory.TextQuery, fields ...string) map[string][]string {
 	if tq != nil {
@@ -1405,12 +1441,6 @@ func (fg *FragmentGraph) NewFields(tq *memory.TextQuery, fields ...string) map[s
 	}
 	fg.Fields = make(map[string][]string)
 
-	type hlEntry struct {
-		searchLabel string
-		docID       int
-		text        string
-	}
-
 	hlFields := []hlEntry{}
 
 	for searchLabel, rawFields := range fieldMap {
@@ -1464,9 +1494,39 @@ func (fg *FragmentGraph) NewFields(tq *memory.TextQuery, fields ...string) map[s
 	}
 	fg.Fields = flatFields
 
+	if tq != nil && tq.SnippetConfig != nil {
+		fg.Highlights = append(fg.Highlights, newSnippetHighlights(tq, hlFields)...)
+	}
+
 	return fg.Fields
 }
 
+// newSnippetHighlights creates the keyword-in-context snippets for the fields
+// in the same format as the ElasticSearch highlights.
+func newSnippetHighlights(tq *memory.TextQuery, fields []hlEntry) []*ResourceEntryHighlight {
+	highlights := []*ResourceEntryHighlight{}
+	labels := map[string]*ResourceEntryHighlight{}
+
+	for _, field := range fields {
+		snippets, ok := tq.Snippets(field.text, field.docID)
+		if !ok {
+			continue
+		}
+
+		hl, ok := labels[field.searchLabel]
+		if !ok {
+			hl = &ResourceEntryHighlight{SearchLabel: field.searchLabel}
+			labels[field.searchLabel] = hl
+
+			highlights = append(highlights, hl)
+		}
+
+		hl.MarkDown = append(hl.MarkDown, snippets...)
+	}
+
+	return highlights
+}
+
 // NewTree returns the output as navigation tree
 func (fg *FragmentGraph) NewTree() *Tree {
 	return fg.Tree
@@ -0,0 +1,294 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NewSearchResponse converts the ScrollResultV4 to the protobuf SearchResponse.
+// Tree, collapse and peek results are not part of the SearchResponse.
+func NewSearchResponse(result *ScrollResultV4) (*SearchResponse, error) {
+	resp := &SearchResponse{Query: result.Query}
+
+	if p := result.Pager; p != nil {
+		resp.Pager = &SearchPager{
+			PreviousScrollID: p.PreviousScrollID,
+			NextScrollID:     p.NextScrollID,
+			Cursor:           p.Cursor,
+			Total:            p.Total,
+			Rows:             p.Rows,
+			NextCursor:       p.NextCursor,
+		}
+	}
+
+	for _, item := range result.Items {
+		hit, err := NewSearchHit(item)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Hits = append(resp.Hits, hit)
+	}
+
+	for _, facet := range result.Facets {
+		sf := &SearchFacet{
+			Name:        facet.Name,
+			Field:       facet.Field,
+			IsSelected:  facet.IsSelected,
+			I18N:        facet.I18n,
+			Total:       facet.Total,
+			MissingDocs: facet.MissingDocs,
+			OtherDocs:   facet.OtherDocs,
+			Min:         facet.Min,
+			Max:         facet.Max,
+			Type:        facet.Type,
+		}
+
+		for _, link := range facet.Links {
+			sf.Links = append(sf.Links, &SearchFacetLink{
+				Url:           link.URL,
+				IsSelected:    link.IsSelected,
+				Value:         link.Value,
+				DisplayString: link.DisplayString,
+				Count:         link.Count,
+			})
+		}
+
+		resp.Facets = append(resp.Facets, sf)
+	}
+
+	return resp, nil
+}
+
+// ScrollResult converts the SearchResponse back to a ScrollResultV4.
+func (x *SearchResponse) ScrollResult() (*ScrollResultV4, error) {
+	result := &ScrollResultV4{Query: x.GetQuery()}
+
+	if p := x.GetPager(); p != nil {
+		result.Pager = &ScrollPager{
+			PreviousScrollID: p.GetPreviousScrollID(),
+			NextScrollID:     p.GetNextScrollID(),
+			Cursor:           p.GetCursor(),
+			Total:            p.GetTotal(),
+			Rows:             p.GetRows(),
+			NextCursor:       p.GetNextCursor(),
+		}
+	}
+
+	for _, hit := range x.GetHits() {
+		fg, err := hit.FragmentGraph()
+		if err != nil {
+			return nil, err
+		}
+
+		result.Items = append(result.Items, fg)
+	}
+
+	for _, sf := range x.GetFacets() {
+		facet := &QueryFacet{
+			Name:        sf.GetName(),
+			Field:       sf.GetField(),
+			IsSelected:  sf.GetIsSelected(),
+			I18n:        sf.GetI18N(),
+			Total:       sf.GetTotal(),
+			MissingDocs: sf.GetMissingDocs(),
+			OtherDocs:   sf.GetOtherDocs(),
+			Min:         sf.GetMin(),
+			Max:         sf.GetMax(),
+			Type:        sf.GetType(),
+		}
+
+		for _, link := range sf.GetLinks() {
+			facet.Links = append(facet.Links, &FacetLink{
+				URL:           link.GetUrl(),
+				IsSelected:    link.GetIsSelected(),
+				Value:         link.GetValue(),
+				DisplayString: link.GetDisplayString(),
+				Count:         link.GetCount(),
+			})
+		}
+
+		result.Facets = append(result.Facets, facet)
+	}
+
+	return result, nil
+}
+
+// NewSearchHit converts the FragmentGraph to the protobuf SearchHit.
+func NewSearchHit(fg *FragmentGraph) (*SearchHit, error) {
+	hit := &SearchHit{
+		Meta:    fg.Meta,
+		Summary: fg.Summary,
+	}
+
+	if len(fg.Fields) != 0 {
+		hit.Fields = make(map[string]*SearchFieldValues, len(fg.Fields))
+		for k, v := range fg.Fields {
+			hit.Fields[k] = &SearchFieldValues{Values: v}
+		}
+	}
+
+	for _, rsc := range fg.Resources {
+		hit.Resources = append(hit.Resources, newSearchResource(rsc))
+	}
+
+	for _, hl := range fg.Highlights {
+		hit.Highlights = append(hit.Highlights, &SearchHighlight{
+			SearchLabel: hl.SearchLabel,
+			MarkDown:    hl.MarkDown,
+		})
+	}
+
+	if len(fg.JSONLD) != 0 {
+		b, err := json.Marshal(fg.JSONLD)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal json-ld for %s; %w", fg.Meta.GetHubID(), err)
+		}
+
+		hit.Jsonld = b
+	}
+
+	return hit, nil
+}
+
+// FragmentGraph converts the SearchHit back to a FragmentGraph.
+func (x *SearchHit) FragmentGraph() (*FragmentGraph, error) {
+	fg := &FragmentGraph{
+		Meta:    x.GetMeta(),
+		Summary: x.GetSummary(),
+	}
+
+	if len(x.GetFields()) != 0 {
+		fg.Fields = make(map[string][]string, len(x.GetFields()))
+		for k, v := range x.GetFields() {
+			fg.Fields[k] = v.GetValues()
+		}
+	}
+
+	for _, rsc := range x.GetResources() {
+		fg.Resources = append(fg.Resources, rsc.fragmentResource())
+	}
+
+	for _, hl := range x.GetHighlights() {
+		fg.Highlights = append(fg.Highlights, &ResourceEntryHighlight{
+			SearchLabel: hl.GetSearchLabel(),
+			MarkDown:    hl.GetMarkDown(),
+		})
+	}
+
+	if len(x.GetJsonld()) != 0 {
+		if err := json.Unmarshal(x.GetJsonld(), &fg.JSONLD); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal json-ld for %s; %w", x.GetMeta().GetHubID(), err)
+		}
+	}
+
+	return fg, nil
+}
+
+func newSearchResource(fr *FragmentResource) *SearchResource {
+	if fr == nil {
+		return nil
+	}
+
+	rsc := &SearchResource{
+		Id:                   fr.ID,
+		Types:                fr.Types,
+		GraphExternalContext: fr.GraphExternalContext,
+		Context:              fr.Context,
+		Tags:                 fr.Tags,
+	}
+
+	for _, entry := range fr.Entries {
+		rsc.Entries = append(rsc.Entries, &SearchEntry{
+			Id:          entry.ID,
+			Value:       entry.Value,
+			Language:    entry.Language,
+			DataType:    entry.DataType,
+			EntryType:   entry.EntryType,
+			Predicate:   entry.Predicate,
+			SearchLabel: entry.SearchLabel,
+			Level:       entry.Level,
+			Tags:        entry.Tags,
+			IsoDate:     entry.Date,
+			DateRange:   newSearchRange(entry.DateRange),
+			Integer:     int64(entry.Integer),
+			Float:       entry.Float,
+			IntRange:    newSearchRange(entry.IntRange),
+			LatLong:     entry.LatLong,
+			Phonetic:    entry.Phonetic,
+			Inline:      newSearchResource(entry.Inline),
+			Order:       int32(entry.Order),
+		})
+	}
+
+	return rsc
+}
+
+func (x *SearchResource) fragmentResource() *FragmentResource {
+	if x == nil {
+		return nil
+	}
+
+	fr := &FragmentResource{
+		ID:                   x.GetId(),
+		Types:                x.GetTypes(),
+		GraphExternalContext: x.GetGraphExternalContext(),
+		Context:              x.GetContext(),
+		Tags:                 x.GetTags(),
+	}
+
+	for _, entry := range x.GetEntries() {
+		fr.Entries = append(fr.Entries, &ResourceEntry{
+			ID:          entry.GetId(),
+			Value:       entry.GetValue(),
+			Language:    entry.GetLanguage(),
+			DataType:    entry.GetDataType(),
+			EntryType:   entry.GetEntryType(),
+			Predicate:   entry.GetPredicate(),
+			SearchLabel: entry.GetSearchLabel(),
+			Level:       entry.GetLevel(),
+			Tags:        entry.GetTags(),
+			Date:        entry.GetIsoDate(),
+			DateRange:   entry.GetDateRange().indexRange(),
+			Integer:     int(entry.GetInteger()),
+			Float:       entry.GetFloat(),
+			IntRange:    entry.GetIntRange().indexRange(),
+			LatLong:     entry.GetLatLong(),
+			Phonetic:    entry.GetPhonetic(),
+			Inline:      entry.GetInline().fragmentResource(),
+			Order:       int(entry.GetOrder()),
+		})
+	}
+
+	return fr
+}
+
+func newSearchRange(ir *IndexRange) *SearchRange {
+	if ir == nil {
+		return nil
+	}
+
+	return &SearchRange{Gte: ir.Greater, Lte: ir.Less}
+}
+
+func (x *SearchRange) indexRange() *IndexRange {
+	if x == nil {
+		return nil
+	}
+
+	return &IndexRange{Greater: x.GetGte(), Less: x.GetLte()}
+}
@@ -0,0 +1,1287 @@
+// Copyright 2017 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Run 'make protobuffer' from the root directory to generate 'searchresponse.pb.go'.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.24.0
+// 	protoc        v3.11.4
+// source: hub3/fragments/searchresponse.proto
+
+package fragments
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+// SearchResponse is the protobuf version of the v2 search result.
+type SearchResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Pager  *SearchPager   `protobuf:"bytes,1,opt,name=pager,proto3" json:"pager,omitempty"`
+	Query  *Query         `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	Hits   []*SearchHit   `protobuf:"bytes,3,rep,name=hits,proto3" json:"hits,omitempty"`
+	Facets []*SearchFacet `protobuf:"bytes,4,rep,name=facets,proto3" json:"facets,omitempty"`
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SearchResponse) GetPager() *SearchPager {
+	if x != nil {
+		return x.Pager
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetQuery() *Query {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetHits() []*SearchHit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetFacets() []*SearchFacet {
+	if x != nil {
+		return x.Facets
+	}
+	return nil
+}
+
+// SearchPager holds all paging information for a search result.
+type SearchPager struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PreviousScrollID string `protobuf:"bytes,1,opt,name=previousScrollID,proto3" json:"previousScrollID,omitempty"`
+	NextScrollID     string `protobuf:"bytes,2,opt,name=nextScrollID,proto3" json:"nextScrollID,omitempty"`
+	Cursor           int32  `protobuf:"varint,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Total            int64  `protobuf:"varint,4,opt,name=total,proto3" json:"total,omitempty"`
+	Rows             int32  `protobuf:"varint,5,opt,name=rows,proto3" json:"rows,omitempty"`
+	NextCursor       string `protobuf:"bytes,6,opt,name=nextCursor,proto3" json:"nextCursor,omitempty"`
+}
+
+func (x *SearchPager) Reset() {
+	*x = SearchPager{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchPager) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPager) ProtoMessage() {}
+
+func (x *SearchPager) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPager.ProtoReflect.Descriptor instead.
+func (*SearchPager) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SearchPager) GetPreviousScrollID() string {
+	if x != nil {
+		return x.PreviousScrollID
+	}
+	return ""
+}
+
+func (x *SearchPager) GetNextScrollID() string {
+	if x != nil {
+		return x.NextScrollID
+	}
+	return ""
+}
+
+func (x *SearchPager) GetCursor() int32 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *SearchPager) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *SearchPager) GetRows() int32 {
+	if x != nil {
+		return x.Rows
+	}
+	return 0
+}
+
+func (x *SearchPager) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+// SearchHit is a single record in the search result.
+// Depending on the itemFormat only some of the fields are set.
+type SearchHit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Meta       *Header                       `protobuf:"bytes,1,opt,name=meta,proto3" json:"meta,omitempty"`
+	Summary    *ResultSummary                `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`
+	Fields     map[string]*SearchFieldValues `protobuf:"bytes,3,rep,name=fields,proto3" json:"fields,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Resources  []*SearchResource             `protobuf:"bytes,4,rep,name=resources,proto3" json:"resources,omitempty"`
+	Highlights []*SearchHighlight            `protobuf:"bytes,5,rep,name=highlights,proto3" json:"highlights,omitempty"`
+	// JSON-LD serialization of the record
+	Jsonld []byte `protobuf:"bytes,6,opt,name=jsonld,proto3" json:"jsonld,omitempty"`
+}
+
+func (x *SearchHit) Reset() {
+	*x = SearchHit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHit) ProtoMessage() {}
+
+func (x *SearchHit) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHit.ProtoReflect.Descriptor instead.
+func (*SearchHit) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchHit) GetMeta() *Header {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *SearchHit) GetSummary() *ResultSummary {
+	if x != nil {
+		return x.Summary
+	}
+	return nil
+}
+
+func (x *SearchHit) GetFields() map[string]*SearchFieldValues {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *SearchHit) GetResources() []*SearchResource {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *SearchHit) GetHighlights() []*SearchHighlight {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+func (x *SearchHit) GetJsonld() []byte {
+	if x != nil {
+		return x.Jsonld
+	}
+	return nil
+}
+
+// SearchFieldValues holds all values of a searchLabel.
+type SearchFieldValues struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values []string `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *SearchFieldValues) Reset() {
+	*x = SearchFieldValues{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchFieldValues) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchFieldValues) ProtoMessage() {}
+
+func (x *SearchFieldValues) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchFieldValues.ProtoReflect.Descriptor instead.
+func (*SearchFieldValues) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchFieldValues) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+// SearchHighlight holds the highlighted snippets of a searchLabel.
+type SearchHighlight struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SearchLabel string   `protobuf:"bytes,1,opt,name=searchLabel,proto3" json:"searchLabel,omitempty"`
+	MarkDown    []string `protobuf:"bytes,2,rep,name=markDown,proto3" json:"markDown,omitempty"`
+}
+
+func (x *SearchHighlight) Reset() {
+	*x = SearchHighlight{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchHighlight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchHighlight) ProtoMessage() {}
+
+func (x *SearchHighlight) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchHighlight.ProtoReflect.Descriptor instead.
+func (*SearchHighlight) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchHighlight) GetSearchLabel() string {
+	if x != nil {
+		return x.SearchLabel
+	}
+	return ""
+}
+
+func (x *SearchHighlight) GetMarkDown() []string {
+	if x != nil {
+		return x.MarkDown
+	}
+	return nil
+}
+
+// SearchResource is a resource of the record graph.
+type SearchResource struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id                   string                     `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Types                []string                   `protobuf:"bytes,2,rep,name=types,proto3" json:"types,omitempty"`
+	GraphExternalContext []*FragmentReferrerContext `protobuf:"bytes,3,rep,name=graphExternalContext,proto3" json:"graphExternalContext,omitempty"`
+	Context              []*FragmentReferrerContext `protobuf:"bytes,4,rep,name=context,proto3" json:"context,omitempty"`
+	Entries              []*SearchEntry             `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"`
+	Tags                 []string                   `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+}
+
+func (x *SearchResource) Reset() {
+	*x = SearchResource{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchResource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResource) ProtoMessage() {}
+
+func (x *SearchResource) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResource.ProtoReflect.Descriptor instead.
+func (*SearchResource) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchResource) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SearchResource) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *SearchResource) GetGraphExternalContext() []*FragmentReferrerContext {
+	if x != nil {
+		return x.GraphExternalContext
+	}
+	return nil
+}
+
+func (x *SearchResource) GetContext() []*FragmentReferrerContext {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *SearchResource) GetEntries() []*SearchEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *SearchResource) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// SearchEntry is a single predicate and object of a SearchResource.
+type SearchEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id          string          `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Value       string          `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Language    string          `protobuf:"bytes,3,opt,name=language,proto3" json:"language,omitempty"`
+	DataType    string          `protobuf:"bytes,4,opt,name=dataType,proto3" json:"dataType,omitempty"`
+	EntryType   string          `protobuf:"bytes,5,opt,name=entryType,proto3" json:"entryType,omitempty"`
+	Predicate   string          `protobuf:"bytes,6,opt,name=predicate,proto3" json:"predicate,omitempty"`
+	SearchLabel string          `protobuf:"bytes,7,opt,name=searchLabel,proto3" json:"searchLabel,omitempty"`
+	Level       int32           `protobuf:"varint,8,opt,name=level,proto3" json:"level,omitempty"`
+	Tags        []string        `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	IsoDate     []string        `protobuf:"bytes,10,rep,name=isoDate,proto3" json:"isoDate,omitempty"`
+	DateRange   *SearchRange    `protobuf:"bytes,11,opt,name=dateRange,proto3" json:"dateRange,omitempty"`
+	Integer     int64           `protobuf:"varint,12,opt,name=integer,proto3" json:"integer,omitempty"`
+	Float       float64         `protobuf:"fixed64,13,opt,name=float,proto3" json:"float,omitempty"`
+	IntRange    *SearchRange    `protobuf:"bytes,14,opt,name=intRange,proto3" json:"intRange,omitempty"`
+	LatLong     string          `protobuf:"bytes,15,opt,name=latLong,proto3" json:"latLong,omitempty"`
+	Phonetic    []string        `protobuf:"bytes,16,rep,name=phonetic,proto3" json:"phonetic,omitempty"`
+	Inline      *SearchResource `protobuf:"bytes,17,opt,name=inline,proto3" json:"inline,omitempty"`
+	Order       int32           `protobuf:"varint,18,opt,name=order,proto3" json:"order,omitempty"`
+}
+
+func (x *SearchEntry) Reset() {
+	*x = SearchEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchEntry) ProtoMessage() {}
+
+func (x *SearchEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchEntry.ProtoReflect.Descriptor instead.
+func (*SearchEntry) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchEntry) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetDataType() string {
+	if x != nil {
+		return x.DataType
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetEntryType() string {
+	if x != nil {
+		return x.EntryType
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetPredicate() string {
+	if x != nil {
+		return x.Predicate
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetSearchLabel() string {
+	if x != nil {
+		return x.SearchLabel
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *SearchEntry) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetIsoDate() []string {
+	if x != nil {
+		return x.IsoDate
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetDateRange() *SearchRange {
+	if x != nil {
+		return x.DateRange
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetInteger() int64 {
+	if x != nil {
+		return x.Integer
+	}
+	return 0
+}
+
+func (x *SearchEntry) GetFloat() float64 {
+	if x != nil {
+		return x.Float
+	}
+	return 0
+}
+
+func (x *SearchEntry) GetIntRange() *SearchRange {
+	if x != nil {
+		return x.IntRange
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetLatLong() string {
+	if x != nil {
+		return x.LatLong
+	}
+	return ""
+}
+
+func (x *SearchEntry) GetPhonetic() []string {
+	if x != nil {
+		return x.Phonetic
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetInline() *SearchResource {
+	if x != nil {
+		return x.Inline
+	}
+	return nil
+}
+
+func (x *SearchEntry) GetOrder() int32 {
+	if x != nil {
+		return x.Order
+	}
+	return 0
+}
+
+// SearchRange is an inclusive range.
+type SearchRange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Gte string `protobuf:"bytes,1,opt,name=gte,proto3" json:"gte,omitempty"`
+	Lte string `protobuf:"bytes,2,opt,name=lte,proto3" json:"lte,omitempty"`
+}
+
+func (x *SearchRange) Reset() {
+	*x = SearchRange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRange) ProtoMessage() {}
+
+func (x *SearchRange) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRange.ProtoReflect.Descriptor instead.
+func (*SearchRange) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchRange) GetGte() string {
+	if x != nil {
+		return x.Gte
+	}
+	return ""
+}
+
+func (x *SearchRange) GetLte() string {
+	if x != nil {
+		return x.Lte
+	}
+	return ""
+}
+
+// SearchFacet is an aggregation of the values of a field.
+type SearchFacet struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name        string             `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Field       string             `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	IsSelected  bool               `protobuf:"varint,3,opt,name=isSelected,proto3" json:"isSelected,omitempty"`
+	I18N        string             `protobuf:"bytes,4,opt,name=i18n,proto3" json:"i18n,omitempty"`
+	Total       int64              `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"`
+	MissingDocs int64              `protobuf:"varint,6,opt,name=missingDocs,proto3" json:"missingDocs,omitempty"`
+	OtherDocs   int64              `protobuf:"varint,7,opt,name=otherDocs,proto3" json:"otherDocs,omitempty"`
+	Min         string             `protobuf:"bytes,8,opt,name=min,proto3" json:"min,omitempty"`
+	Max         string             `protobuf:"bytes,9,opt,name=max,proto3" json:"max,omitempty"`
+	Type        string             `protobuf:"bytes,10,opt,name=type,proto3" json:"type,omitempty"`
+	Links       []*SearchFacetLink `protobuf:"bytes,11,rep,name=links,proto3" json:"links,omitempty"`
+}
+
+func (x *SearchFacet) Reset() {
+	*x = SearchFacet{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchFacet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchFacet) ProtoMessage() {}
+
+func (x *SearchFacet) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchFacet.ProtoReflect.Descriptor instead.
+func (*SearchFacet) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchFacet) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetIsSelected() bool {
+	if x != nil {
+		return x.IsSelected
+	}
+	return false
+}
+
+func (x *SearchFacet) GetI18N() string {
+	if x != nil {
+		return x.I18N
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *SearchFacet) GetMissingDocs() int64 {
+	if x != nil {
+		return x.MissingDocs
+	}
+	return 0
+}
+
+func (x *SearchFacet) GetOtherDocs() int64 {
+	if x != nil {
+		return x.OtherDocs
+	}
+	return 0
+}
+
+func (x *SearchFacet) GetMin() string {
+	if x != nil {
+		return x.Min
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetMax() string {
+	if x != nil {
+		return x.Max
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SearchFacet) GetLinks() []*SearchFacetLink {
+	if x != nil {
+		return x.Links
+	}
+	return nil
+}
+
+// SearchFacetLink contains all the information for creating a filter for a facet value.
+type SearchFacetLink struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Url           string `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	IsSelected    bool   `protobuf:"varint,2,opt,name=isSelected,proto3" json:"isSelected,omitempty"`
+	Value         string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	DisplayString string `protobuf:"bytes,4,opt,name=displayString,proto3" json:"displayString,omitempty"`
+	Count         int64  `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+}
+
+func (x *SearchFacetLink) Reset() {
+	*x = SearchFacetLink{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_hub3_fragments_searchresponse_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchFacetLink) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchFacetLink) ProtoMessage() {}
+
+func (x *SearchFacetLink) ProtoReflect() protoreflect.Message {
+	mi := &file_hub3_fragments_searchresponse_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchFacetLink.ProtoReflect.Descriptor instead.
+func (*SearchFacetLink) Descriptor() ([]byte, []int) {
+	return file_hub3_fragments_searchresponse_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchFacetLink) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *SearchFacetLink) GetIsSelected() bool {
+	if x != nil {
+		return x.IsSelected
+	}
+	return false
+}
+
+func (x *SearchFacetLink) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *SearchFacetLink) GetDisplayString() string {
+	if x != nil {
+		return x.DisplayString
+	}
+	return ""
+}
+
+func (x *SearchFacetLink) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+var File_hub3_fragments_searchresponse_proto protoreflect.FileDescriptor
+
+var file_hub3_fragments_searchresponse_proto_rawDesc = []byte{
+	0x0a, 0x23, 0x68, 0x75, 0x62, 0x33, 0x2f, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x2f, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x1a, 0x18, 0x68, 0x75, 0x62, 0x33, 0x2f, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x2f, 0x61, 0x70, 0x69, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xc0, 0x01, 0x0a, 0x0e, 0x53,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2c, 0x0a,
+	0x05, 0x70, 0x61, 0x67, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x66,
+	0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x50,
+	0x61, 0x67, 0x65, 0x72, 0x52, 0x05, 0x70, 0x61, 0x67, 0x65, 0x72, 0x12, 0x26, 0x0a, 0x05, 0x71,
+	0x75, 0x65, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x66, 0x72, 0x61,
+	0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x05, 0x71, 0x75,
+	0x65, 0x72, 0x79, 0x12, 0x28, 0x0a, 0x04, 0x68, 0x69, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x14, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x48, 0x69, 0x74, 0x52, 0x04, 0x68, 0x69, 0x74, 0x73, 0x12, 0x2e, 0x0a,
+	0x06, 0x66, 0x61, 0x63, 0x65, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68,
+	0x46, 0x61, 0x63, 0x65, 0x74, 0x52, 0x06, 0x66, 0x61, 0x63, 0x65, 0x74, 0x73, 0x22, 0xbf, 0x01,
+	0x0a, 0x0b, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x50, 0x61, 0x67, 0x65, 0x72, 0x12, 0x2a, 0x0a,
+	0x10, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75, 0x73, 0x53, 0x63, 0x72, 0x6f, 0x6c, 0x6c, 0x49,
+	0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x70, 0x72, 0x65, 0x76, 0x69, 0x6f, 0x75,
+	0x73, 0x53, 0x63, 0x72, 0x6f, 0x6c, 0x6c, 0x49, 0x44, 0x12, 0x22, 0x0a, 0x0c, 0x6e, 0x65, 0x78,
+	0x74, 0x53, 0x63, 0x72, 0x6f, 0x6c, 0x6c, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6e, 0x65, 0x78, 0x74, 0x53, 0x63, 0x72, 0x6f, 0x6c, 0x6c, 0x49, 0x44, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x63,
+	0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x72,
+	0x6f, 0x77, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0a, 0x6e, 0x65, 0x78, 0x74, 0x43, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x22,
+	0x86, 0x03, 0x0a, 0x09, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x48, 0x69, 0x74, 0x12, 0x25, 0x0a,
+	0x04, 0x6d, 0x65, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x66, 0x72,
+	0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x04,
+	0x6d, 0x65, 0x74, 0x61, 0x12, 0x32, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74,
+	0x73, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x52,
+	0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x38, 0x0a, 0x06, 0x66, 0x69, 0x65, 0x6c,
+	0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d,
+	0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x48, 0x69, 0x74, 0x2e, 0x46,
+	0x69, 0x65, 0x6c, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x66, 0x69, 0x65, 0x6c,
+	0x64, 0x73, 0x12, 0x37, 0x0a, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74,
+	0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65,
+	0x52, 0x09, 0x72, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x0a, 0x68,
+	0x69, 0x67, 0x68, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x48, 0x69, 0x67, 0x68, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x52, 0x0a, 0x68, 0x69, 0x67,
+	0x68, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6a, 0x73, 0x6f, 0x6e, 0x6c,
+	0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x6a, 0x73, 0x6f, 0x6e, 0x6c, 0x64, 0x1a,
+	0x57, 0x0a, 0x0b, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x32, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x2b, 0x0a, 0x11, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x16, 0x0a,
+	0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x73, 0x22, 0x4f, 0x0a, 0x0f, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x48,
+	0x69, 0x67, 0x68, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x6d, 0x61,
+	0x72, 0x6b, 0x44, 0x6f, 0x77, 0x6e, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x61,
+	0x72, 0x6b, 0x44, 0x6f, 0x77, 0x6e, 0x22, 0x92, 0x02, 0x0a, 0x0e, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x65, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x79, 0x70,
+	0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x12,
+	0x56, 0x0a, 0x14, 0x67, 0x72, 0x61, 0x70, 0x68, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c,
+	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e,
+	0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65,
+	0x6e, 0x74, 0x52, 0x65, 0x66, 0x65, 0x72, 0x72, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78,
+	0x74, 0x52, 0x14, 0x67, 0x72, 0x61, 0x70, 0x68, 0x45, 0x78, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c,
+	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x3c, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65,
+	0x78, 0x74, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d,
+	0x65, 0x6e, 0x74, 0x73, 0x2e, 0x46, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x66,
+	0x65, 0x72, 0x72, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x78, 0x74, 0x52, 0x07, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x78, 0x74, 0x12, 0x30, 0x0a, 0x07, 0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73,
+	0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e,
+	0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07,
+	0x65, 0x6e, 0x74, 0x72, 0x69, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x61, 0x67, 0x73, 0x18,
+	0x06, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67, 0x73, 0x22, 0xa6, 0x04, 0x0a, 0x0b,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x64, 0x61, 0x74, 0x61, 0x54, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x64, 0x61, 0x74, 0x61, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x65, 0x6e, 0x74,
+	0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x65, 0x6e,
+	0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x65, 0x64, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x70, 0x72, 0x65, 0x64,
+	0x69, 0x63, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x4c,
+	0x61, 0x62, 0x65, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x61, 0x67, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x74, 0x61, 0x67,
+	0x73, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x73, 0x6f, 0x44, 0x61, 0x74, 0x65, 0x18, 0x0a, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x07, 0x69, 0x73, 0x6f, 0x44, 0x61, 0x74, 0x65, 0x12, 0x34, 0x0a, 0x09, 0x64,
+	0x61, 0x74, 0x65, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x09, 0x64, 0x61, 0x74, 0x65, 0x52, 0x61, 0x6e, 0x67,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72, 0x18, 0x0c, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x69, 0x6e, 0x74, 0x65, 0x67, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x66,
+	0x6c, 0x6f, 0x61, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x66, 0x6c, 0x6f, 0x61,
+	0x74, 0x12, 0x32, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x18, 0x0e, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x52, 0x08, 0x69, 0x6e, 0x74,
+	0x52, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6c, 0x61, 0x74, 0x4c, 0x6f, 0x6e, 0x67,
+	0x18, 0x0f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6c, 0x61, 0x74, 0x4c, 0x6f, 0x6e, 0x67, 0x12,
+	0x1a, 0x0a, 0x08, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x74, 0x69, 0x63, 0x18, 0x10, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x08, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x74, 0x69, 0x63, 0x12, 0x31, 0x0a, 0x06, 0x69,
+	0x6e, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x66, 0x72,
+	0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x52, 0x06, 0x69, 0x6e, 0x6c, 0x69, 0x6e, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x18, 0x12, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6f,
+	0x72, 0x64, 0x65, 0x72, 0x22, 0x31, 0x0a, 0x0b, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x61,
+	0x6e, 0x67, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x67, 0x74, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6c, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6c, 0x74, 0x65, 0x22, 0xab, 0x02, 0x0a, 0x0b, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x46, 0x61, 0x63, 0x65, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x66,
+	0x69, 0x65, 0x6c, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x66, 0x69, 0x65, 0x6c,
+	0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x73, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x73, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x69, 0x31, 0x38, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x69, 0x31, 0x38, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x20, 0x0a, 0x0b, 0x6d,
+	0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x44, 0x6f, 0x63, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0b, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x44, 0x6f, 0x63, 0x73, 0x12, 0x1c, 0x0a,
+	0x09, 0x6f, 0x74, 0x68, 0x65, 0x72, 0x44, 0x6f, 0x63, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x09, 0x6f, 0x74, 0x68, 0x65, 0x72, 0x44, 0x6f, 0x63, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x6d,
+	0x69, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x69, 0x6e, 0x12, 0x10, 0x0a,
+	0x03, 0x6d, 0x61, 0x78, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12,
+	0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x12, 0x30, 0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x18, 0x0b, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x53,
+	0x65, 0x61, 0x72, 0x63, 0x68, 0x46, 0x61, 0x63, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x52, 0x05,
+	0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x22, 0x95, 0x01, 0x0a, 0x0f, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68,
+	0x46, 0x61, 0x63, 0x65, 0x74, 0x4c, 0x69, 0x6e, 0x6b, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x1e, 0x0a, 0x0a, 0x69,
+	0x73, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0a, 0x69, 0x73, 0x53, 0x65, 0x6c, 0x65, 0x63, 0x74, 0x65, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x12, 0x24, 0x0a, 0x0d, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x72, 0x69,
+	0x6e, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x64, 0x69, 0x73, 0x70, 0x6c, 0x61,
+	0x79, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x42, 0x10, 0x5a,
+	0x0e, 0x68, 0x75, 0x62, 0x33, 0x2f, 0x66, 0x72, 0x61, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_hub3_fragments_searchresponse_proto_rawDescOnce sync.Once
+	file_hub3_fragments_searchresponse_proto_rawDescData = file_hub3_fragments_searchresponse_proto_rawDesc
+)
+
+func file_hub3_fragments_searchresponse_proto_rawDescGZIP() []byte {
+	file_hub3_fragments_searchresponse_proto_rawDescOnce.Do(func() {
+		file_hub3_fragments_searchresponse_proto_rawDescData = protoimpl.X.CompressGZIP(file_hub3_fragments_searchresponse_proto_rawDescData)
+	})
+	return file_hub3_fragments_searchresponse_proto_rawDescData
+}
+
+var file_hub3_fragments_searchresponse_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_hub3_fragments_searchresponse_proto_goTypes = []interface{}{
+	(*SearchResponse)(nil),          // 0: fragments.SearchResponse
+	(*SearchPager)(nil),             // 1: fragments.SearchPager
+	(*SearchHit)(nil),               // 2: fragments.SearchHit
+	(*SearchFieldValues)(nil),       // 3: fragments.SearchFieldValues
+	(*SearchHighlight)(nil),         // 4: fragments.SearchHighlight
+	(*SearchResource)(nil),          // 5: fragments.SearchResource
+	(*SearchEntry)(nil),             // 6: fragments.SearchEntry
+	(*SearchRange)(nil),             // 7: fragments.SearchRange
+	(*SearchFacet)(nil),             // 8: fragments.SearchFacet
+	(*SearchFacetLink)(nil),         // 9: fragments.SearchFacetLink
+	nil,                             // 10: fragments.SearchHit.FieldsEntry
+	(*Query)(nil),                   // 11: fragments.Query
+	(*Header)(nil),                  // 12: fragments.Header
+	(*ResultSummary)(nil),           // 13: fragments.ResultSummary
+	(*FragmentReferrerContext)(nil), // 14: fragments.FragmentReferrerContext
+}
+var file_hub3_fragments_searchresponse_proto_depIdxs = []int32{
+	1,  // 0: fragments.SearchResponse.pager:type_name -> fragments.SearchPager
+	11, // 1: fragments.SearchResponse.query:type_name -> fragments.Query
+	2,  // 2: fragments.SearchResponse.hits:type_name -> fragments.SearchHit
+	8,  // 3: fragments.SearchResponse.facets:type_name -> fragments.SearchFacet
+	12, // 4: fragments.SearchHit.meta:type_name -> fragments.Header
+	13, // 5: fragments.SearchHit.summary:type_name -> fragments.ResultSummary
+	10, // 6: fragments.SearchHit.fields:type_name -> fragments.SearchHit.FieldsEntry
+	5,  // 7: fragments.SearchHit.resources:type_name -> fragments.SearchResource
+	4,  // 8: fragments.SearchHit.highlights:type_name -> fragments.SearchHighlight
+	14, // 9: fragments.SearchResource.graphExternalContext:type_name -> fragments.FragmentReferrerContext
+	14, // 10: fragments.SearchResource.context:type_name -> fragments.FragmentReferrerContext
+	6,  // 11: fragments.SearchResource.entries:type_name -> fragments.SearchEntry
+	7,  // 12: fragments.SearchEntry.dateRange:type_name -> fragments.SearchRange
+	7,  // 13: fragments.SearchEntry.intRange:type_name -> fragments.SearchRange
+	5,  // 14: fragments.SearchEntry.inline:type_name -> fragments.SearchResource
+	9,  // 15: fragments.SearchFacet.links:type_name -> fragments.SearchFacetLink
+	3,  // 16: fragments.SearchHit.FieldsEntry.value:type_name -> fragments.SearchFieldValues
+	17, // [17:17] is the sub-list for method output_type
+	17, // [17:17] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_hub3_fragments_searchresponse_proto_init() }
+func file_hub3_fragments_searchresponse_proto_init() {
+	if File_hub3_fragments_searchresponse_proto != nil {
+		return
+	}
+	file_hub3_fragments_api_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_hub3_fragments_searchresponse_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchPager); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchHit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchFieldValues); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchHighlight); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchResource); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchRange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchFacet); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_hub3_fragments_searchresponse_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchFacetLink); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_hub3_fragments_searchresponse_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_hub3_fragments_searchresponse_proto_goTypes,
+		DependencyIndexes: file_hub3_fragments_searchresponse_proto_depIdxs,
+		MessageInfos:      file_hub3_fragments_searchresponse_proto_msgTypes,
+	}.Build()
+	File_hub3_fragments_searchresponse_proto = out.File
+	file_hub3_fragments_searchresponse_proto_rawDesc = nil
+	file_hub3_fragments_searchresponse_proto_goTypes = nil
+	file_hub3_fragments_searchresponse_proto_depIdxs = nil
+}
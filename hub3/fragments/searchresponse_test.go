@@ -0,0 +1,135 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSearchResponse_roundTrip(t *testing.T) {
+	flat := exportGraph()
+	flat.NewFields(nil, "dc_title", "dc_creator")
+	flat.Highlights = []*ResourceEntryHighlight{{SearchLabel: "dc_title", MarkDown: []string{"**first** title"}}}
+	flat.Resources = nil
+
+	summary := exportGraph()
+	summary.Meta.HubID = "hub3_test_2"
+	summary.NewResultSummary()
+	summary.Resources = nil
+
+	jsonld := exportGraph()
+	jsonld.Meta.HubID = "hub3_test_3"
+	jsonld.NewJSONLD()
+	jsonld.Resources = nil
+
+	full := exportGraph()
+	full.Meta.HubID = "hub3_test_4"
+	full.Meta.Revision = 3
+	full.Meta.Modified = 1595341640000
+	full.Resources[0].Context = []*FragmentReferrerContext{{Subject: "http://example.org/0", Predicate: "http://example.org/has", Level: 1}}
+	full.Resources[0].Entries = append(full.Resources[0].Entries, &ResourceEntry{
+		Value:     "1900",
+		Predicate: "http://example.org/range",
+		Date:      []string{"1900-01-01"},
+		DateRange: &IndexRange{Greater: "1900-01-01", Less: "1900-12-31"},
+		Integer:   1900,
+		Float:     19.5,
+		IntRange:  &IndexRange{Greater: "1900", Less: "1910"},
+		LatLong:   "52.1,5.1",
+		Phonetic:  []string{"TTL"},
+		Inline: &FragmentResource{
+			ID:      "_:b1",
+			Types:   []string{"http://example.org/Place"},
+			Entries: []*ResourceEntry{{Value: "inline", Level: 2, Order: 7}},
+		},
+		Level: 1,
+		Order: 6,
+	})
+
+	result := &ScrollResultV4{
+		Pager: &ScrollPager{PreviousScrollID: "prev", NextScrollID: "next", NextCursor: "cursor", Cursor: 16, Total: 4, Rows: 16},
+		Query: &Query{
+			Numfound:    4,
+			Terms:       "title",
+			BreadCrumbs: []*BreadCrumb{{Href: "q=title", Display: "title", IsLast: true}},
+		},
+		Items: []*FragmentGraph{flat, summary, jsonld, full},
+		Facets: []*QueryFacet{
+			{
+				Name:        "creator",
+				Field:       "dc_creator",
+				IsSelected:  true,
+				I18n:        "Creator",
+				Total:       4,
+				MissingDocs: 1,
+				OtherDocs:   2,
+				Type:        "Terms",
+				Links:       []*FacetLink{{URL: "qf=dc_creator:x", IsSelected: true, Value: "x", DisplayString: "x (1)", Count: 1}},
+			},
+		},
+	}
+
+	want, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("unable to marshal ScrollResultV4: %s", err)
+	}
+
+	resp, err := NewSearchResponse(result)
+	if err != nil {
+		t.Fatalf("NewSearchResponse() error = %s", err)
+	}
+
+	b, err := proto.Marshal(resp)
+	if err != nil {
+		t.Fatalf("unable to marshal SearchResponse: %s", err)
+	}
+
+	decoded := &SearchResponse{}
+	if err = proto.Unmarshal(b, decoded); err != nil {
+		t.Fatalf("unable to unmarshal SearchResponse: %s", err)
+	}
+
+	back, err := decoded.ScrollResult()
+	if err != nil {
+		t.Fatalf("SearchResponse.ScrollResult() error = %s", err)
+	}
+
+	got, err := json.Marshal(back)
+	if err != nil {
+		t.Fatalf("unable to marshal ScrollResultV4: %s", err)
+	}
+
+	// compare the decoded JSON because the JSON-LD key order is not preserved
+	var wantJSON, gotJSON interface{}
+	if err := json.Unmarshal(want, &wantJSON); err != nil {
+		t.Fatalf("unable to decode JSON: %s", err)
+	}
+
+	if err := json.Unmarshal(got, &gotJSON); err != nil {
+		t.Fatalf("unable to decode JSON: %s", err)
+	}
+
+	if diff := cmp.Diff(wantJSON, gotJSON); diff != "" {
+		t.Errorf("SearchResponse round-trip mismatch (-want +got):\n%s", diff)
+	}
+
+	if len(b) >= len(want) {
+		t.Errorf("SearchResponse should be smaller than the JSON; got %d >= %d bytes", len(b), len(want))
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	c "github.com/delving/hub3/config"
+	proto "github.com/golang/protobuf/proto"
+)
+
+// DefaultDataSetConfigID is the ID of the DataSetConfig that applies to all
+// datasets of an organization that have no DataSetConfig of their own.
+const DefaultDataSetConfigID = "_default"
+
+var dataSetConfigIDRe = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+// Validate returns an error describing all invalid settings of the DataSetConfig.
+func (x *DataSetConfig) Validate() error {
+	var errs []string
+
+	if x.GetOrgID() == "" {
+		errs = append(errs, "orgID is required")
+	}
+
+	if !dataSetConfigIDRe.MatchString(x.GetID()) {
+		errs = append(errs, fmt.Sprintf("invalid ID %q; only letters, digits, '_', '-' and '.' are allowed", x.GetID()))
+	}
+
+	for i, ff := range x.GetFacets() {
+		if ff.GetField() == "" {
+			errs = append(errs, fmt.Sprintf("facets[%d]: field is required", i))
+		}
+
+		if ff.GetSize() < 0 {
+			errs = append(errs, fmt.Sprintf("facets[%d]: size must not be negative", i))
+		}
+	}
+
+	for i, sc := range x.GetResultConfig().GetSort() {
+		if sc.GetField() == "" {
+			errs = append(errs, fmt.Sprintf("resultConfig.sort[%d]: field is required", i))
+		}
+	}
+
+	for i, pf := range x.GetResultConfig().GetResultFields() {
+		if pf.GetPredicate() == "" {
+			errs = append(errs, fmt.Sprintf("resultConfig.resultFields[%d]: predicate is required", i))
+		}
+	}
+
+	for i, block := range x.GetViewConfig().GetBlocks() {
+		for j, pf := range block.GetFields() {
+			if pf.GetPredicate() == "" {
+				errs = append(errs, fmt.Sprintf("viewConfig.blocks[%d].fields[%d]: predicate is required", i, j))
+			}
+		}
+	}
+
+	if len(errs) != 0 {
+		return &ValidationError{Errors: errs}
+	}
+
+	return nil
+}
+
+// AppliesTo returns true when the DataSetConfig is configured for the dataset.
+func (x *DataSetConfig) AppliesTo(spec string) bool {
+	for _, excluded := range x.GetExcludeSpec() {
+		if excluded == spec {
+			return false
+		}
+	}
+
+	if x.GetID() == spec || x.GetID() == DefaultDataSetConfigID {
+		return true
+	}
+
+	for _, s := range x.GetSpec() {
+		if s == spec {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResultFieldLabels returns the search labels of the configured result fields in order.
+func (x *DataSetConfig) ResultFieldLabels() []string {
+	fields := append([]*PresentationField{}, x.GetResultConfig().GetResultFields()...)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].GetOrder() < fields[j].GetOrder()
+	})
+
+	labels := make([]string, 0, len(fields))
+	for _, pf := range fields {
+		labels = append(labels, searchLabel(pf.GetPredicate()))
+	}
+
+	return labels
+}
+
+// ValidationError contains all validation errors of a DataSetConfig.
+type ValidationError struct {
+	Errors []string `json:"errors"`
+}
+
+func (ve *ValidationError) Error() string {
+	return fmt.Sprintf("invalid dataset config: %s", strings.Join(ve.Errors, "; "))
+}
+
+// searchLabel returns the search label for a predicate URI.
+// Values that are not URIs are returned as is.
+func searchLabel(predicate string) string {
+	if !strings.Contains(predicate, "://") || c.Config.NameSpaceMap == nil {
+		return predicate
+	}
+
+	label, err := c.Config.NameSpaceMap.GetSearchLabel(predicate)
+	if err != nil {
+		return predicate
+	}
+
+	return label
+}
+
+// SpecFilter returns the dataset the SearchRequest is limited to.
+// An empty string is returned when the request is not limited to a single dataset.
+func (sr *SearchRequest) SpecFilter() string {
+	var specs []string
+
+	for _, qf := range sr.GetQueryFilter() {
+		if qf.GetExclude() {
+			continue
+		}
+
+		if qf.GetSearchLabel() == c.Config.ElasticSearch.SpecKey || qf.GetSearchLabel() == "meta.spec" {
+			specs = append(specs, qf.GetValue())
+		}
+	}
+
+	if len(specs) != 1 {
+		return ""
+	}
+
+	return specs[0]
+}
+
+// ApplyDataSetConfig applies the configured facets and default sort order of
+// the DataSetConfig. Facets and sort orders given in the request take precedence.
+func (sr *SearchRequest) ApplyDataSetConfig(cfg *DataSetConfig) {
+	if cfg == nil {
+		return
+	}
+
+	if len(sr.GetFacetField()) == 0 {
+		for _, ff := range cfg.GetFacets() {
+			sr.FacetField = append(sr.FacetField, proto.Clone(ff).(*FacetField))
+		}
+	}
+
+	if sr.GetSortBy() == "" && len(cfg.GetResultConfig().GetSort()) != 0 {
+		sc := cfg.GetResultConfig().GetSort()[0]
+		if sc.GetField() != "_score" {
+			sr.SortBy = searchLabel(sc.GetField())
+			sr.SortAsc = sc.GetAsc()
+		}
+	}
+}
+
+// DetailView is the record presentation configured by a DetailViewConfig.
+type DetailView struct {
+	Blocks []*DetailViewBlock `json:"blocks"`
+}
+
+// DetailViewBlock is a labeled group of fields in the DetailView.
+type DetailViewBlock struct {
+	Label         string             `json:"label,omitempty"`
+	ResourceLabel string             `json:"resourceLabel,omitempty"`
+	ResourceType  string             `json:"resourceType,omitempty"`
+	Fields        []*DetailViewField `json:"fields"`
+}
+
+// DetailViewField contains the values of a predicate in the DetailView.
+type DetailViewField struct {
+	Label       string   `json:"label"`
+	Predicate   string   `json:"predicate"`
+	SearchLabel string   `json:"searchLabel"`
+	Values      []string `json:"values"`
+}
+
+// NewDetailView returns the DetailView of the record.
+// Blocks and fields are ordered by their configured order. Fields without
+// values in the record are left out.
+func (fg *FragmentGraph) NewDetailView(cfg *DetailViewConfig) *DetailView {
+	view := &DetailView{Blocks: []*DetailViewBlock{}}
+
+	blocks := append([]*DetailBlock{}, cfg.GetBlocks()...)
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].GetOrder() < blocks[j].GetOrder()
+	})
+
+	for _, block := range blocks {
+		vb := &DetailViewBlock{
+			Label:         block.GetI18NLabel().GetName(),
+			ResourceLabel: block.GetResourceLabel(),
+			ResourceType:  block.GetResourceType(),
+		}
+
+		fields := append([]*PresentationField{}, block.GetFields()...)
+		sort.SliceStable(fields, func(i, j int) bool {
+			return fields[i].GetOrder() < fields[j].GetOrder()
+		})
+
+		for _, pf := range fields {
+			values := fg.predicateValues(pf.GetPredicate(), block.GetResourceType())
+			if len(values) == 0 {
+				continue
+			}
+
+			if pf.GetSingle() {
+				values = values[:1]
+			}
+
+			label := searchLabel(pf.GetPredicate())
+
+			vf := &DetailViewField{
+				Label:       pf.GetI18NLabel().GetName(),
+				Predicate:   pf.GetPredicate(),
+				SearchLabel: label,
+				Values:      values,
+			}
+
+			if vf.Label == "" {
+				vf.Label = label
+			}
+
+			vb.Fields = append(vb.Fields, vf)
+		}
+
+		if len(vb.Fields) != 0 {
+			view.Blocks = append(view.Blocks, vb)
+		}
+	}
+
+	return view
+}
+
+// predicateValues returns the unique values of the predicate, given as URI or
+// search label, in entry order. When resourceType is not empty only resources
+// of that type are used.
+func (fg *FragmentGraph) predicateValues(predicate, resourceType string) []string {
+	var values []string
+
+	seen := map[string]bool{}
+
+	for _, rsc := range fg.Resources {
+		if resourceType != "" && !containsString(rsc.Types, resourceType) {
+			continue
+		}
+
+		entries := append([]*ResourceEntry{}, rsc.Entries...)
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Order < entries[j].Order
+		})
+
+		for _, entry := range entries {
+			if entry.Predicate != predicate && entry.SearchLabel != predicate {
+				continue
+			}
+
+			value := entry.Value
+			if value == "" {
+				value = entry.ID
+			}
+
+			if value == "" || seen[value] {
+				continue
+			}
+
+			seen[value] = true
+
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
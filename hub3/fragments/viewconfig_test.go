@@ -0,0 +1,195 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDataSetConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *DataSetConfig
+		wantErr []string
+	}{
+		{
+			"valid",
+			&DataSetConfig{
+				ID:     "spec1",
+				OrgID:  "hub3",
+				Facets: []*FacetField{{Field: "dc_subject", Size: 10}},
+			},
+			nil,
+		},
+		{
+			"invalid",
+			&DataSetConfig{
+				ID:     "spec 1",
+				Facets: []*FacetField{{Size: -1}},
+				ResultConfig: &ResultViewConfig{
+					Sort:         []*SortConfig{{}},
+					ResultFields: []*PresentationField{{}},
+				},
+			},
+			[]string{
+				"orgID is required",
+				`invalid ID "spec 1"; only letters, digits, '_', '-' and '.' are allowed`,
+				"facets[0]: field is required",
+				"facets[0]: size must not be negative",
+				"resultConfig.sort[0]: field is required",
+				"resultConfig.resultFields[0]: predicate is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Errorf("DataSetConfig.Validate() unexpected error = %v", err)
+				}
+
+				return
+			}
+
+			var ve *ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("DataSetConfig.Validate() error = %v, want *ValidationError", err)
+			}
+
+			if diff := cmp.Diff(tt.wantErr, ve.Errors); diff != "" {
+				t.Errorf("DataSetConfig.Validate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDataSetConfig_AppliesTo(t *testing.T) {
+	cfg := &DataSetConfig{ID: "collection", Spec: []string{"spec1", "spec2"}}
+	defaultCfg := &DataSetConfig{ID: DefaultDataSetConfigID, ExcludeSpec: []string{"spec3"}}
+
+	tests := []struct {
+		name string
+		cfg  *DataSetConfig
+		spec string
+		want bool
+	}{
+		{"own ID", cfg, "collection", true},
+		{"listed spec", cfg, "spec2", true},
+		{"other spec", cfg, "spec3", false},
+		{"default", defaultCfg, "spec1", true},
+		{"excluded from default", defaultCfg, "spec3", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.AppliesTo(tt.spec); got != tt.want {
+				t.Errorf("DataSetConfig.AppliesTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRequest_ApplyDataSetConfig(t *testing.T) {
+	cfg := &DataSetConfig{
+		Facets: []*FacetField{{Field: "dc_subject", Size: 10}},
+		ResultConfig: &ResultViewConfig{
+			Sort: []*SortConfig{{Field: "dc_date", Asc: true}, {Field: "_score"}},
+		},
+	}
+
+	sr := &SearchRequest{}
+	sr.ApplyDataSetConfig(cfg)
+
+	if len(sr.GetFacetField()) != 1 || sr.GetFacetField()[0].GetField() != "dc_subject" {
+		t.Errorf("ApplyDataSetConfig() facets = %v, want dc_subject", sr.GetFacetField())
+	}
+
+	if sr.GetSortBy() != "dc_date" || !sr.GetSortAsc() {
+		t.Errorf("ApplyDataSetConfig() sort = %s asc %v, want dc_date asc", sr.GetSortBy(), sr.GetSortAsc())
+	}
+
+	sr = &SearchRequest{
+		FacetField: []*FacetField{{Field: "dc_creator"}},
+		SortBy:     "dc_title",
+	}
+	sr.ApplyDataSetConfig(cfg)
+
+	if len(sr.GetFacetField()) != 1 || sr.GetFacetField()[0].GetField() != "dc_creator" {
+		t.Errorf("ApplyDataSetConfig() request facets should take precedence; got %v", sr.GetFacetField())
+	}
+
+	if sr.GetSortBy() != "dc_title" {
+		t.Errorf("ApplyDataSetConfig() request sort should take precedence; got %s", sr.GetSortBy())
+	}
+}
+
+func TestFragmentGraph_NewDetailView(t *testing.T) {
+	fg := &FragmentGraph{
+		Resources: []*FragmentResource{
+			{
+				ID:    "http://example.org/1",
+				Types: []string{"http://www.europeana.eu/schemas/edm/ProvidedCHO"},
+				Entries: []*ResourceEntry{
+					{Value: "title", SearchLabel: "dc_title", Order: 1},
+					{Value: "portrait", SearchLabel: "dc_subject", Order: 3},
+					{Value: "painting", SearchLabel: "dc_subject", Order: 2},
+					{Value: "painting", SearchLabel: "dc_subject", Order: 4},
+				},
+			},
+		},
+	}
+
+	cfg := &DetailViewConfig{
+		Blocks: []*DetailBlock{
+			{
+				I18NLabel: &I18NLabel{Name: "Empty"},
+				Order:     1,
+				Fields:    []*PresentationField{{Predicate: "dc_creator"}},
+			},
+			{
+				I18NLabel: &I18NLabel{Name: "Object"},
+				Fields: []*PresentationField{
+					{Predicate: "dc_subject", Order: 2},
+					{Predicate: "dc_title", Order: 1, I18NLabel: &I18NLabel{Name: "Title"}},
+				},
+			},
+		},
+	}
+
+	want := &DetailView{
+		Blocks: []*DetailViewBlock{
+			{
+				Label: "Object",
+				Fields: []*DetailViewField{
+					{Label: "Title", Predicate: "dc_title", SearchLabel: "dc_title", Values: []string{"title"}},
+					{Label: "dc_subject", Predicate: "dc_subject", SearchLabel: "dc_subject", Values: []string{"painting", "portrait"}},
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, fg.NewDetailView(cfg)); diff != "" {
+		t.Errorf("FragmentGraph.NewDetailView() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/xml"
+	"sort"
+)
+
+// XMLSearchResult is the XML serialization of the ScrollResultV4.
+// The schema is documented in docs/hub3/search-xml.md.
+type XMLSearchResult struct {
+	XMLName xml.Name    `xml:"searchResult"`
+	Pager   *XMLPager   `xml:"pager,omitempty"`
+	Query   *XMLQuery   `xml:"query,omitempty"`
+	Items   []*XMLItem  `xml:"items>item"`
+	Facets  []*XMLFacet `xml:"facets>facet,omitempty"`
+}
+
+// XMLPager holds the paging information of the XMLSearchResult.
+type XMLPager struct {
+	Total            int64  `xml:"total,attr"`
+	Cursor           int32  `xml:"cursor,attr"`
+	Rows             int32  `xml:"rows,attr"`
+	PreviousScrollID string `xml:"previousScrollID,omitempty"`
+	NextScrollID     string `xml:"nextScrollID,omitempty"`
+	NextCursor       string `xml:"nextCursor,omitempty"`
+}
+
+// XMLQuery holds the query and its breadcrumbs.
+type XMLQuery struct {
+	NumFound    int32            `xml:"numFound,attr"`
+	Terms       string           `xml:"terms"`
+	BreadCrumbs []*XMLBreadCrumb `xml:"breadCrumbs>breadCrumb,omitempty"`
+}
+
+// XMLBreadCrumb is a single step of the query breadcrumbs.
+type XMLBreadCrumb struct {
+	Href    string `xml:"href,attr"`
+	Field   string `xml:"field,attr,omitempty"`
+	Value   string `xml:"value,attr,omitempty"`
+	IsLast  bool   `xml:"isLast,attr"`
+	Display string `xml:",chardata"`
+}
+
+// XMLItem is a single search hit.
+type XMLItem struct {
+	HubID   string      `xml:"hubID,attr"`
+	Spec    string      `xml:"spec,attr"`
+	OrgID   string      `xml:"orgID,attr"`
+	Summary *XMLSummary `xml:"summary,omitempty"`
+	Fields  []*XMLField `xml:"field"`
+}
+
+// XMLField holds all the values of a searchLabel.
+type XMLField struct {
+	Name   string   `xml:"name,attr"`
+	Values []string `xml:"value"`
+}
+
+// XMLSummary is the XML serialization of the ResultSummary.
+type XMLSummary struct {
+	Title         string `xml:"title,omitempty"`
+	Owner         string `xml:"owner,omitempty"`
+	DatasetTitle  string `xml:"datasetTitle,omitempty"`
+	Thumbnail     string `xml:"thumbnail,omitempty"`
+	LandingPage   string `xml:"landingPage,omitempty"`
+	LatLong       string `xml:"latLong,omitempty"`
+	Date          string `xml:"date,omitempty"`
+	Description   string `xml:"description,omitempty"`
+	Subject       string `xml:"subject,omitempty"`
+	Collection    string `xml:"collection,omitempty"`
+	SubCollection string `xml:"subCollection,omitempty"`
+	ObjectID      string `xml:"objectID,omitempty"`
+	ObjectType    string `xml:"objectType,omitempty"`
+	Creator       string `xml:"creator,omitempty"`
+}
+
+// XMLFacet is the XML serialization of the QueryFacet.
+type XMLFacet struct {
+	Name        string          `xml:"name,attr"`
+	Field       string          `xml:"field,attr"`
+	IsSelected  bool            `xml:"isSelected,attr"`
+	Total       int64           `xml:"total,attr"`
+	MissingDocs int64           `xml:"missingDocs,attr"`
+	OtherDocs   int64           `xml:"otherDocs,attr"`
+	Links       []*XMLFacetLink `xml:"link"`
+}
+
+// XMLFacetLink is the XML serialization of the FacetLink.
+type XMLFacetLink struct {
+	Value      string `xml:"value,attr"`
+	Count      int64  `xml:"count,attr"`
+	IsSelected bool   `xml:"isSelected,attr"`
+	URL        string `xml:"url,attr"`
+	Display    string `xml:",chardata"`
+}
+
+// NewXMLSearchResult converts the ScrollResultV4 to its XML serialization.
+func NewXMLSearchResult(result *ScrollResultV4) *XMLSearchResult {
+	xr := &XMLSearchResult{Items: []*XMLItem{}}
+
+	if result.Pager != nil {
+		xr.Pager = &XMLPager{
+			Total:            result.Pager.Total,
+			Cursor:           result.Pager.Cursor,
+			Rows:             result.Pager.Rows,
+			PreviousScrollID: result.Pager.PreviousScrollID,
+			NextScrollID:     result.Pager.NextScrollID,
+			NextCursor:       result.Pager.NextCursor,
+		}
+	}
+
+	if result.Query != nil {
+		xr.Query = &XMLQuery{
+			NumFound: result.Query.GetNumfound(),
+			Terms:    result.Query.GetTerms(),
+		}
+
+		for _, bc := range result.Query.GetBreadCrumbs() {
+			xr.Query.BreadCrumbs = append(xr.Query.BreadCrumbs, &XMLBreadCrumb{
+				Href:    bc.GetHref(),
+				Field:   bc.GetField(),
+				Value:   bc.GetValue(),
+				IsLast:  bc.GetIsLast(),
+				Display: bc.GetDisplay(),
+			})
+		}
+	}
+
+	for _, item := range result.Items {
+		xr.Items = append(xr.Items, NewXMLItem(item))
+	}
+
+	for _, facet := range result.Facets {
+		xf := &XMLFacet{
+			Name:        facet.Name,
+			Field:       facet.Field,
+			IsSelected:  facet.IsSelected,
+			Total:       facet.Total,
+			MissingDocs: facet.MissingDocs,
+			OtherDocs:   facet.OtherDocs,
+		}
+
+		for _, link := range facet.Links {
+			xf.Links = append(xf.Links, &XMLFacetLink{
+				Value:      link.Value,
+				Count:      link.Count,
+				IsSelected: link.IsSelected,
+				URL:        link.URL,
+				Display:    link.DisplayString,
+			})
+		}
+
+		xr.Facets = append(xr.Facets, xf)
+	}
+
+	return xr
+}
+
+// NewXMLItem converts a FragmentGraph to an XMLItem.
+// When the FragmentGraph has no flat fields they are created from its resources.
+func NewXMLItem(fg *FragmentGraph) *XMLItem {
+	item := &XMLItem{
+		HubID: fg.Meta.GetHubID(),
+		Spec:  fg.Meta.GetSpec(),
+		OrgID: fg.Meta.GetOrgID(),
+	}
+
+	if s := fg.Summary; s != nil {
+		item.Summary = &XMLSummary{
+			Title:         s.GetTitle(),
+			Owner:         s.GetOwner(),
+			DatasetTitle:  s.GetDatasetTitle(),
+			Thumbnail:     s.GetThumbnail(),
+			LandingPage:   s.GetLandingPage(),
+			LatLong:       s.GetLatLong(),
+			Date:          s.GetDate(),
+			Description:   s.GetDescription(),
+			Subject:       s.GetSubject(),
+			Collection:    s.GetCollection(),
+			SubCollection: s.GetSubCollection(),
+			ObjectID:      s.GetObjectID(),
+			ObjectType:    s.GetObjectType(),
+			Creator:       s.GetCreator(),
+		}
+	}
+
+	fields := fg.Fields
+	if len(fields) == 0 && len(fg.Resources) > 0 {
+		fields = fg.NewFields(nil, fg.searchLabels()...)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		item.Fields = append(item.Fields, &XMLField{Name: name, Values: fields[name]})
+	}
+
+	return item
+}
+
+// searchLabels returns the searchLabels of all the entries in the FragmentGraph.
+func (fg *FragmentGraph) searchLabels() []string {
+	labels := []string{}
+	seen := map[string]bool{}
+
+	for _, rsc := range fg.Resources {
+		for _, entry := range rsc.Entries {
+			if entry.SearchLabel == "" || seen[entry.SearchLabel] {
+				continue
+			}
+
+			seen[entry.SearchLabel] = true
+
+			labels = append(labels, entry.SearchLabel)
+		}
+	}
+
+	return labels
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fragments
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewXMLSearchResult(t *testing.T) {
+	flat := exportGraph()
+	flat.NewFields(nil, "dc_title")
+	flat.Resources = nil
+
+	summary := exportGraph()
+	summary.Meta.HubID = "hub3_test_2"
+	summary.Summary = &ResultSummary{Title: "summary title"}
+	summary.Resources = nil
+
+	result := &ScrollResultV4{
+		Pager: &ScrollPager{Total: 2, Cursor: 0, Rows: 2, NextScrollID: "next"},
+		Query: &Query{
+			Numfound:    2,
+			Terms:       "title",
+			BreadCrumbs: []*BreadCrumb{{Href: "q=title", Display: "title", IsLast: true}},
+		},
+		Items: []*FragmentGraph{flat, summary, exportGraph()},
+		Facets: []*QueryFacet{
+			{
+				Name:  "creator",
+				Field: "dc_creator",
+				Total: 1,
+				Links: []*FacetLink{{URL: "qf=dc_creator:x", Value: "x", DisplayString: "x (1)", Count: 1}},
+			},
+		},
+	}
+
+	got, err := xml.Marshal(NewXMLSearchResult(result))
+	if err != nil {
+		t.Fatalf("unable to marshal XMLSearchResult: %s", err)
+	}
+
+	want := `<searchResult>` +
+		`<pager total="2" cursor="0" rows="2"><nextScrollID>next</nextScrollID></pager>` +
+		`<query numFound="2"><terms>title</terms><breadCrumbs><breadCrumb href="q=title" isLast="true">title</breadCrumb></breadCrumbs></query>` +
+		`<items>` +
+		`<item hubID="hub3_test_1" spec="test" orgID="hub3"><field name="dc_title"><value>first title</value><value>second &#34;title&#34;</value></field></item>` +
+		`<item hubID="hub3_test_2" spec="test" orgID="hub3"><summary><title>summary title</title></summary></item>` +
+		`<item hubID="hub3_test_1" spec="test" orgID="hub3">` +
+		`<field name="dc_creator"><value>http://example.org/creator/1</value></field>` +
+		`<field name="dc_date"><value>1900</value></field>` +
+		`<field name="dc_title"><value>first title</value><value>second &#34;title&#34;</value></field>` +
+		`</item>` +
+		`</items>` +
+		`<facets><facet name="creator" field="dc_creator" isSelected="false" total="1" missingDocs="0" otherDocs="0">` +
+		`<link value="x" count="1" isSelected="false" url="qf=dc_creator:x">x (1)</link>` +
+		`</facet></facets>` +
+		`</searchResult>`
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("NewXMLSearchResult() mismatch (-want +got):\n%s", diff)
+	}
+}
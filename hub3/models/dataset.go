@@ -549,8 +549,12 @@ func (ds DataSet) deleteAllGraphs() (bool, error) {
 	return DeleteAllGraphsBySpec(ds.Spec)
 }
 
-// DeleteIndexOrphans deletes all the Orphaned records from the Search Index linked to this dataset
-func (ds DataSet) deleteIndexOrphans(ctx context.Context, wp *wp.WorkerPool) (int, error) {
+// OrphansRemovedFunc is called with the number of orphans removed from an index.
+type OrphansRemovedFunc func(removed int)
+
+// DeleteIndexOrphans deletes all the Orphaned records from the Search Index linked to this dataset.
+// The records with the hubIDs in keep are not deleted.
+func (ds DataSet) deleteIndexOrphans(ctx context.Context, wp *wp.WorkerPool, removed OrphansRemovedFunc, keep []string) (int, error) {
 
 	v2 := elastic.NewBoolQuery()
 	v2 = v2.MustNot(elastic.NewMatchQuery(c.Config.ElasticSearch.RevisionKey, ds.Revision))
@@ -562,6 +566,12 @@ func (ds DataSet) deleteIndexOrphans(ctx context.Context, wp *wp.WorkerPool) (in
 	v1 = v1.Must(elastic.NewTermQuery("spec.raw", ds.Spec))
 	v1 = v1.Must(elastic.NewTermQuery("orgID", c.Config.OrgID))
 
+	// the records are indexed with the hubID as document id
+	if len(keep) != 0 {
+		v2 = v2.MustNot(elastic.NewIdsQuery().Ids(keep...))
+		v1 = v1.MustNot(elastic.NewIdsQuery().Ids(keep...))
+	}
+
 	queries := map[*elastic.BoolQuery][]string{
 		v1: []string{c.Config.ElasticSearch.GetV1IndexName()},
 		v2: []string{
@@ -599,6 +609,10 @@ func (ds DataSet) deleteIndexOrphans(ctx context.Context, wp *wp.WorkerPool) (in
 				ds.Spec,
 				ds.Revision,
 			)
+
+			if removed != nil {
+				removed(int(res.Deleted))
+			}
 		}
 	}()
 
@@ -643,6 +657,16 @@ func (ds DataSet) deleteAllIndexRecords(ctx context.Context, wp *wp.WorkerPool)
 
 //DropOrphans removes all records of different revision that the current from the attached datastores
 func (ds DataSet) DropOrphans(ctx context.Context, p *elastic.BulkProcessor, wp *wp.WorkerPool) (bool, error) {
+	return ds.DropOrphansWithCallback(ctx, wp, nil, nil)
+}
+
+// DropOrphansWithCallback removes the orphans like DropOrphans. The orphans are
+// removed from the index in the background, so removed is called with the
+// number of removed orphans after DropOrphansWithCallback has returned.
+//
+// The records with the hubIDs in keep are not removed from the index, e.g.
+// unchanged records that were not indexed again with the current revision.
+func (ds DataSet) DropOrphansWithCallback(ctx context.Context, wp *wp.WorkerPool, removed OrphansRemovedFunc, keep []string) (bool, error) {
 	ok := true
 
 	// TODO(kiivihal): replace flush with TRS
@@ -661,7 +685,7 @@ func (ds DataSet) DropOrphans(ctx context.Context, p *elastic.BulkProcessor, wp
 		}
 	}
 	if c.Config.ElasticSearch.Enabled {
-		_, err := ds.deleteIndexOrphans(ctx, wp)
+		_, err := ds.deleteIndexOrphans(ctx, wp, removed, keep)
 		if err != nil {
 			log.Warn().Msgf("Unable to remove RDF orphan graphs from spec %s: %s", ds.Spec, err)
 			return false, err
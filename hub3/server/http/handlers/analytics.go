@@ -0,0 +1,64 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"time"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/analytics"
+)
+
+// SearchRecorder records the search requests of the v2 search API.
+type SearchRecorder interface {
+	Record(e *analytics.Event)
+}
+
+var searchRecorder SearchRecorder
+
+// SetSearchRecorder sets the SearchRecorder for the v2 search API.
+// It must be called before the routes are served.
+func SetSearchRecorder(rec SearchRecorder) {
+	searchRecorder = rec
+}
+
+// recordSearch records the first page of a search request.
+// Follow-up pages are not recorded, so paging does not inflate the counts.
+func recordSearch(sr *fragments.SearchRequest, cur *fragments.Cursor, hits int64, took time.Duration) {
+	if searchRecorder == nil || sr.GetPaging() || sr.GetStart() != 0 || (cur != nil && len(cur.SearchAfter) != 0) {
+		return
+	}
+
+	filters := make([]string, 0, len(sr.GetQueryFilter()))
+
+	for _, qf := range sr.GetQueryFilter() {
+		field := qf.GetSearchLabel()
+		if qf.GetExclude() {
+			field = "-" + field
+		}
+
+		filters = append(filters, field+":"+qf.GetValue())
+	}
+
+	searchRecorder.Record(&analytics.Event{
+		OrgID:   c.Config.OrgID,
+		Query:   sr.GetQuery(),
+		Filters: filters,
+		Hits:    hits,
+		Latency: took.Milliseconds(),
+		Source:  "v2",
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/go-chi/render"
+)
+
+var (
+	cursorOnce  sync.Once
+	cursorCodec *fragments.CursorCodec
+	cursorErr   error
+)
+
+// getCursorCodec returns the CursorCodec configured with the cursor secret.
+func getCursorCodec() (*fragments.CursorCodec, error) {
+	cursorOnce.Do(func() {
+		cursorCodec, cursorErr = fragments.NewCursorCodec(c.Config.ElasticSearch.CursorSecret)
+	})
+
+	return cursorCodec, cursorErr
+}
+
+// cursorTTL returns how long cursors and their point-in-time stay valid.
+func cursorTTL() time.Duration {
+	ttl := c.Config.ElasticSearch.CursorTTL
+	if ttl <= 0 {
+		ttl = 10
+	}
+
+	return time.Duration(ttl) * time.Minute
+}
+
+// decodeCursor returns the Cursor and its SearchRequest from a cursor token.
+func decodeCursor(token string) (*fragments.Cursor, *fragments.SearchRequest, error) {
+	codec, err := getCursorCodec()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cur, err := codec.Decode(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sr, err := cur.SearchRequest()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cur, sr, nil
+}
+
+// encodeNextCursor returns the cursor token for the next page or an empty string
+// when there is no next page.
+func encodeNextCursor(next *fragments.Cursor) (string, error) {
+	if next == nil {
+		return "", nil
+	}
+
+	codec, err := getCursorCodec()
+	if err != nil {
+		return "", err
+	}
+
+	return codec.Encode(next)
+}
+
+// renderCursorError writes the error response for invalid or expired cursors.
+func renderCursorError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, fragments.ErrCursorExpired):
+		status = http.StatusGone
+	case errors.Is(err, fragments.ErrInvalidCursor):
+		status = http.StatusBadRequest
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, &ErrorMessage{Status: http.StatusText(status), Message: err.Error()})
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/delving/hub3/hub3/fragments"
+)
+
+// DataSetConfigLookup resolves the DataSetConfig that applies to a dataset.
+type DataSetConfigLookup interface {
+	Lookup(ctx context.Context, orgID, spec string) (*fragments.DataSetConfig, bool)
+}
+
+var dataSetConfigs DataSetConfigLookup
+
+// SetDataSetConfigs sets the DataSetConfigLookup for the v2 search API.
+// It must be called before the routes are served.
+func SetDataSetConfigs(lookup DataSetConfigLookup) {
+	dataSetConfigs = lookup
+}
+
+// lookupDataSetConfig returns the DataSetConfig of the dataset or nil when
+// no DataSetConfig applies.
+func lookupDataSetConfig(ctx context.Context, orgID, spec string) *fragments.DataSetConfig {
+	if dataSetConfigs == nil {
+		return nil
+	}
+
+	cfg, ok := dataSetConfigs.Lookup(ctx, orgID, spec)
+	if !ok {
+		return nil
+	}
+
+	return cfg
+}
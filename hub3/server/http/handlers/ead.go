@@ -233,6 +233,8 @@ func TreeDescriptionSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if descriptionIndex != nil {
+		defer descriptionIndex.Close()
+
 		searhHits, searchErr := descriptionIndex.SearchWithString(rawQuery)
 		if searchErr != nil && !errors.Is(searchErr, memory.ErrSearchNoMatch) {
 			c.Config.Logger.Error().Err(searchErr).
@@ -301,12 +303,24 @@ func TreeDescriptionAPI(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		defer descIndex.Close()
+
 		hits, searchErr := descIndex.SearchWithString(query)
 		if searchErr != nil && !errors.Is(searchErr, memory.ErrSearchNoMatch) {
 			http.Error(w, searchErr.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		snippets, snippetErr := snippetConfig(params)
+		if snippetErr != nil {
+			http.Error(w, snippetErr.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if snippets != nil {
+			desc.Highlights = descIndex.Snippets(hits, desc.Item, snippets)
+		}
+
 		desc.Item = descIndex.HighlightMatches(hits, desc.Item, filter)
 
 		if echo == "hits" {
@@ -76,6 +76,6 @@ type APIErrorMessage struct {
 
 // ErrorMessage is a placeholder for disabled endpoints
 type ErrorMessage struct {
-	Status  string `json:"status"`
-	Message string `json:"message"`
+	Status  string `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
 }
@@ -0,0 +1,204 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/hub3/index"
+)
+
+const (
+	exportBatchSize = 500
+	exportKeepAlive = "5m"
+)
+
+// exports limits the number of concurrent exports per organization.
+var exports = &exportLimiter{active: map[string]int{}}
+
+type exportLimiter struct {
+	sync.Mutex
+	active map[string]int
+}
+
+// acquire reserves an export slot for the orgID. It returns false when max
+// exports are already running.
+func (l *exportLimiter) acquire(orgID string, max int) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if max > 0 && l.active[orgID] >= max {
+		return false
+	}
+
+	l.active[orgID]++
+
+	return true
+}
+
+func (l *exportLimiter) release(orgID string) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.active[orgID]--
+	if l.active[orgID] <= 0 {
+		delete(l.active, orgID)
+	}
+}
+
+// exportSearch streams all the hits of a search request using the
+// ElasticSearch scroll API.
+func exportSearch(w http.ResponseWriter, r *http.Request) {
+	cfg, err := fragments.NewExportConfig(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sr, err := fragments.NewSearchRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the query is restricted to the organization of the configuration
+	query, err := sr.ElasticQuery()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orgID := c.Config.OrgID
+
+	if !exports.acquire(orgID, c.Config.ElasticSearch.MaxExports) {
+		http.Error(w, "too many concurrent exports for this organization", http.StatusTooManyRequests)
+		return
+	}
+	defer exports.release(orgID)
+
+	scroll := index.ESClient().Scroll(c.Config.ElasticSearch.GetIndexName()).
+		Query(query).
+		Size(exportBatchSize).
+		KeepAlive(exportKeepAlive).
+		Sort("_doc", true)
+
+	defer func() {
+		if clearErr := scroll.Clear(context.Background()); clearErr != nil {
+			log.Printf("unable to clear export scroll: %s", clearErr)
+		}
+	}()
+
+	// get the first batch before writing the headers so errors can be returned
+	res, err := scroll.Do(r.Context())
+	if err != nil && err != io.EOF {
+		log.Printf("unable to start export: %s", err)
+		http.Error(w, "unable to start export", http.StatusInternalServerError)
+
+		return
+	}
+
+	filename := "export." + cfg.Format.Extension()
+
+	var out io.Writer = w
+
+	var gz *gzip.Writer
+
+	switch {
+	case strings.EqualFold(r.URL.Query().Get("gzip"), "true"):
+		filename += ".gz"
+		gz = gzip.NewWriter(w)
+
+		w.Header().Set("Content-Type", "application/gzip")
+	case strings.Contains(r.Header.Get("Accept-Encoding"), "gzip"):
+		gz = gzip.NewWriter(w)
+
+		w.Header().Set("Content-Type", cfg.Format.ContentType())
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+	default:
+		w.Header().Set("Content-Type", cfg.Format.ContentType())
+	}
+
+	if gz != nil {
+		out = gz
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	ew, err := fragments.NewExportWriter(out, cfg)
+	if err != nil {
+		log.Printf("unable to create export writer: %s", err)
+		return
+	}
+
+	for err != io.EOF {
+		records, _, decodeErr := decodeFragmentGraphs(res)
+		if decodeErr != nil {
+			log.Printf("unable to decode export records: %s", decodeErr)
+			break
+		}
+
+		if writeErr := writeExportBatch(w, gz, ew, records); writeErr != nil {
+			log.Printf("unable to write export records: %s", writeErr)
+			break
+		}
+
+		res, err = scroll.Do(r.Context())
+		if err != nil && err != io.EOF {
+			log.Printf("unable to continue export: %s", err)
+			break
+		}
+	}
+
+	if gz != nil {
+		if closeErr := gz.Close(); closeErr != nil {
+			log.Printf("unable to close export gzip writer: %s", closeErr)
+		}
+	}
+}
+
+// writeExportBatch writes the records and flushes them to the client.
+func writeExportBatch(w http.ResponseWriter, gz *gzip.Writer, ew *fragments.ExportWriter, records []*fragments.FragmentGraph) error {
+	for _, rec := range records {
+		if err := ew.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	if err := ew.Flush(); err != nil {
+		return err
+	}
+
+	if gz != nil {
+		if err := gz.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	return nil
+}
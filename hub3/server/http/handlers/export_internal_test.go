@@ -0,0 +1,41 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"testing"
+)
+
+func Test_exportLimiter(t *testing.T) {
+	limiter := &exportLimiter{active: map[string]int{}}
+
+	if !limiter.acquire("hub3", 1) {
+		t.Fatal("acquire() = false; want first export of hub3 to be allowed")
+	}
+
+	if limiter.acquire("hub3", 1) {
+		t.Error("acquire() = true; want second export of hub3 to be limited")
+	}
+
+	if !limiter.acquire("other", 1) {
+		t.Error("acquire() = false; want exports of other organizations not to be limited by hub3")
+	}
+
+	limiter.release("hub3")
+
+	if !limiter.acquire("hub3", 1) {
+		t.Error("acquire() = false; want export of hub3 to be allowed after release")
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	c "github.com/delving/hub3/config"
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/hub3/index"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// SearchCache executes ElasticSearch search requests through a cache.
+// It is implemented by the elasticsearch.Proxy.
+type SearchCache interface {
+	Search(ctx context.Context, index string, body []byte) ([]byte, error)
+}
+
+var searchCache SearchCache
+
+// SetSearchCache sets the SearchCache for the related records endpoint.
+// When no SearchCache is set the search requests are not cached.
+func SetSearchCache(cache SearchCache) {
+	searchCache = cache
+}
+
+// RelatedResult is the response of the related records endpoint.
+type RelatedResult struct {
+	HubID    string                     `json:"hubID"`
+	Strategy string                     `json:"strategy,omitempty"`
+	Total    int64                      `json:"total"`
+	Items    []*fragments.FragmentGraph `json:"items"`
+}
+
+func getRelatedRecords(w http.ResponseWriter, r *http.Request) {
+	hubID := chi.URLParam(r, "id")
+
+	rr, err := fragments.NewRelatedRequest(hubID, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := index.ESClient().Get().
+		Index(c.Config.ElasticSearch.GetIndexName()).
+		Id(hubID).
+		Do(r.Context())
+	if err != nil || res == nil || !res.Found {
+		http.Error(w, fmt.Sprintf("%s was not found", hubID), http.StatusNotFound)
+		return
+	}
+
+	record, err := decodeFragmentGraph(res.Source)
+	if err != nil {
+		log.Printf("Unable to decode record %s: %s", hubID, err)
+		http.Error(w, "unable to decode record", http.StatusInternalServerError)
+		return
+	}
+
+	result := &RelatedResult{HubID: hubID, Items: []*fragments.FragmentGraph{}}
+
+	strategies := []struct {
+		name  string
+		query func(fg *fragments.FragmentGraph) (elastic.Query, bool)
+	}{
+		{fragments.RelatedMoreLikeThis, rr.MoreLikeThisQuery},
+		{fragments.RelatedSharedURI, rr.SharedURIQuery},
+	}
+
+	// the shared-URI query is the fallback when more_like_this finds nothing
+	for _, strategy := range strategies {
+		q, ok := strategy.query(record)
+		if !ok {
+			continue
+		}
+
+		body, err := rr.SearchBody(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sr, err := relatedSearch(r.Context(), body)
+		if err != nil {
+			log.Printf("Unable to search related records for %s: %s", hubID, err)
+			http.Error(w, "unable to search related records", http.StatusInternalServerError)
+			return
+		}
+
+		if sr.TotalHits() == 0 {
+			continue
+		}
+
+		records, _, err := decodeFragmentGraphs(sr)
+		if err != nil {
+			log.Printf("Unable to decode related records for %s: %s", hubID, err)
+			http.Error(w, "unable to decode related records", http.StatusInternalServerError)
+			return
+		}
+
+		for _, rec := range records {
+			renderItemFormat(rec, r.URL.Query().Get("itemFormat"))
+		}
+
+		result.Strategy = strategy.name
+		result.Total = sr.TotalHits()
+		result.Items = records
+
+		break
+	}
+
+	render.JSON(w, r, result)
+}
+
+// relatedSearch executes the search request body through the SearchCache when it is set.
+func relatedSearch(ctx context.Context, body []byte) (*elastic.SearchResult, error) {
+	indexName := c.Config.ElasticSearch.GetIndexName()
+
+	if searchCache == nil {
+		return index.ESClient().Search(indexName).
+			Source(json.RawMessage(body)).
+			Do(ctx)
+	}
+
+	data, err := searchCache.Search(ctx, indexName, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res elastic.SearchResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("unable to decode search response; %w", err)
+	}
+
+	return &res, nil
+}
+
+// renderItemFormat prepares the record for the itemFormat.
+// The default is the summary format.
+func renderItemFormat(rec *fragments.FragmentGraph, itemFormat string) {
+	switch itemFormat {
+	case "flat":
+		rec.NewFields(nil)
+	case "jsonld":
+		rec.NewJSONLD()
+	default:
+		rec.NewResultSummary()
+	}
+
+	rec.Resources = nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/render"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxCallbackLength   = 128
+	protobufContentType = "application/x-protobuf"
+)
+
+// jsonpCallback only allows (dotted) JavaScript identifiers as callback names.
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+// validCallback returns an error when the JSONP callback name is not safe to
+// be echoed in the response.
+func validCallback(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("jsonp format requires the 'callback' parameter")
+	case len(name) > maxCallbackLength:
+		return fmt.Errorf("jsonp callback may not be longer than %d characters", maxCallbackLength)
+	case !jsonpCallback.MatchString(name):
+		return fmt.Errorf("invalid jsonp callback name: %q", name)
+	}
+
+	return nil
+}
+
+// renderJSONP writes v as JSON wrapped in the function from the 'callback' parameter.
+func renderJSONP(w http.ResponseWriter, r *http.Request, v interface{}) {
+	callback := r.URL.Query().Get("callback")
+	if err := validCallback(callback); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	// the leading comment prevents the response from being sniffed as another content-type
+	fmt.Fprintf(&buf, "/**/ typeof %s === 'function' && %s(", callback, callback)
+	buf.Write(b)
+	buf.WriteString(");")
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+
+	_, _ = w.Write(buf.Bytes())
+}
+
+// renderFormat renders v as XML or JSONP when requested with the 'format'
+// parameter and as JSON otherwise.
+func renderFormat(w http.ResponseWriter, r *http.Request, v interface{}) {
+	switch r.URL.Query().Get("format") {
+	case "xml":
+		render.XML(w, r, v)
+	case "jsonp":
+		renderJSONP(w, r, v)
+	default:
+		render.JSON(w, r, v)
+	}
+}
+
+// acceptsProtobuf returns true when the client requests a protobuf response
+// with the Accept header.
+func acceptsProtobuf(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), protobufContentType)
+}
+
+// renderProtobuf writes m in the protobuf binary wire format.
+func renderProtobuf(w http.ResponseWriter, r *http.Request, m proto.Message) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", protobufContentType)
+
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+
+	_, _ = w.Write(b)
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_validCallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		callback string
+		wantErr  bool
+	}{
+		{"simple", "handleResults", false},
+		{"dotted", "jQuery.results_1", false},
+		{"dollar", "$callback", false},
+		{"empty", "", true},
+		{"script injection", "alert(1);cb", true},
+		{"html", "<script>", true},
+		{"leading digit", "1cb", true},
+		{"trailing dot", "cb.", true},
+		{"too long", string(make([]byte, maxCallbackLength+1)), true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validCallback(tt.callback); (err != nil) != tt.wantErr {
+				t.Errorf("validCallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_renderFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			"json",
+			"",
+			http.StatusOK,
+			"application/json; charset=utf-8",
+			"{\"status\":\"not enabled\",\"message\":\"\"}\n",
+		},
+		{
+			"xml",
+			"format=xml",
+			http.StatusOK,
+			"application/xml; charset=utf-8",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<ErrorMessage><status>not enabled</status><message></message></ErrorMessage>",
+		},
+		{
+			"jsonp",
+			"format=jsonp&callback=cb",
+			http.StatusOK,
+			"application/javascript; charset=utf-8",
+			"/**/ typeof cb === 'function' && cb({\"status\":\"not enabled\",\"message\":\"\"});",
+		},
+		{
+			"jsonp with invalid callback",
+			"format=jsonp&callback=alert(1)",
+			http.StatusBadRequest,
+			"text/plain; charset=utf-8",
+			"invalid jsonp callback name: \"alert(1)\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/search/v1?"+tt.query, nil)
+
+			renderFormat(w, r, &ErrorMessage{"not enabled", ""})
+
+			if w.Code != tt.status {
+				t.Errorf("renderFormat() status = %d, want %d", w.Code, tt.status)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("renderFormat() Content-Type = %s, want %s", got, tt.contentType)
+			}
+
+			if diff := cmp.Diff(tt.body, w.Body.String()); diff != "" {
+				t.Errorf("renderFormat() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_renderProtobuf(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/search/v2", nil)
+	r.Header.Set("Accept", "application/x-protobuf")
+
+	if !acceptsProtobuf(r) {
+		t.Fatalf("acceptsProtobuf() should be true for %s", r.Header.Get("Accept"))
+	}
+
+	want := &fragments.SearchResponse{Pager: &fragments.SearchPager{Total: 10, Rows: 2, NextCursor: "cursor"}}
+
+	w := httptest.NewRecorder()
+	renderProtobuf(w, r, want)
+
+	if got := w.Header().Get("Content-Type"); got != protobufContentType {
+		t.Errorf("renderProtobuf() Content-Type = %s, want %s", got, protobufContentType)
+	}
+
+	got := &fragments.SearchResponse{}
+	if err := proto.Unmarshal(w.Body.Bytes(), got); err != nil {
+		t.Fatalf("unable to unmarshal response: %s", err)
+	}
+
+	if !proto.Equal(want, got) {
+		t.Errorf("renderProtobuf() = %v, want %v", got, want)
+	}
+}
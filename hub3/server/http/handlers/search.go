@@ -17,6 +17,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	log "log"
 	"net/http"
@@ -31,8 +32,11 @@ import (
 	"github.com/delving/hub3/config"
 	c "github.com/delving/hub3/config"
 	"github.com/delving/hub3/hub3"
+	"github.com/delving/hub3/hub3/ead"
 	"github.com/delving/hub3/hub3/fragments"
 	"github.com/delving/hub3/hub3/index"
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	"github.com/delving/hub3/ikuzo/storage/x/memory"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -51,17 +55,20 @@ func RegisterSearch(router chi.Router) {
 	r.Use(middleware.Throttle(100))
 
 	r.Get("/v2", GetScrollResult)
+	r.Get("/explain", explainSearch)
+	r.Get("/v2/_export", exportSearch)
 	r.Get("/v2/{id}", func(w http.ResponseWriter, r *http.Request) {
 		getSearchRecord(w, r)
 		return
 	})
+	r.Get("/v2/{id}/related", getRelatedRecords)
 
 	r.Get("/v1", func(w http.ResponseWriter, r *http.Request) {
-		render.JSON(w, r, &ErrorMessage{"not enabled", ""})
+		renderFormat(w, r, &ErrorMessage{"not enabled", ""})
 		return
 	})
 	r.Get("/v1/{id}", func(w http.ResponseWriter, r *http.Request) {
-		render.JSON(w, r, &ErrorMessage{"not enabled", ""})
+		renderFormat(w, r, &ErrorMessage{"not enabled", ""})
 		return
 	})
 
@@ -70,30 +77,105 @@ func RegisterSearch(router chi.Router) {
 }
 
 func GetScrollResult(w http.ResponseWriter, r *http.Request) {
-	searchRequest, err := fragments.NewSearchRequest(r.URL.Query())
+	params := r.URL.Query()
+
+	if token := params.Get("cursor"); token != "" {
+		cur, searchRequest, err := decodeCursor(token)
+		if err != nil {
+			renderCursorError(w, r, err)
+			return
+		}
+
+		processSearchRequest(w, r, searchRequest, cur)
+
+		return
+	}
+
+	searchRequest, err := fragments.NewSearchRequest(params)
 	if err != nil {
 		log.Println("Unable to create Search request")
 		render.Status(r, http.StatusBadRequest)
 		render.PlainText(w, r, err.Error())
 		return
 	}
-	ProcessSearchRequest(w, r, searchRequest)
-	return
+
+	var cur *fragments.Cursor
+
+	if strings.EqualFold(params.Get("pit"), "true") {
+		pit, err := fragments.OpenPointInTime(
+			r.Context(),
+			index.ESClient(),
+			c.Config.ElasticSearch.GetIndexName(),
+			cursorTTL(),
+		)
+		if err != nil {
+			log.Printf("Unable to open point-in-time: %s", err)
+			http.Error(w, "unable to open point-in-time", http.StatusInternalServerError)
+			return
+		}
+
+		cur = &fragments.Cursor{PIT: pit}
+	}
+
+	processSearchRequest(w, r, searchRequest, cur)
 }
 
 //
 func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest *fragments.SearchRequest) {
+	processSearchRequest(w, r, searchRequest, nil)
+}
 
-	s, fub, err := searchRequest.ElasticSearchService(index.ESClient())
+// processSearchRequest runs the search. When the cursor is not nil the search
+// continues after the cursor and uses its point-in-time when present.
+func processSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest *fragments.SearchRequest, cur *fragments.Cursor) {
+	if searchRequest.GetResponseFormatType() == fragments.ResponseFormatType_JSON && acceptsProtobuf(r) {
+		searchRequest.ResponseFormatType = fragments.ResponseFormatType_PROTOBUF
+	}
+
+	if searchRequest.GetResponseFormatType() == fragments.ResponseFormatType_JSONP {
+		if err := validCallback(r.URL.Query().Get("callback")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+
+	if searchRequest.GetLang() == "" {
+		searchRequest.Lang = fragments.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	}
+
+	dataSetConfig := lookupDataSetConfig(r.Context(), c.Config.OrgID, searchRequest.SpecFilter())
+	searchRequest.ApplyDataSetConfig(dataSetConfig)
+
+	s, fub, err := searchRequest.CursorSearchService(index.ESClient(), cur, cursorTTL())
 	if err != nil {
 		log.Printf("Unable to create Search Service: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	explain := strings.EqualFold(r.URL.Query().Get("explain"), "true")
+	if explain {
+		s = s.Explain(true)
+	}
+
+	var geoPrecision int
+
+	if searchRequest.GetResponseFormatType() == fragments.ResponseFormatType_GEOCLUSTER {
+		geoPrecision, err = fragments.GeohashPrecision(r.URL.Query().Get("zoom"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		s = s.Size(0).Aggregation(fragments.GeoClusterAggName, searchRequest.GeoClusterAggregation(geoPrecision))
+	}
+
 	// suggestion
 	//s.Suggester(elastic.NewSuggestField)
 
+	start := time.Now()
 	res, err := s.Do(r.Context())
 	echoRequest := r.URL.Query().Get("echo")
 	if err != nil {
@@ -109,6 +191,10 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 				return
 			}
 		}
+		if cur.HasPIT() && elastic.IsNotFound(err) {
+			renderCursorError(w, r, fragments.ErrCursorExpired)
+			return
+		}
 		log.Println("Unable to get search result.")
 		log.Println(err)
 		return
@@ -118,6 +204,24 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		return
 	}
 
+	recordSearch(searchRequest, cur, res.TotalHits(), time.Since(start))
+
+	if searchRequest.GetResponseFormatType() == fragments.ResponseFormatType_GEOCLUSTER {
+		clusters, err := fragments.DecodeGeoClusters(res)
+		if err != nil {
+			log.Printf("Unable to decode geo clusters: %s", err)
+			http.Error(w, "unable to decode geo clusters", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, &fragments.GeoClusterResult{
+			Pager:     &fragments.ScrollPager{Total: res.TotalHits()},
+			Precision: geoPrecision,
+			Clusters:  clusters,
+		})
+		return
+	}
+
 	if searchRequest.Peek != "" {
 		aggs, err := searchRequest.DecodeFacets(res, nil)
 		if err != nil {
@@ -169,9 +273,29 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		return
 	}
 
+	var pit string
+	if cur.HasPIT() {
+		pit = cur.PIT
+	}
+
+	next, err := searchRequest.NextCursor(res, pit, time.Now().Add(cursorTTL()))
+	if err != nil {
+		log.Printf("Unable to create next cursor: %s", err)
+		http.Error(w, "unable to create next cursor", http.StatusInternalServerError)
+		return
+	}
+
+	pager.NextCursor, err = encodeNextCursor(next)
+	if err != nil {
+		log.Printf("Unable to encode next cursor: %s", err)
+		http.Error(w, "unable to encode next cursor", http.StatusInternalServerError)
+		return
+	}
+
 	// Add scrollID pager information to the header
 	w.Header().Add("P_PREVIOUS_SCROLL_ID", pager.PreviousScrollID)
 	w.Header().Add("P_NEXT_SCROLL_ID", pager.NextScrollID)
+	w.Header().Add("P_NEXT_CURSOR", pager.NextCursor)
 	w.Header().Add("P_CURSOR", strconv.Itoa(int(pager.Cursor)))
 	w.Header().Add("P_TOTAL", strconv.Itoa(int(pager.Total)))
 	w.Header().Add("P_ROWS", strconv.Itoa(int(pager.Rows)))
@@ -277,6 +401,21 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		render.JSON(w, r, entries)
 		w.Header().Set("Content-Type", "application/json-ld; charset=utf-8")
 		return
+	case fragments.ResponseFormatType_GEOJSON:
+		w.Header().Set("Content-Type", "application/geo+json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(fragments.NewFeatureCollection(records)); err != nil {
+			log.Printf("Unable to render GeoJSON: %s", err)
+		}
+		return
+	case fragments.ResponseFormatType_KML:
+		w.Header().Set("Content-Type", "application/vnd.google-earth.kml+xml; charset=utf-8")
+		fmt.Fprint(w, xml.Header)
+		enc := xml.NewEncoder(w)
+		enc.Indent("", "  ")
+		if err := enc.Encode(fragments.NewKML(records)); err != nil {
+			log.Printf("Unable to render KML: %s", err)
+		}
+		return
 	case fragments.ResponseFormatType_BULKACTION:
 		actions := []string{}
 		for _, rec := range records {
@@ -329,12 +468,26 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 			return
 		}
 
+		textQuery.SnippetConfig, textQueryErr = snippetConfig(r.URL.Query())
+		if textQueryErr != nil {
+			http.Error(w, textQueryErr.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// JSON-LD keeps the literals in all languages
+	if searchRequest.GetLang() != "" && searchRequest.ItemFormat != fragments.ItemFormatType_JSONLD {
+		for _, rec := range records {
+			rec.PreferLanguage(searchRequest.GetLang())
+		}
 	}
 
 	switch searchRequest.ItemFormat {
 	case fragments.ItemFormatType_FLAT:
+		resultFields := dataSetConfig.ResultFieldLabels()
+
 		for _, rec := range records {
-			rec.NewFields(textQuery)
+			rec.NewFields(textQuery, resultFields...)
 			rec.Resources = nil
 		}
 	case fragments.ItemFormatType_SUMMARY:
@@ -651,21 +804,137 @@ func ProcessSearchRequest(w http.ResponseWriter, r *http.Request, searchRequest
 		result.Facets = aggs
 	}
 
+	if explain {
+		result.Explain, err = newSearchExplanation(r.Context(), searchRequest, res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
 	switch searchRequest.GetResponseFormatType() {
-	// TODO enable later again
-	//case fragments.ResponseFormatType_PROTOBUF:
-	//output, err := proto.Marshal(result)
-	//if err != nil {
-	//log.Println("Unable to marshal result to protobuf format.")
-	//return
-	//}
-	//render.Data(w, r, output)
+	case fragments.ResponseFormatType_PROTOBUF:
+		resp, err := fragments.NewSearchResponse(result)
+		if err != nil {
+			log.Printf("Unable to create protobuf search response: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderProtobuf(w, r, resp)
+	case fragments.ResponseFormatType_XML:
+		render.XML(w, r, fragments.NewXMLSearchResult(result))
+	case fragments.ResponseFormatType_JSONP:
+		renderJSONP(w, r, result)
 	default:
 		render.JSON(w, r, result)
 	}
 	return
 }
 
+// explainSearch returns how the search query is parsed, analyzed and scored.
+// With mode=memory the query is explained against the EAD description index
+// of the given spec instead of ElasticSearch.
+func explainSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	if strings.EqualFold(params.Get("mode"), "memory") {
+		explainDescriptionSearch(w, r)
+		return
+	}
+
+	searchRequest, err := fragments.NewSearchRequest(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, _, err := searchRequest.ElasticSearchService(index.ESClient())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := s.Explain(true).Do(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	explanation, err := newSearchExplanation(r.Context(), searchRequest, res)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, explanation)
+}
+
+func explainDescriptionSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	spec := params.Get("spec")
+	if spec == "" {
+		http.Error(w, "spec is required when mode is memory", http.StatusBadRequest)
+		return
+	}
+
+	descIndex, err := ead.GetDescriptionIndex(spec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	defer descIndex.Close()
+
+	explanation, err := descIndex.Explain(params.Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, explanation)
+}
+
+// newSearchExplanation creates the search.Explanation for the SearchRequest.
+// The query is explained both as the QueryTerm tree with the analyzer output
+// per term and as it is rewritten by the validate query API of ElasticSearch.
+// The hits are only explained when the search was executed with explain enabled.
+func newSearchExplanation(ctx context.Context, sr *fragments.SearchRequest, res *elastic.SearchResult) (*search.Explanation, error) {
+	explanation, err := search.NewExplanation(sr.GetQuery(), search.SetDefaultOperator(search.AndOperator))
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := sr.ElasticQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	explanation.ElasticQuery, err = query.Source()
+	if err != nil {
+		return nil, err
+	}
+
+	explain, rewrite := true, true
+
+	validation, err := index.ESClient().Validate(c.Config.ElasticSearch.GetIndexName()).
+		Query(query).
+		Explain(&explain).
+		Rewrite(&rewrite).
+		Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to validate query; %w", err)
+	}
+
+	explanation.Rewritten = elasticsearch.RewrittenQueries(validation)
+
+	if res != nil && res.Hits != nil {
+		explanation.Hits = elasticsearch.ExplainHits(res.Hits.Hits)
+	}
+
+	return explanation, nil
+}
+
 func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	res, err := index.ESClient().Get().
@@ -700,6 +969,19 @@ func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Add("Vary", "Accept-Language")
+
+	if lang := recordLanguage(r); lang != "" {
+		record.PreferLanguage(lang)
+	}
+
+	if record.Meta != nil {
+		dataSetConfig := lookupDataSetConfig(r.Context(), record.Meta.OrgID, record.Meta.Spec)
+		if len(dataSetConfig.GetViewConfig().GetBlocks()) != 0 {
+			record.View = record.NewDetailView(dataSetConfig.GetViewConfig())
+		}
+	}
+
 	switch r.URL.Query().Get("itemFormat") {
 	case "flat":
 		record.NewFields(nil)
@@ -721,7 +1003,12 @@ func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	switch r.URL.Query().Get("format") {
+	format := r.URL.Query().Get("format")
+	if format == "" && acceptsProtobuf(r) {
+		format = "protobuf"
+	}
+
+	switch format {
 	case "jsonld":
 		entries := []map[string]interface{}{}
 		for _, json := range record.NewJSONLD() {
@@ -731,21 +1018,42 @@ func getSearchRecord(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, entries)
 		w.Header().Set("Content-Type", "application/json-ld; charset=utf-8")
 		return
-
-	// TODO enable protobuf later again
-	//case "protobuf":
-	//output, err := proto.Marshal(record)
-	//if err != nil {
-	//log.Println("Unable to marshal result to protobuf format.")
-	//return
-	//}
-	//render.Data(w, r, output)
+	case "xml":
+		render.XML(w, r, fragments.NewXMLItem(record))
+		return
+	case "jsonp":
+		renderJSONP(w, r, record)
+		return
+	case "protobuf":
+		hit, err := fragments.NewSearchHit(record)
+		if err != nil {
+			log.Printf("Unable to create protobuf search hit: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		renderProtobuf(w, r, hit)
 	default:
 		render.JSON(w, r, record)
 	}
 	return
 }
 
+// recordLanguage returns the preferred language of the record from the 'lang'
+// parameter or the Accept-Language header. JSON-LD keeps the literals in all
+// languages, so no language is returned for JSON-LD responses.
+func recordLanguage(r *http.Request) string {
+	params := r.URL.Query()
+	if params.Get("format") == "jsonld" || params.Get("itemFormat") == "jsonld" {
+		return ""
+	}
+
+	if lang := params.Get("lang"); lang != "" {
+		return fragments.BaseLanguage(lang)
+	}
+
+	return fragments.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
 func decodeFragmentGraph(hit json.RawMessage) (*fragments.FragmentGraph, error) {
 	r := new(fragments.FragmentGraph)
 	if err := json.Unmarshal(hit, r); err != nil {
@@ -847,3 +1155,42 @@ func decodeFragmentGraphs(res *elastic.SearchResult) ([]*fragments.FragmentGraph
 	}
 	return records, searchAfter, nil
 }
+
+// snippetConfig returns the search.SnippetConfig from the request parameters.
+// When snippets are not requested nil is returned.
+func snippetConfig(params url.Values) (*search.SnippetConfig, error) {
+	if !strings.EqualFold(params.Get("snippets"), "true") {
+		return nil, nil
+	}
+
+	cfg := search.NewSnippetConfig()
+
+	// 0 fragments highlights the entire text and 0 is the default merge distance
+	for _, param := range []struct {
+		key    string
+		min    int
+		setter func(int) search.SnippetOption
+	}{
+		{"fragmentSize", 1, search.SetFragmentSize},
+		{"nrFragments", 0, search.SetNumberOfFragments},
+		{"fragmentMerge", 0, search.SetMergeDistance},
+	} {
+		value := params.Get(param.key)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < param.min {
+			return nil, fmt.Errorf("%s must be an integer of at least %d: %s", param.key, param.min, value)
+		}
+
+		param.setter(n)(cfg)
+	}
+
+	if strings.EqualFold(params.Get("sentenceAware"), "true") {
+		search.SetSentenceAware()(cfg)
+	}
+
+	return cfg, nil
+}
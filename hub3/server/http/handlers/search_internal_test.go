@@ -0,0 +1,79 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package handlers
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_snippetConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *search.SnippetConfig
+		wantErr bool
+	}{
+		{"not requested", "fragmentSize=10", nil, false},
+		{"defaults", "snippets=true", search.NewSnippetConfig(), false},
+		{
+			"custom",
+			"snippets=true&fragmentSize=50&nrFragments=2&fragmentMerge=1&sentenceAware=true",
+			search.NewSnippetConfig(
+				search.SetFragmentSize(50),
+				search.SetNumberOfFragments(2),
+				search.SetMergeDistance(1),
+				search.SetSentenceAware(),
+			),
+			false,
+		},
+		{
+			"zero fragments and merge distance",
+			"snippets=true&nrFragments=0&fragmentMerge=0",
+			search.NewSnippetConfig(
+				search.SetNumberOfFragments(0),
+				search.SetMergeDistance(0),
+			),
+			false,
+		},
+		{"zero", "snippets=true&fragmentSize=0", nil, true},
+		{"negative", "snippets=true&nrFragments=-1", nil, true},
+		{"not a number", "snippets=true&fragmentMerge=x", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := snippetConfig(params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("snippetConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("snippetConfig() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
@@ -52,6 +52,9 @@ type IndexMessage struct {
 	Deleted        bool      `protobuf:"varint,5,opt,name=Deleted,proto3" json:"Deleted,omitempty"`
 	Revision       *Revision `protobuf:"bytes,6,opt,name=Revision,proto3" json:"Revision,omitempty"`
 	Source         []byte    `protobuf:"bytes,7,opt,name=Source,proto3" json:"Source,omitempty"`
+	IngestID       string    `protobuf:"bytes,8,opt,name=IngestID,proto3" json:"IngestID,omitempty"`
+	Update         bool      `protobuf:"varint,9,opt,name=Update,proto3" json:"Update,omitempty"`
+	ContentHash    string    `protobuf:"bytes,10,opt,name=ContentHash,proto3" json:"ContentHash,omitempty"`
 }
 
 func (x *IndexMessage) Reset() {
@@ -135,6 +138,27 @@ func (x *IndexMessage) GetSource() []byte {
 	return nil
 }
 
+func (x *IndexMessage) GetIngestID() string {
+	if x != nil {
+		return x.IngestID
+	}
+	return ""
+}
+
+func (x *IndexMessage) GetUpdate() bool {
+	if x != nil {
+		return x.Update
+	}
+	return false
+}
+
+func (x *IndexMessage) GetContentHash() string {
+	if x != nil {
+		return x.ContentHash
+	}
+	return ""
+}
+
 // Version of the record in the time-revision-store.
 type Revision struct {
 	state         protoimpl.MessageState
@@ -196,7 +220,7 @@ var File_ikuzo_domain_domainpb_index_proto protoreflect.FileDescriptor
 var file_ikuzo_domain_domainpb_index_proto_rawDesc = []byte{
 	0x0a, 0x21, 0x69, 0x6b, 0x75, 0x7a, 0x6f, 0x2f, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x2f, 0x64,
 	0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x70, 0x62, 0x2f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x12, 0x08, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x70, 0x62, 0x22, 0xf0, 0x01,
+	0x6f, 0x74, 0x6f, 0x12, 0x08, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x70, 0x62, 0x22, 0xc6, 0x02,
 	0x0a, 0x0c, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x26,
 	0x0a, 0x0e, 0x4f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x49, 0x44,
 	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x4f, 0x72, 0x67, 0x61, 0x6e, 0x69, 0x73, 0x61,
@@ -212,12 +236,17 @@ var file_ikuzo_domain_domainpb_index_proto_rawDesc = []byte{
 	0x61, 0x69, 0x6e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x08,
 	0x52, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x6f, 0x75, 0x72,
 	0x63, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65,
-	0x22, 0x30, 0x0a, 0x08, 0x52, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03,
-	0x53, 0x48, 0x41, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x53, 0x48, 0x41, 0x12, 0x12,
-	0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70, 0x61,
-	0x74, 0x68, 0x42, 0x17, 0x5a, 0x15, 0x69, 0x6b, 0x75, 0x7a, 0x6f, 0x2f, 0x64, 0x6f, 0x6d, 0x61,
-	0x69, 0x6e, 0x2f, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x33,
+	0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x49, 0x44, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x49, 0x6e, 0x67, 0x65, 0x73, 0x74, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06,
+	0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x55, 0x70,
+	0x64, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x48,
+	0x61, 0x73, 0x68, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x43, 0x6f, 0x6e, 0x74, 0x65,
+	0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x22, 0x30, 0x0a, 0x08, 0x52, 0x65, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x53, 0x48, 0x41, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x53, 0x48, 0x41, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x42, 0x17, 0x5a, 0x15, 0x69, 0x6b, 0x75, 0x7a,
+	0x6f, 0x2f, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x2f, 0x64, 0x6f, 0x6d, 0x61, 0x69, 0x6e, 0x70,
+	0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -0,0 +1,119 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"expvar"
+	"fmt"
+
+	"github.com/delving/hub3/hub3/server/http/handlers"
+	"github.com/delving/hub3/ikuzo"
+	"github.com/delving/hub3/ikuzo/service/x/analytics"
+	"github.com/delving/hub3/ikuzo/storage/x/boltdb"
+)
+
+type Analytics struct {
+	// enable recording of search analytics
+	Enabled bool `json:"enabled"`
+	// path to the embedded analytics database
+	DBPath string `json:"dbPath"`
+	// number of days search events are kept. default: 90
+	RetentionDays int `json:"retentionDays"`
+	// Retention overrides the retention per organization
+	Retention []OrgRetention `json:"retention"`
+	// header with the admin key. default: X-API-Key
+	AdminHeader string `json:"adminHeader"`
+	// Admins can request the reports of their organization
+	Admins []AnalyticsAdmin `json:"admins"`
+	// gather analytics metrics
+	Metrics bool `json:"metrics"`
+	// svc is the shared analytics service
+	svc *analytics.Service
+}
+
+// OrgRetention is the number of days search events of an organization are kept.
+type OrgRetention struct {
+	OrgID string `json:"orgID"`
+	Days  int    `json:"days"`
+}
+
+// AnalyticsAdmin is an admin that can request the reports of an organization.
+type AnalyticsAdmin struct {
+	// value of the admin header
+	Key string `json:"key"`
+	// organization of the admin
+	OrgID string `json:"orgID"`
+}
+
+// GetService returns the shared analytics.Service. It is created on first use.
+func (a *Analytics) GetService(cfg *Config) (*analytics.Service, error) {
+	if a.svc != nil {
+		return a.svc, nil
+	}
+
+	store, err := boltdb.NewAnalyticsStore(a.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []analytics.Option{
+		analytics.SetStore(store),
+		analytics.SetDefaultOrgID(cfg.OrgID),
+		analytics.SetRetention(a.RetentionDays),
+		analytics.SetAdminHeader(a.AdminHeader),
+	}
+
+	for _, r := range a.Retention {
+		options = append(options, analytics.SetOrgRetention(r.OrgID, r.Days))
+	}
+
+	for _, admin := range a.Admins {
+		options = append(options, analytics.SetAdmin(admin.Key, admin.OrgID))
+	}
+
+	svc, err := analytics.NewService(options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create analytics service; %w", err)
+	}
+
+	if a.Metrics {
+		expvar.Publish("hub3-analytics-service", expvar.Func(func() interface{} { m := svc.Metrics(); return m }))
+	}
+
+	a.svc = svc
+
+	return svc, nil
+}
+
+func (a *Analytics) AddOptions(cfg *Config) error {
+	if !a.Enabled {
+		return nil
+	}
+
+	svc, err := a.GetService(cfg)
+	if err != nil {
+		return err
+	}
+
+	handlers.SetSearchRecorder(svc)
+
+	cfg.options = append(
+		cfg.options,
+		ikuzo.SetAnalyticsService(svc),
+		ikuzo.SetShutdownHook("analytics-service", svc),
+	)
+
+	return nil
+}
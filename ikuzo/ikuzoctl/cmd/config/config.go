@@ -15,9 +15,12 @@
 package config
 
 import (
+	"context"
+
 	"github.com/delving/hub3/ikuzo"
 	"github.com/delving/hub3/ikuzo/logger"
 	"github.com/delving/hub3/ikuzo/service/x/index"
+	eshub "github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	"github.com/spf13/viper"
 )
 
@@ -34,9 +37,15 @@ type Config struct {
 	TimeRevisionStore `json:"timeRevisionStore"`
 	Logging           `json:"logging"`
 	Nats              `json:"nats"`
+	IndexQueue        `json:"indexQueue"`
+	DeadLetter        `json:"deadLetter"`
+	ContentHash       `json:"contentHash"`
 	EAD               `json:"ead"`
 	DB                `json:"db"`
 	ImageProxy        `json:"imageProxy"`
+	Analytics         `json:"analytics"`
+	ViewConfig        `json:"viewConfig"`
+	Snapshot          `json:"snapshot"`
 	PostHooks         []PostHook `json:"posthooks"`
 	options           []ikuzo.Option
 	logger            logger.CustomLogger
@@ -56,6 +65,9 @@ func (cfg *Config) Options(cfgOptions ...ConfigOption) ([]ikuzo.Option, error) {
 			&cfg.TimeRevisionStore,
 			&cfg.EAD,
 			&cfg.ImageProxy,
+			&cfg.Analytics,
+			&cfg.ViewConfig,
+			&cfg.Snapshot,
 			&cfg.Logging,
 		}
 	}
@@ -80,23 +92,50 @@ func (cfg *Config) Options(cfgOptions ...ConfigOption) ([]ikuzo.Option, error) {
 func SetViperDefaults() {
 	// setting defaults
 	viper.SetDefault("HTTP.port", 3001)
+	viper.SetDefault("ElasticSearch.migrateMappings", true)
+	viper.SetDefault("ElasticSearch.routing", "shared")
 	viper.SetDefault("TimeRevisionStore.dataPath", "/tmp/trs")
+	viper.SetDefault("Analytics.dbPath", "/tmp/hub3/analytics.db")
+	viper.SetDefault("ViewConfig.dbPath", "/tmp/hub3/viewconfig.db")
+	viper.SetDefault("Snapshot.repository", "ikuzo")
+	viper.SetDefault("IndexQueue.path", "/tmp/hub3/index-queue.db")
+	viper.SetDefault("DeadLetter.dbPath", "/tmp/hub3/deadletter.db")
+	viper.SetDefault("DeadLetter.maxAttempts", 5)
+	viper.SetDefault("DeadLetter.retryInterval", 1)
+	viper.SetDefault("DeadLetter.maxRetryInterval", 60)
+	viper.SetDefault("ContentHash.dbPath", "/tmp/hub3/contenthash.db")
 }
 
 func (cfg *Config) GetIndexService() (*index.Service, error) {
 	var (
-		ncfg *index.NatsConfig
-		err  error
+		q   index.Queue
+		err error
 	)
 
-	if cfg.Nats.Enabled {
-		ncfg, err = cfg.Nats.GetConfig()
-		if err != nil {
-			return nil, err
-		}
+	switch {
+	case cfg.Nats.Enabled:
+		q, err = cfg.Nats.GetQueue()
+	case cfg.IndexQueue.Enabled:
+		q, err = cfg.IndexQueue.GetQueue()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := cfg.DeadLetter.indexOptions()
+	if err != nil {
+		return nil, err
 	}
 
-	is, err := cfg.ElasticSearch.IndexService(&cfg.logger, ncfg)
+	hashOptions, err := cfg.ContentHash.indexOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	options = append(options, hashOptions...)
+
+	is, err := cfg.ElasticSearch.IndexService(&cfg.logger, q, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +143,15 @@ func (cfg *Config) GetIndexService() (*index.Service, error) {
 	return is, nil
 }
 
+// Reindex copies the index of the indexType into a new index with the current
+// mapping and switches the alias when the document counts per dataset match.
+//
+// When keepOldIndex is true the old index is not deleted, so the alias can be
+// switched back for a rollback.
+func (cfg *Config) Reindex(ctx context.Context, indexType string, keepOldIndex bool, progress func(eshub.ReindexProgress)) (*eshub.ReindexResult, error) {
+	return cfg.ElasticSearch.reindex(ctx, &cfg.logger, indexType, keepOldIndex, progress)
+}
+
 func (cfg *Config) defaultOptions() error {
 	// db, err := cfg.DB.getDB()
 	// if err != nil {
@@ -0,0 +1,92 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+
+	"github.com/delving/hub3/ikuzo/service/x/bulk"
+	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/delving/hub3/ikuzo/storage/x/boltdb"
+)
+
+// ContentHash configures skipping records that are unchanged since the previous bulk ingest.
+//
+// The content hashes are stored by the index service of this node, so records
+// that are indexed by a remote indexer are always indexed in full.
+type ContentHash struct {
+	// store the content hash per record and skip unchanged records
+	Enabled bool `json:"enabled"`
+	// path to the embedded content hash database
+	DBPath string `json:"dbPath"`
+	store  *boltdb.ContentHashStore
+}
+
+func (ch *ContentHash) AddOptions(cfg *Config) error {
+	return nil
+}
+
+// getStore returns the ContentHashStore that is shared by the bulk and the index service.
+func (ch *ContentHash) getStore() (*boltdb.ContentHashStore, error) {
+	if ch.store != nil {
+		return ch.store, nil
+	}
+
+	store, err := boltdb.NewContentHashStore(ch.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ch.store = store
+
+	return store, nil
+}
+
+// bulkOptions returns the bulk.Service options for the content hash store.
+func (ch *ContentHash) bulkOptions() ([]bulk.Option, error) {
+	if !ch.Enabled {
+		return nil, nil
+	}
+
+	store, err := ch.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return []bulk.Option{bulk.SetContentHashStore(store)}, nil
+}
+
+// indexOptions returns the index.Service options for the content hash store.
+func (ch *ContentHash) indexOptions() ([]index.Option, error) {
+	if !ch.Enabled {
+		return nil, nil
+	}
+
+	store, err := ch.getStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return []index.Option{index.SetContentHashStore(store)}, nil
+}
+
+// reset removes all content hashes, so all records are indexed again.
+func (ch *ContentHash) reset(ctx context.Context) error {
+	if ch.store == nil {
+		return nil
+	}
+
+	return ch.store.DeleteAll(ctx)
+}
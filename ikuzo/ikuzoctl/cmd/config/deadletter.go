@@ -0,0 +1,64 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/delving/hub3/ikuzo/storage/x/boltdb"
+)
+
+// DeadLetter configures the retries of failed index messages and the
+// dead-letter store for the messages that could not be indexed.
+type DeadLetter struct {
+	// enable the dead-letter store and the /api/index/deadletters API
+	Enabled bool `json:"enabled"`
+	// path to the embedded dead-letter database
+	DBPath string `json:"dbPath"`
+	// maximum number of index attempts for transient errors. default: 5
+	MaxAttempts int `json:"maxAttempts"`
+	// seconds before the first retry. default: 1
+	RetryInterval int `json:"retryInterval"`
+	// maximum seconds between retries. default: 60
+	MaxRetryInterval int `json:"maxRetryInterval"`
+}
+
+func (d *DeadLetter) AddOptions(cfg *Config) error {
+	return nil
+}
+
+// indexOptions returns the index.Service options for the retries and the dead-letter store.
+func (d *DeadLetter) indexOptions() ([]index.Option, error) {
+	options := []index.Option{
+		index.SetRetryPolicy(index.RetryPolicy{
+			MaxAttempts:     d.MaxAttempts,
+			InitialInterval: time.Duration(d.RetryInterval) * time.Second,
+			MaxInterval:     time.Duration(d.MaxRetryInterval) * time.Second,
+			Multiplier:      2,
+		}),
+	}
+
+	if !d.Enabled {
+		return options, nil
+	}
+
+	store, err := boltdb.NewDeadLetterStore(d.DBPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(options, index.SetDeadLetterStore(store)), nil
+}
@@ -25,14 +25,17 @@ import (
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/delving/hub3/hub3/models"
+	"github.com/delving/hub3/hub3/server/http/handlers"
 	"github.com/delving/hub3/ikuzo"
 	"github.com/delving/hub3/ikuzo/logger"
+	"github.com/delving/hub3/ikuzo/search"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
 	"github.com/delving/hub3/ikuzo/service/x/index"
 	eshub "github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/mapping"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/go-chi/chi"
 	"github.com/rs/zerolog/log"
 )
 
@@ -71,12 +74,80 @@ type ElasticSearch struct {
 	IndexTypes []string
 	// use FastHTTP transport for communication with the ElasticSearch cluster
 	FastHTTP bool
+	// apply additive mapping changes to the live indices at startup. default: true
+	MigrateMappings bool
+	// ProxyPolicy restricts the queries of the elasticsearch proxy
+	ProxyPolicy ProxyPolicy
+	// RefreshInterval is the refresh_interval of the indices in seconds. The proxy
+	// cache is invalidated again after it when records are indexed. default: 1
+	RefreshInterval int
+	// caching elasticsearch proxy
+	proxy *eshub.Proxy
+	// Routing selects the index of each organization: shared, organization or dataset. default: shared
+	// Additive mapping migrations are applied to each routed index. Reindexing and
+	// restoring snapshots are refused when the base alias refers to routed indices.
+	Routing string
+	// router selects the routed index aliases
+	router *eshub.IndexRouter
+	// contentHash is cleared when the indices are reset
+	contentHash *ContentHash
+}
+
+// ProxyPolicy configures which queries are allowed by the elasticsearch proxy.
+type ProxyPolicy struct {
+	// enable the policy. When disabled any search request is forwarded to any index
+	Enabled bool
+	// header that identifies the caller. default: X-API-Key
+	CallerHeader string
+	// callers with their organization and allowed index patterns.
+	// Unknown callers are restricted to the default orgID and the configured indices.
+	Callers []ProxyCaller
+}
+
+// ProxyCaller is a caller of the elasticsearch proxy.
+type ProxyCaller struct {
+	// value of the caller header
+	Key string
+	// orgID that is added as a filter to each query
+	OrgID string
+	// index patterns the caller is allowed to query, e.g. 'hub3v2*'
+	Indices []string
+}
+
+func (e *ElasticSearch) proxyPolicy(orgID string) *eshub.ProxyPolicy {
+	policy := &eshub.ProxyPolicy{
+		CallerHeader: e.ProxyPolicy.CallerHeader,
+		Callers:      map[string]eshub.CallerPolicy{},
+		Default: &eshub.CallerPolicy{
+			OrgID:   orgID,
+			Indices: []string{e.normalizedIndexName() + "*"},
+		},
+	}
+
+	for _, caller := range e.ProxyPolicy.Callers {
+		policy.Callers[caller.Key] = eshub.CallerPolicy{
+			OrgID:   caller.OrgID,
+			Indices: caller.Indices,
+		}
+	}
+
+	return policy
 }
 
 func (e *ElasticSearch) normalizedIndexName() string {
 	return strings.ToLower(e.IndexName)
 }
 
+func (e *ElasticSearch) hasIndexType(indexType string) bool {
+	for _, t := range e.IndexTypes {
+		if t == indexType {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (e *ElasticSearch) AddOptions(cfg *Config) error {
 	if !e.Enabled || len(e.Urls) == 0 {
 		return nil
@@ -87,15 +158,68 @@ func (e *ElasticSearch) AddOptions(cfg *Config) error {
 		return fmt.Errorf("unable to create elasticsearch.Client: %w", err)
 	}
 
+	router, err := e.indexRouter(client)
+	if err != nil {
+		return err
+	}
+
 	if e.Proxy {
-		esProxy, proxyErr := eshub.NewProxy(client)
+		proxyOptions := []eshub.ProxyOption{}
+		if e.RefreshInterval > 0 {
+			proxyOptions = append(proxyOptions, eshub.SetProxyRefreshInterval(time.Duration(e.RefreshInterval)*time.Second))
+		}
+
+		if e.ProxyPolicy.Enabled {
+			proxyOptions = append(proxyOptions, eshub.SetProxyPolicy(e.proxyPolicy(cfg.OrgID)))
+		}
+
+		if router != nil {
+			proxyOptions = append(proxyOptions, eshub.SetProxyIndexRouter(router))
+		}
+
+		esProxy, proxyErr := eshub.NewProxy(client, proxyOptions...)
 		if proxyErr != nil {
 			return fmt.Errorf("unable to create ES proxy: %w", proxyErr)
 		}
 
+		e.proxy = esProxy
+
+		// related records in the v2 search API are cached by the proxy
+		handlers.SetSearchCache(esProxy)
+
 		cfg.options = append(cfg.options, ikuzo.SetElasticSearchProxy(esProxy))
 	}
 
+	if e.hasIndexType("v2") {
+		searcherOptions := []eshub.SearcherOption{}
+		if router != nil {
+			searcherOptions = append(searcherOptions, eshub.SetSearcherIndexRouter(router))
+		}
+
+		searcher, searchErr := eshub.NewSearcher(client, fmt.Sprintf("%sv2", e.normalizedIndexName()), searcherOptions...)
+		if searchErr != nil {
+			return fmt.Errorf("unable to create ES searcher: %w", searchErr)
+		}
+
+		searchOptions := []search.OptionFunc{search.SetSearcher(searcher), search.SetOrgID(cfg.OrgID)}
+
+		if cfg.Analytics.Enabled {
+			recorder, recErr := cfg.Analytics.GetService(cfg)
+			if recErr != nil {
+				return recErr
+			}
+
+			searchOptions = append(searchOptions, search.SetRecorder(recorder))
+		}
+
+		searchSvc, searchErr := search.NewService(searchOptions...)
+		if searchErr != nil {
+			return fmt.Errorf("unable to create search service; %w", searchErr)
+		}
+
+		cfg.options = append(cfg.options, ikuzo.SetSearchService(searchSvc))
+	}
+
 	// when not in datanode mode no service should be started
 	if !cfg.IsDataNode() {
 		return nil
@@ -112,10 +236,20 @@ func (e *ElasticSearch) AddOptions(cfg *Config) error {
 		return fmt.Errorf("unable to create posthook service; %w", phErr)
 	}
 
+	bulkOptions, chErr := cfg.ContentHash.bulkOptions()
+	if chErr != nil {
+		return fmt.Errorf("unable to create content hash store; %w", chErr)
+	}
+
+	e.contentHash = &cfg.ContentHash
+
 	bulkSvc, bulkErr := bulk.NewService(
-		bulk.SetIndexService(is),
-		bulk.SetIndexTypes(e.IndexTypes...),
-		bulk.SetPostHookService(postHooks...),
+		append(
+			bulkOptions,
+			bulk.SetIndexService(is),
+			bulk.SetIndexTypes(e.IndexTypes...),
+			bulk.SetPostHookService(postHooks...),
+		)...,
 	)
 	if bulkErr != nil {
 		return fmt.Errorf("unable to create bulk service; %w", isErr)
@@ -125,13 +259,38 @@ func (e *ElasticSearch) AddOptions(cfg *Config) error {
 		cfg.options,
 		ikuzo.SetBulkService(bulkSvc),
 		ikuzo.SetShutdownHook("elasticsearch", is),
+		ikuzo.SetShutdownHook("bulk", bulkSvc),
+		ikuzo.SetRouters(func(r chi.Router) {
+			r.Mount("/api/index/ingest", is.IngestRoutes())
+		}),
 	)
 
+	if cfg.DeadLetter.Enabled {
+		cfg.options = append(cfg.options, ikuzo.SetRouters(func(r chi.Router) {
+			r.Mount("/api/index/deadletters", is.DeadLetterRoutes())
+		}))
+	}
+
 	_, err = e.CreateDefaultMappings(client, true, false)
 	if err != nil {
 		return err
 	}
 
+	migrator := eshub.NewMappingMigrator(client, e.MigrateMappings, e.mappingTargets()...)
+
+	if _, err := migrator.Check(context.Background()); err != nil {
+		return fmt.Errorf("unable to check elasticsearch mappings; %w", err)
+	}
+
+	cfg.options = append(
+		cfg.options,
+		ikuzo.SetReadinessCheck("elasticsearch-mappings", migrator),
+		ikuzo.SetShutdownHook("elasticsearch-mappings", migrator),
+		ikuzo.SetRouters(func(r chi.Router) {
+			r.Mount("/api/es/mappings", migrator.Routes())
+		}),
+	)
+
 	return nil
 }
 
@@ -141,6 +300,14 @@ func (e *ElasticSearch) ResetAll(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 	}
+
+	// reset the content hashes, so the records are not skipped by the next ingest
+	if e.contentHash != nil {
+		if err := e.contentHash.reset(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
 	// reset Key Value Store
 	models.ResetStorm()
 
@@ -150,20 +317,87 @@ func (e *ElasticSearch) ResetAll(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// indexMapping returns the alias, the mapping and the field with the dataset
+// of each document for the indexType.
+func (e *ElasticSearch) indexMapping(indexType string) (alias string, m func(shards, replicas int) string, countField string, ok bool) {
+	switch indexType {
+	case "v1":
+		return fmt.Sprintf("%sv1", e.normalizedIndexName()), mapping.V1ESMapping, "system.spec.raw", true
+	case "v2":
+		return fmt.Sprintf("%sv2", e.normalizedIndexName()), mapping.V2ESMapping, "meta.spec", true
+	case "fragments":
+		return fmt.Sprintf("%sv2_frag", e.normalizedIndexName()), mapping.FragmentESMapping, "meta.spec", true
+	}
+
+	return "", nil, "", false
+}
+
+// indexRouter returns the IndexRouter for the configured Routing strategy.
+// When the indices are shared no IndexRouter is returned.
+func (e *ElasticSearch) indexRouter(es *elasticsearch.Client) (*eshub.IndexRouter, error) {
+	if e.router != nil {
+		return e.router, nil
+	}
+
+	strategy, err := eshub.ParseRoutingStrategy(e.Routing)
+	if err != nil {
+		return nil, err
+	}
+
+	if strategy == eshub.RoutingShared {
+		return nil, nil
+	}
+
+	mappings := map[string]string{}
+
+	for _, indexType := range e.IndexTypes {
+		alias, m, _, ok := e.indexMapping(indexType)
+		if !ok {
+			continue
+		}
+
+		mappings[alias] = m(e.Shards, e.Replicas)
+	}
+
+	e.router = eshub.NewIndexRouter(es, strategy, mappings)
+
+	return e.router, nil
+}
+
+// mappingTargets returns a MappingTarget for each configured indexType.
+func (e *ElasticSearch) mappingTargets() []eshub.MappingTarget {
+	targets := []eshub.MappingTarget{}
+
+	for _, indexType := range e.IndexTypes {
+		alias, m, countField, ok := e.indexMapping(indexType)
+		if !ok {
+			continue
+		}
+
+		targets = append(targets, eshub.MappingTarget{
+			Name: indexType,
+			ReindexConfig: eshub.ReindexConfig{
+				Alias:      alias,
+				Mapping:    m(e.Shards, e.Replicas),
+				CountField: countField,
+			},
+		})
+	}
+
+	return targets
+}
+
 func (e *ElasticSearch) CreateDefaultMappings(es *elasticsearch.Client, withAlias bool, withReset bool) ([]string, error) {
 	mappings := map[string]func(shards, replicas int) string{}
 
 	for _, indexType := range e.IndexTypes {
-		switch indexType {
-		case "v1":
-			mappings[fmt.Sprintf("%sv1", e.normalizedIndexName())] = mapping.V1ESMapping
-		case "v2":
-			mappings[fmt.Sprintf("%sv2", e.normalizedIndexName())] = mapping.V2ESMapping
-		case "fragments":
-			mappings[fmt.Sprintf("%sv2_frag", e.normalizedIndexName())] = mapping.FragmentESMapping
-		default:
+		alias, m, _, ok := e.indexMapping(indexType)
+		if !ok {
 			log.Warn().Msgf("ignoring unknown indexType %s during mapping creation", indexType)
+			continue
 		}
+
+		mappings[alias] = m
 	}
 
 	indexNames := []string{}
@@ -209,6 +443,34 @@ func (e *ElasticSearch) CreateDefaultMappings(es *elasticsearch.Client, withAlia
 	return indexNames, nil
 }
 
+// reindex copies the index of the indexType into a new index with the current
+// mapping and switches the alias when the document counts per dataset match.
+func (e *ElasticSearch) reindex(
+	ctx context.Context,
+	l *logger.CustomLogger,
+	indexType string,
+	keepOldIndex bool,
+	progress func(eshub.ReindexProgress),
+) (*eshub.ReindexResult, error) {
+	alias, m, countField, ok := e.indexMapping(indexType)
+	if !ok {
+		return nil, fmt.Errorf("unknown indexType %s", indexType)
+	}
+
+	es, err := e.NewClient(l)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create elasticsearch.Client: %w", err)
+	}
+
+	return eshub.Reindex(ctx, es, eshub.ReindexConfig{
+		Alias:        alias,
+		Mapping:      m(e.Shards, e.Replicas),
+		CountField:   countField,
+		KeepOldIndex: keepOldIndex,
+		Progress:     progress,
+	})
+}
+
 func (e *ElasticSearch) NewClient(l *logger.CustomLogger) (*elasticsearch.Client, error) {
 	if e.client != nil {
 		return e.client, nil
@@ -292,6 +554,12 @@ func (e *ElasticSearch) NewBulkIndexer(es *elasticsearch.Client) (esutil.BulkInd
 		OnError: func(ctx context.Context, err error) {
 			e.logger.Error().Err(err).Msg("flush: bulk indexing error")
 		},
+		OnFlushEnd: func(ctx context.Context) {
+			// the FlushHooks of the index service are called once per flush
+			if e.is != nil {
+				e.is.FlushEnd(ctx)
+			}
+		},
 	})
 
 	if e.Metrics {
@@ -303,16 +571,14 @@ func (e *ElasticSearch) NewBulkIndexer(es *elasticsearch.Client) (esutil.BulkInd
 	return e.bi, err
 }
 
-func (e *ElasticSearch) IndexService(l *logger.CustomLogger, ncfg *index.NatsConfig) (*index.Service, error) {
+func (e *ElasticSearch) IndexService(l *logger.CustomLogger, q index.Queue, options ...index.Option) (*index.Service, error) {
 	if e.is != nil {
 		return e.is, nil
 	}
 
 	var err error
 
-	options := []index.Option{}
-
-	if !e.UseRemoteIndexer || ncfg == nil {
+	if !e.UseRemoteIndexer || q == nil {
 		l.Info().Msg("setting up bulk indexer")
 
 		es, clientErr := e.NewClient(l)
@@ -327,12 +593,26 @@ func (e *ElasticSearch) IndexService(l *logger.CustomLogger, ncfg *index.NatsCon
 
 		options = append(
 			options,
-			index.SetBulkIndexer(bi, ncfg == nil),
+			index.SetBulkIndexer(bi, q == nil),
 		)
+
+		router, routerErr := e.indexRouter(es)
+		if routerErr != nil {
+			return nil, routerErr
+		}
+
+		if router != nil {
+			options = append(options, index.SetIndexRouter(router))
+		}
+	}
+
+	if q != nil {
+		options = append(options, index.SetQueue(q))
 	}
 
-	if ncfg != nil {
-		options = append(options, index.SetNatsConfiguration(ncfg))
+	if e.proxy != nil {
+		// cached proxy responses are invalidated when new records are indexed
+		options = append(options, index.SetFlushHook(e.proxy.Invalidate))
 	}
 
 	e.is, err = index.NewService(options...)
@@ -340,7 +620,7 @@ func (e *ElasticSearch) IndexService(l *logger.CustomLogger, ncfg *index.NatsCon
 		return nil, err
 	}
 
-	if !e.UseRemoteIndexer && ncfg != nil {
+	if !e.UseRemoteIndexer && q != nil {
 		err := e.is.Start(context.Background(), 1)
 		if err != nil {
 			return nil, err
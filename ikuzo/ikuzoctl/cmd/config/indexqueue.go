@@ -0,0 +1,49 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/delving/hub3/ikuzo/service/x/index"
+)
+
+// IndexQueue configures the embedded disk-backed index queue. It provides a
+// durable index queue for single node deployments without a NATS server.
+type IndexQueue struct {
+	// enable the embedded index queue. It is ignored when nats is enabled.
+	Enabled bool `json:"enabled"`
+	// path of the queue database. default: /tmp/hub3/index-queue.db
+	Path  string `json:"path"`
+	queue *index.BoltQueue
+}
+
+func (q *IndexQueue) AddOptions(cfg *Config) error {
+	return nil
+}
+
+// GetQueue returns the embedded index.Queue.
+func (q *IndexQueue) GetQueue() (index.Queue, error) {
+	if q.queue != nil {
+		return q.queue, nil
+	}
+
+	queue, err := index.NewBoltQueue(q.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	q.queue = queue
+
+	return q.queue, nil
+}
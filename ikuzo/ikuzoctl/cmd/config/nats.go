@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/nats-io/nats.go"
 	"github.com/nats-io/stan.go"
 	"github.com/rs/zerolog/log"
 )
@@ -29,8 +30,13 @@ type Nats struct {
 	ClientID     string `json:"clientID"`
 	DurableName  string `json:"durableName"`
 	DurableQueue string `json:"durableQueue"`
+	SubjectID    string `json:"subjectID"`
 	URL          string `json:"url"`
-	cfg          *index.NatsConfig
+	// JetStream uses NATS JetStream instead of the deprecated NATS streaming server
+	JetStream bool `json:"jetStream"`
+	// StreamName is the name of the JetStream stream. default: HUB3-INDEX
+	StreamName string `json:"streamName"`
+	cfg        *index.NatsConfig
 }
 
 func (n *Nats) AddOptions(cfg *Config) error {
@@ -64,6 +70,7 @@ func (n *Nats) GetConfig() (*index.NatsConfig, error) {
 		ClientID:     n.ClientID,
 		DurableName:  n.DurableName,
 		DurableQueue: n.DurableQueue,
+		SubjectID:    n.SubjectID,
 	}
 
 	conn, err := n.newClient(cfg)
@@ -77,3 +84,32 @@ func (n *Nats) GetConfig() (*index.NatsConfig, error) {
 
 	return n.cfg, nil
 }
+
+// GetQueue returns the index.Queue for NATS streaming or NATS JetStream.
+func (n *Nats) GetQueue() (index.Queue, error) {
+	if !n.JetStream {
+		cfg, err := n.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		return index.NewStanQueue(cfg)
+	}
+
+	if n.URL == "" {
+		n.URL = nats.DefaultURL
+	}
+
+	conn, err := nats.Connect(n.URL)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect: %w.\nMake sure a NATS JetStream Server is running at: %s", err, n.URL)
+	}
+
+	return index.NewJetStreamQueue(&index.JetStreamConfig{
+		Conn:         conn,
+		StreamName:   n.StreamName,
+		SubjectID:    n.SubjectID,
+		DurableName:  n.DurableName,
+		DurableQueue: n.DurableQueue,
+	})
+}
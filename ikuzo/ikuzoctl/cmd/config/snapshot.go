@@ -0,0 +1,94 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/delving/hub3/ikuzo"
+	eshub "github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
+	"github.com/go-chi/chi"
+)
+
+type Snapshot struct {
+	// enable the snapshot admin API and schedule
+	Enabled bool `json:"enabled"`
+	// name of the snapshot repository. default: ikuzo
+	Repository string `json:"repository"`
+	// path of the shared filesystem repository; it must be listed in 'path.repo' of each ElasticSearch node
+	Location string `json:"location"`
+	// hours between scheduled snapshots. When 0 no snapshots are scheduled
+	IntervalHours int `json:"intervalHours"`
+	// maximum number of snapshots that are kept. 0 is unlimited
+	RetentionCount int `json:"retentionCount"`
+	// maximum number of days snapshots are kept. 0 is unlimited
+	RetentionDays int `json:"retentionDays"`
+}
+
+// GetSnapshotter returns an eshub.Snapshotter for the index aliases of the
+// configured index types. When scheduled is false no snapshots are scheduled.
+func (s *Snapshot) GetSnapshotter(cfg *Config, scheduled bool) (*eshub.Snapshotter, error) {
+	client, err := cfg.ElasticSearch.NewClient(&cfg.logger)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create elasticsearch.Client: %w", err)
+	}
+
+	aliases := []string{}
+
+	for _, indexType := range cfg.ElasticSearch.IndexTypes {
+		if alias, _, _, ok := cfg.ElasticSearch.indexMapping(indexType); ok {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	snapshotCfg := eshub.SnapshotConfig{
+		Repository: s.Repository,
+		Location:   s.Location,
+		Aliases:    aliases,
+		Retention: eshub.RetentionPolicy{
+			MaxCount: s.RetentionCount,
+			MaxAge:   time.Duration(s.RetentionDays) * 24 * time.Hour,
+		},
+	}
+
+	if scheduled {
+		snapshotCfg.Interval = time.Duration(s.IntervalHours) * time.Hour
+	}
+
+	return eshub.NewSnapshotter(context.Background(), client, snapshotCfg)
+}
+
+func (s *Snapshot) AddOptions(cfg *Config) error {
+	if !s.Enabled || !cfg.ElasticSearch.Enabled || !cfg.IsDataNode() {
+		return nil
+	}
+
+	snapshotter, err := s.GetSnapshotter(cfg, true)
+	if err != nil {
+		return fmt.Errorf("unable to create snapshotter; %w", err)
+	}
+
+	cfg.options = append(
+		cfg.options,
+		ikuzo.SetShutdownHook("elasticsearch-snapshots", snapshotter),
+		ikuzo.SetRouters(func(r chi.Router) {
+			r.Mount("/api/es/snapshots", snapshotter.Routes())
+		}),
+	)
+
+	return nil
+}
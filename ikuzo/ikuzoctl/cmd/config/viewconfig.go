@@ -0,0 +1,57 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/delving/hub3/hub3/server/http/handlers"
+	"github.com/delving/hub3/ikuzo"
+	"github.com/delving/hub3/ikuzo/service/x/viewconfig"
+	"github.com/delving/hub3/ikuzo/storage/x/boltdb"
+)
+
+type ViewConfig struct {
+	// enable the dataset config API
+	Enabled bool `json:"enabled"`
+	// path to the embedded viewconfig database
+	DBPath string `json:"dbPath"`
+}
+
+func (v *ViewConfig) AddOptions(cfg *Config) error {
+	if !v.Enabled {
+		return nil
+	}
+
+	store, err := boltdb.NewViewConfigStore(v.DBPath)
+	if err != nil {
+		return err
+	}
+
+	svc, err := viewconfig.NewService(viewconfig.SetStore(store))
+	if err != nil {
+		return fmt.Errorf("unable to create viewconfig service; %w", err)
+	}
+
+	handlers.SetDataSetConfigs(svc)
+
+	cfg.options = append(
+		cfg.options,
+		ikuzo.SetViewConfigService(svc),
+		ikuzo.SetShutdownHook("viewconfig-service", svc),
+	)
+
+	return nil
+}
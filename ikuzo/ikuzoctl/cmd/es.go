@@ -0,0 +1,33 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// esCmd represents the es command
+var esCmd = &cobra.Command{
+	Use:   "es",
+	Short: "manage the ElasticSearch cluster",
+	Long: `The subcommands of this command manage the ElasticSearch cluster
+	that contains the ikuzo indices.
+
+	This command uses the default hub3 configuration file`,
+}
+
+func init() {
+	rootCmd.AddCommand(esCmd)
+}
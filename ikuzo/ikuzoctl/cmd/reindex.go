@@ -0,0 +1,105 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	eshub "github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// reindexCmd represents the index reindex command
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "rebuild the index with the current mapping without downtime",
+	Long: `This command copies the documents of the aliased index into a new
+	versioned index with the current mapping using the ElasticSearch _reindex API.
+
+	The alias is switched atomically to the new index when the document counts
+	per dataset of both indices are equal. When the counts differ the new index
+	is deleted and the alias is left untouched.
+
+	This command uses the default hub3 configuration file`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := reindex()
+		if err != nil {
+			log.Fatal().Err(err).Msg("error reindexing")
+		}
+	},
+}
+
+var (
+	reindexType  string
+	keepOldIndex bool
+)
+
+func init() {
+	indexCmd.AddCommand(reindexCmd)
+
+	reindexCmd.Flags().StringVarP(&reindexType, "indexType", "t", "v2", "which index is rebuilt: v1, v2 or fragments")
+	reindexCmd.Flags().BoolVarP(&keepOldIndex, "keep", "k", false, "keep the old index for rollback")
+}
+
+func reindex() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigs
+		log.Info().Msg("caught shutdown signal; cancelling reindex")
+		cancel()
+	}()
+
+	progress := func(p eshub.ReindexProgress) {
+		log.Info().
+			Int64("processed", p.Processed()).
+			Int64("total", p.Total).
+			Int64("batches", p.Batches).
+			Int64("versionConflicts", p.VersionConflicts).
+			Bool("completed", p.Completed).
+			Msg("reindex progress")
+	}
+
+	result, err := cfg.Reindex(ctx, reindexType, keepOldIndex, progress)
+	if err != nil {
+		return err
+	}
+
+	for spec, count := range result.Counts {
+		log.Info().Str("dataset", spec).Int64("count", count).Msg("verified dataset")
+	}
+
+	event := log.Info().
+		Str("alias", result.Alias).
+		Str("newIndex", result.NewIndex).
+		Int64("total", result.Total).
+		Dur("duration", result.Duration)
+
+	if keepOldIndex {
+		event = event.Str("rollbackIndex", result.OldIndex)
+	}
+
+	event.Msg("alias switched to reindexed index")
+
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the es snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "create a snapshot of the index aliases",
+	Long: `This command creates a snapshot of the index aliases of the configured
+	index types in the snapshot repository of the [snapshot] configuration.
+
+	The subcommands list, restore and prune the snapshots.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := createSnapshot(); err != nil {
+			log.Fatal().Err(err).Msg("error creating snapshot")
+		}
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the snapshots with their status",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := listSnapshots(); err != nil {
+			log.Fatal().Err(err).Msg("error listing snapshots")
+		}
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore [snapshot]",
+	Short: "restore an alias from a snapshot into a new index and switch the alias",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := restoreSnapshot(args[0]); err != nil {
+			log.Fatal().Err(err).Msg("error restoring snapshot")
+		}
+	},
+}
+
+var snapshotPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "delete the snapshots that are outside the retention policy",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pruneSnapshots(); err != nil {
+			log.Fatal().Err(err).Msg("error pruning snapshots")
+		}
+	},
+}
+
+var restoreAlias string
+
+func init() {
+	esCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotListCmd, snapshotRestoreCmd, snapshotPruneCmd)
+
+	snapshotRestoreCmd.Flags().StringVarP(&restoreAlias, "alias", "a", "", "alias that is restored, e.g. hub3v2")
+	_ = snapshotRestoreCmd.MarkFlagRequired("alias")
+}
+
+func createSnapshot() error {
+	snapshotter, err := cfg.Snapshot.GetSnapshotter(&cfg, false)
+	if err != nil {
+		return err
+	}
+
+	name, err := snapshotter.Create(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("snapshot", name).Msg("started snapshot; use 'es snapshot list' for its status")
+
+	return nil
+}
+
+func listSnapshots() error {
+	snapshotter, err := cfg.Snapshot.GetSnapshotter(&cfg, false)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := snapshotter.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		fmt.Printf("%s\t%s\t%s\t%v\n", s.Name, s.State, s.Start.Format("2006-01-02 15:04:05"), s.Indices)
+	}
+
+	return nil
+}
+
+func restoreSnapshot(name string) error {
+	snapshotter, err := cfg.Snapshot.GetSnapshotter(&cfg, false)
+	if err != nil {
+		return err
+	}
+
+	indexName, err := snapshotter.Restore(context.Background(), name, restoreAlias)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("snapshot", name).Str("alias", restoreAlias).Str("index", indexName).
+		Msg("restored snapshot and switched alias")
+
+	return nil
+}
+
+func pruneSnapshots() error {
+	snapshotter, err := cfg.Snapshot.GetSnapshotter(&cfg, false)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := snapshotter.Prune(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Info().Strs("deleted", deleted).Msg("pruned snapshots")
+
+	return nil
+}
@@ -22,11 +22,14 @@ import (
 
 	"github.com/delving/hub3/config"
 	"github.com/delving/hub3/ikuzo/logger"
+	"github.com/delving/hub3/ikuzo/search"
 	"github.com/delving/hub3/ikuzo/service/organization"
+	"github.com/delving/hub3/ikuzo/service/x/analytics"
 	"github.com/delving/hub3/ikuzo/service/x/bulk"
 	"github.com/delving/hub3/ikuzo/service/x/ead"
 	"github.com/delving/hub3/ikuzo/service/x/imageproxy"
 	"github.com/delving/hub3/ikuzo/service/x/revision"
+	"github.com/delving/hub3/ikuzo/service/x/viewconfig"
 	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch"
 	"github.com/go-chi/chi"
 )
@@ -213,6 +216,45 @@ func SetBulkService(svc *bulk.Service) Option {
 	}
 }
 
+// SetSearchService mounts the search.Service on /api/search/v3.
+func SetSearchService(svc *search.Service) Option {
+	return func(s *server) error {
+		s.routerFuncs = append(s.routerFuncs,
+			func(r chi.Router) {
+				r.Mount("/api/search/v3", svc.Routes())
+			},
+		)
+
+		return nil
+	}
+}
+
+// SetAnalyticsService mounts the search analytics reports on /api/analytics/search.
+func SetAnalyticsService(svc *analytics.Service) Option {
+	return func(s *server) error {
+		s.routerFuncs = append(s.routerFuncs,
+			func(r chi.Router) {
+				r.Mount("/api/analytics/search", svc.Routes())
+			},
+		)
+
+		return nil
+	}
+}
+
+// SetViewConfigService mounts the dataset config API on /api/viewconfig.
+func SetViewConfigService(svc *viewconfig.Service) Option {
+	return func(s *server) error {
+		s.routerFuncs = append(s.routerFuncs,
+			func(r chi.Router) {
+				r.Mount("/api/viewconfig", svc.Routes())
+			},
+		)
+
+		return nil
+	}
+}
+
 func SetShutdownHook(name string, hook Shutdown) Option {
 	return func(s *server) error {
 		if _, ok := s.shutdownHooks[name]; !ok {
@@ -223,6 +265,15 @@ func SetShutdownHook(name string, hook Shutdown) Option {
 	}
 }
 
+// SetReadinessCheck adds a ReadinessCheck that must pass before '/ready'
+// reports that the server is ready to serve requests.
+func SetReadinessCheck(name string, check ReadinessCheck) Option {
+	return func(s *server) error {
+		s.readinessChecks[name] = check
+		return nil
+	}
+}
+
 func SetImageProxyService(service *imageproxy.Service) Option {
 	return func(s *server) error {
 		s.routerFuncs = append(s.routerFuncs,
@@ -41,6 +41,7 @@ func DefaultMiddleware() []func(http.Handler) http.Handler {
 // no connections should be initialized.
 func (s *server) routes() {
 	s.router.Get("/", s.handleIndex())
+	s.router.Get("/ready", s.handleReadiness())
 
 	s.fileServer("/static", assets.FileSystem)
 }
@@ -20,4 +20,6 @@ const (
 	resourceField = "@id"
 	dateField     = "date"
 	tagField      = "tags"
+	integerField  = "integer"
+	latLongField  = "latLong"
 )
@@ -29,6 +29,35 @@ type FacetField struct {
 	aggregationType string
 	size            int
 	orderByKey      bool
+	// interval of the histogram buckets
+	interval float64
+	// ranges are the bucket edges of range and geodistance facets
+	ranges []float64
+	// precision is the geohash precision of geogrid facets
+	precision int
+	// origin is the point from which the geodistance is measured
+	origin *GeoPoint
+	// pivot is the facet that is aggregated for each value of this facet
+	pivot *FacetField
+}
+
+// Aggregation types of the FacetField.
+const (
+	AggregationDateHistogram = "datehistogram"
+	AggregationDateMinMax    = "dateminmax"
+	AggregationHistogram     = "histogram"
+	AggregationRange         = "range"
+	AggregationGeoGrid       = "geogrid"
+	AggregationGeoDistance   = "geodistance"
+)
+
+// defaultGeoPrecision is the geohash precision when none is given.
+const defaultGeoPrecision = 5
+
+// GeoPoint is a WGS84 coordinate.
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
 }
 
 // newFacetField parses a string and returns a *FacetField.
@@ -86,12 +115,47 @@ type FacetField struct {
 // id: used the 'id' field instead of the '@value' field. This means that it is
 // aggregation the RDF resource URI instead of the literal value.
 //
+// The numeric and geo field-prefixes take options that are separated by ':'
+// from the field.
+//
+// histogram: uses the integer field and returns buckets of a fixed interval.
+// The interval is required.
+// Example: histogram.dc_extent:10
+//
+// range: uses the integer field and returns a bucket between each of the
+// comma separated edges, and an open-ended bucket below the first and above
+// the last edge.
+// Example: range.dc_extent:10,100,1000
+//
+// geogrid: uses the latLong field and returns geohash cells with their
+// centroid. The optional option sets the geohash precision from 1 to 12.
+// The default precision is 5.
+// Example: geogrid.wgs84_pos_lat_long:4
+//
+// geodistance: uses the latLong field and returns the number of records within
+// rings around the origin. The first option is the 'lat,lon' origin, the second
+// the comma separated distances in kilometers.
+// Example: geodistance.wgs84_pos_lat_long:52.16,4.49:5,25,100
+//
+// For the geo field-prefixes the field 'latLong' aggregates all coordinates,
+// regardless of their SearchLabel.
+//
+// Facets can be nested as pivots with the '>' separator. For each value of
+// the first facet the second facet is returned. Only term facets can have a
+// pivot.
+// Example: meta.spec>dc_type
+//
 // Empty values are not allowed and will return an error.
 func newFacetField(field string) (*FacetField, error) {
 	if field == "" {
 		return nil, fmt.Errorf("empty input is not allowed: %s", field)
 	}
 
+	// > separates the facet from its pivot
+	if strings.Contains(field, ">") {
+		return newPivotFacetField(field)
+	}
+
 	ff := FacetField{
 		path: nestedPath,
 	}
@@ -136,11 +200,35 @@ func newFacetField(field string) (*FacetField, error) {
 	case strings.HasPrefix(ff.Field, "datehistogram."):
 		ff.nestedField = dateField
 		ff.Field = strings.TrimPrefix(ff.Field, "datehistogram.")
-		ff.aggregationType = "datehistogram"
+		ff.aggregationType = AggregationDateHistogram
 	case strings.HasPrefix(ff.Field, "dateminmax."):
 		ff.nestedField = dateField
 		ff.Field = strings.TrimPrefix(ff.Field, "dateminmax.")
-		ff.aggregationType = "dateminmax"
+		ff.aggregationType = AggregationDateMinMax
+	case strings.HasPrefix(ff.Field, "histogram."):
+		ff.nestedField = integerField
+		ff.aggregationType = AggregationHistogram
+		if err := ff.parseOptions(strings.TrimPrefix(ff.Field, "histogram.")); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(ff.Field, "range."):
+		ff.nestedField = integerField
+		ff.aggregationType = AggregationRange
+		if err := ff.parseOptions(strings.TrimPrefix(ff.Field, "range.")); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(ff.Field, "geogrid."):
+		ff.nestedField = latLongField
+		ff.aggregationType = AggregationGeoGrid
+		if err := ff.parseOptions(strings.TrimPrefix(ff.Field, "geogrid.")); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(ff.Field, "geodistance."):
+		ff.nestedField = latLongField
+		ff.aggregationType = AggregationGeoDistance
+		if err := ff.parseOptions(strings.TrimPrefix(ff.Field, "geodistance.")); err != nil {
+			return nil, err
+		}
 	case strings.HasPrefix(ff.Field, "tag."):
 		ff.nestedField = tagField
 		ff.Field = strings.TrimPrefix(ff.Field, "tag.")
@@ -158,3 +246,195 @@ func newFacetField(field string) (*FacetField, error) {
 
 	return &ff, nil
 }
+
+// newPivotFacetField parses the '>' separated facets and sets each next
+// facet as the pivot of the previous one.
+func newPivotFacetField(field string) (*FacetField, error) {
+	parts := strings.SplitN(field, ">", 2)
+
+	ff, err := newFacetField(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if ff.aggregationType != "" {
+		return nil, fmt.Errorf("pivot is only supported for term facets: %s", field)
+	}
+
+	ff.pivot, err = newFacetField(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return ff, nil
+}
+
+// parseOptions sets the field and the ':' separated options of the numeric
+// and geo aggregation types.
+func (ff *FacetField) parseOptions(input string) error {
+	parts := strings.Split(input, ":")
+	ff.Field = parts[0]
+	options := parts[1:]
+
+	switch ff.aggregationType {
+	case AggregationHistogram:
+		if len(options) != 1 {
+			return fmt.Errorf("histogram facet requires an interval: %s", input)
+		}
+
+		interval, err := strconv.ParseFloat(options[0], 64)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("histogram interval must be a positive number: %s", options[0])
+		}
+
+		ff.interval = interval
+	case AggregationRange:
+		if len(options) != 1 {
+			return fmt.Errorf("range facet requires the range edges: %s", input)
+		}
+
+		ranges, err := parseEdges(options[0])
+		if err != nil {
+			return err
+		}
+
+		ff.ranges = ranges
+	case AggregationGeoGrid:
+		ff.precision = defaultGeoPrecision
+
+		if len(options) > 1 {
+			return fmt.Errorf("geogrid facet only supports a precision option: %s", input)
+		}
+
+		if len(options) == 1 {
+			precision, err := strconv.Atoi(options[0])
+			if err != nil || precision < 1 || precision > 12 {
+				return fmt.Errorf("geogrid precision must be between 1 and 12: %s", options[0])
+			}
+
+			ff.precision = precision
+		}
+	case AggregationGeoDistance:
+		if len(options) != 2 {
+			return fmt.Errorf("geodistance facet requires an origin and distances: %s", input)
+		}
+
+		origin, err := parseGeoPoint(options[0])
+		if err != nil {
+			return err
+		}
+
+		ranges, err := parseEdges(options[1])
+		if err != nil {
+			return err
+		}
+
+		ff.origin = origin
+		ff.ranges = ranges
+	}
+
+	return nil
+}
+
+// parseEdges parses comma separated numbers that must be in ascending order.
+func parseEdges(input string) ([]float64, error) {
+	edges := []float64{}
+
+	for _, part := range strings.Split(input, ",") {
+		edge, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range edge %q; %w", part, err)
+		}
+
+		if len(edges) > 0 && edge <= edges[len(edges)-1] {
+			return nil, fmt.Errorf("range edges must be in ascending order: %s", input)
+		}
+
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+// parseGeoPoint parses a 'lat,lon' coordinate.
+func parseGeoPoint(input string) (*GeoPoint, error) {
+	parts := strings.Split(input, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("geo point must be in the form of 'lat,lon': %s", input)
+	}
+
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return nil, fmt.Errorf("invalid latitude in geo point: %s", input)
+	}
+
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return nil, fmt.Errorf("invalid longitude in geo point: %s", input)
+	}
+
+	return &GeoPoint{Lat: lat, Lon: lon}, nil
+}
+
+// Path returns the path in the index of the field that is aggregated.
+func (ff *FacetField) Path() string {
+	return ff.path
+}
+
+// NestedField returns the field of the resource entry that is aggregated.
+// It is empty when the Path is not nested.
+func (ff *FacetField) NestedField() string {
+	return ff.nestedField
+}
+
+// AggregationType returns the type of aggregation. It is empty for the
+// default term-aggregation.
+func (ff *FacetField) AggregationType() string {
+	return ff.aggregationType
+}
+
+// Size returns the maximum number of facet entries that are returned.
+func (ff *FacetField) Size() int {
+	return ff.size
+}
+
+// SortAsc returns true when the facet entries are sorted ascending.
+func (ff *FacetField) SortAsc() bool {
+	return ff.sortAsc
+}
+
+// OrderByKey returns true when the facet entries are sorted by their value
+// instead of their count.
+func (ff *FacetField) OrderByKey() bool {
+	return ff.orderByKey
+}
+
+// IsNested returns true when the facet aggregates the nested resource entries.
+func (ff *FacetField) IsNested() bool {
+	return ff.path == nestedPath
+}
+
+// Interval returns the bucket interval of a histogram facet.
+func (ff *FacetField) Interval() float64 {
+	return ff.interval
+}
+
+// Ranges returns the bucket edges of range and geodistance facets.
+func (ff *FacetField) Ranges() []float64 {
+	return ff.ranges
+}
+
+// Precision returns the geohash precision of a geogrid facet.
+func (ff *FacetField) Precision() int {
+	return ff.precision
+}
+
+// Origin returns the point from which a geodistance facet is measured.
+func (ff *FacetField) Origin() *GeoPoint {
+	return ff.origin
+}
+
+// Pivot returns the facet that is aggregated for each value of this facet.
+func (ff *FacetField) Pivot() *FacetField {
+	return ff.pivot
+}
@@ -180,6 +180,145 @@ func Test_newFacetField(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"when field is prefixed with histogram. it should use the 'integer' nested field",
+			args{field: "histogram.dc_extent:10"},
+			&FacetField{
+				Field:           "dc_extent",
+				path:            "resources.entries",
+				nestedField:     integerField,
+				aggregationType: "histogram",
+				interval:        10,
+			},
+			false,
+		},
+		{
+			"histogram without interval should throw an error",
+			args{field: "histogram.dc_extent"},
+			nil,
+			true,
+		},
+		{
+			"histogram with a negative interval should throw an error",
+			args{field: "histogram.dc_extent:-1"},
+			nil,
+			true,
+		},
+		{
+			"when field is prefixed with range. it should parse the edges",
+			args{field: "range.dc_extent:10,100.5,1000~5"},
+			&FacetField{
+				Field:           "dc_extent",
+				path:            "resources.entries",
+				nestedField:     integerField,
+				aggregationType: "range",
+				ranges:          []float64{10, 100.5, 1000},
+				size:            5,
+			},
+			false,
+		},
+		{
+			"range edges that are not ascending should throw an error",
+			args{field: "range.dc_extent:100,10"},
+			nil,
+			true,
+		},
+		{
+			"when field is prefixed with geogrid. it should use the default precision",
+			args{field: "geogrid.latLong"},
+			&FacetField{
+				Field:           "latLong",
+				path:            "resources.entries",
+				nestedField:     latLongField,
+				aggregationType: "geogrid",
+				precision:       5,
+			},
+			false,
+		},
+		{
+			"geogrid with precision",
+			args{field: "geogrid.wgs84_pos_lat_long:3"},
+			&FacetField{
+				Field:           "wgs84_pos_lat_long",
+				path:            "resources.entries",
+				nestedField:     latLongField,
+				aggregationType: "geogrid",
+				precision:       3,
+			},
+			false,
+		},
+		{
+			"geogrid precision above 12 should throw an error",
+			args{field: "geogrid.latLong:13"},
+			nil,
+			true,
+		},
+		{
+			"when field is prefixed with geodistance. it should parse origin and distances",
+			args{field: "geodistance.latLong:52.16,4.49:5,25"},
+			&FacetField{
+				Field:           "latLong",
+				path:            "resources.entries",
+				nestedField:     latLongField,
+				aggregationType: "geodistance",
+				origin:          &GeoPoint{Lat: 52.16, Lon: 4.49},
+				ranges:          []float64{5, 25},
+			},
+			false,
+		},
+		{
+			"geodistance with invalid origin should throw an error",
+			args{field: "geodistance.latLong:95,4.49:5,25"},
+			nil,
+			true,
+		},
+		{
+			"when field contains `>` the second facet is the pivot",
+			args{field: "meta.spec~5>^dc_type"},
+			&FacetField{
+				Field: "meta.spec",
+				path:  "meta.spec",
+				size:  5,
+				pivot: &FacetField{
+					Field:       "dc_type",
+					path:        "resources.entries",
+					nestedField: literalField,
+					sortAsc:     true,
+				},
+			},
+			false,
+		},
+		{
+			"pivots can be nested",
+			args{field: "meta.spec>dc_type>tags"},
+			&FacetField{
+				Field: "meta.spec",
+				path:  "meta.spec",
+				pivot: &FacetField{
+					Field:       "dc_type",
+					path:        "resources.entries",
+					nestedField: literalField,
+					pivot: &FacetField{
+						Field:       "tags",
+						path:        "resources.entries",
+						nestedField: tagField,
+					},
+				},
+			},
+			false,
+		},
+		{
+			"only term facets can have a pivot",
+			args{field: "datehistogram.dc_date>dc_type"},
+			nil,
+			true,
+		},
+		{
+			"empty pivot should throw an error",
+			args{field: "meta.spec>"},
+			nil,
+			true,
+		},
 		{
 			"^@ is not a valid facet field",
 			args{field: "^@"},
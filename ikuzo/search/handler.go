@@ -0,0 +1,81 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+func (s *Service) Routes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Get("/", s.handleSearch)
+
+	return router
+}
+
+func (s *Service) handleSearch(w http.ResponseWriter, r *http.Request) {
+	req, err := NewRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req.OrgID, err = s.requestOrgID(r)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrOrgIDNotAllowed) {
+			status = http.StatusForbidden
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	resp, err := s.Search(r.Context(), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUnsupportedFacet) {
+			status = http.StatusBadRequest
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	render.JSON(w, r, resp)
+}
+
+// requestOrgID returns the organization of the Service. The search API never
+// searches without an organization and the 'orgID' parameter can only select
+// the organization of the Service.
+func (s *Service) requestOrgID(r *http.Request) (string, error) {
+	if s.orgID == "" {
+		return "", ErrNoOrgID
+	}
+
+	if orgID := r.URL.Query().Get("orgID"); orgID != "" && orgID != s.orgID {
+		return "", fmt.Errorf("%w: %s", ErrOrgIDNotAllowed, orgID)
+	}
+
+	return s.orgID, nil
+}
@@ -13,3 +13,192 @@
 // limitations under the License.
 
 package search
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Sort fields that are not resource entries.
+const (
+	// SortScore sorts on the relevance score of the hit.
+	SortScore = "_score"
+	// SortID sorts on the hubID of the hit.
+	SortID = "meta.hubID"
+)
+
+// Request is a typed search request that is executed by a Searcher.
+type Request struct {
+	// OrgID limits the results to a single organization.
+	OrgID string
+	// Query is the full-text query in the syntax of the QueryParser.
+	Query string
+	// Filters limit the results. Filters on the same field are combined with OR,
+	// filters on different fields are combined with AND.
+	Filters []Filter
+	// Facets are the aggregations returned with the Response.
+	Facets []*FacetField
+	// Page is the 1-based page of the results.
+	Page int
+	// Size is the number of hits returned per page.
+	Size int
+	// Sort determines the order of the hits. When empty the hits are sorted
+	// on descending score.
+	Sort []Sort
+}
+
+// Sort determines the order of the hits in the Response.
+//
+// The Field is either SortScore, SortID, a 'meta.' field or the SearchLabel of
+// a resource entry.
+type Sort struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc,omitempty"`
+}
+
+// NewRequest creates a Request from URL query parameters.
+//
+// The following parameters are supported:
+//
+// q: the full-text query
+// qf or qf[]: filter in the form of 'field:value'
+// facet.field: a FacetField shorthand, see newFacetField
+// page: the 1-based page of the results
+// rows: the number of hits per page
+// sort: comma separated list of sort fields. A '-' prefix sorts descending.
+//
+// Invalid parameter values return an error.
+func NewRequest(params url.Values) (*Request, error) {
+	req := &Request{
+		Query: params.Get("q"),
+	}
+
+	for _, key := range []string{"qf", "qf[]"} {
+		for _, value := range params[key] {
+			if err := req.parseFilter(value); err != nil {
+				return nil, fmt.Errorf("invalid value for param %s: %s; %w", key, value, err)
+			}
+		}
+	}
+
+	for _, value := range params["facet.field"] {
+		if err := req.AddFacet(value); err != nil {
+			return nil, fmt.Errorf("invalid value for param facet.field: %s; %w", value, err)
+		}
+	}
+
+	var err error
+
+	if req.Page, err = intParam(params, "page"); err != nil {
+		return nil, err
+	}
+
+	if req.Size, err = intParam(params, "rows"); err != nil {
+		return nil, err
+	}
+
+	for _, value := range params["sort"] {
+		req.parseSort(value)
+	}
+
+	return req, nil
+}
+
+// AddFilter adds a Filter to the Request.
+func (req *Request) AddFilter(field, value string) {
+	req.Filters = append(req.Filters, Filter{Field: field, Value: value})
+}
+
+// AddFacet parses the FacetField shorthand and adds it to the Request.
+func (req *Request) AddFacet(field string) error {
+	ff, err := newFacetField(field)
+	if err != nil {
+		return err
+	}
+
+	req.Facets = append(req.Facets, ff)
+
+	return nil
+}
+
+// Start returns the offset of the first hit of the requested page.
+func (req *Request) Start() int {
+	if req.Page < 1 {
+		return 0
+	}
+
+	return (req.Page - 1) * req.Size
+}
+
+// FilterGroups returns the filter values grouped by field, in the order in
+// which the fields were first added.
+func (req *Request) FilterGroups() (fields []string, values map[string][]string) {
+	values = map[string][]string{}
+
+	for _, f := range req.Filters {
+		if _, ok := values[f.Field]; !ok {
+			fields = append(fields, f.Field)
+		}
+
+		values[f.Field] = append(values[f.Field], f.Value)
+	}
+
+	return fields, values
+}
+
+// IsSelected returns true when the Request is filtered on the field and value.
+func (req *Request) IsSelected(field, value string) bool {
+	for _, f := range req.Filters {
+		if f.Field == field && f.Value == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (req *Request) parseFilter(input string) error {
+	parts := strings.SplitN(input, ":", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("filter must be in the form of 'field:value'")
+	}
+
+	req.AddFilter(parts[0], parts[1])
+
+	return nil
+}
+
+func (req *Request) parseSort(input string) {
+	for _, field := range strings.Split(input, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		s := Sort{Field: field}
+
+		if strings.HasPrefix(field, "-") {
+			s.Field = strings.TrimPrefix(field, "-")
+			s.Desc = true
+		}
+
+		req.Sort = append(req.Sort, s)
+	}
+}
+
+// intParam returns the integer value of the param or 0 when it is not set.
+func intParam(params url.Values, key string) (int, error) {
+	value := params.Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for param %s: %s; %w", key, value, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  string
+		want    *Request
+		wantErr bool
+	}{
+		{
+			"empty params",
+			"",
+			&Request{},
+			false,
+		},
+		{
+			"query with paging",
+			"q=leiden&page=2&rows=20",
+			&Request{Query: "leiden", Page: 2, Size: 20},
+			false,
+		},
+		{
+			"filters",
+			"qf=dc_type:painting&qf[]=meta.spec:museum&qf=dc_date:1650:1700",
+			&Request{
+				Filters: []Filter{
+					{Field: "dc_type", Value: "painting"},
+					{Field: "dc_date", Value: "1650:1700"},
+					{Field: "meta.spec", Value: "museum"},
+				},
+			},
+			false,
+		},
+		{
+			"facets",
+			"facet.field=dc_type&facet.field=^meta.spec~5",
+			&Request{
+				Facets: []*FacetField{
+					{Field: "dc_type", path: nestedPath, nestedField: literalField},
+					{Field: "meta.spec", path: "meta.spec", sortAsc: true, size: 5},
+				},
+			},
+			false,
+		},
+		{
+			"sort",
+			"sort=-dc_date,meta.spec&sort=_score",
+			&Request{
+				Sort: []Sort{
+					{Field: "dc_date", Desc: true},
+					{Field: "meta.spec"},
+					{Field: SortScore},
+				},
+			},
+			false,
+		},
+		{
+			"invalid filter",
+			"qf=painting",
+			nil,
+			true,
+		},
+		{
+			"invalid facet",
+			"facet.field=dc_type~ten",
+			nil,
+			true,
+		},
+		{
+			"invalid page",
+			"page=first",
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.params)
+			if err != nil {
+				t.Fatalf("unable to parse params: %s", err)
+			}
+
+			got, err := NewRequest(params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewRequest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			opt := cmp.AllowUnexported(FacetField{})
+			if diff := cmp.Diff(tt.want, got, opt); diff != "" {
+				t.Errorf("NewRequest() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequest_Start(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want int
+	}{
+		{"no page", Request{Size: 10}, 0},
+		{"first page", Request{Page: 1, Size: 10}, 0},
+		{"third page", Request{Page: 3, Size: 10}, 20},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Start(); got != tt.want {
+				t.Errorf("Request.Start() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
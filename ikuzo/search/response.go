@@ -14,6 +14,8 @@
 
 package search
 
+import "encoding/json"
+
 // Facet is used in the search response to render Facet information.
 type Facet struct {
 	Name        string       `json:"name"`
@@ -36,17 +38,60 @@ type FacetLink struct {
 	Value         string `json:"value"`
 	DisplayString string `json:"displayString"`
 	Count         int64  `json:"count"`
+	// Range is set for histogram, range and geodistance facets.
+	Range *FacetRange `json:"range,omitempty"`
+	// Cell is set for geogrid facets.
+	Cell *GeoCell `json:"cell,omitempty"`
+	// Pivot is the pivot facet for the records with this value.
+	Pivot *Facet `json:"pivot,omitempty"`
+}
+
+// FacetRange is the numeric range of a FacetLink. The From is inclusive and
+// the To is exclusive. They are nil for open-ended ranges.
+type FacetRange struct {
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+}
+
+// GeoCell is a geohash cell of a geogrid FacetLink.
+type GeoCell struct {
+	Geohash string `json:"geohash"`
+	// Centroid is the center of the coordinates in the cell.
+	Centroid GeoPoint `json:"centroid"`
+}
+
+// Hit is a single search result.
+type Hit struct {
+	ID     string          `json:"id"`
+	Score  float64         `json:"score"`
+	Source json.RawMessage `json:"source,omitempty"`
 }
 
+// Response is returned by a Searcher for a Request.
 type Response struct {
-	//Pager      *ScrollPager       `json:"pager"`
-	//Query      *Query             `json:"query"`
-	//Items      []*FragmentGraph   `json:"items,omitempty"`
-	//Collapsed  []*Collapsed       `json:"collapse,omitempty"`
-	//Peek       map[string]int64   `json:"peek,omitempty"`
-	//Facets     []*QueryFacet      `json:"facets,omitempty"`
-	//TreeHeader *TreeHeader        `json:"treeHeader,omitempty"`
-	//Tree       []*Tree            `json:"tree,omitempty"`
-	//TreePage   map[string][]*Tree `json:"treePage,omitempty"`
-	//ProtoBuf   *ProtoBuf          `json:"protobuf,omitempty"`
+	Pager  ScrollPager `json:"pager"`
+	Query  string      `json:"query,omitempty"`
+	Hits   []*Hit      `json:"hits"`
+	Facets []*Facet    `json:"facets,omitempty"`
+}
+
+// markSelected sets the selection state of the Facets for the filters
+// in the Request.
+func (resp *Response) markSelected(req *Request) {
+	markSelectedFacets(resp.Facets, req)
+}
+
+func markSelectedFacets(facets []*Facet, req *Request) {
+	for _, facet := range facets {
+		for _, link := range facet.Links {
+			if req.IsSelected(facet.Field, link.Value) {
+				link.IsSelected = true
+				facet.IsSelected = true
+			}
+
+			if link.Pivot != nil {
+				markSelectedFacets([]*Facet{link.Pivot}, req)
+			}
+		}
+	}
 }
@@ -13,3 +13,67 @@
 // limitations under the License.
 
 package search
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrNoSearcher is returned when the Service is used without a Searcher.
+	ErrNoSearcher = errors.New("no searcher configured for search.Service")
+	// ErrUnsupportedFacet is returned when a Searcher is not able to
+	// aggregate the FacetField.
+	ErrUnsupportedFacet = errors.New("facet type is not supported by searcher")
+	// ErrNoOrgID is returned when the search API is used without an organization.
+	ErrNoOrgID = errors.New("no organization configured for search.Service")
+	// ErrOrgIDNotAllowed is returned when a search request is for another organization.
+	ErrOrgIDNotAllowed = errors.New("search of another organization is not allowed")
+)
+
+// Searcher executes a Request against a search backend.
+//
+// Implementations must return the hits of the requested page, the total
+// number of hits and the requested facets. A query that does not match any
+// document is not an error but returns an empty Response.
+type Searcher interface {
+	Search(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Document is the minimal representation of a record in the search-index.
+//
+// It is used by Searchers that build their own index and by the conformance
+// test-suite in the searchtest package.
+type Document struct {
+	ID     string              `json:"id"`
+	OrgID  string              `json:"orgID"`
+	Spec   string              `json:"spec"`
+	Tags   []string            `json:"tags,omitempty"`
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+// MetaValues returns the values of a 'meta.' field of the Document.
+func (doc *Document) MetaValues(field string) []string {
+	switch field {
+	case SortID:
+		return []string{doc.ID}
+	case "meta.orgID":
+		return []string{doc.OrgID}
+	case "meta.spec":
+		return []string{doc.Spec}
+	case "meta.tags":
+		return doc.Tags
+	}
+
+	return nil
+}
+
+// Values returns the values of the field. 'meta.' fields are returned via
+// MetaValues, all other fields are looked up in the Fields.
+func (doc *Document) Values(field string) []string {
+	if values := doc.MetaValues(field); values != nil {
+		return values
+	}
+
+	return doc.Fields[field]
+}
@@ -0,0 +1,357 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package searchtest contains a conformance test-suite for search.Searcher
+// implementations.
+//
+// Each Searcher must be able to index the Documents and pass RunSuite.
+package searchtest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+// NewSearcherFunc returns a Searcher that contains the documents.
+type NewSearcherFunc func(t *testing.T, docs []*search.Document) search.Searcher
+
+// Documents returns the documents that are used by the conformance tests.
+func Documents() []*search.Document {
+	return []*search.Document{
+		{
+			ID: "hub3_archive_1", OrgID: "hub3", Spec: "archive",
+			Fields: map[string][]string{
+				"dc_title":   {"Map of Leiden"},
+				"dc_subject": {"map"},
+				"dc_type":    {"map"},
+				"dc_date":    {"1700"},
+			},
+		},
+		{
+			ID: "hub3_archive_2", OrgID: "hub3", Spec: "archive",
+			Fields: map[string][]string{
+				"dc_title":   {"Letter about a windmill in Leiden"},
+				"dc_subject": {"windmill", "letter"},
+				"dc_type":    {"letter"},
+			},
+		},
+		{
+			ID: "hub3_museum_1", OrgID: "hub3", Spec: "museum", Tags: []string{"image"},
+			Fields: map[string][]string{
+				"dc_title":   {"Windmill near Leiden"},
+				"dc_subject": {"windmill", "landscape"},
+				"dc_type":    {"painting"},
+				"dc_date":    {"1650"},
+			},
+		},
+		{
+			ID: "hub3_museum_2", OrgID: "hub3", Spec: "museum", Tags: []string{"image"},
+			Fields: map[string][]string{
+				"dc_title":   {"Harbour of Amsterdam"},
+				"dc_subject": {"harbour", "ship"},
+				"dc_type":    {"painting"},
+				"dc_date":    {"1665"},
+			},
+		},
+		{
+			ID: "hub3_museum_3", OrgID: "hub3", Spec: "museum", Tags: []string{"image"},
+			Fields: map[string][]string{
+				"dc_title":   {"Portrait of a miller"},
+				"dc_subject": {"portrait", "windmill"},
+				"dc_type":    {"drawing"},
+				"dc_date":    {"1640"},
+			},
+		},
+		{
+			ID: "other_library_1", OrgID: "other", Spec: "library",
+			Fields: map[string][]string{
+				"dc_title":   {"Windmill handbook"},
+				"dc_subject": {"windmill"},
+				"dc_type":    {"book"},
+				"dc_date":    {"1800"},
+			},
+		},
+	}
+}
+
+// facet is the comparable part of a search.Facet.
+type facet struct {
+	Field string
+	Total int64
+	Links []link
+}
+
+// link is the comparable part of a search.FacetLink.
+type link struct {
+	Value string
+	Count int64
+	Pivot *facet
+}
+
+type testCase struct {
+	name string
+	req  *search.Request
+	// ids of the hits. When unordered is true the order of the hits is ignored.
+	ids       []string
+	unordered bool
+	total     int64
+	facets    []facet
+}
+
+func testCases(t *testing.T) []testCase {
+	is := is.New(t)
+
+	withFacets := func(req *search.Request, fields ...string) *search.Request {
+		for _, field := range fields {
+			is.NoErr(req.AddFacet(field))
+		}
+
+		return req
+	}
+
+	return []testCase{
+		{
+			name:  "match all sorted by score and id",
+			req:   &search.Request{Page: 1, Size: 10},
+			ids:   []string{"hub3_archive_1", "hub3_archive_2", "hub3_museum_1", "hub3_museum_2", "hub3_museum_3", "other_library_1"},
+			total: 6,
+		},
+		{
+			name:  "limit to organization",
+			req:   &search.Request{OrgID: "other", Page: 1, Size: 10},
+			ids:   []string{"other_library_1"},
+			total: 1,
+		},
+		{
+			name:      "term query",
+			req:       &search.Request{OrgID: "hub3", Query: "windmill", Page: 1, Size: 10},
+			ids:       []string{"hub3_archive_2", "hub3_museum_1", "hub3_museum_3"},
+			unordered: true,
+			total:     3,
+		},
+		{
+			name:      "query is case insensitive",
+			req:       &search.Request{OrgID: "hub3", Query: "LEIDEN", Page: 1, Size: 10},
+			ids:       []string{"hub3_archive_1", "hub3_archive_2", "hub3_museum_1"},
+			unordered: true,
+			total:     3,
+		},
+		{
+			name:      "should query",
+			req:       &search.Request{OrgID: "hub3", Query: "harbour OR portrait", Page: 1, Size: 10},
+			ids:       []string{"hub3_museum_2", "hub3_museum_3"},
+			unordered: true,
+			total:     2,
+		},
+		{
+			name:      "must query",
+			req:       &search.Request{OrgID: "hub3", Query: "windmill AND leiden", Page: 1, Size: 10},
+			ids:       []string{"hub3_archive_2", "hub3_museum_1"},
+			unordered: true,
+			total:     2,
+		},
+		{
+			name:  "phrase query",
+			req:   &search.Request{Query: `"map of leiden"`, Page: 1, Size: 10},
+			ids:   []string{"hub3_archive_1"},
+			total: 1,
+		},
+		{
+			name:  "no match is not an error",
+			req:   &search.Request{Query: "unicorn", Page: 1, Size: 10},
+			ids:   []string{},
+			total: 0,
+		},
+		{
+			name: "filter",
+			req: &search.Request{
+				Filters: []search.Filter{{Field: "dc_type", Value: "painting"}},
+				Page:    1, Size: 10,
+			},
+			ids:   []string{"hub3_museum_1", "hub3_museum_2"},
+			total: 2,
+		},
+		{
+			name: "filters on the same field are combined with OR",
+			req: &search.Request{
+				Filters: []search.Filter{
+					{Field: "dc_type", Value: "painting"},
+					{Field: "dc_type", Value: "drawing"},
+				},
+				Page: 1, Size: 10,
+			},
+			ids:   []string{"hub3_museum_1", "hub3_museum_2", "hub3_museum_3"},
+			total: 3,
+		},
+		{
+			name: "filters on different fields are combined with AND",
+			req: &search.Request{
+				Filters: []search.Filter{
+					{Field: "dc_subject", Value: "windmill"},
+					{Field: "meta.spec", Value: "museum"},
+				},
+				Page: 1, Size: 10,
+			},
+			ids:   []string{"hub3_museum_1", "hub3_museum_3"},
+			total: 2,
+		},
+		{
+			name:  "paging",
+			req:   &search.Request{Page: 2, Size: 2},
+			ids:   []string{"hub3_museum_1", "hub3_museum_2"},
+			total: 6,
+		},
+		{
+			name:  "page beyond the results",
+			req:   &search.Request{Page: 4, Size: 2},
+			ids:   []string{},
+			total: 6,
+		},
+		{
+			name: "sort on field descending",
+			req: &search.Request{
+				Sort: []search.Sort{{Field: "dc_date", Desc: true}},
+				Page: 1, Size: 10,
+			},
+			// documents without the sort field are sorted last
+			ids:   []string{"other_library_1", "hub3_archive_1", "hub3_museum_2", "hub3_museum_1", "hub3_museum_3", "hub3_archive_2"},
+			total: 6,
+		},
+		{
+			name: "sort on id",
+			req: &search.Request{
+				Sort: []search.Sort{{Field: search.SortID, Desc: true}},
+				Page: 1, Size: 3,
+			},
+			ids:   []string{"other_library_1", "hub3_museum_3", "hub3_museum_2"},
+			total: 6,
+		},
+		{
+			name: "facets",
+			req: withFacets(
+				&search.Request{OrgID: "hub3", Page: 1, Size: 1},
+				"dc_type~10", "meta.spec~10", "meta.tags~10",
+			),
+			ids:   []string{"hub3_archive_1"},
+			total: 5,
+			facets: []facet{
+				{Field: "dc_type", Total: 5, Links: []link{{"painting", 2, nil}, {"drawing", 1, nil}, {"letter", 1, nil}, {"map", 1, nil}}},
+				{Field: "meta.spec", Total: 5, Links: []link{{"museum", 3, nil}, {"archive", 2, nil}}},
+				{Field: "meta.tags", Total: 3, Links: []link{{"image", 3, nil}}},
+			},
+		},
+		{
+			name: "pivot facets",
+			req: withFacets(
+				&search.Request{OrgID: "hub3", Page: 1, Size: 1},
+				"meta.spec~10>dc_type~10",
+			),
+			ids:   []string{"hub3_archive_1"},
+			total: 5,
+			facets: []facet{
+				{
+					Field: "meta.spec",
+					Total: 5,
+					Links: []link{
+						{"museum", 3, &facet{Field: "dc_type", Total: 3, Links: []link{{"painting", 2, nil}, {"drawing", 1, nil}}}},
+						{"archive", 2, &facet{Field: "dc_type", Total: 2, Links: []link{{"letter", 1, nil}, {"map", 1, nil}}}},
+					},
+				},
+			},
+		},
+		{
+			name: "facets with query and filter",
+			req: withFacets(
+				&search.Request{
+					Query:   "windmill",
+					Filters: []search.Filter{{Field: "meta.orgID", Value: "hub3"}},
+					Page:    1, Size: 10,
+				},
+				"dc_subject~3",
+			),
+			ids:       []string{"hub3_archive_2", "hub3_museum_1", "hub3_museum_3"},
+			unordered: true,
+			total:     3,
+			facets: []facet{
+				// the size limits the links, but not the total
+				{Field: "dc_subject", Total: 6, Links: []link{{"windmill", 3, nil}, {"landscape", 1, nil}, {"letter", 1, nil}}},
+			},
+		},
+	}
+}
+
+// newFacet returns the comparable part of the search.Facet.
+func newFacet(f *search.Facet) *facet {
+	got := &facet{Field: f.Field, Total: f.Total, Links: []link{}}
+
+	for _, l := range f.Links {
+		var pivot *facet
+		if l.Pivot != nil {
+			pivot = newFacet(l.Pivot)
+		}
+
+		got.Links = append(got.Links, link{Value: l.Value, Count: l.Count, Pivot: pivot})
+	}
+
+	return got
+}
+
+// RunSuite runs the conformance tests against the Searcher returned by fn.
+func RunSuite(t *testing.T, fn NewSearcherFunc) {
+	searcher := fn(t, Documents())
+
+	for _, tt := range testCases(t) {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			resp, err := searcher.Search(context.Background(), tt.req)
+			is.NoErr(err)
+
+			is.Equal(resp.Pager.Total, tt.total)
+
+			ids := []string{}
+			for _, hit := range resp.Hits {
+				ids = append(ids, hit.ID)
+			}
+
+			if tt.unordered {
+				sort.Strings(ids)
+			}
+
+			if diff := cmp.Diff(tt.ids, ids); diff != "" {
+				t.Errorf("Search() hits mismatch (-want +got):\n%s", diff)
+			}
+
+			facets := []facet{}
+			for _, f := range resp.Facets {
+				facets = append(facets, *newFacet(f))
+			}
+
+			if tt.facets == nil {
+				tt.facets = []facet{}
+			}
+
+			if diff := cmp.Diff(tt.facets, facets); diff != "" {
+				t.Errorf("Search() facets mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -14,12 +14,27 @@
 
 package search
 
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Recorder records the executed search requests, for example for search analytics.
+// RecordSearch is called for the first page of each successful search and must not block.
+type Recorder interface {
+	RecordSearch(req *Request, resp *Response, took time.Duration)
+}
+
 // Service is the central search service that should be initialised once and
 // shared between requests. It is safe for concurrent use by multiple goroutines.
 type Service struct {
 	responseSize    int
 	maxResponseSize int
 	facetSize       int
+	searcher        Searcher
+	recorder        Recorder
+	orgID           string
 }
 
 // OptionFunc is a function that configures a Service.
@@ -70,3 +85,70 @@ func ResponseSize(size int) OptionFunc {
 		return nil
 	}
 }
+
+// SetSearcher sets the Searcher that executes the search requests.
+func SetSearcher(searcher Searcher) OptionFunc {
+	return func(s *Service) error {
+		s.searcher = searcher
+		return nil
+	}
+}
+
+// SetRecorder sets the Recorder that is notified of executed search requests.
+func SetRecorder(recorder Recorder) OptionFunc {
+	return func(s *Service) error {
+		s.recorder = recorder
+		return nil
+	}
+}
+
+// SetOrgID sets the organization that is searched by the search API.
+func SetOrgID(orgID string) OptionFunc {
+	return func(s *Service) error {
+		s.orgID = orgID
+		return nil
+	}
+}
+
+// Search applies the Service defaults to the Request and executes it with
+// the configured Searcher.
+func (s *Service) Search(ctx context.Context, req *Request) (*Response, error) {
+	if s.searcher == nil {
+		return nil, ErrNoSearcher
+	}
+
+	if req.Size <= 0 {
+		req.Size = s.responseSize
+	}
+
+	if req.Size > s.maxResponseSize {
+		req.Size = s.maxResponseSize
+	}
+
+	if req.Page < 1 {
+		req.Page = 1
+	}
+
+	for _, ff := range req.Facets {
+		for ; ff != nil; ff = ff.pivot {
+			if ff.size == 0 {
+				ff.size = s.facetSize
+			}
+		}
+	}
+
+	start := time.Now()
+
+	resp, err := s.searcher.Search(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to execute search request; %w", err)
+	}
+
+	if s.recorder != nil && req.Page == 1 {
+		s.recorder.RecordSearch(req, resp, time.Since(start))
+	}
+
+	resp.markSelected(req)
+
+	return resp, nil
+}
@@ -15,10 +15,16 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
 )
 
 func TestNewService(t *testing.T) {
@@ -95,3 +101,122 @@ func errorOpt() OptionFunc {
 		return fmt.Errorf("we expect this error")
 	}
 }
+
+// stubSearcher returns a Response with a single facet and records the
+// Request it received.
+type stubSearcher struct {
+	req *Request
+}
+
+func (s *stubSearcher) Search(ctx context.Context, req *Request) (*Response, error) {
+	s.req = req
+
+	return &Response{
+		Facets: []*Facet{
+			{
+				Field: "dc_type",
+				Links: []*FacetLink{{Value: "painting"}, {Value: "drawing"}},
+			},
+		},
+	}, nil
+}
+
+func TestService_Search(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService()
+	is.NoErr(err)
+
+	_, err = svc.Search(context.Background(), &Request{})
+	is.True(errors.Is(err, ErrNoSearcher))
+
+	searcher := &stubSearcher{}
+
+	svc, err = NewService(SetSearcher(searcher))
+	is.NoErr(err)
+
+	req := &Request{Size: 1000}
+	is.NoErr(req.AddFacet("dc_type"))
+	req.AddFilter("dc_type", "drawing")
+
+	resp, err := svc.Search(context.Background(), req)
+	is.NoErr(err)
+
+	// service defaults are applied to the request
+	is.Equal(searcher.req.Page, 1)
+	is.Equal(searcher.req.Size, 500)
+	is.Equal(searcher.req.Facets[0].Size(), 50)
+
+	// facet links are selected by the filters
+	is.True(resp.Facets[0].IsSelected)
+	is.True(!resp.Facets[0].Links[0].IsSelected)
+	is.True(resp.Facets[0].Links[1].IsSelected)
+}
+
+// stubRecorder records the Requests it received.
+type stubRecorder struct {
+	calls []*Request
+}
+
+func (r *stubRecorder) RecordSearch(req *Request, resp *Response, took time.Duration) {
+	r.calls = append(r.calls, req)
+}
+
+func TestService_SearchRecorder(t *testing.T) {
+	is := is.New(t)
+
+	recorder := &stubRecorder{}
+
+	svc, err := NewService(SetSearcher(&stubSearcher{}), SetRecorder(recorder))
+	is.NoErr(err)
+
+	_, err = svc.Search(context.Background(), &Request{Query: "rembrandt"})
+	is.NoErr(err)
+
+	// only the first page is recorded
+	_, err = svc.Search(context.Background(), &Request{Query: "rembrandt", Page: 2})
+	is.NoErr(err)
+
+	is.Equal(len(recorder.calls), 1)
+	is.Equal(recorder.calls[0].Query, "rembrandt")
+}
+
+func TestService_handleSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		orgID  string
+		target string
+		status int
+	}{
+		{"organization of the service", "hub3", "/?q=rembrandt", http.StatusOK},
+		{"same organization", "hub3", "/?q=rembrandt&orgID=hub3", http.StatusOK},
+		{"other organization", "hub3", "/?q=rembrandt&orgID=other", http.StatusForbidden},
+		{"no organization", "", "/?q=rembrandt&orgID=other", http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			searcher := &stubSearcher{}
+			recorder := &stubRecorder{}
+
+			svc, err := NewService(SetSearcher(searcher), SetRecorder(recorder), SetOrgID(tt.orgID))
+			is.NoErr(err)
+
+			w := httptest.NewRecorder()
+			svc.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			is.Equal(w.Code, tt.status)
+
+			if tt.status != http.StatusOK {
+				is.True(searcher.req == nil)
+				return
+			}
+
+			is.Equal(searcher.req.OrgID, "hub3")
+			is.Equal(recorder.calls[0].OrgID, "hub3")
+		})
+	}
+}
@@ -63,6 +63,12 @@ type Shutdown interface {
 	Shutdown(ctx context.Context) error
 }
 
+// ReadinessCheck must be implemented by each service that can be unable to
+// serve requests, e.g. when its storage must be migrated first.
+type ReadinessCheck interface {
+	Ready(ctx context.Context) error
+}
+
 type server struct {
 	// router is compatible with http.Mux
 	router chi.Router
@@ -96,6 +102,8 @@ type server struct {
 	revision *revision.Service
 	// shutdownHooks are called on server shutdown
 	shutdownHooks map[string]Shutdown
+	// readinessChecks must all pass before the server reports it is ready
+	readinessChecks map[string]ReadinessCheck
 	// service context
 	ctx context.Context
 	// dataNodeProxy is the httputil.ReverseProxy for the datanode
@@ -118,6 +126,7 @@ func newServer(options ...Option) (*server, error) {
 		workers:         newWorkerPool(ctx),
 		gracefulTimeout: defaultShutdownTimeout * time.Second,
 		shutdownHooks:   make(map[string]Shutdown),
+		readinessChecks: make(map[string]ReadinessCheck),
 	}
 
 	s.setRouterdefaults()
@@ -296,6 +305,40 @@ func (s *server) handleIndex() http.HandlerFunc {
 	}
 }
 
+// handleReadiness returns the result of each ReadinessCheck.
+// When a check fails http.StatusServiceUnavailable is returned.
+func (s *server) handleReadiness() http.HandlerFunc {
+	type response struct {
+		Ready  bool              `json:"ready"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := response{
+			Ready:  true,
+			Checks: map[string]string{},
+		}
+
+		for name, check := range s.readinessChecks {
+			if err := check.Ready(r.Context()); err != nil {
+				resp.Ready = false
+				resp.Checks[name] = err.Error()
+
+				continue
+			}
+
+			resp.Checks[name] = "ok"
+		}
+
+		status := http.StatusOK
+		if !resp.Ready {
+			status = http.StatusServiceUnavailable
+		}
+
+		s.respond(w, r, resp, status)
+	}
+}
+
 // handleMethodNotAllowed returns a custom response when a method is not allowed.
 func (s *server) handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	s.respondWithError(w, r, fmt.Errorf("method %s is not allowed", r.Method), http.StatusMethodNotAllowed)
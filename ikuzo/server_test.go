@@ -61,6 +61,53 @@ func Test_server_handleHeartbeat(t *testing.T) {
 	is.Equal(w.Header().Get("Content-Type"), "text/plain")
 }
 
+type readinessFunc func(ctx context.Context) error
+
+func (f readinessFunc) Ready(ctx context.Context) error {
+	return f(ctx)
+}
+
+func Test_server_handleReadiness(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		statusCode int
+		body       string
+	}{
+		{
+			"ready",
+			nil,
+			http.StatusOK,
+			`{"ready":true,"checks":{"mapping":"ok"}}`,
+		},
+		{
+			"not ready",
+			errors.New("reindex required"),
+			http.StatusServiceUnavailable,
+			`{"ready":false,"checks":{"mapping":"reindex required"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			svr, err := newServer(
+				SetDisableRequestLogger(),
+				SetReadinessCheck("mapping", readinessFunc(func(ctx context.Context) error { return tt.err })),
+			)
+			is.NoErr(err)
+
+			req, err := http.NewRequest("GET", "/ready", nil)
+			is.NoErr(err)
+
+			w := httptest.NewRecorder()
+			svr.ServeHTTP(w, req)
+			is.Equal(w.Code, tt.statusCode)
+			is.Equal(strings.TrimSpace(w.Body.String()), tt.body)
+		})
+	}
+}
+
 func Test_server_handleStripSlashes(t *testing.T) {
 	is := is.New(t)
 	svr, err := newServer(
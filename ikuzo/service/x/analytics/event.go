@@ -0,0 +1,86 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxQueryLength is the maximum number of runes of a recorded query.
+const maxQueryLength = 256
+
+var (
+	emailRe  = regexp.MustCompile(`[^\s@]+@[^\s@]+\.[^\s@]+`)
+	numberRe = regexp.MustCompile(`\+?\d[\d\-]{6,}\d`)
+)
+
+// Event is a single recorded search request.
+type Event struct {
+	// OrgID is the organization the search was executed for.
+	OrgID string `json:"orgID"`
+	// Query is the normalized full-text query.
+	Query string `json:"query,omitempty"`
+	// Filters are the selected filters in the form 'field:value'.
+	Filters []string `json:"filters,omitempty"`
+	// Hits is the total number of hits of the search.
+	Hits int64 `json:"hits"`
+	// Latency is the duration of the search in milliseconds.
+	Latency int64 `json:"latencyMs"`
+	// Source is the search API that was used, for example 'v2' or 'v3'.
+	Source string `json:"source,omitempty"`
+	// Time is when the search was executed.
+	Time time.Time `json:"time"`
+}
+
+// normalize normalizes the query and filters of the Event.
+func (e *Event) normalize() {
+	e.Query = NormalizeQuery(e.Query)
+
+	filters := make([]string, 0, len(e.Filters))
+
+	for _, f := range e.Filters {
+		f = strings.TrimSpace(f)
+		if f != "" {
+			filters = append(filters, f)
+		}
+	}
+
+	sort.Strings(filters)
+
+	e.Filters = filters
+}
+
+// NormalizeQuery lowercases the query, collapses whitespace and redacts
+// e-mail addresses and long numbers, such as phone numbers, from the query.
+func NormalizeQuery(q string) string {
+	q = strings.Join(strings.Fields(strings.ToLower(q)), " ")
+
+	if q == "*" || q == "*:*" {
+		return ""
+	}
+
+	q = emailRe.ReplaceAllString(q, "<email>")
+	q = numberRe.ReplaceAllString(q, "<number>")
+
+	if utf8.RuneCountInString(q) > maxQueryLength {
+		q = string([]rune(q)[:maxQueryLength])
+	}
+
+	return q
+}
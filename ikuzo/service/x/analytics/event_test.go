@@ -0,0 +1,41 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"lowercase and whitespace", "  Rembrandt   VAN Rijn ", "rembrandt van rijn"},
+		{"match all", "*:*", ""},
+		{"email", "letters from jan@example.org", "letters from <email>"},
+		{"phone number", "call 06-12345678", "call <number>"},
+		{"years are kept", "1900 1950", "1900 1950"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuery(tt.query); got != tt.want {
+				t.Errorf("NormalizeQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
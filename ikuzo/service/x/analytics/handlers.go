@@ -0,0 +1,189 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultWindow = 7 * 24 * time.Hour
+	defaultLimit  = 25
+	maxLimit      = 500
+)
+
+type reportFn func(ctx context.Context, w Window, limit int) (*Report, error)
+
+// Routes returns the report endpoints of the Service.
+// The reports are only available to the admins of the organization.
+func (s *Service) Routes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Get("/top-queries", s.handleReport(s.TopQueries))
+	router.Get("/zero-results", s.handleReport(s.ZeroResultQueries))
+	router.Get("/top-facets", s.handleReport(s.TopFacets))
+
+	return router
+}
+
+func (s *Service) handleReport(fn reportFn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgID, err := s.authorize(r)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrForbidden) {
+				status = http.StatusForbidden
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+
+		window, limit, err := s.parseReportParams(orgID, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := fn(r.Context(), window, limit)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrNoOrgID) {
+				status = http.StatusBadRequest
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+
+		render.JSON(w, r, report)
+	}
+}
+
+// authorize returns the organization of the admin that requests a report.
+// The admin is identified by the admin header. When the orgID parameter is
+// given it must be the organization of the admin.
+func (s *Service) authorize(r *http.Request) (string, error) {
+	orgID, ok := s.admins[r.Header.Get(s.adminHeader)]
+	if !ok {
+		return "", ErrUnauthorized
+	}
+
+	if requested := r.URL.Query().Get("orgID"); requested != "" && requested != orgID {
+		return "", ErrForbidden
+	}
+
+	return orgID, nil
+}
+
+// parseReportParams returns the Window for the organization and the limit
+// from the URL query parameters.
+//
+// The following parameters are supported:
+//
+// orgID: the organization, must be the organization of the admin
+// window: the duration before until, for example '24h' or '30d' (default: 7d)
+// from: start of the window as RFC3339 timestamp; overrides window
+// until: end of the window as RFC3339 timestamp (default: now)
+// limit: maximum number of returned entries (default: 25, max: 500)
+func (s *Service) parseReportParams(orgID string, params url.Values) (Window, int, error) {
+	w := Window{
+		OrgID: orgID,
+		Until: s.now(),
+	}
+
+	if until := params.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return w, 0, fmt.Errorf("invalid until %q; %w", until, err)
+		}
+
+		w.Until = t
+	}
+
+	window := defaultWindow
+
+	if v := params.Get("window"); v != "" {
+		d, err := parseWindow(v)
+		if err != nil {
+			return w, 0, err
+		}
+
+		window = d
+	}
+
+	w.From = w.Until.Add(-window)
+
+	if from := params.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return w, 0, fmt.Errorf("invalid from %q; %w", from, err)
+		}
+
+		w.From = t
+	}
+
+	if !w.From.Before(w.Until) {
+		return w, 0, fmt.Errorf("from must be before until")
+	}
+
+	limit := defaultLimit
+
+	if v := params.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 {
+			return w, 0, fmt.Errorf("invalid limit %q", v)
+		}
+
+		limit = l
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return w, limit, nil
+}
+
+// parseWindow parses a duration that also supports days, for example '7d'.
+func parseWindow(v string) (time.Duration, error) {
+	if strings.HasSuffix(v, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || days < 1 {
+			return 0, fmt.Errorf("invalid window %q", v)
+		}
+
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", v)
+	}
+
+	return d, nil
+}
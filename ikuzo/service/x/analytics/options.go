@@ -0,0 +1,124 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Option is a closure to configure the Service.
+// It is used in NewService.
+type Option func(*Service) error
+
+// SetStore sets the Store where the events are persisted.
+func SetStore(store Store) Option {
+	return func(s *Service) error {
+		s.store = store
+		return nil
+	}
+}
+
+// SetDefaultOrgID sets the organization for events that have no orgID.
+func SetDefaultOrgID(orgID string) Option {
+	return func(s *Service) error {
+		s.defaultOrgID = orgID
+		return nil
+	}
+}
+
+// SetAdmin allows the admin with the key to request the reports of the organization.
+// Reports can only be requested by admins.
+func SetAdmin(key, orgID string) Option {
+	return func(s *Service) error {
+		if key == "" || orgID == "" {
+			return fmt.Errorf("analytics admin requires a key and an orgID")
+		}
+
+		s.admins[key] = orgID
+
+		return nil
+	}
+}
+
+// SetAdminHeader sets the header with the admin key. default: X-API-Key
+func SetAdminHeader(header string) Option {
+	return func(s *Service) error {
+		if header != "" {
+			s.adminHeader = header
+		}
+
+		return nil
+	}
+}
+
+// SetRetention sets the number of days events are kept.
+// When days is 0 the default retention of 90 days is used.
+func SetRetention(days int) Option {
+	return func(s *Service) error {
+		if days > 0 {
+			s.retention = days
+		}
+
+		return nil
+	}
+}
+
+// SetOrgRetention overrides the number of days events are kept for an organization.
+func SetOrgRetention(orgID string, days int) Option {
+	return func(s *Service) error {
+		if days > 0 {
+			s.orgRetention[orgID] = days
+		}
+
+		return nil
+	}
+}
+
+// SetPruneInterval sets how often events outside the retention are removed.
+func SetPruneInterval(interval time.Duration) Option {
+	return func(s *Service) error {
+		if interval > 0 {
+			s.pruneInterval = interval
+		}
+
+		return nil
+	}
+}
+
+// SetFlushInterval sets how often the queued events are written when the
+// batch is not full.
+func SetFlushInterval(interval time.Duration) Option {
+	return func(s *Service) error {
+		if interval > 0 {
+			s.flushInterval = interval
+		}
+
+		return nil
+	}
+}
+
+// SetBufferSize sets the number of events that can be queued for writing.
+// When the queue is full new events are dropped, so recording never slows
+// down the search requests.
+func SetBufferSize(size int) Option {
+	return func(s *Service) error {
+		if size > 0 {
+			s.bufferSize = size
+		}
+
+		return nil
+	}
+}
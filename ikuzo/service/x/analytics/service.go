@@ -0,0 +1,440 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultAdminHeader   = "X-API-Key"
+	defaultRetention     = 90
+	defaultBufferSize    = 1024
+	defaultPruneInterval = time.Hour
+	defaultFlushInterval = time.Second
+	// maxBatchSize is the maximum number of events that are written at once.
+	maxBatchSize = 256
+)
+
+var (
+	// ErrNoStore is returned when the Service is created without a Store.
+	ErrNoStore = errors.New("analytics store is required")
+	// ErrNoOrgID is returned when a report is requested without an orgID.
+	ErrNoOrgID = errors.New("orgID is required")
+	// ErrUnauthorized is returned when a report is requested without a known admin key.
+	ErrUnauthorized = errors.New("a valid analytics admin key is required")
+	// ErrForbidden is returned when a report is requested for another organization than the admin's.
+	ErrForbidden = errors.New("reports are restricted to the organization of the admin")
+)
+
+// Metrics are the counters of the recorded events.
+type Metrics struct {
+	Recorded uint64
+	Dropped  uint64
+	Failed   uint64
+}
+
+// Service records search events and creates reports from them.
+// It is safe for concurrent use by multiple goroutines.
+type Service struct {
+	store         Store
+	defaultOrgID  string
+	adminHeader   string
+	admins        map[string]string
+	retention     int
+	orgRetention  map[string]int
+	pruneInterval time.Duration
+	flushInterval time.Duration
+	bufferSize    int
+	events        chan *Event
+	m             Metrics
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	now           func() time.Time
+}
+
+// NewService creates a Service and starts the background writer.
+// The Service must be stopped with Shutdown.
+func NewService(options ...Option) (*Service, error) {
+	s := &Service{
+		retention:     defaultRetention,
+		orgRetention:  map[string]int{},
+		adminHeader:   defaultAdminHeader,
+		admins:        map[string]string{},
+		pruneInterval: defaultPruneInterval,
+		flushInterval: defaultFlushInterval,
+		bufferSize:    defaultBufferSize,
+		now:           time.Now,
+	}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.store == nil {
+		return nil, ErrNoStore
+	}
+
+	s.events = make(chan *Event, s.bufferSize)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+
+	go s.run(ctx)
+
+	return s, nil
+}
+
+// Record queues the Event for writing. It never blocks; when the queue is
+// full the Event is dropped.
+func (s *Service) Record(e *Event) {
+	if e.OrgID == "" {
+		e.OrgID = s.defaultOrgID
+	}
+
+	if e.OrgID == "" {
+		atomic.AddUint64(&s.m.Dropped, 1)
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+
+	e.normalize()
+
+	select {
+	case s.events <- e:
+	default:
+		atomic.AddUint64(&s.m.Dropped, 1)
+	}
+}
+
+// RecordSearch records a search executed by the search.Service.
+// It implements search.Recorder.
+func (s *Service) RecordSearch(req *search.Request, resp *search.Response, took time.Duration) {
+	filters := make([]string, 0, len(req.Filters))
+	for _, f := range req.Filters {
+		filters = append(filters, f.Field+":"+f.Value)
+	}
+
+	s.Record(&Event{
+		OrgID:   req.OrgID,
+		Query:   req.Query,
+		Filters: filters,
+		Hits:    resp.Pager.Total,
+		Latency: took.Milliseconds(),
+		Source:  "v3",
+	})
+}
+
+// Metrics returns a snapshot of the counters of the Service.
+func (s *Service) Metrics() Metrics {
+	return Metrics{
+		Recorded: atomic.LoadUint64(&s.m.Recorded),
+		Dropped:  atomic.LoadUint64(&s.m.Dropped),
+		Failed:   atomic.LoadUint64(&s.m.Failed),
+	}
+}
+
+// Shutdown writes the queued events and closes the Store.
+func (s *Service) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return s.store.Close()
+}
+
+// run writes the queued events in batches. A batch is written when it is
+// full or when the flush interval has passed.
+func (s *Service) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	pruneTicker := time.NewTicker(s.pruneInterval)
+	defer pruneTicker.Stop()
+
+	flushTicker := time.NewTicker(s.flushInterval)
+	defer flushTicker.Stop()
+
+	s.prune(ctx)
+
+	batch := make([]*Event, 0, maxBatchSize)
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		s.write(ctx, batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-s.events:
+			batch = append(batch, e)
+			if len(batch) >= maxBatchSize {
+				flush(ctx)
+			}
+		case <-flushTicker.C:
+			flush(ctx)
+		case <-pruneTicker.C:
+			s.prune(ctx)
+		case <-ctx.Done():
+			// write the queued events before stopping
+			for {
+				select {
+				case e := <-s.events:
+					batch = append(batch, e)
+					if len(batch) >= maxBatchSize {
+						flush(context.Background())
+					}
+				default:
+					flush(context.Background())
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) write(ctx context.Context, events []*Event) {
+	if err := s.store.Put(ctx, events...); err != nil {
+		atomic.AddUint64(&s.m.Failed, uint64(len(events)))
+		log.Error().Err(err).Str("svc", "analytics").Int("events", len(events)).Msg("unable to store search events")
+
+		return
+	}
+
+	atomic.AddUint64(&s.m.Recorded, uint64(len(events)))
+}
+
+// retentionFor returns the number of days events of the organization are kept.
+func (s *Service) retentionFor(orgID string) int {
+	if days, ok := s.orgRetention[orgID]; ok {
+		return days
+	}
+
+	return s.retention
+}
+
+// prune removes the events that are outside the retention of their organization.
+func (s *Service) prune(ctx context.Context) {
+	orgIDs, err := s.store.OrgIDs(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("svc", "analytics").Msg("unable to list organizations for pruning")
+		return
+	}
+
+	for _, orgID := range orgIDs {
+		before := s.now().AddDate(0, 0, -s.retentionFor(orgID))
+
+		n, err := s.store.Delete(ctx, orgID, before)
+		if err != nil {
+			log.Error().Err(err).Str("svc", "analytics").Str("orgID", orgID).Msg("unable to prune search events")
+			continue
+		}
+
+		if n > 0 {
+			log.Info().Str("svc", "analytics").Str("orgID", orgID).Int("removed", n).Msg("pruned search events")
+		}
+	}
+}
+
+// Window is the time window of a report for an organization.
+type Window struct {
+	OrgID string
+	From  time.Time
+	Until time.Time
+}
+
+// QueryCount is the number of times a query was searched.
+type QueryCount struct {
+	Query    string    `json:"query"`
+	Count    int       `json:"count"`
+	AvgHits  float64   `json:"avgHits"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// FacetCount is the number of times a filter was selected.
+type FacetCount struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Report is the result of an aggregation over a Window.
+type Report struct {
+	OrgID    string       `json:"orgID"`
+	From     time.Time    `json:"from"`
+	Until    time.Time    `json:"until"`
+	Searches int          `json:"searches"`
+	Queries  []QueryCount `json:"queries,omitempty"`
+	Facets   []FacetCount `json:"facets,omitempty"`
+}
+
+// TopQueries returns the most searched queries in the Window.
+func (s *Service) TopQueries(ctx context.Context, w Window, limit int) (*Report, error) {
+	return s.queryReport(ctx, w, limit, func(e *Event) bool { return true })
+}
+
+// ZeroResultQueries returns the most searched queries in the Window that returned no hits.
+func (s *Service) ZeroResultQueries(ctx context.Context, w Window, limit int) (*Report, error) {
+	return s.queryReport(ctx, w, limit, func(e *Event) bool { return e.Hits == 0 })
+}
+
+// TopFacets returns the most selected filters in the Window.
+func (s *Service) TopFacets(ctx context.Context, w Window, limit int) (*Report, error) {
+	report := &Report{OrgID: w.OrgID, From: w.From, Until: w.Until}
+	counts := map[string]*FacetCount{}
+
+	err := s.scan(ctx, w, func(e *Event) error {
+		report.Searches++
+
+		for _, f := range e.Filters {
+			fc, ok := counts[f]
+			if !ok {
+				parts := strings.SplitN(f, ":", 2)
+				fc = &FacetCount{Field: parts[0]}
+
+				if len(parts) == 2 {
+					fc.Value = parts[1]
+				}
+
+				counts[f] = fc
+			}
+
+			fc.Count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fc := range counts {
+		report.Facets = append(report.Facets, *fc)
+	}
+
+	sort.Slice(report.Facets, func(i, j int) bool {
+		a, b := report.Facets[i], report.Facets[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+
+		return a.Value < b.Value
+	})
+
+	if limit > 0 && len(report.Facets) > limit {
+		report.Facets = report.Facets[:limit]
+	}
+
+	return report, nil
+}
+
+func (s *Service) queryReport(ctx context.Context, w Window, limit int, include func(e *Event) bool) (*Report, error) {
+	report := &Report{OrgID: w.OrgID, From: w.From, Until: w.Until}
+	counts := map[string]*QueryCount{}
+	hits := map[string]int64{}
+
+	err := s.scan(ctx, w, func(e *Event) error {
+		report.Searches++
+
+		if e.Query == "" || !include(e) {
+			return nil
+		}
+
+		qc, ok := counts[e.Query]
+		if !ok {
+			qc = &QueryCount{Query: e.Query}
+			counts[e.Query] = qc
+		}
+
+		qc.Count++
+		hits[e.Query] += e.Hits
+
+		if e.Time.After(qc.LastSeen) {
+			qc.LastSeen = e.Time
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for q, qc := range counts {
+		qc.AvgHits = float64(hits[q]) / float64(qc.Count)
+		report.Queries = append(report.Queries, *qc)
+	}
+
+	sort.Slice(report.Queries, func(i, j int) bool {
+		a, b := report.Queries[i], report.Queries[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+
+		return a.Query < b.Query
+	})
+
+	if limit > 0 && len(report.Queries) > limit {
+		report.Queries = report.Queries[:limit]
+	}
+
+	return report, nil
+}
+
+func (s *Service) scan(ctx context.Context, w Window, fn func(e *Event) error) error {
+	if w.OrgID == "" {
+		return ErrNoOrgID
+	}
+
+	if err := s.store.Scan(ctx, w.OrgID, w.From, w.Until, fn); err != nil {
+		return fmt.Errorf("unable to scan search events; %w", err)
+	}
+
+	return nil
+}
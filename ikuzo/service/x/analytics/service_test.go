@@ -0,0 +1,315 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+// memoryStore is an in-memory Store for testing.
+type memoryStore struct {
+	sync.Mutex
+	events []*Event
+	puts   int
+}
+
+func (m *memoryStore) Put(ctx context.Context, events ...*Event) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.events = append(m.events, events...)
+	m.puts++
+
+	return nil
+}
+
+func (m *memoryStore) Scan(ctx context.Context, orgID string, from, until time.Time, fn func(e *Event) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, e := range m.events {
+		if e.OrgID != orgID || e.Time.Before(from) || !e.Time.Before(until) {
+			continue
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, orgID string, before time.Time) (int, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	var (
+		kept    []*Event
+		removed int
+	)
+
+	for _, e := range m.events {
+		if e.OrgID == orgID && e.Time.Before(before) {
+			removed++
+			continue
+		}
+
+		kept = append(kept, e)
+	}
+
+	m.events = kept
+
+	return removed, nil
+}
+
+func (m *memoryStore) OrgIDs(ctx context.Context) ([]string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	seen := map[string]bool{}
+	orgIDs := []string{}
+
+	for _, e := range m.events {
+		if !seen[e.OrgID] {
+			seen[e.OrgID] = true
+			orgIDs = append(orgIDs, e.OrgID)
+		}
+	}
+
+	return orgIDs, nil
+}
+
+func (m *memoryStore) Close() error { return nil }
+
+// setNow sets the clock of the Service before the background writer starts.
+func setNow(now time.Time) Option {
+	return func(s *Service) error {
+		s.now = func() time.Time { return now }
+		return nil
+	}
+}
+
+func TestNewService(t *testing.T) {
+	is := is.New(t)
+
+	_, err := NewService()
+	is.Equal(err, ErrNoStore)
+}
+
+func TestService_Record(t *testing.T) {
+	is := is.New(t)
+
+	store := &memoryStore{}
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	svc, err := NewService(SetStore(store), SetDefaultOrgID("hub3"), setNow(now))
+	is.NoErr(err)
+
+	svc.Record(&Event{Query: " Rembrandt ", Filters: []string{"dc_type:painting", "dc_creator:rembrandt"}, Hits: 10})
+	svc.Record(&Event{OrgID: "other", Query: "vermeer"})
+
+	// shutdown writes the queued events
+	is.NoErr(svc.Shutdown(context.Background()))
+
+	want := []*Event{
+		{OrgID: "hub3", Query: "rembrandt", Filters: []string{"dc_creator:rembrandt", "dc_type:painting"}, Hits: 10, Time: now},
+		{OrgID: "other", Query: "vermeer", Filters: []string{}, Time: now},
+	}
+
+	if diff := cmp.Diff(want, store.events); diff != "" {
+		t.Errorf("Record() mismatch (-want +got):\n%s", diff)
+	}
+
+	is.Equal(svc.Metrics().Recorded, uint64(2))
+	is.Equal(store.puts, 1) // the events are written in a single batch
+}
+
+func TestService_flushInterval(t *testing.T) {
+	is := is.New(t)
+
+	store := &memoryStore{}
+
+	svc, err := NewService(SetStore(store), SetDefaultOrgID("hub3"), SetFlushInterval(10*time.Millisecond))
+	is.NoErr(err)
+
+	defer svc.Shutdown(context.Background())
+
+	for i := 0; i < 3; i++ {
+		svc.Record(&Event{Query: "rembrandt"})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for svc.Metrics().Recorded != 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// the partial batch is written without waiting for shutdown
+	is.Equal(svc.Metrics().Recorded, uint64(3))
+}
+
+func testService(t *testing.T, options ...Option) (*Service, *memoryStore, time.Time) {
+	t.Helper()
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := &memoryStore{}
+
+	add := func(query string, hits int64, age time.Duration, filters ...string) {
+		store.events = append(store.events, &Event{
+			OrgID: "hub3", Query: query, Hits: hits, Filters: filters, Time: now.Add(-age),
+		})
+	}
+
+	add("rembrandt", 10, time.Hour, "dc_type:painting")
+	add("rembrandt", 20, 2*time.Hour, "dc_type:painting", "dc_creator:rembrandt")
+	add("vermeer", 0, 3*time.Hour)
+	add("vermeer", 0, 4*time.Hour, "dc_type:painting")
+	add("", 100, 5*time.Hour, "dc_type:drawing")
+	add("rembrandt", 0, 48*time.Hour)
+	add("old", 0, 100*24*time.Hour)
+
+	svc, err := NewService(append([]Option{SetStore(store), setNow(now)}, options...)...)
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = svc.Shutdown(context.Background())
+	})
+
+	return svc, store, now
+}
+
+func TestService_Reports(t *testing.T) {
+	is := is.New(t)
+
+	svc, _, now := testService(t)
+	ctx := context.Background()
+	day := Window{OrgID: "hub3", From: now.Add(-24 * time.Hour), Until: now}
+
+	top, err := svc.TopQueries(ctx, day, 10)
+	is.NoErr(err)
+	is.Equal(top.Searches, 5)
+
+	want := []QueryCount{
+		{Query: "rembrandt", Count: 2, AvgHits: 15, LastSeen: now.Add(-time.Hour)},
+		{Query: "vermeer", Count: 2, AvgHits: 0, LastSeen: now.Add(-3 * time.Hour)},
+	}
+	if diff := cmp.Diff(want, top.Queries); diff != "" {
+		t.Errorf("TopQueries() mismatch (-want +got):\n%s", diff)
+	}
+
+	zero, err := svc.ZeroResultQueries(ctx, Window{OrgID: "hub3", From: now.Add(-72 * time.Hour), Until: now}, 10)
+	is.NoErr(err)
+
+	wantZero := []QueryCount{
+		{Query: "vermeer", Count: 2, AvgHits: 0, LastSeen: now.Add(-3 * time.Hour)},
+		{Query: "rembrandt", Count: 1, AvgHits: 0, LastSeen: now.Add(-48 * time.Hour)},
+	}
+	if diff := cmp.Diff(wantZero, zero.Queries); diff != "" {
+		t.Errorf("ZeroResultQueries() mismatch (-want +got):\n%s", diff)
+	}
+
+	facets, err := svc.TopFacets(ctx, day, 2)
+	is.NoErr(err)
+
+	wantFacets := []FacetCount{
+		{Field: "dc_type", Value: "painting", Count: 3},
+		{Field: "dc_creator", Value: "rembrandt", Count: 1},
+	}
+	if diff := cmp.Diff(wantFacets, facets.Facets); diff != "" {
+		t.Errorf("TopFacets() mismatch (-want +got):\n%s", diff)
+	}
+
+	_, err = svc.TopQueries(ctx, Window{From: day.From, Until: day.Until}, 10)
+	is.Equal(err, ErrNoOrgID)
+}
+
+func TestService_prune(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    int
+	}{
+		{"default retention", nil, 6},
+		{"org retention", []Option{SetOrgRetention("hub3", 1)}, 5},
+		{"other org retention", []Option{SetOrgRetention("other", 1)}, 6},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := testService(t, tt.options...)
+
+			svc.prune(context.Background())
+
+			store.Lock()
+			defer store.Unlock()
+
+			if got := len(store.events); got != tt.want {
+				t.Errorf("prune() kept %d events, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_Routes(t *testing.T) {
+	svc, _, _ := testService(t, SetDefaultOrgID("hub3"), SetAdmin("secret", "hub3"), SetAdmin("other-secret", "other"))
+
+	tests := []struct {
+		name       string
+		url        string
+		key        string
+		wantStatus int
+	}{
+		{"top queries", "/top-queries?window=24h", "secret", http.StatusOK},
+		{"zero results", "/zero-results?window=3d&limit=1", "secret", http.StatusOK},
+		{"top facets", "/top-facets?from=2020-05-01T00:00:00Z&until=2020-06-01T00:00:00Z", "secret", http.StatusOK},
+		{"own organization", "/top-queries?orgID=hub3", "secret", http.StatusOK},
+		{"invalid window", "/top-queries?window=week", "secret", http.StatusBadRequest},
+		{"invalid limit", "/top-queries?limit=-1", "secret", http.StatusBadRequest},
+		{"from after until", "/top-queries?from=2020-07-01T00:00:00Z", "secret", http.StatusBadRequest},
+		{"without key", "/top-queries", "", http.StatusUnauthorized},
+		{"unknown key", "/top-queries", "guess", http.StatusUnauthorized},
+		{"other organization", "/top-queries?orgID=hub3", "other-secret", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+
+			w := httptest.NewRecorder()
+			svc.Routes().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("%s status = %d, want %d; body %s", tt.url, w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
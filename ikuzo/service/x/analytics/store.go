@@ -0,0 +1,36 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists the recorded search events.
+type Store interface {
+	// Put stores the events in a single transaction.
+	Put(ctx context.Context, events ...*Event) error
+	// Scan calls fn for each Event of the organization recorded between from
+	// (inclusive) and until (exclusive) in chronological order.
+	Scan(ctx context.Context, orgID string, from, until time.Time, fn func(e *Event) error) error
+	// Delete removes all events of the organization recorded before t.
+	// It returns the number of removed events.
+	Delete(ctx context.Context, orgID string, before time.Time) (int, error)
+	// OrgIDs returns the organizations that have recorded events.
+	OrgIDs(ctx context.Context) ([]string, error)
+	// Close closes the Store.
+	Close() error
+}
@@ -0,0 +1,225 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/delving/hub3/config"
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/delving/hub3/ikuzo/service/x/index"
+)
+
+// ContentHashStore stores the content hash of the last indexed version of each record.
+//
+// The content hashes are stored by the index.Service when the IndexMessages
+// of the record are written to the index.
+type ContentHashStore interface {
+	index.ContentHashStore
+	// Get returns the content hash of the record or an empty string when the record is unknown.
+	Get(ctx context.Context, orgID, datasetID, hubID string) (string, error)
+	// PutRevision stores the revision of the dataset in which the records
+	// were received. Skipped records were unchanged and not written to the index.
+	PutRevision(ctx context.Context, orgID, datasetID string, revision int, records map[string]bool) error
+	// ClearOrphans removes the content hashes of the records that were not
+	// received in the revision and returns the hubIDs of the records that were
+	// skipped in the revision.
+	ClearOrphans(ctx context.Context, orgID, datasetID string, revision int) ([]string, error)
+	// Delete removes all content hashes of the dataset.
+	Delete(ctx context.Context, orgID, datasetID string) error
+	// Close closes the ContentHashStore.
+	Close() error
+}
+
+// recordChange is the result of comparing the content hash of a record with the stored hash.
+type recordChange int
+
+const (
+	recordUnknown recordChange = iota
+	recordNew
+	recordChanged
+	recordUnchanged
+)
+
+// contentHash returns the ContentHash of the Request or the SHA-256 of its graph.
+func (req *Request) contentHash() string {
+	if req.ContentHash != "" {
+		return req.ContentHash
+	}
+
+	sum := sha256.Sum256([]byte(req.Graph))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// contentChange compares the content hash of the Request with the stored hash
+// and updates the stats.
+func (p *Parser) contentChange(req *Request) (recordChange, error) {
+	if p.hashes == nil {
+		return recordUnknown, nil
+	}
+
+	stored, err := p.hashes.Get(context.Background(), req.OrgID, req.DatasetID, req.HubID)
+	if err != nil {
+		return recordUnknown, fmt.Errorf("unable to get content hash for %s; %w", req.HubID, err)
+	}
+
+	switch stored {
+	case "":
+		atomic.AddUint64(&p.stats.RecordsNew, 1)
+
+		if p.ingest != nil {
+			p.ingest.AddNew(1)
+		}
+
+		return recordNew, nil
+	case req.contentHash():
+		atomic.AddUint64(&p.stats.RecordsUnchanged, 1)
+
+		if p.ingest != nil {
+			p.ingest.AddUnchanged(1)
+		}
+
+		return recordUnchanged, nil
+	default:
+		atomic.AddUint64(&p.stats.RecordsChanged, 1)
+
+		if p.ingest != nil {
+			p.ingest.AddChanged(1)
+		}
+
+		return recordChanged, nil
+	}
+}
+
+// skipUnchanged returns true when unchanged records are not written to the index. The
+// fragments and the RDF store always need the full graph of the record, and
+// the posthooks remove the records that are not part of the current revision.
+func (p *Parser) skipUnchanged() bool {
+	if p.hashes == nil || p.force || p.postHooks != nil || config.Config.RDF.RDFStoreEnabled {
+		return false
+	}
+
+	for _, indexType := range p.indexTypes {
+		if indexType != "v1" && indexType != "v2" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// recordIndex returns the BulkIndex for the IndexMessages of the record of the
+// indexType. The IndexMessages carry the content hash of the record, which is
+// stored after they are written to the index.
+func (p *Parser) recordIndex(req *Request, indexType string) index.BulkIndex {
+	if p.hashes == nil || (indexType != "v1" && indexType != "v2") {
+		return p.bi
+	}
+
+	return &recordIndex{bi: p.bi, hash: req.contentHash()}
+}
+
+// recordIndex sets the content hash on the IndexMessages of a record.
+type recordIndex struct {
+	bi   index.BulkIndex
+	hash string
+}
+
+func (ri *recordIndex) Publish(ctx context.Context, messages ...*domainpb.IndexMessage) error {
+	for _, m := range messages {
+		m.ContentHash = ri.hash
+	}
+
+	return ri.bi.Publish(ctx, messages...)
+}
+
+// addRevision buffers the revision of the received record until the end of
+// the request or the next clear_orphans.
+func (p *Parser) addRevision(req *Request, skipped bool) {
+	if p.hashes == nil {
+		return
+	}
+
+	p.revisionMu.Lock()
+	defer p.revisionMu.Unlock()
+
+	if p.revisions == nil {
+		p.revisions = map[string]bool{}
+	}
+
+	p.revisions[req.HubID] = skipped
+}
+
+// flushRevisions stores the buffered revisions of the received records.
+func (p *Parser) flushRevisions() error {
+	if p.hashes == nil || p.ds == nil {
+		return nil
+	}
+
+	p.revisionMu.Lock()
+	revisions := p.revisions
+	p.revisions = nil
+	p.revisionMu.Unlock()
+
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	err := p.hashes.PutRevision(context.Background(), p.stats.OrgID, p.stats.Spec, p.ds.Revision, revisions)
+	if err != nil {
+		return fmt.Errorf("unable to store record revisions; %w", err)
+	}
+
+	return nil
+}
+
+// skippedRecords returns the hubIDs of the unchanged records of the current
+// revision, which are not written to the index and must be kept by clear_orphans.
+// The content hashes of the orphans are removed.
+func (p *Parser) skippedRecords(orgID, datasetID string) ([]string, error) {
+	if p.hashes == nil {
+		return nil, nil
+	}
+
+	if err := p.flushRevisions(); err != nil {
+		return nil, err
+	}
+
+	skipped, err := p.hashes.ClearOrphans(context.Background(), orgID, datasetID, p.ds.Revision)
+	if err != nil {
+		return nil, fmt.Errorf("unable to clear orphaned content hashes; %w", err)
+	}
+
+	return skipped, nil
+}
+
+// dropHashes removes the content hashes of the dataset, so its records are
+// indexed again by the next ingest.
+func (p *Parser) dropHashes(orgID, datasetID string) error {
+	if p.hashes == nil {
+		return nil
+	}
+
+	if err := p.hashes.Delete(context.Background(), orgID, datasetID); err != nil {
+		return fmt.Errorf("unable to delete content hashes; %w", err)
+	}
+
+	return nil
+}
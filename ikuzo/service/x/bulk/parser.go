@@ -37,10 +37,18 @@ import (
 )
 
 type Parser struct {
-	once       sync.Once
-	ds         *models.DataSet
-	stats      *Stats
-	bi         index.BulkIndex
+	once   sync.Once
+	ds     *models.DataSet
+	stats  *Stats
+	bi     index.BulkIndex
+	index  *index.Service
+	ingest *index.Ingest
+	hashes ContentHashStore
+	// force publishes unchanged records as well
+	force bool
+	// revisions buffers the received records by hubID; true marks skipped records
+	revisionMu sync.Mutex
+	revisions  map[string]bool
 	indexTypes []string
 	// TODO(kiivihal): find better solution for this
 	sparqlUpdates []fragments.SparqlUpdate // store all the triples here for bulk insert
@@ -54,6 +62,13 @@ func (p *Parser) Parse(ctx context.Context, r io.Reader) error {
 
 	defer done()
 
+	defer func() {
+		// mark the ingest as done after all IndexMessages are published
+		if p.ingest != nil {
+			p.ingest.Done()
+		}
+	}()
+
 	workers := 4
 
 	actions := make(chan Request)
@@ -112,7 +127,14 @@ func (p *Parser) Parse(ctx context.Context, r io.Reader) error {
 		})
 	}
 
-	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+	err := g.Wait()
+
+	// store the revisions of the received records, also when the request failed
+	if flushErr := p.flushRevisions(); flushErr != nil {
+		log.Error().Err(flushErr).Str("datasetID", p.stats.Spec).Msg("unable to store record revisions")
+	}
+
+	if err != nil && !errors.Is(err, context.Canceled) {
 		log.Error().Err(err).Msg("workers with errors")
 		return err
 	}
@@ -146,6 +168,12 @@ func (p *Parser) setDataSet(req *Request) {
 	p.stats.OrgID = req.OrgID
 	req.Revision = ds.Revision
 	p.ds = ds
+
+	if p.index != nil {
+		p.ingest = p.index.NewIngest(req.OrgID, req.DatasetID, ds.Revision)
+		p.stats.IngestID = p.ingest.ID()
+		p.bi = p.ingest
+	}
 }
 
 func (p *Parser) process(ctx context.Context, req *Request) error {
@@ -160,6 +188,10 @@ func (p *Parser) process(ctx context.Context, req *Request) error {
 
 	switch req.Action {
 	case "index":
+		if p.ingest != nil {
+			p.ingest.AddReceived(1)
+		}
+
 		return p.Publish(req)
 	case "increment_revision":
 		ds, err := p.ds.IncrementRevision()
@@ -171,13 +203,25 @@ func (p *Parser) process(ctx context.Context, req *Request) error {
 		log.Info().Str("datasetID", req.DatasetID).Int("revision", ds.Revision).Msg("Incremented dataset")
 	case "clear_orphans":
 		// clear triples
-		ok, err := p.ds.DropOrphans(context.Background(), nil, nil)
+		var removed models.OrphansRemovedFunc
+		if p.ingest != nil {
+			removed = func(n int) { p.ingest.AddOrphansRemoved(uint64(n)) }
+		}
+
+		skipped, err := p.skippedRecords(req.OrgID, req.DatasetID)
+		if err != nil {
+			log.Error().Err(err).Str("datasetID", req.DatasetID).Msg("Unable to get skipped records")
+			return err
+		}
+
+		ok, err := p.ds.DropOrphansWithCallback(context.Background(), nil, removed, skipped)
 		if !ok || err != nil {
 			log.Error().Err(err).Str("datasetID", req.DatasetID).Msg("Unable to drop orphans")
 			return err
 		}
 
 		p.dropPosthook(req.OrgID, req.DatasetID, p.ds.Revision)
+		p.flushed(req.OrgID, req.DatasetID)
 
 		log.Info().Str("datasetID", req.DatasetID).Int("revision", p.ds.Revision).Msg("mark orphans and delete them")
 	case "disable_index":
@@ -187,7 +231,12 @@ func (p *Parser) process(ctx context.Context, req *Request) error {
 			return err
 		}
 
+		if err := p.dropHashes(req.OrgID, req.DatasetID); err != nil {
+			return err
+		}
+
 		p.dropPosthook(req.OrgID, req.DatasetID, -1)
+		p.flushed(req.OrgID, req.DatasetID)
 
 		log.Info().Str("datasetID", req.DatasetID).Int("revision", p.ds.Revision).Msg("remove dataset from index")
 	case "drop_dataset":
@@ -197,7 +246,12 @@ func (p *Parser) process(ctx context.Context, req *Request) error {
 			return err
 		}
 
+		if err := p.dropHashes(req.OrgID, req.DatasetID); err != nil {
+			return err
+		}
+
 		p.dropPosthook(req.OrgID, req.DatasetID, -1)
+		p.flushed(req.OrgID, req.DatasetID)
 
 		log.Info().Str("datasetID", req.DatasetID).Int("revision", p.ds.Revision).Msg("dropped dataset")
 	default:
@@ -207,6 +261,14 @@ func (p *Parser) process(ctx context.Context, req *Request) error {
 	return nil
 }
 
+// flushed calls the FlushHooks of the index service after records of the
+// dataset are removed from the index.
+func (p *Parser) flushed(orgID, datasetID string) {
+	if p.index != nil {
+		p.index.RunFlushHooks(orgID, datasetID)
+	}
+}
+
 func (p *Parser) dropPosthook(orgID, datasetID string, revision int) {
 	if p.postHooks != nil {
 		p.postHooks = append(
@@ -226,6 +288,19 @@ func (p *Parser) Publish(req *Request) error {
 		return err
 	}
 
+	change, err := p.contentChange(req)
+	if err != nil {
+		return err
+	}
+
+	// unchanged records are not written to the index; clear_orphans keeps them
+	if change == recordUnchanged && p.skipUnchanged() {
+		p.addRevision(req, true)
+		return nil
+	}
+
+	p.addRevision(req, false)
+
 	fb, err := req.createFragmentBuilder(req.Revision)
 	if err != nil {
 		log.Error().Err(err).Str("datasetID", req.DatasetID).Msg("unable to build fragment builder")
@@ -239,17 +314,19 @@ func (p *Parser) Publish(req *Request) error {
 	}
 
 	for _, indexType := range p.indexTypes {
+		bi := p.recordIndex(req, indexType)
+
 		switch indexType {
 		case "v1":
-			if err := req.processV1(fb, p.bi); err != nil {
+			if err := req.processV1(fb, bi); err != nil {
 				return err
 			}
 		case "v2":
-			if err := req.processV2(fb, p.bi); err != nil {
+			if err := req.processV2(fb, bi); err != nil {
 				return err
 			}
 		case "fragments":
-			if err := req.processFragments(fb, p.bi); err != nil {
+			if err := req.processFragments(fb, bi); err != nil {
 				return err
 			}
 		default:
@@ -313,5 +390,10 @@ type Stats struct {
 	RecordsStored uint64 `json:"recordsStored"` // originally json was records_stored
 	JSONErrors    uint64 `json:"jsonErrors"`
 	TriplesStored uint64 `json:"triplesStored"`
-	// ContentHashMatches uint64    `json:"contentHashMatches"` // originally json was content_hash_matches
+	// IngestID is used to follow the progress of the IndexMessages of the request
+	IngestID string `json:"ingestID,omitempty"`
+	// the content hash counters are only set when a ContentHashStore is configured
+	RecordsNew       uint64 `json:"recordsNew"`
+	RecordsChanged   uint64 `json:"recordsChanged"`
+	RecordsUnchanged uint64 `json:"recordsUnchanged"` // unchanged records are not indexed again
 }
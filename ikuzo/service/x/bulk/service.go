@@ -16,7 +16,9 @@ package bulk
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/delving/hub3/hub3/fragments"
 	"github.com/delving/hub3/ikuzo/service/x/index"
@@ -30,6 +32,7 @@ type Service struct {
 	index      *index.Service
 	indexTypes []string
 	postHooks  map[string][]PostHookService
+	hashes     ContentHashStore
 }
 
 func NewService(options ...Option) (*Service, error) {
@@ -72,10 +75,30 @@ func SetPostHookService(hooks ...PostHookService) Option {
 	}
 }
 
+// SetContentHashStore enables skipping records that are unchanged since the
+// previous ingest.
+func SetContentHashStore(store ContentHashStore) Option {
+	return func(s *Service) error {
+		s.hashes = store
+		return nil
+	}
+}
+
 // bulkApi receives bulkActions in JSON form (1 per line) and processes them in
-// ingestion pipeline.
+// ingestion pipeline. With the query parameter 'force=true' unchanged records
+// are indexed again.
 func (s *Service) Handle(w http.ResponseWriter, r *http.Request) {
 	p := s.NewParser()
+
+	if force := r.URL.Query().Get("force"); force != "" {
+		var err error
+
+		p.force, err = strconv.ParseBool(force)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid force parameter %q", force), http.StatusBadRequest)
+			return
+		}
+	}
 	if err := p.Parse(r.Context(), r.Body); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -114,6 +137,8 @@ func (s *Service) NewParser() *Parser {
 		stats:         &Stats{},
 		indexTypes:    s.indexTypes,
 		bi:            s.index,
+		index:         s.index,
+		hashes:        s.hashes,
 		sparqlUpdates: []fragments.SparqlUpdate{},
 	}
 
@@ -128,5 +153,9 @@ func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) Shutdown(ctx context.Context) error {
+	if s.hashes != nil {
+		return s.hashes.Close()
+	}
+
 	return nil
 }
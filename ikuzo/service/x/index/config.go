@@ -15,6 +15,8 @@
 package index
 
 import (
+	"time"
+
 	"github.com/nats-io/stan.go"
 )
 
@@ -33,6 +35,8 @@ type NatsConfig struct {
 	ClientID     string
 	DurableName  string
 	DurableQueue string
+	// AckWait is the time after which unacknowledged messages are redelivered. default: 30s
+	AckWait time.Duration
 }
 
 func (c *NatsConfig) setDefaults() {
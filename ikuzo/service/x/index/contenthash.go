@@ -0,0 +1,89 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/rs/zerolog/log"
+)
+
+// ContentHashStore stores the content hash of the records that are written to the index.
+type ContentHashStore interface {
+	// Put stores the content hashes of the dataset by hubID. An empty hash
+	// removes the content hash of the record.
+	Put(ctx context.Context, orgID, datasetID string, hashes map[string]string) error
+}
+
+// acknowledged updates the Ingest and the content hash of the IndexMessage
+// after it is written to the index or failed permanently.
+func (s *Service) acknowledged(m *domainpb.IndexMessage, indexed bool) {
+	s.ingests.acknowledged(m, indexed)
+	s.ackContentHash(m, indexed)
+}
+
+// ackContentHash buffers the content hash of an indexed IndexMessage until
+// the end of the bulk flush. The content hash of a record that failed is
+// removed, so the record is indexed in full by the next ingest.
+func (s *Service) ackContentHash(m *domainpb.IndexMessage, indexed bool) {
+	if s.contentHashes == nil || m.GetContentHash() == "" {
+		return
+	}
+
+	key := flushKey{orgID: m.GetOrganisationID(), datasetID: m.GetDatasetID()}
+
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	hashes, ok := s.pendingHashes[key]
+	if !ok {
+		hashes = map[string]string{}
+		s.pendingHashes[key] = hashes
+	}
+
+	if !indexed {
+		hashes[m.GetRecordID()] = ""
+		return
+	}
+
+	// a record fails when any of its IndexMessages fails
+	if hash, seen := hashes[m.GetRecordID()]; seen && hash == "" {
+		return
+	}
+
+	hashes[m.GetRecordID()] = m.GetContentHash()
+}
+
+// storeContentHashes stores the content hashes that are buffered since the previous flush.
+func (s *Service) storeContentHashes(ctx context.Context) {
+	if s.contentHashes == nil {
+		return
+	}
+
+	s.flushMu.Lock()
+	pending := s.pendingHashes
+	s.pendingHashes = map[flushKey]map[string]string{}
+	s.flushMu.Unlock()
+
+	for key, hashes := range pending {
+		if err := s.contentHashes.Put(ctx, key.orgID, key.datasetID, hashes); err != nil {
+			log.Error().Err(err).
+				Str("orgID", key.orgID).
+				Str("datasetID", key.datasetID).
+				Msg("unable to store content hashes")
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/rs/zerolog/log"
+	proto "google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrDeadLetterNotFound is returned when the DeadLetter does not exist.
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	// ErrNoDeadLetterStore is returned when the Service has no DeadLetterStore.
+	ErrNoDeadLetterStore = errors.New("dead letter store is not configured")
+)
+
+// DeadLetter is an IndexMessage that could not be written to the index.
+type DeadLetter struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"orgID"`
+	DatasetID string    `json:"datasetID"`
+	RecordID  string    `json:"recordID"`
+	Index     string    `json:"index"`
+	ErrorType string    `json:"errorType"`
+	Reason    string    `json:"reason"`
+	Attempts  int       `json:"attempts"`
+	Failed    time.Time `json:"failed"`
+	// Message is the protobuf encoded IndexMessage
+	Message []byte `json:"message,omitempty"`
+}
+
+// newDeadLetter returns the DeadLetter for the IndexMessage.
+// A later failure of the same record replaces the DeadLetter.
+func newDeadLetter(m *domainpb.IndexMessage, index string, attempts int, errorType, reason string) (*DeadLetter, error) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal index message; %w", err)
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(index + "/" + m.GetRecordID()))
+
+	return &DeadLetter{
+		ID:        fmt.Sprintf("%016x", h.Sum64()),
+		OrgID:     m.GetOrganisationID(),
+		DatasetID: m.GetDatasetID(),
+		RecordID:  m.GetRecordID(),
+		Index:     index,
+		ErrorType: errorType,
+		Reason:    reason,
+		Attempts:  attempts,
+		Failed:    time.Now(),
+		Message:   b,
+	}, nil
+}
+
+// IndexMessage returns the IndexMessage that failed.
+func (dl *DeadLetter) IndexMessage() (*domainpb.IndexMessage, error) {
+	var m domainpb.IndexMessage
+	if err := proto.Unmarshal(dl.Message, &m); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal dead letter %s; %w", dl.ID, err)
+	}
+
+	return &m, nil
+}
+
+// DeadLetterStore persists the DeadLetters.
+type DeadLetterStore interface {
+	// Put stores the DeadLetter. An existing DeadLetter with the same ID is replaced.
+	Put(ctx context.Context, dl *DeadLetter) error
+	// Get returns the DeadLetter or ErrDeadLetterNotFound.
+	Get(ctx context.Context, orgID, datasetID, id string) (*DeadLetter, error)
+	// List returns the DeadLetters of the organization. When datasetID is
+	// empty the DeadLetters of all datasets are returned.
+	List(ctx context.Context, orgID, datasetID string) ([]*DeadLetter, error)
+	// Delete removes the DeadLetter or returns ErrDeadLetterNotFound.
+	Delete(ctx context.Context, orgID, datasetID, id string) error
+	// Close closes the DeadLetterStore.
+	Close() error
+}
+
+// RetryPolicy determines how often and when transient index failures are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts. 0 or 1 disables retries.
+	MaxAttempts int
+	// InitialInterval is the delay before the first retry. default: 1s
+	InitialInterval time.Duration
+	// MaxInterval is the maximum delay between retries. default: 1m
+	MaxInterval time.Duration
+	// Multiplier is applied to the delay after each retry. default: 2
+	Multiplier float64
+}
+
+// DefaultRetryPolicy retries transient failures four times within about 15 seconds.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:     5,
+	InitialInterval: time.Second,
+	MaxInterval:     time.Minute,
+	Multiplier:      2,
+}
+
+// Backoff returns the delay before the next attempt after the given attempt.
+func (rp RetryPolicy) Backoff(attempt int) time.Duration {
+	initial := rp.InitialInterval
+	if initial <= 0 {
+		initial = time.Second
+	}
+
+	maxInterval := rp.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = time.Minute
+	}
+
+	multiplier := rp.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	backoff := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if backoff > float64(maxInterval) {
+		return maxInterval
+	}
+
+	return time.Duration(backoff)
+}
+
+// retry returns true when the failed attempt must be retried.
+func (rp RetryPolicy) retry(attempt int) bool {
+	return attempt < rp.MaxAttempts
+}
+
+// isTransient returns true when the bulk item failed with a status that
+// is likely to succeed on retry, e.g. a rejected execution, an unavailable shard
+// or the write block of an index that is being reindexed.
+func isTransient(status int, errType string) bool {
+	if errType == "cluster_block_exception" {
+		return true
+	}
+
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// DeadLetters returns the DeadLetters of the organization. When datasetID is
+// empty the DeadLetters of all datasets are returned.
+func (s *Service) DeadLetters(ctx context.Context, orgID, datasetID string) ([]*DeadLetter, error) {
+	if s.deadLetters == nil {
+		return nil, ErrNoDeadLetterStore
+	}
+
+	return s.deadLetters.List(ctx, orgID, datasetID)
+}
+
+// DeadLetter returns the DeadLetter or ErrDeadLetterNotFound.
+func (s *Service) DeadLetter(ctx context.Context, orgID, datasetID, id string) (*DeadLetter, error) {
+	if s.deadLetters == nil {
+		return nil, ErrNoDeadLetterStore
+	}
+
+	return s.deadLetters.Get(ctx, orgID, datasetID, id)
+}
+
+// RetryDeadLetter publishes the IndexMessage of the DeadLetter again and
+// removes the DeadLetter. When it fails again a new DeadLetter is stored.
+func (s *Service) RetryDeadLetter(ctx context.Context, orgID, datasetID, id string) error {
+	dl, err := s.DeadLetter(ctx, orgID, datasetID, id)
+	if err != nil {
+		return err
+	}
+
+	m, err := dl.IndexMessage()
+	if err != nil {
+		return err
+	}
+
+	if err := s.deadLetters.Delete(ctx, orgID, datasetID, id); err != nil {
+		return err
+	}
+
+	// the failure is already counted by the Ingest of the message
+	m.IngestID = ""
+
+	if err := s.Publish(ctx, m); err != nil {
+		// keep the DeadLetter, so the retry can be repeated
+		if putErr := s.deadLetters.Put(ctx, dl); putErr != nil {
+			log.Error().Err(putErr).Str("id", id).Msg("unable to restore dead letter")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// RetryDeadLetters retries all DeadLetters of the dataset and returns the number of retried DeadLetters.
+func (s *Service) RetryDeadLetters(ctx context.Context, orgID, datasetID string) (int, error) {
+	dls, err := s.DeadLetters(ctx, orgID, datasetID)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, dl := range dls {
+		if err := s.RetryDeadLetter(ctx, orgID, dl.DatasetID, dl.ID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(dls), nil
+}
+
+// DiscardDeadLetter removes the DeadLetter or returns ErrDeadLetterNotFound.
+func (s *Service) DiscardDeadLetter(ctx context.Context, orgID, datasetID, id string) error {
+	if s.deadLetters == nil {
+		return ErrNoDeadLetterStore
+	}
+
+	return s.deadLetters.Delete(ctx, orgID, datasetID, id)
+}
+
+// DiscardDeadLetters removes all DeadLetters of the dataset and returns the number of removed DeadLetters.
+func (s *Service) DiscardDeadLetters(ctx context.Context, orgID, datasetID string) (int, error) {
+	dls, err := s.DeadLetters(ctx, orgID, datasetID)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, dl := range dls {
+		if err := s.deadLetters.Delete(ctx, orgID, dl.DatasetID, dl.ID); err != nil {
+			return i, err
+		}
+	}
+
+	return len(dls), nil
+}
@@ -0,0 +1,332 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/matryer/is"
+)
+
+type memoryDeadLetterStore struct {
+	rw          sync.Mutex
+	deadLetters map[string]*DeadLetter
+}
+
+func newMemoryDeadLetterStore() *memoryDeadLetterStore {
+	return &memoryDeadLetterStore{deadLetters: map[string]*DeadLetter{}}
+}
+
+func (m *memoryDeadLetterStore) key(orgID, datasetID, id string) string {
+	return orgID + "/" + datasetID + "/" + id
+}
+
+func (m *memoryDeadLetterStore) Put(ctx context.Context, dl *DeadLetter) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	m.deadLetters[m.key(dl.OrgID, dl.DatasetID, dl.ID)] = dl
+
+	return nil
+}
+
+func (m *memoryDeadLetterStore) Get(ctx context.Context, orgID, datasetID, id string) (*DeadLetter, error) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	dl, ok := m.deadLetters[m.key(orgID, datasetID, id)]
+	if !ok {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return dl, nil
+}
+
+func (m *memoryDeadLetterStore) List(ctx context.Context, orgID, datasetID string) ([]*DeadLetter, error) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	dls := []*DeadLetter{}
+
+	for _, dl := range m.deadLetters {
+		if dl.OrgID == orgID && (datasetID == "" || dl.DatasetID == datasetID) {
+			dls = append(dls, dl)
+		}
+	}
+
+	sort.Slice(dls, func(i, j int) bool { return dls[i].ID < dls[j].ID })
+
+	return dls, nil
+}
+
+func (m *memoryDeadLetterStore) Delete(ctx context.Context, orgID, datasetID, id string) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	key := m.key(orgID, datasetID, id)
+	if _, ok := m.deadLetters[key]; !ok {
+		return ErrDeadLetterNotFound
+	}
+
+	delete(m.deadLetters, key)
+
+	return nil
+}
+
+func (m *memoryDeadLetterStore) Close() error {
+	return nil
+}
+
+func failure(status int, errorType, reason string) esutil.BulkIndexerResponseItem {
+	item := esutil.BulkIndexerResponseItem{Status: status}
+	item.Error.Type = errorType
+	item.Error.Reason = reason
+
+	return item
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	rp := RetryPolicy{InitialInterval: 100 * time.Millisecond, MaxInterval: time.Second, Multiplier: 2}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := rp.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("RetryPolicy.Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+// nolint:funlen
+func TestService_deadLetters(t *testing.T) {
+	msg := &domainpb.IndexMessage{
+		OrganisationID: "hub3",
+		DatasetID:      "spec1",
+		RecordID:       "hub3_spec1_1",
+		IndexName:      "hub3v2",
+		Source:         []byte(`{"meta": {"spec": "spec1"}}`),
+	}
+
+	retry := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}
+
+	tests := []struct {
+		name         string
+		failures     []esutil.BulkIndexerResponseItem
+		successful   uint64
+		retried      uint64
+		deadLettered uint64
+		errorType    string
+		attempts     int
+	}{
+		{
+			"transient failure is retried",
+			[]esutil.BulkIndexerResponseItem{failure(http.StatusTooManyRequests, "es_rejected_execution_exception", "queue is full")},
+			1, 1, 0, "", 0,
+		},
+		{
+			"write block during reindex is retried",
+			[]esutil.BulkIndexerResponseItem{failure(http.StatusForbidden, "cluster_block_exception", "index [hub3v2] blocked by: [FORBIDDEN/8/index write (api)];")},
+			1, 1, 0, "", 0,
+		},
+		{
+			"permanent failure is dead lettered",
+			[]esutil.BulkIndexerResponseItem{failure(http.StatusBadRequest, "mapper_parsing_exception", "failed to parse")},
+			0, 0, 1, "mapper_parsing_exception", 1,
+		},
+		{
+			"retries are exhausted",
+			[]esutil.BulkIndexerResponseItem{
+				failure(http.StatusServiceUnavailable, "unavailable_shards_exception", "primary shard is not active"),
+				failure(http.StatusServiceUnavailable, "unavailable_shards_exception", "primary shard is not active"),
+				failure(http.StatusServiceUnavailable, "unavailable_shards_exception", "primary shard is not active"),
+			},
+			0, 2, 1, "unavailable_shards_exception", 3,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			bi := &mockBulkIndexer{failures: tt.failures}
+			store := newMemoryDeadLetterStore()
+
+			svc, err := NewService(
+				SetBulkIndexer(bi, true),
+				SetRetryPolicy(retry),
+				SetDeadLetterStore(store),
+			)
+			is.NoErr(err)
+
+			is.NoErr(svc.Publish(context.Background(), msg))
+
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				m := svc.Metrics()
+				if m.Index.Successful+m.Index.DeadLettered > 0 {
+					break
+				}
+
+				time.Sleep(time.Millisecond)
+			}
+
+			m := svc.Metrics()
+			is.Equal(m.Index.Successful, tt.successful)
+			is.Equal(m.Index.Retried, tt.retried)
+			is.Equal(m.Index.DeadLettered, tt.deadLettered)
+
+			dls, err := svc.DeadLetters(context.Background(), "hub3", "spec1")
+			is.NoErr(err)
+			is.Equal(len(dls), int(tt.deadLettered))
+
+			if tt.deadLettered == 0 {
+				return
+			}
+
+			is.Equal(dls[0].ErrorType, tt.errorType)
+			is.Equal(dls[0].Attempts, tt.attempts)
+			is.Equal(dls[0].Index, "hub3v2")
+
+			got, err := dls[0].IndexMessage()
+			is.NoErr(err)
+			is.Equal(got.GetRecordID(), msg.GetRecordID())
+		})
+	}
+}
+
+func TestService_ShutdownPendingRetry(t *testing.T) {
+	is := is.New(t)
+
+	bi := &mockBulkIndexer{
+		failures:   []esutil.BulkIndexerResponseItem{failure(http.StatusTooManyRequests, "es_rejected_execution_exception", "queue is full")},
+		closeDelay: 50 * time.Millisecond,
+	}
+	store := newMemoryDeadLetterStore()
+
+	svc, err := NewService(
+		SetBulkIndexer(bi, true),
+		SetRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialInterval: 20 * time.Millisecond, MaxInterval: 20 * time.Millisecond}),
+		SetDeadLetterStore(store),
+	)
+	is.NoErr(err)
+
+	is.NoErr(svc.Publish(context.Background(), &domainpb.IndexMessage{
+		OrganisationID: "hub3",
+		DatasetID:      "spec1",
+		RecordID:       "hub3_spec1_1",
+		IndexName:      "hub3v2",
+	}))
+	is.Equal(svc.Metrics().Index.Retried, uint64(1))
+
+	is.NoErr(svc.Shutdown(context.Background()))
+
+	bi.rw.Lock()
+	is.Equal(bi.addedAfterClose, 0)
+	bi.rw.Unlock()
+
+	dls, err := store.List(context.Background(), "hub3", "spec1")
+	is.NoErr(err)
+	is.Equal(len(dls), 1)
+	is.Equal(dls[0].ErrorType, "retry_canceled")
+}
+
+func TestService_DeadLetterRoutes(t *testing.T) {
+	is := is.New(t)
+
+	bi := &mockBulkIndexer{
+		failures: []esutil.BulkIndexerResponseItem{
+			failure(http.StatusBadRequest, "mapper_parsing_exception", "failed to parse"),
+			failure(http.StatusBadRequest, "mapper_parsing_exception", "failed to parse"),
+		},
+	}
+
+	svc, err := NewService(
+		SetBulkIndexer(bi, true),
+		SetDeadLetterStore(newMemoryDeadLetterStore()),
+	)
+	is.NoErr(err)
+
+	err = svc.Publish(
+		context.Background(),
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "1", IndexName: "hub3v2", Source: []byte(`{"id": 1}`)},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec2", RecordID: "2", IndexName: "hub3v2"},
+	)
+	is.NoErr(err)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		svc.DeadLetterRoutes().ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+		return w
+	}
+
+	var list []map[string]interface{}
+
+	w := serve(http.MethodGet, "/hub3")
+	is.Equal(w.Code, http.StatusOK)
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &list))
+	is.Equal(len(list), 2)
+
+	list = nil
+
+	w = serve(http.MethodGet, "/hub3/spec1")
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &list))
+	is.Equal(len(list), 1)
+	is.Equal(list[0]["reason"], "failed to parse")
+	is.Equal(list[0]["message"], nil)
+
+	id := list[0]["id"].(string)
+
+	var inspect map[string]interface{}
+
+	w = serve(http.MethodGet, "/hub3/spec1/"+id)
+	is.Equal(w.Code, http.StatusOK)
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &inspect))
+	is.Equal(inspect["source"], map[string]interface{}{"id": float64(1)})
+
+	w = serve(http.MethodPost, "/hub3/spec1/"+id+"/_retry")
+	is.Equal(w.Code, http.StatusAccepted)
+	is.Equal(svc.Metrics().Index.Successful, uint64(1))
+
+	w = serve(http.MethodGet, "/hub3/spec1/"+id)
+	is.Equal(w.Code, http.StatusNotFound)
+
+	w = serve(http.MethodDelete, "/hub3/spec2")
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Body.String(), "{\"discarded\":1}\n")
+
+	dls, err := svc.DeadLetters(context.Background(), "hub3", "")
+	is.NoErr(err)
+	is.Equal(len(dls), 0)
+}
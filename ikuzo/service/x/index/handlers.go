@@ -0,0 +1,240 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// deadLetterView is the API representation of a DeadLetter.
+type deadLetterView struct {
+	*DeadLetter
+	// Message hides the protobuf encoded IndexMessage of the DeadLetter
+	Message json.RawMessage `json:"message,omitempty"`
+	Deleted bool            `json:"deleted,omitempty"`
+	// Source is the document that was rejected by the index
+	Source json.RawMessage `json:"source,omitempty"`
+}
+
+func newDeadLetterView(dl *DeadLetter, withSource bool) (*deadLetterView, error) {
+	view := &deadLetterView{DeadLetter: dl}
+
+	if !withSource {
+		return view, nil
+	}
+
+	m, err := dl.IndexMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	view.Deleted = m.GetDeleted()
+
+	if json.Valid(m.GetSource()) {
+		view.Source = m.GetSource()
+	}
+
+	return view, nil
+}
+
+// DeadLetterRoutes returns the endpoints to manage the DeadLetters.
+//
+//	GET    /{orgID}                          list the dead letters of the organization
+//	GET    /{orgID}/{datasetID}              list the dead letters of the dataset
+//	POST   /{orgID}/{datasetID}/_retry       retry all dead letters of the dataset
+//	DELETE /{orgID}/{datasetID}              discard all dead letters of the dataset
+//	GET    /{orgID}/{datasetID}/{id}         inspect a dead letter with its source
+//	POST   /{orgID}/{datasetID}/{id}/_retry  retry a dead letter
+//	DELETE /{orgID}/{datasetID}/{id}         discard a dead letter
+func (s *Service) DeadLetterRoutes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Get("/{orgID}", s.handleListDeadLetters)
+	router.Get("/{orgID}/{datasetID}", s.handleListDeadLetters)
+	router.Post("/{orgID}/{datasetID}/_retry", s.handleRetryDeadLetters)
+	router.Delete("/{orgID}/{datasetID}", s.handleDiscardDeadLetters)
+	router.Get("/{orgID}/{datasetID}/{id}", s.handleGetDeadLetter)
+	router.Post("/{orgID}/{datasetID}/{id}/_retry", s.handleRetryDeadLetter)
+	router.Delete("/{orgID}/{datasetID}/{id}", s.handleDiscardDeadLetter)
+
+	return router
+}
+
+func (s *Service) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	dls, err := s.DeadLetters(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	views := make([]*deadLetterView, 0, len(dls))
+	for _, dl := range dls {
+		view, _ := newDeadLetterView(dl, false)
+		views = append(views, view)
+	}
+
+	render.JSON(w, r, views)
+}
+
+func (s *Service) handleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	dl, err := s.DeadLetter(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"), chi.URLParam(r, "id"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	view, err := newDeadLetterView(dl, true)
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	render.JSON(w, r, view)
+}
+
+func (s *Service) handleRetryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	err := s.RetryDeadLetter(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"), chi.URLParam(r, "id"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]int{"retried": 1})
+}
+
+func (s *Service) handleRetryDeadLetters(w http.ResponseWriter, r *http.Request) {
+	n, err := s.RetryDeadLetters(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]int{"retried": n})
+}
+
+func (s *Service) handleDiscardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	err := s.DiscardDeadLetter(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"), chi.URLParam(r, "id"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) handleDiscardDeadLetters(w http.ResponseWriter, r *http.Request) {
+	n, err := s.DiscardDeadLetters(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID"))
+	if err != nil {
+		renderDeadLetterError(w, err)
+		return
+	}
+
+	render.JSON(w, r, map[string]int{"discarded": n})
+}
+
+func renderDeadLetterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrDeadLetterNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrNoDeadLetterStore):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// maxIngestWait is the maximum duration a client can wait for an Ingest to complete.
+const maxIngestWait = 5 * time.Minute
+
+// IngestRoutes returns the endpoints to follow the progress of the ingests.
+//
+//	GET /_stats/{orgID}              counters per dataset revision of the organization
+//	GET /_stats/{orgID}/{datasetID}  counters per revision of the dataset
+//	GET /{id}?wait=30s               progress of an ingest, optionally waiting for completion
+//
+// The progress of an ingest is returned with status 200 when it is complete
+// and with status 202 while IndexMessages are still pending.
+//
+// The progress is only kept in memory of the node that received the bulk
+// request and it is lost on restart. Behind a shared NATS queue an ingest
+// only completes when this node consumes all its IndexMessages.
+func (s *Service) IngestRoutes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Get("/_stats/{orgID}", s.handleIngestStats)
+	router.Get("/_stats/{orgID}/{datasetID}", s.handleIngestStats)
+	router.Get("/{id}", s.handleGetIngest)
+
+	return router
+}
+
+func (s *Service) handleIngestStats(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, s.IngestStats(chi.URLParam(r, "orgID"), chi.URLParam(r, "datasetID")))
+}
+
+func (s *Service) handleGetIngest(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var (
+		stats IngestStats
+		err   error
+	)
+
+	if wait := r.URL.Query().Get("wait"); wait != "" {
+		d, parseErr := time.ParseDuration(wait)
+		if parseErr != nil {
+			http.Error(w, fmt.Sprintf("invalid wait duration %q", wait), http.StatusBadRequest)
+			return
+		}
+
+		if d > maxIngestWait {
+			d = maxIngestWait
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+
+		stats, err = s.WaitForIngest(ctx, id)
+	} else {
+		stats, err = s.Ingest(id)
+	}
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrIngestNotFound) {
+			status = http.StatusNotFound
+		}
+
+		http.Error(w, err.Error(), status)
+
+		return
+	}
+
+	if !stats.Complete {
+		render.Status(r, http.StatusAccepted)
+	}
+
+	render.JSON(w, r, stats)
+}
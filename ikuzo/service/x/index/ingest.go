@@ -0,0 +1,421 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/rs/xid"
+)
+
+// ErrIngestNotFound is returned when the Ingest is unknown or already expired.
+var ErrIngestNotFound = errors.New("ingest not found")
+
+const (
+	// ingestRetention is how long a complete Ingest can still be requested.
+	ingestRetention = time.Hour
+	// ingestIdleRetention is how long an incomplete Ingest is kept after its
+	// last update, e.g. when its IndexMessages are consumed by another node.
+	ingestIdleRetention = 24 * time.Hour
+	// revisionRetention is how long the counters of a dataset revision are
+	// kept after their last update.
+	revisionRetention = 7 * 24 * time.Hour
+)
+
+// IngestCounters are the progress counters of an ingest.
+type IngestCounters struct {
+	// Received is the number of records received by the bulk request
+	Received uint64 `json:"received"`
+	// New, Changed and Unchanged are the received records by their content
+	// hash compared to the previous ingest
+	New       uint64 `json:"new"`
+	Changed   uint64 `json:"changed"`
+	Unchanged uint64 `json:"unchanged"`
+	// Queued is the number of IndexMessages published for the records
+	Queued uint64 `json:"queued"`
+	// Indexed is the number of IndexMessages written to the index
+	Indexed uint64 `json:"indexed"`
+	// Failed is the number of IndexMessages that could not be written to the index
+	Failed uint64 `json:"failed"`
+	// OrphansRemoved is the number of documents of previous revisions removed from the index
+	OrphansRemoved uint64 `json:"orphansRemoved"`
+}
+
+func (c *IngestCounters) load() IngestCounters {
+	return IngestCounters{
+		Received:       atomic.LoadUint64(&c.Received),
+		New:            atomic.LoadUint64(&c.New),
+		Changed:        atomic.LoadUint64(&c.Changed),
+		Unchanged:      atomic.LoadUint64(&c.Unchanged),
+		Queued:         atomic.LoadUint64(&c.Queued),
+		Indexed:        atomic.LoadUint64(&c.Indexed),
+		Failed:         atomic.LoadUint64(&c.Failed),
+		OrphansRemoved: atomic.LoadUint64(&c.OrphansRemoved),
+	}
+}
+
+// RevisionStats are the IngestCounters of all ingests of a dataset revision.
+type RevisionStats struct {
+	OrgID     string    `json:"orgID"`
+	DatasetID string    `json:"datasetID"`
+	Revision  int       `json:"revision"`
+	Updated   time.Time `json:"updated"`
+	IngestCounters
+}
+
+// IngestStats is the progress of a single Ingest.
+type IngestStats struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"orgID"`
+	DatasetID string    `json:"datasetID"`
+	Revision  int       `json:"revision"`
+	Started   time.Time `json:"started"`
+	// Done is true when all records of the bulk request are received
+	Done bool `json:"done"`
+	// Complete is true when all queued IndexMessages are acknowledged
+	Complete bool `json:"complete"`
+	IngestCounters
+}
+
+type revisionKey struct {
+	orgID     string
+	datasetID string
+	revision  int
+}
+
+type revisionCounters struct {
+	key     revisionKey
+	updated atomic.Value
+	IngestCounters
+}
+
+func (rc *revisionCounters) stats() RevisionStats {
+	updated, _ := rc.updated.Load().(time.Time)
+
+	return RevisionStats{
+		OrgID:          rc.key.orgID,
+		DatasetID:      rc.key.datasetID,
+		Revision:       rc.key.revision,
+		Updated:        updated,
+		IngestCounters: rc.load(),
+	}
+}
+
+// Ingest tracks the IndexMessages published for a single bulk request.
+//
+// Ingest implements BulkIndex, so it can be used in place of the Service to
+// tag the published IndexMessages with the ID of the Ingest.
+//
+// An Ingest is not persisted. It is only acknowledged by the IndexMessages that
+// are consumed by the same Service, so it never completes when other nodes
+// consume from the same queue.
+type Ingest struct {
+	s        *Service
+	id       string
+	key      revisionKey
+	started  time.Time
+	rev      *revisionCounters
+	c        IngestCounters
+	once     sync.Once
+	done     int32
+	complete chan struct{}
+	expires  atomic.Value
+	updated  atomic.Value
+}
+
+var _ BulkIndex = (*Ingest)(nil)
+
+// ID returns the ID of the Ingest that is used to request its progress.
+func (i *Ingest) ID() string {
+	return i.id
+}
+
+// Publish tags the messages with the ID of the Ingest and publishes them
+// with the Service.
+func (i *Ingest) Publish(ctx context.Context, messages ...*domainpb.IndexMessage) error {
+	for _, m := range messages {
+		m.IngestID = i.id
+		i.add(func(c *IngestCounters) *uint64 { return &c.Queued }, 1)
+
+		if err := i.s.Publish(ctx, m); err != nil {
+			i.add(func(c *IngestCounters) *uint64 { return &c.Failed }, 1)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddReceived adds n to the records received.
+func (i *Ingest) AddReceived(n uint64) {
+	i.add(func(c *IngestCounters) *uint64 { return &c.Received }, n)
+}
+
+// AddNew adds n to the received records that were not ingested before.
+func (i *Ingest) AddNew(n uint64) {
+	i.add(func(c *IngestCounters) *uint64 { return &c.New }, n)
+}
+
+// AddChanged adds n to the received records that changed since the previous ingest.
+func (i *Ingest) AddChanged(n uint64) {
+	i.add(func(c *IngestCounters) *uint64 { return &c.Changed }, n)
+}
+
+// AddUnchanged adds n to the received records that are unchanged since the previous ingest.
+func (i *Ingest) AddUnchanged(n uint64) {
+	i.add(func(c *IngestCounters) *uint64 { return &c.Unchanged }, n)
+}
+
+// AddOrphansRemoved adds n to the orphans removed from the index.
+func (i *Ingest) AddOrphansRemoved(n uint64) {
+	i.add(func(c *IngestCounters) *uint64 { return &c.OrphansRemoved }, n)
+}
+
+// Done marks that no more IndexMessages are published for the Ingest.
+// The Ingest is complete when all queued IndexMessages are acknowledged.
+func (i *Ingest) Done() {
+	atomic.StoreInt32(&i.done, 1)
+	i.checkComplete()
+}
+
+// Stats returns the progress of the Ingest.
+func (i *Ingest) Stats() IngestStats {
+	stats := IngestStats{
+		ID:             i.id,
+		OrgID:          i.key.orgID,
+		DatasetID:      i.key.datasetID,
+		Revision:       i.key.revision,
+		Started:        i.started,
+		Done:           atomic.LoadInt32(&i.done) == 1,
+		IngestCounters: i.c.load(),
+	}
+
+	select {
+	case <-i.complete:
+		stats.Complete = true
+	default:
+	}
+
+	return stats
+}
+
+// add increments the counter of the Ingest and of its dataset revision.
+func (i *Ingest) add(counter func(c *IngestCounters) *uint64, n uint64) {
+	now := time.Now()
+
+	atomic.AddUint64(counter(&i.c), n)
+	atomic.AddUint64(counter(&i.rev.IngestCounters), n)
+	i.updated.Store(now)
+	i.rev.updated.Store(now)
+
+	i.checkComplete()
+}
+
+func (i *Ingest) checkComplete() {
+	if atomic.LoadInt32(&i.done) == 0 {
+		return
+	}
+
+	// Failed is loaded before Indexed and Queued last, so a message that is
+	// queued and acknowledged concurrently never makes the Ingest complete early.
+	failed := atomic.LoadUint64(&i.c.Failed)
+	indexed := atomic.LoadUint64(&i.c.Indexed)
+	queued := atomic.LoadUint64(&i.c.Queued)
+
+	if indexed+failed < queued {
+		return
+	}
+
+	i.once.Do(func() {
+		i.expires.Store(time.Now().Add(ingestRetention))
+		close(i.complete)
+	})
+}
+
+// expired returns true when the Ingest is complete for longer than the
+// ingestRetention or has not been updated for longer than the ingestIdleRetention.
+func (i *Ingest) expired(now time.Time) bool {
+	if expires, ok := i.expires.Load().(time.Time); ok {
+		return now.After(expires)
+	}
+
+	updated, ok := i.updated.Load().(time.Time)
+	if !ok {
+		updated = i.started
+	}
+
+	return now.After(updated.Add(ingestIdleRetention))
+}
+
+// ingestTracker keeps the Ingests and the counters per dataset revision in memory.
+type ingestTracker struct {
+	rw        sync.RWMutex
+	ingests   map[string]*Ingest
+	revisions map[revisionKey]*revisionCounters
+}
+
+func newIngestTracker() *ingestTracker {
+	return &ingestTracker{
+		ingests:   map[string]*Ingest{},
+		revisions: map[revisionKey]*revisionCounters{},
+	}
+}
+
+// acknowledged updates the Ingest of the IndexMessage after it is written to the
+// index or failed permanently.
+func (t *ingestTracker) acknowledged(m *domainpb.IndexMessage, indexed bool) {
+	if m.GetIngestID() == "" {
+		return
+	}
+
+	t.rw.RLock()
+	i, ok := t.ingests[m.GetIngestID()]
+	t.rw.RUnlock()
+
+	if !ok {
+		return
+	}
+
+	if indexed {
+		i.add(func(c *IngestCounters) *uint64 { return &c.Indexed }, 1)
+		return
+	}
+
+	i.add(func(c *IngestCounters) *uint64 { return &c.Failed }, 1)
+}
+
+// NewIngest starts tracking the IndexMessages that are published for a
+// revision of the dataset.
+func (s *Service) NewIngest(orgID, datasetID string, revision int) *Ingest {
+	t := s.ingests
+	key := revisionKey{orgID: orgID, datasetID: datasetID, revision: revision}
+
+	t.rw.Lock()
+	defer t.rw.Unlock()
+
+	now := time.Now()
+
+	t.prune(now)
+
+	rev, ok := t.revisions[key]
+	if !ok {
+		rev = &revisionCounters{key: key}
+		rev.updated.Store(now)
+		t.revisions[key] = rev
+	}
+
+	i := &Ingest{
+		s:        s,
+		id:       xid.New().String(),
+		key:      key,
+		started:  now,
+		rev:      rev,
+		complete: make(chan struct{}),
+	}
+
+	t.ingests[i.id] = i
+
+	return i
+}
+
+// prune removes the expired Ingests and the counters of the dataset revisions
+// that are not updated within the revisionRetention and have no Ingests left.
+// The write lock must be held.
+func (t *ingestTracker) prune(now time.Time) {
+	active := map[revisionKey]bool{}
+
+	for id, i := range t.ingests {
+		if i.expired(now) {
+			delete(t.ingests, id)
+			continue
+		}
+
+		active[i.key] = true
+	}
+
+	for key, rev := range t.revisions {
+		updated, _ := rev.updated.Load().(time.Time)
+
+		if !active[key] && now.After(updated.Add(revisionRetention)) {
+			delete(t.revisions, key)
+		}
+	}
+}
+
+// Ingest returns the progress of the Ingest or ErrIngestNotFound.
+func (s *Service) Ingest(id string) (IngestStats, error) {
+	s.ingests.rw.RLock()
+	i, ok := s.ingests.ingests[id]
+	s.ingests.rw.RUnlock()
+
+	if !ok {
+		return IngestStats{}, ErrIngestNotFound
+	}
+
+	return i.Stats(), nil
+}
+
+// WaitForIngest blocks until the Ingest is complete or the context is done
+// and returns the progress of the Ingest.
+func (s *Service) WaitForIngest(ctx context.Context, id string) (IngestStats, error) {
+	s.ingests.rw.RLock()
+	i, ok := s.ingests.ingests[id]
+	s.ingests.rw.RUnlock()
+
+	if !ok {
+		return IngestStats{}, ErrIngestNotFound
+	}
+
+	select {
+	case <-i.complete:
+	case <-ctx.Done():
+	}
+
+	return i.Stats(), nil
+}
+
+// IngestStats returns the counters per dataset revision of the organization
+// ordered by dataset and revision. When datasetID is empty the counters of all
+// datasets are returned.
+func (s *Service) IngestStats(orgID, datasetID string) []RevisionStats {
+	s.ingests.rw.RLock()
+	defer s.ingests.rw.RUnlock()
+
+	stats := []RevisionStats{}
+
+	for key, rev := range s.ingests.revisions {
+		if key.orgID != orgID || (datasetID != "" && key.datasetID != datasetID) {
+			continue
+		}
+
+		stats = append(stats, rev.stats())
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].DatasetID != stats[j].DatasetID {
+			return stats[i].DatasetID < stats[j].DatasetID
+		}
+
+		return stats[i].Revision < stats[j].Revision
+	})
+
+	return stats
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func ingestMessages(n int) []*domainpb.IndexMessage {
+	messages := []*domainpb.IndexMessage{}
+
+	for i := 0; i < n; i++ {
+		messages = append(messages, &domainpb.IndexMessage{
+			OrganisationID: "hub3",
+			DatasetID:      "spec1",
+			RecordID:       fmt.Sprintf("hub3_spec1_%d", i),
+			IndexName:      "hub3v2",
+		})
+	}
+
+	return messages
+}
+
+func TestService_Ingest(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []esutil.BulkIndexerResponseItem
+		want     IngestCounters
+	}{
+		{
+			"all messages indexed",
+			nil,
+			IngestCounters{Received: 3, Queued: 3, Indexed: 3},
+		},
+		{
+			"permanent failure",
+			[]esutil.BulkIndexerResponseItem{failure(http.StatusBadRequest, "mapper_parsing_exception", "failed to parse")},
+			IngestCounters{Received: 3, Queued: 3, Indexed: 2, Failed: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			svc, err := NewService(SetBulkIndexer(&mockBulkIndexer{failures: tt.failures}, true))
+			is.NoErr(err)
+
+			ingest := svc.NewIngest("hub3", "spec1", 2)
+			ingest.AddReceived(3)
+			is.NoErr(ingest.Publish(context.Background(), ingestMessages(3)...))
+
+			got, err := svc.Ingest(ingest.ID())
+			is.NoErr(err)
+			is.True(!got.Complete) // not complete before done
+
+			ingest.Done()
+
+			got, err = svc.Ingest(ingest.ID())
+			is.NoErr(err)
+			is.True(got.Complete)
+
+			if diff := cmp.Diff(tt.want, got.IngestCounters); diff != "" {
+				t.Errorf("Ingest() mismatch (-want +got):\n%s", diff)
+			}
+
+			stats := svc.IngestStats("hub3", "spec1")
+			is.Equal(len(stats), 1)
+			is.Equal(stats[0].Revision, 2)
+			is.Equal(stats[0].IngestCounters, tt.want)
+		})
+	}
+}
+
+func TestService_WaitForIngest(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "ingest")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	q, err := NewBoltQueue(filepath.Join(dir, "queue.db"))
+	is.NoErr(err)
+
+	svc, err := NewService(
+		SetQueue(q),
+		SetBulkIndexer(&mockBulkIndexer{}, false),
+	)
+	is.NoErr(err)
+
+	defer svc.Shutdown(context.Background())
+
+	ingest := svc.NewIngest("hub3", "spec1", 1)
+	is.NoErr(ingest.Publish(context.Background(), ingestMessages(10)...))
+	ingest.Done()
+
+	is.NoErr(svc.Start(context.Background(), 2))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got, err := svc.WaitForIngest(ctx, ingest.ID())
+	is.NoErr(err)
+	is.True(got.Complete)
+	is.Equal(got.Indexed, uint64(10))
+
+	_, err = svc.WaitForIngest(ctx, "unknown")
+	is.Equal(err, ErrIngestNotFound)
+}
+
+func TestIngestTracker_prune(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetBulkIndexer(&mockBulkIndexer{}, true))
+	is.NoErr(err)
+
+	now := time.Now()
+
+	// complete, idle and active ingests of three revisions
+	complete := svc.NewIngest("hub3", "spec1", 1)
+	complete.Done()
+
+	idle := svc.NewIngest("hub3", "spec1", 2)
+	idle.started = now.Add(-25 * time.Hour)
+
+	active := svc.NewIngest("hub3", "spec1", 3)
+	active.started = now.Add(-25 * time.Hour)
+	active.AddReceived(1)
+
+	t.Run("expired ingests", func(t *testing.T) {
+		is := is.New(t)
+
+		svc.ingests.prune(now.Add(2 * ingestRetention))
+
+		_, err := svc.Ingest(complete.ID())
+		is.Equal(err, ErrIngestNotFound)
+
+		_, err = svc.Ingest(idle.ID())
+		is.Equal(err, ErrIngestNotFound)
+
+		_, err = svc.Ingest(active.ID())
+		is.NoErr(err)
+
+		is.Equal(len(svc.IngestStats("hub3", "spec1")), 3)
+	})
+
+	t.Run("expired revisions", func(t *testing.T) {
+		is := is.New(t)
+
+		for _, rev := range svc.ingests.revisions {
+			rev.updated.Store(now.Add(-revisionRetention - time.Hour))
+		}
+
+		svc.ingests.prune(now.Add(2 * ingestRetention))
+
+		// the revision of the remaining ingest is not removed
+		stats := svc.IngestStats("hub3", "spec1")
+		is.Equal(len(stats), 1)
+		is.Equal(stats[0].Revision, 3)
+	})
+}
+
+func TestService_IngestRoutes(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetBulkIndexer(&mockBulkIndexer{}, true))
+	is.NoErr(err)
+
+	ingest := svc.NewIngest("hub3", "spec1", 1)
+	is.NoErr(ingest.Publish(context.Background(), ingestMessages(2)...))
+
+	svc.NewIngest("hub3", "spec2", 4).Done()
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		svc.IngestRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w
+	}
+
+	w := serve("/" + ingest.ID() + "?wait=10ms")
+	is.Equal(w.Code, http.StatusAccepted)
+
+	ingest.Done()
+
+	var got IngestStats
+
+	w = serve("/" + ingest.ID() + "?wait=1s")
+	is.Equal(w.Code, http.StatusOK)
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &got))
+	is.True(got.Complete)
+	is.Equal(got.Indexed, uint64(2))
+
+	w = serve("/" + ingest.ID() + "?wait=forever")
+	is.Equal(w.Code, http.StatusBadRequest)
+
+	w = serve("/unknown")
+	is.Equal(w.Code, http.StatusNotFound)
+
+	var stats []RevisionStats
+
+	w = serve("/_stats/hub3")
+	is.Equal(w.Code, http.StatusOK)
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &stats))
+	is.Equal(len(stats), 2)
+	is.Equal(stats[1].DatasetID, "spec2")
+
+	stats = nil
+
+	w = serve("/_stats/hub3/spec1")
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &stats))
+	is.Equal(len(stats), 1)
+	is.Equal(stats[0].Queued, uint64(2))
+}
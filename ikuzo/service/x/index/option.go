@@ -29,8 +29,21 @@ func SetBulkIndexer(bi esutil.BulkIndexer, direct bool) Option {
 
 func SetNatsConfiguration(ncfg *NatsConfig) Option {
 	return func(s *Service) error {
-		s.stan = ncfg
-		s.stan.setDefaults()
+		q, err := NewStanQueue(ncfg)
+		if err != nil {
+			return err
+		}
+
+		s.queue = q
+
+		return nil
+	}
+}
+
+// SetQueue sets the Queue that is used to publish and consume the IndexMessages.
+func SetQueue(q Queue) Option {
+	return func(s *Service) error {
+		s.queue = q
 
 		return nil
 	}
@@ -42,3 +55,51 @@ func WithDefaultMessageHandle() Option {
 		return nil
 	}
 }
+
+// SetFlushHook adds FlushHooks that are called after records are written to the index.
+// The FlushHooks are only called after a bulk flush when Service.FlushEnd is set
+// as the OnFlushEnd callback of the BulkIndexer.
+func SetFlushHook(hooks ...FlushHook) Option {
+	return func(s *Service) error {
+		s.flushHooks = append(s.flushHooks, hooks...)
+
+		return nil
+	}
+}
+
+// SetIndexRouter sets the IndexRouter that selects the index of each IndexMessage.
+func SetIndexRouter(router IndexRouter) Option {
+	return func(s *Service) error {
+		s.router = router
+
+		return nil
+	}
+}
+
+// SetRetryPolicy sets the RetryPolicy for transient index failures.
+func SetRetryPolicy(rp RetryPolicy) Option {
+	return func(s *Service) error {
+		s.retry = rp
+
+		return nil
+	}
+}
+
+// SetDeadLetterStore sets the DeadLetterStore for IndexMessages that could not be indexed.
+func SetDeadLetterStore(store DeadLetterStore) Option {
+	return func(s *Service) error {
+		s.deadLetters = store
+
+		return nil
+	}
+}
+
+// SetContentHashStore sets the ContentHashStore where the content hashes of the
+// IndexMessages are stored after they are written to the index.
+func SetContentHashStore(store ContentHashStore) Option {
+	return func(s *Service) error {
+		s.contentHashes = store
+
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueClosed is returned when a closed Queue is used.
+var ErrQueueClosed = errors.New("queue is closed")
+
+// Queue is a durable queue between the publishers and the consumers of the
+// index.Service.
+//
+// Each implementation provides at-least-once delivery: a message is
+// redelivered until it is acknowledged, also when the consumer is restarted.
+type Queue interface {
+	// Publish stores the messages in the queue.
+	Publish(ctx context.Context, data ...[]byte) error
+	// Consume delivers the messages to the handler from the given number of
+	// workers. It returns after the consumers are started.
+	Consume(ctx context.Context, workers int, handler DeliveryHandler) error
+	// StopConsuming stops the delivery of new messages and waits for the
+	// running handlers. Deliveries can be acknowledged until the Queue is closed.
+	StopConsuming() error
+	// Close stops the consumers and closes the connection to the queue.
+	// Unacknowledged messages are redelivered after a restart.
+	Close() error
+}
+
+// DeliveryHandler processes a Delivery. The Delivery can be acknowledged
+// after the handler returns.
+type DeliveryHandler func(d *Delivery)
+
+// Delivery is a message that is delivered by a Queue.
+type Delivery struct {
+	// Data is the published message
+	Data []byte
+	ack  func() error
+	nack func() error
+}
+
+// Ack acknowledges that the message is processed, so it is not redelivered.
+func (d *Delivery) Ack() error {
+	if d.ack == nil {
+		return nil
+	}
+
+	return d.ack()
+}
+
+// Nack requests redelivery of the message. Queues that don't support
+// negative acknowledgement redeliver the message after the ack timeout.
+func (d *Delivery) Nack() error {
+	if d.nack == nil {
+		return nil
+	}
+
+	return d.nack()
+}
+
+// consumers tracks the running handlers of a Queue, so the delivery of new
+// messages can be stopped before the connection is closed.
+type consumers struct {
+	rw      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// handle calls the handler unless the consumers are stopped. A message that is
+// not handled is redelivered.
+func (c *consumers) handle(handler DeliveryHandler, d *Delivery) {
+	c.rw.RLock()
+	if c.stopped {
+		c.rw.RUnlock()
+		return
+	}
+
+	c.wg.Add(1)
+	c.rw.RUnlock()
+
+	defer c.wg.Done()
+
+	handler(d)
+}
+
+// stop prevents new messages from being handled and waits for the running handlers.
+func (c *consumers) stop() {
+	c.rw.Lock()
+	c.stopped = true
+	c.rw.Unlock()
+
+	c.wg.Wait()
+}
@@ -0,0 +1,301 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	bolt "go.etcd.io/bbolt"
+)
+
+var queueBucket = []byte("index-queue")
+
+// boltBatchSize is the maximum number of messages that is read from disk at once.
+const boltBatchSize = 256
+
+// BoltQueue is an embedded disk-backed Queue for single node deployments.
+//
+// Messages are stored in a bolt database until they are acknowledged. Messages
+// that are in flight when the process stops are redelivered on restart.
+type BoltQueue struct {
+	db       *bolt.DB
+	rw       sync.Mutex
+	inflight map[uint64]bool
+	notify   chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+	stopped  bool
+	closed   bool
+}
+
+// NewBoltQueue opens or creates the bolt queue database at path.
+func NewBoltQueue(path string) (*BoltQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create queue directory; %w", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open bolt queue %s; %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, bucketErr := tx.CreateBucketIfNotExists(queueBucket)
+		return bucketErr
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltQueue{
+		db:       db,
+		inflight: map[uint64]bool{},
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func queueKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	return key
+}
+
+// signal wakes up the dispatcher without blocking.
+func (q *BoltQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Publish stores the messages in a single transaction. Concurrent publishers
+// are combined into a single transaction with bolt's Batch, so not every
+// message waits for its own disk sync.
+func (q *BoltQueue) Publish(ctx context.Context, data ...[]byte) error {
+	q.rw.Lock()
+	closed := q.closed
+	q.rw.Unlock()
+
+	if closed {
+		return ErrQueueClosed
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	err := q.db.Batch(func(tx *bolt.Tx) error {
+		b := tx.Bucket(queueBucket)
+
+		for _, msg := range data {
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			if err := b.Put(queueKey(seq), msg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store message in bolt queue; %w", err)
+	}
+
+	q.signal()
+
+	return nil
+}
+
+// Consume starts a dispatcher that delivers the stored messages to the workers.
+func (q *BoltQueue) Consume(ctx context.Context, workers int, handler DeliveryHandler) error {
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	if q.closed || q.stopped {
+		return ErrQueueClosed
+	}
+
+	deliveries := make(chan *Delivery)
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+
+		go func() {
+			defer q.wg.Done()
+
+			for d := range deliveries {
+				handler(d)
+			}
+		}()
+	}
+
+	q.wg.Add(1)
+
+	go q.dispatch(deliveries)
+
+	return nil
+}
+
+func (q *BoltQueue) dispatch(deliveries chan<- *Delivery) {
+	defer q.wg.Done()
+	defer close(deliveries)
+
+	// poll regularly, so nacked messages from a slow consumer are picked up
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		pending, err := q.pending()
+		if err != nil {
+			log.Error().Err(err).Msg("unable to read messages from bolt queue")
+		}
+
+		for _, d := range pending {
+			select {
+			case deliveries <- d:
+			case <-q.done:
+				return
+			}
+		}
+
+		if len(pending) == boltBatchSize {
+			continue
+		}
+
+		select {
+		case <-q.notify:
+		case <-ticker.C:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// pending returns the next batch of stored messages that are not in flight
+// and marks them as in flight.
+func (q *BoltQueue) pending() ([]*Delivery, error) {
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	pending := []*Delivery{}
+
+	err := q.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(queueBucket).Cursor()
+
+		for k, v := c.First(); k != nil && len(pending) < boltBatchSize; k, v = c.Next() {
+			seq := binary.BigEndian.Uint64(k)
+			if q.inflight[seq] {
+				continue
+			}
+
+			data := make([]byte, len(v))
+			copy(data, v)
+
+			q.inflight[seq] = true
+
+			pending = append(pending, &Delivery{
+				Data: data,
+				ack:  func() error { return q.ack(seq) },
+				nack: func() error { return q.nack(seq) },
+			})
+		}
+
+		return nil
+	})
+
+	return pending, err
+}
+
+func (q *BoltQueue) ack(seq uint64) error {
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(queueBucket).Delete(queueKey(seq))
+	})
+
+	q.rw.Lock()
+	delete(q.inflight, seq)
+	q.rw.Unlock()
+
+	return err
+}
+
+func (q *BoltQueue) nack(seq uint64) error {
+	q.rw.Lock()
+	delete(q.inflight, seq)
+	q.rw.Unlock()
+
+	q.signal()
+
+	return nil
+}
+
+// Len returns the number of stored messages, including the messages in flight.
+func (q *BoltQueue) Len() (int, error) {
+	var n int
+
+	err := q.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(queueBucket).Stats().KeyN
+		return nil
+	})
+
+	return n, err
+}
+
+// Close stops the dispatcher and the workers and closes the database.
+func (q *BoltQueue) Close() error {
+	if err := q.StopConsuming(); err != nil {
+		return err
+	}
+
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	if q.closed {
+		return nil
+	}
+
+	q.closed = true
+
+	return q.db.Close()
+}
+
+// StopConsuming stops the dispatcher and waits for the workers. The database
+// is kept open until Close, so the handled messages can still be acknowledged.
+func (q *BoltQueue) StopConsuming() error {
+	q.rw.Lock()
+
+	if !q.stopped {
+		q.stopped = true
+		close(q.done)
+	}
+
+	q.rw.Unlock()
+
+	q.wg.Wait()
+
+	return nil
+}
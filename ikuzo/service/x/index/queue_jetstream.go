@@ -0,0 +1,187 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const streamName = "HUB3-INDEX"
+
+// JetStreamConfig configures the JetStreamQueue.
+type JetStreamConfig struct {
+	Conn         *nats.Conn
+	StreamName   string
+	SubjectID    string
+	DurableName  string
+	DurableQueue string
+	// AckWait is the time after which unacknowledged messages are redelivered. default: 30s
+	AckWait time.Duration
+}
+
+func (c *JetStreamConfig) setDefaults() {
+	if c.StreamName == "" {
+		c.StreamName = streamName
+	}
+
+	if c.SubjectID == "" {
+		c.SubjectID = subjectID
+	}
+
+	if c.DurableName == "" {
+		c.DurableName = durableName
+	}
+
+	if c.DurableQueue == "" {
+		c.DurableQueue = durableQueue
+	}
+}
+
+// JetStreamQueue is a Queue backed by a NATS JetStream work queue stream.
+type JetStreamQueue struct {
+	cfg       *JetStreamConfig
+	js        nats.JetStreamContext
+	rw        sync.Mutex
+	subs      []*nats.Subscription
+	consumers consumers
+}
+
+// NewJetStreamQueue returns a JetStreamQueue. The stream is created when it does not exist.
+func NewJetStreamQueue(cfg *JetStreamConfig) (*JetStreamQueue, error) {
+	if cfg == nil || cfg.Conn == nil {
+		return nil, fmt.Errorf("nats.Conn must be established before jetstream queue can be used")
+	}
+
+	cfg.setDefaults()
+
+	js, err := cfg.Conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get jetstream context; %w", err)
+	}
+
+	if _, err := js.StreamInfo(cfg.StreamName); err != nil {
+		_, err = js.AddStream(&nats.StreamConfig{
+			Name:      cfg.StreamName,
+			Subjects:  []string{cfg.SubjectID},
+			Retention: nats.WorkQueuePolicy,
+			Storage:   nats.FileStorage,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create jetstream stream %s; %w", cfg.StreamName, err)
+		}
+	}
+
+	return &JetStreamQueue{cfg: cfg, js: js}, nil
+}
+
+func (q *JetStreamQueue) Publish(ctx context.Context, data ...[]byte) error {
+	for _, msg := range data {
+		if _, err := q.js.Publish(q.cfg.SubjectID, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Consume starts a subscription on the durable consumer for each worker.
+func (q *JetStreamQueue) Consume(ctx context.Context, workers int, handler DeliveryHandler) error {
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	options := []nats.SubOpt{
+		nats.Durable(q.cfg.DurableName),
+		nats.DeliverAll(),
+		nats.AckExplicit(),
+		nats.ManualAck(),
+	}
+
+	if q.cfg.AckWait > 0 {
+		options = append(options, nats.AckWait(q.cfg.AckWait))
+	}
+
+	for i := 0; i < workers; i++ {
+		sub, err := q.js.QueueSubscribe(
+			q.cfg.SubjectID,
+			q.cfg.DurableQueue,
+			func(m *nats.Msg) {
+				q.consumers.handle(handler, &Delivery{
+					Data: m.Data,
+					ack:  func() error { return m.Ack() },
+					nack: func() error { return m.Nak() },
+				})
+			},
+			options...,
+		)
+		if err != nil {
+			return err
+		}
+
+		q.subs = append(q.subs, sub)
+	}
+
+	return nil
+}
+
+// StopConsuming stops handling new messages. The connection is kept open until
+// Close, so the handled messages can still be acknowledged. Messages that are
+// not handled are redelivered after the AckWait.
+func (q *JetStreamQueue) StopConsuming() error {
+	q.consumers.stop()
+
+	return nil
+}
+
+// Close drains the subscriptions and waits until the connection is closed. The durable consumer is kept.
+func (q *JetStreamQueue) Close() error {
+	q.consumers.stop()
+
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	q.subs = nil
+
+	if err := q.cfg.Conn.Drain(); err != nil {
+		return err
+	}
+
+	return waitClosed(q.cfg.Conn)
+}
+
+// waitClosed waits until the draining connection is closed, so the messages
+// in flight are handled before Close returns.
+func waitClosed(conn *nats.Conn) error {
+	timeout := conn.Opts.DrainTimeout
+	if timeout == 0 {
+		timeout = nats.DefaultDrainTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for !conn.IsClosed() {
+		if time.Now().After(deadline) {
+			return nats.ErrDrainTimeout
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return nil
+}
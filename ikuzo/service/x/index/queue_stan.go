@@ -0,0 +1,115 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/stan.go"
+)
+
+// StanQueue is a Queue backed by NATS streaming.
+type StanQueue struct {
+	cfg       *NatsConfig
+	rw        sync.Mutex
+	subs      []stan.Subscription
+	consumers consumers
+}
+
+// NewStanQueue returns a StanQueue for the established connection of the NatsConfig.
+func NewStanQueue(cfg *NatsConfig) (*StanQueue, error) {
+	if cfg == nil || cfg.Conn == nil || cfg.Conn.NatsConn() == nil {
+		return nil, fmt.Errorf("stan.Conn must be established before nats queue can be used")
+	}
+
+	cfg.setDefaults()
+
+	return &StanQueue{cfg: cfg}, nil
+}
+
+func (q *StanQueue) Publish(ctx context.Context, data ...[]byte) error {
+	for _, msg := range data {
+		if err := q.cfg.Conn.Publish(q.cfg.SubjectID, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Consume starts a durable queue subscription for each worker.
+// Messages that are not acknowledged within the AckWait are redelivered.
+func (q *StanQueue) Consume(ctx context.Context, workers int, handler DeliveryHandler) error {
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	options := []stan.SubscriptionOption{
+		stan.DurableName(q.cfg.DurableName),
+		stan.DeliverAllAvailable(),
+		stan.SetManualAckMode(),
+	}
+
+	if q.cfg.AckWait > 0 {
+		options = append(options, stan.AckWait(q.cfg.AckWait))
+	}
+
+	for i := 0; i < workers; i++ {
+		sub, err := q.cfg.Conn.QueueSubscribe(
+			q.cfg.SubjectID,
+			q.cfg.DurableQueue,
+			func(m *stan.Msg) {
+				q.consumers.handle(handler, &Delivery{Data: m.Data, ack: m.Ack})
+			},
+			options...,
+		)
+		if err != nil {
+			return err
+		}
+
+		q.subs = append(q.subs, sub)
+	}
+
+	return nil
+}
+
+// StopConsuming stops handling new messages. The subscriptions are kept open
+// until Close, so the handled messages can still be acknowledged. Messages that
+// are not handled are redelivered after the AckWait.
+func (q *StanQueue) StopConsuming() error {
+	q.consumers.stop()
+
+	return nil
+}
+
+// Close closes the subscriptions without removing the durable subscription and
+// closes the connection.
+func (q *StanQueue) Close() error {
+	q.consumers.stop()
+
+	q.rw.Lock()
+	defer q.rw.Unlock()
+
+	for _, sub := range q.subs {
+		if err := sub.Close(); err != nil {
+			return err
+		}
+	}
+
+	q.subs = nil
+
+	return q.cfg.Conn.Close()
+}
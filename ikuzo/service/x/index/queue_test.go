@@ -0,0 +1,341 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/stan.go"
+)
+
+// queueFactory opens the Queue with the given name. Opening a Queue with the
+// same name again must give access to the same stored messages.
+type queueFactory func(t *testing.T, name string) Queue
+
+// deliveryRecorder records the deliveries of a Queue.
+type deliveryRecorder struct {
+	rw        sync.Mutex
+	delivered map[string]int
+	received  chan string
+}
+
+func newDeliveryRecorder() *deliveryRecorder {
+	return &deliveryRecorder{
+		delivered: map[string]int{},
+		received:  make(chan string, 1000),
+	}
+}
+
+func (dr *deliveryRecorder) record(d *Delivery) int {
+	dr.rw.Lock()
+	dr.delivered[string(d.Data)]++
+	n := dr.delivered[string(d.Data)]
+	dr.rw.Unlock()
+
+	dr.received <- string(d.Data)
+
+	return n
+}
+
+// wait returns the data of the next n deliveries.
+func (dr *deliveryRecorder) wait(t *testing.T, n int, timeout time.Duration) []string {
+	t.Helper()
+
+	received := []string{}
+	deadline := time.After(timeout)
+
+	for len(received) < n {
+		select {
+		case data := <-dr.received:
+			received = append(received, data)
+		case <-deadline:
+			t.Fatalf("received %d of %d deliveries before timeout", len(received), n)
+		}
+	}
+
+	return received
+}
+
+// none asserts that nothing is delivered within the timeout.
+func (dr *deliveryRecorder) none(t *testing.T, timeout time.Duration) {
+	t.Helper()
+
+	select {
+	case data := <-dr.received:
+		t.Fatalf("unexpected delivery: %s", data)
+	case <-time.After(timeout):
+	}
+}
+
+// testQueue is the shared test suite for the Queue implementations.
+// The ackWait is the redelivery timeout configured by the queueFactory.
+// nolint:funlen
+func testQueue(t *testing.T, open queueFactory, ackWait time.Duration) {
+	ctx := context.Background()
+	timeout := 5*time.Second + 2*ackWait
+
+	t.Run("at-least-once delivery", func(t *testing.T) {
+		is := is.New(t)
+
+		q := open(t, "delivery")
+		defer q.Close()
+
+		msgCount := 100
+
+		for i := 0; i < msgCount; i++ {
+			is.NoErr(q.Publish(ctx, []byte(fmt.Sprintf("msg-%d", i))))
+		}
+
+		dr := newDeliveryRecorder()
+
+		err := q.Consume(ctx, 4, func(d *Delivery) {
+			dr.record(d)
+			is.NoErr(d.Ack())
+		})
+		is.NoErr(err)
+
+		unique := map[string]bool{}
+		for _, data := range dr.wait(t, msgCount, timeout) {
+			unique[data] = true
+		}
+
+		is.Equal(len(unique), msgCount)
+	})
+
+	t.Run("concurrent batch publish", func(t *testing.T) {
+		is := is.New(t)
+
+		q := open(t, "batch")
+		defer q.Close()
+
+		publishers, batchSize := 4, 25
+
+		var wg sync.WaitGroup
+
+		for p := 0; p < publishers; p++ {
+			wg.Add(1)
+
+			go func(p int) {
+				defer wg.Done()
+
+				batch := [][]byte{}
+				for i := 0; i < batchSize; i++ {
+					batch = append(batch, []byte(fmt.Sprintf("msg-%d-%d", p, i)))
+				}
+
+				is.NoErr(q.Publish(ctx, batch...))
+			}(p)
+		}
+
+		wg.Wait()
+
+		dr := newDeliveryRecorder()
+
+		err := q.Consume(ctx, 2, func(d *Delivery) {
+			dr.record(d)
+			is.NoErr(d.Ack())
+		})
+		is.NoErr(err)
+
+		unique := map[string]bool{}
+		for _, data := range dr.wait(t, publishers*batchSize, timeout) {
+			unique[data] = true
+		}
+
+		is.Equal(len(unique), publishers*batchSize)
+	})
+
+	t.Run("redelivery without acknowledgement", func(t *testing.T) {
+		is := is.New(t)
+
+		q := open(t, "redelivery")
+		defer q.Close()
+
+		is.NoErr(q.Publish(ctx, []byte("retry")))
+
+		dr := newDeliveryRecorder()
+
+		err := q.Consume(ctx, 1, func(d *Delivery) {
+			if dr.record(d) == 1 {
+				is.NoErr(d.Nack())
+				return
+			}
+
+			is.NoErr(d.Ack())
+		})
+		is.NoErr(err)
+
+		is.Equal(dr.wait(t, 2, timeout), []string{"retry", "retry"})
+		dr.none(t, ackWait+500*time.Millisecond)
+	})
+
+	t.Run("restart recovery", func(t *testing.T) {
+		is := is.New(t)
+
+		q := open(t, "restart")
+		is.NoErr(q.Publish(ctx, []byte("unacked")))
+
+		dr := newDeliveryRecorder()
+
+		is.NoErr(q.Consume(ctx, 1, func(d *Delivery) { dr.record(d) }))
+		is.Equal(dr.wait(t, 1, timeout), []string{"unacked"})
+		is.NoErr(q.Close())
+
+		// the unacknowledged message is redelivered after the restart
+		q = open(t, "restart")
+
+		is.NoErr(q.Consume(ctx, 1, func(d *Delivery) {
+			dr.record(d)
+			is.NoErr(d.Ack())
+		}))
+		is.Equal(dr.wait(t, 1, timeout), []string{"unacked"})
+		is.NoErr(q.Close())
+
+		// the acknowledged message is not redelivered
+		q = open(t, "restart")
+		defer q.Close()
+
+		is.NoErr(q.Consume(ctx, 1, func(d *Delivery) { dr.record(d) }))
+		dr.none(t, ackWait+500*time.Millisecond)
+	})
+
+	t.Run("acknowledge after stop consuming", func(t *testing.T) {
+		is := is.New(t)
+
+		q := open(t, "stop")
+		is.NoErr(q.Publish(ctx, []byte("flushed")))
+
+		dr := newDeliveryRecorder()
+		deliveries := make(chan *Delivery, 1)
+
+		is.NoErr(q.Consume(ctx, 1, func(d *Delivery) {
+			dr.record(d)
+			deliveries <- d
+		}))
+		is.Equal(dr.wait(t, 1, timeout), []string{"flushed"})
+
+		// the delivery is acknowledged by the final flush after the consumers are stopped
+		is.NoErr(q.StopConsuming())
+		is.NoErr((<-deliveries).Ack())
+		is.NoErr(q.Close())
+
+		q = open(t, "stop")
+		defer q.Close()
+
+		is.NoErr(q.Consume(ctx, 1, func(d *Delivery) { dr.record(d) }))
+		dr.none(t, ackWait+500*time.Millisecond)
+	})
+}
+
+func TestBoltQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "indexqueue")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	testQueue(t, func(t *testing.T, name string) Queue {
+		q, err := NewBoltQueue(filepath.Join(dir, name+".db"))
+		if err != nil {
+			t.Fatalf("unable to open bolt queue: %s", err)
+		}
+
+		return q
+	}, 0)
+}
+
+func TestBoltQueue_closed(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "indexqueue")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	q, err := NewBoltQueue(filepath.Join(dir, "closed.db"))
+	is.NoErr(err)
+
+	is.NoErr(q.Publish(context.Background(), []byte("msg")))
+	is.NoErr(q.Close())
+
+	err = q.Publish(context.Background(), []byte("msg"))
+	is.Equal(err, ErrQueueClosed)
+}
+
+// nolint:gocritic
+func (s *indexSuite) TestStanQueue() {
+	natsURL := fmt.Sprintf("nats://%s:%s", s.ip, s.port.Port())
+
+	testQueue(s.T(), func(t *testing.T, name string) Queue {
+		cfg := &NatsConfig{
+			ClientID:     fmt.Sprintf("queue-%s-%d", name, time.Now().UnixNano()),
+			SubjectID:    "queue-" + name,
+			DurableName:  "queue-" + name,
+			DurableQueue: "queue-" + name,
+			AckWait:      time.Second,
+		}
+		cfg.setDefaults()
+
+		conn, err := stan.Connect(cfg.ClusterID, cfg.ClientID, stan.NatsURL(natsURL))
+		if err != nil {
+			t.Fatalf("unable to connect to %s: %s", natsURL, err)
+		}
+
+		cfg.Conn = conn
+
+		q, err := NewStanQueue(cfg)
+		if err != nil {
+			t.Fatalf("unable to create stan queue: %s", err)
+		}
+
+		return q
+	}, time.Second)
+}
+
+// nolint:gocritic
+func (s *indexSuite) TestJetStreamQueue() {
+	natsURL := fmt.Sprintf("nats://%s:%s", s.jsIP, s.jsPort.Port())
+
+	testQueue(s.T(), func(t *testing.T, name string) Queue {
+		conn, err := nats.Connect(natsURL)
+		if err != nil {
+			t.Fatalf("unable to connect to %s: %s", natsURL, err)
+		}
+
+		q, err := NewJetStreamQueue(&JetStreamConfig{
+			Conn:         conn,
+			StreamName:   "QUEUE-" + name,
+			SubjectID:    "queue-" + name,
+			DurableName:  "queue-" + name,
+			DurableQueue: "queue-" + name,
+			AckWait:      time.Second,
+		})
+		if err != nil {
+			t.Fatalf("unable to create jetstream queue: %s", err)
+		}
+
+		return q
+	}, time.Second)
+}
@@ -20,12 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/delving/hub3/ikuzo/domain/domainpb"
 	"github.com/elastic/go-elasticsearch/v8/esutil"
-	"github.com/nats-io/stan.go"
 	"github.com/rs/zerolog/log"
 	proto "google.golang.org/protobuf/proto"
 )
@@ -42,23 +43,61 @@ type Metrics struct {
 		Failed    uint64
 	}
 	Index struct {
-		Successful uint64
-		Failed     uint64
+		Successful   uint64
+		Failed       uint64
+		Retried      uint64
+		DeadLettered uint64
 	}
 }
 
+// FlushHook is called once per bulk flush for each organization and dataset
+// with records that are written to the index, and when records of a dataset
+// are removed from the index outside the BulkIndexer.
+type FlushHook func(orgID, datasetID string)
+
+// flushKey identifies a dataset with records written by the current bulk flush.
+type flushKey struct {
+	orgID     string
+	datasetID string
+}
+
+// IndexRouter returns the name of the index where the IndexMessage is stored.
+type IndexRouter interface {
+	IndexName(ctx context.Context, m *domainpb.IndexMessage) (string, error)
+}
+
 type Service struct {
-	bi         esutil.BulkIndexer
-	stan       *NatsConfig
-	direct     bool
-	MsgHandler func(ctx context.Context, m *domainpb.IndexMessage) error
-	workers    []stan.Subscription // this is for getting statistics
-	m          Metrics
+	bi          esutil.BulkIndexer
+	queue       Queue
+	direct      bool
+	MsgHandler  func(ctx context.Context, m *domainpb.IndexMessage) error
+	consuming   bool
+	m           Metrics
+	flushHooks  []FlushHook
+	flushMu     sync.Mutex
+	flushed     map[flushKey]bool
+	router      IndexRouter
+	retry       RetryPolicy
+	deadLetters DeadLetterStore
+	rw          sync.Mutex
+	retries     map[*indexAttempt]*time.Timer
+	retrying    sync.WaitGroup
+	stopped     bool
+	ingests     *ingestTracker
+
+	contentHashes ContentHashStore
+	// pendingHashes are the content hashes of the records written by the current bulk flush
+	pendingHashes map[flushKey]map[string]string
 }
 
 func NewService(options ...Option) (*Service, error) {
 	s := &Service{
-		m: Metrics{started: time.Now()},
+		m:       Metrics{started: time.Now()},
+		retries: map[*indexAttempt]*time.Timer{},
+		flushed: map[flushKey]bool{},
+		ingests: newIngestTracker(),
+
+		pendingHashes: map[flushKey]map[string]string{},
 	}
 
 	// apply options
@@ -68,140 +107,231 @@ func NewService(options ...Option) (*Service, error) {
 		}
 	}
 
-	if s.stan == nil {
+	if s.queue == nil {
 		s.direct = true
 		if s.bi == nil {
 			return s, fmt.Errorf("in direct mode an esutil.BulkIndexer must be set")
 		}
 	}
 
-	if !s.direct && (s.stan == nil || s.stan.Conn.NatsConn() == nil) {
-		return s, fmt.Errorf("stan.Conn must be established before nats queue can be used")
-	}
-
 	return s, nil
 }
 
+// Publish submits the messages to the BulkIndexer in direct mode. Otherwise
+// the messages are published to the Queue at once.
 func (s *Service) Publish(ctx context.Context, messages ...*domainpb.IndexMessage) error {
-	for _, msg := range messages {
-		// if direct submit msg directly to BulkIndexer
-		if s.direct {
+	if s.direct {
+		for _, msg := range messages {
 			if submitErr := s.submitBulkMsg(ctx, msg); submitErr != nil {
 				return fmt.Errorf("unable to index message; %w", submitErr)
 			}
-
-			continue
 		}
 
+		return nil
+	}
+
+	data := make([][]byte, 0, len(messages))
+
+	for _, msg := range messages {
 		b, err := proto.Marshal(msg)
 		if err != nil {
-			atomic.AddUint64(&s.m.Nats.Failed, 1)
+			atomic.AddUint64(&s.m.Nats.Failed, uint64(len(messages)))
 			return fmt.Errorf("unable to marshal index message; %w", err)
 		}
 
-		if err = s.stan.Conn.Publish(s.stan.SubjectID, b); err != nil {
-			atomic.AddUint64(&s.m.Nats.Failed, 1)
-			log.Error().Msgf("stan config: %+v", s.stan)
+		data = append(data, b)
+	}
 
-			return fmt.Errorf("unable to publish to queue; %w", err)
-		}
+	if err := s.queue.Publish(ctx, data...); err != nil {
+		atomic.AddUint64(&s.m.Nats.Failed, uint64(len(messages)))
 
-		atomic.AddUint64(&s.m.Nats.Published, 1)
+		return fmt.Errorf("unable to publish to queue; %w", err)
 	}
 
+	atomic.AddUint64(&s.m.Nats.Published, uint64(len(messages)))
+
 	return nil
 }
 
 func (s *Service) Metrics() Metrics {
-	return s.m
+	m := Metrics{started: s.m.started}
+
+	m.Nats.Published = atomic.LoadUint64(&s.m.Nats.Published)
+	m.Nats.Consumed = atomic.LoadUint64(&s.m.Nats.Consumed)
+	m.Nats.Failed = atomic.LoadUint64(&s.m.Nats.Failed)
+	m.Index.Successful = atomic.LoadUint64(&s.m.Index.Successful)
+	m.Index.Failed = atomic.LoadUint64(&s.m.Index.Failed)
+	m.Index.Retried = atomic.LoadUint64(&s.m.Index.Retried)
+	m.Index.DeadLettered = atomic.LoadUint64(&s.m.Index.DeadLettered)
+
+	return m
 }
 
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Service) Shutdown(ctx context.Context) error {
-	// stop all the workers before closing channels
-	for _, w := range s.workers {
-		w.Close()
-	}
-
-	if s.stan != nil {
-		if err := s.stan.Conn.Close(); err != nil {
+	// stop the consumers before closing the bulk indexer
+	if s.queue != nil {
+		if err := s.queue.StopConsuming(); err != nil {
 			return err
 		}
 	}
 
+	// cancel the retries before closing the bulk indexer, so they are not
+	// added to the closed bulk indexer
+	s.stopRetries()
+
 	if s.bi != nil {
 		s.bi.Stats()
 
 		if err := s.bi.Close(ctx); err != nil {
 			return err
 		}
+
+		s.FlushEnd(ctx)
+	}
+
+	// close the queue after the final flush, so its messages are acknowledged
+	if s.queue != nil {
+		if err := s.queue.Close(); err != nil {
+			return err
+		}
+	}
+
+	if s.deadLetters != nil {
+		if err := s.deadLetters.Close(); err != nil {
+			return err
+		}
 	}
 
-	// remove workers so the service could restart
-	s.workers = nil
+	s.consuming = false
 
 	return nil
 }
 func (s *Service) Start(ctx context.Context, workers int) error {
-	if len(s.workers) != 0 {
+	if s.consuming {
 		return fmt.Errorf("consumer is already started")
 	}
 
-	for i := 0; i < workers; i++ {
-		// create consumer
-		qsub, err := s.stan.Conn.QueueSubscribe(
-			s.stan.SubjectID,
-			s.stan.DurableQueue,
-			s.handleMessage,
-			stan.DurableName(s.stan.DurableName),
-		)
-		if err != nil {
-			return err
-		}
+	if s.queue == nil {
+		return fmt.Errorf("a queue must be set to start consumers")
+	}
 
-		// add worker for statistics
-		s.workers = append(s.workers, qsub)
+	if err := s.queue.Consume(ctx, workers, s.handleDelivery); err != nil {
+		return err
 	}
 
+	s.consuming = true
+
 	return nil
 }
 
-func (s *Service) handleMessage(m *stan.Msg) {
+// handleDelivery processes a Delivery from the queue. The Delivery is
+// acknowledged after the record is written to the index, so records that are
+// not yet flushed are redelivered when the consumer is restarted.
+func (s *Service) handleDelivery(d *Delivery) {
 	atomic.AddUint64(&s.m.Nats.Consumed, 1)
 
 	var msg domainpb.IndexMessage
-	if err := proto.Unmarshal(m.Data, &msg); err != nil {
+	if err := proto.Unmarshal(d.Data, &msg); err != nil {
 		log.Error().Err(err).Msg("unable to unmarshal indexmessage in index consumer")
+		// the message can never be processed, so it is not redelivered
+		s.ack(d)
+
 		return
 	}
 
+	// TODO(kiivihal): propagate the context
+	ctx := context.Background()
+
 	if s.MsgHandler != nil {
-		if err := s.MsgHandler(context.Background(), &msg); err != nil {
+		if err := s.MsgHandler(ctx, &msg); err != nil {
 			log.Error().Err(err).Msg("unable to process *domain.IndexMessage")
+			s.nack(d)
+
 			return
 		}
+
+		s.acknowledged(&msg, true)
+		s.ack(d)
+
+		return
 	}
 
-	// TODO(kiivihal): propagate the context
-	if s.bi != nil {
-		if err := s.submitBulkMsg(context.Background(), &msg); err != nil {
-			log.Error().Err(err).Msg("unable to process *domain.IndexMessage")
-			return
-		}
+	if s.bi == nil {
+		s.ack(d)
+		return
+	}
+
+	if err := s.addBulkItem(ctx, &indexAttempt{msg: &msg, delivery: d, attempt: 1}); err != nil {
+		log.Error().Err(err).Msg("unable to process *domain.IndexMessage")
+		s.nack(d)
+	}
+}
+
+func (s *Service) ack(d *Delivery) {
+	if err := d.Ack(); err != nil {
+		log.Error().Err(err).Msg("unable to acknowledge queue message")
+	}
+}
+
+func (s *Service) nack(d *Delivery) {
+	if err := d.Nack(); err != nil {
+		log.Error().Err(err).Msg("unable to request redelivery of queue message")
 	}
 }
 
 func (s *Service) submitBulkMsg(ctx context.Context, m *domainpb.IndexMessage) error {
 	if s.MsgHandler != nil {
-		return s.MsgHandler(ctx, m)
+		if err := s.MsgHandler(ctx, m); err != nil {
+			return err
+		}
+
+		s.acknowledged(m, true)
+
+		return nil
 	}
 
+	return s.addBulkItem(ctx, &indexAttempt{msg: m, attempt: 1})
+}
+
+// indexAttempt is an attempt to write an IndexMessage to the index.
+type indexAttempt struct {
+	msg *domainpb.IndexMessage
+	// delivery is set when the IndexMessage is delivered by the queue
+	delivery *Delivery
+	attempt  int
+	// index is the target index of the last attempt
+	index string
+}
+
+// addBulkItem adds the IndexMessage to the BulkIndexer. When the message is
+// delivered by the queue, the Delivery is acknowledged after the flush.
+func (s *Service) addBulkItem(ctx context.Context, a *indexAttempt) error {
+	m := a.msg
+
+	indexName := m.GetIndexName()
+
+	if s.router != nil {
+		var err error
+
+		indexName, err = s.router.IndexName(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	a.index = indexName
+
 	action := "index"
+	body := m.GetSource()
 
-	if m.GetDeleted() {
+	switch {
+	case m.GetDeleted():
 		action = "delete"
+	case m.GetUpdate():
+		action = "update"
 	}
 
 	bulkMsg := esutil.BulkIndexerItem{
@@ -209,32 +339,46 @@ func (s *Service) submitBulkMsg(ctx context.Context, m *domainpb.IndexMessage) e
 		Action: action,
 
 		// Index is the target index
-		Index: m.GetIndexName(),
+		Index: indexName,
 
 		// DocumentID is the (optional) document ID
 		DocumentID: m.GetRecordID(),
 
 		// Body is an `io.Reader` with the payload
-		Body: bytes.NewReader(m.GetSource()),
+		Body: bytes.NewReader(body),
 
 		// OnSuccess is called for each successful operation
 		OnSuccess: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem) {
 			atomic.AddUint64(&s.m.Index.Successful, 1)
+			s.acknowledged(m, true)
+
+			s.markFlushed(m.GetOrganisationID(), m.GetDatasetID())
+
+			if a.delivery != nil {
+				s.ack(a.delivery)
+			}
 		},
 
 		// OnFailure is called for each failed operation
 		OnFailure: func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
 			atomic.AddUint64(&s.m.Index.Failed, 1)
+
 			if err != nil {
 				log.Error().Err(err).Msg("bulk index msg error")
-			} else {
-				log.Error().
-					Str("type", res.Error.Type).
-					Str("hubID", res.DocumentID).
-					Str("index", res.Index).
-					Str("reason", res.Error.Reason).
-					Msg("bulk index msg error")
+				s.handleFailure(a, true, "bulk_request_error", err.Error())
+
+				return
 			}
+
+			log.Error().
+				Str("type", res.Error.Type).
+				Str("hubID", res.DocumentID).
+				Str("index", res.Index).
+				Str("reason", res.Error.Reason).
+				Int("attempt", a.attempt).
+				Msg("bulk index msg error")
+
+			s.handleFailure(a, isTransient(res.Status, res.Error.Type), res.Error.Type, res.Error.Reason)
 		},
 	}
 
@@ -249,6 +393,181 @@ func (s *Service) submitBulkMsg(ctx context.Context, m *domainpb.IndexMessage) e
 	)
 }
 
+// markFlushed records that a record of the dataset is written by the current bulk flush.
+func (s *Service) markFlushed(orgID, datasetID string) {
+	if len(s.flushHooks) == 0 {
+		return
+	}
+
+	s.flushMu.Lock()
+	s.flushed[flushKey{orgID: orgID, datasetID: datasetID}] = true
+	s.flushMu.Unlock()
+}
+
+// FlushEnd stores the content hashes of the records written since the previous
+// flush and calls the FlushHooks once for each dataset with written records.
+// It must be set as the OnFlushEnd callback of the esutil.BulkIndexerConfig.
+func (s *Service) FlushEnd(ctx context.Context) {
+	s.storeContentHashes(ctx)
+
+	s.flushMu.Lock()
+	flushed := s.flushed
+	s.flushed = map[flushKey]bool{}
+	s.flushMu.Unlock()
+
+	keys := make([]flushKey, 0, len(flushed))
+	for key := range flushed {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].orgID != keys[j].orgID {
+			return keys[i].orgID < keys[j].orgID
+		}
+
+		return keys[i].datasetID < keys[j].datasetID
+	})
+
+	for _, key := range keys {
+		s.RunFlushHooks(key.orgID, key.datasetID)
+	}
+}
+
+// RunFlushHooks calls the FlushHooks for the dataset. It is called when records
+// are removed from the index outside the BulkIndexer, e.g. by dropping orphans.
+func (s *Service) RunFlushHooks(orgID, datasetID string) {
+	for _, hook := range s.flushHooks {
+		hook(orgID, datasetID)
+	}
+}
+
+// handleFailure retries transient failures with the RetryPolicy. Other
+// failures are stored in the DeadLetterStore.
+func (s *Service) handleFailure(a *indexAttempt, transient bool, errorType, reason string) {
+	if transient && s.retry.retry(a.attempt) {
+		s.scheduleRetry(a)
+		return
+	}
+
+	if s.deadLetters == nil {
+		// without a DeadLetterStore the queue redelivers records that failed
+		// on a transient error, so they are not lost.
+		if a.delivery != nil {
+			if transient {
+				s.nack(a.delivery)
+				return
+			}
+
+			s.ack(a.delivery)
+		}
+
+		s.acknowledged(a.msg, false)
+
+		return
+	}
+
+	s.acknowledged(a.msg, false)
+	s.storeDeadLetter(a, errorType, reason)
+
+	if a.delivery != nil {
+		s.ack(a.delivery)
+	}
+}
+
+func (s *Service) storeDeadLetter(a *indexAttempt, errorType, reason string) {
+	index := a.index
+	if index == "" {
+		index = a.msg.GetIndexName()
+	}
+
+	dl, err := newDeadLetter(a.msg, index, a.attempt, errorType, reason)
+	if err == nil {
+		err = s.deadLetters.Put(context.Background(), dl)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Str("hubID", a.msg.GetRecordID()).Msg("unable to store dead letter")
+		return
+	}
+
+	atomic.AddUint64(&s.m.Index.DeadLettered, 1)
+}
+
+// scheduleRetry adds the IndexMessage to the BulkIndexer again after the backoff of the RetryPolicy.
+func (s *Service) scheduleRetry(a *indexAttempt) {
+	next := &indexAttempt{msg: a.msg, delivery: a.delivery, attempt: a.attempt + 1, index: a.index}
+
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	if s.stopped {
+		s.abandonRetry(next)
+		return
+	}
+
+	atomic.AddUint64(&s.m.Index.Retried, 1)
+
+	s.retries[next] = time.AfterFunc(s.retry.Backoff(a.attempt), func() {
+		s.rw.Lock()
+		delete(s.retries, next)
+		stopped := s.stopped
+
+		if !stopped {
+			s.retrying.Add(1)
+		}
+		s.rw.Unlock()
+
+		// the timer fired while the Service was stopped
+		if stopped {
+			s.abandonRetry(next)
+			return
+		}
+
+		defer s.retrying.Done()
+
+		if err := s.addBulkItem(context.Background(), next); err != nil {
+			s.handleFailure(next, false, "retry_error", err.Error())
+		}
+	})
+}
+
+// stopRetries cancels the scheduled retries and waits for the retries that
+// are being added to the BulkIndexer.
+func (s *Service) stopRetries() {
+	s.rw.Lock()
+
+	s.stopped = true
+
+	for a, timer := range s.retries {
+		if timer.Stop() {
+			s.abandonRetry(a)
+		}
+
+		delete(s.retries, a)
+	}
+
+	s.rw.Unlock()
+
+	s.retrying.Wait()
+}
+
+// abandonRetry handles a retry that is not executed because the Service is stopped.
+// Records from the queue are redelivered after a restart, other records are
+// stored as dead letters.
+func (s *Service) abandonRetry(a *indexAttempt) {
+	if a.delivery != nil {
+		return
+	}
+
+	s.acknowledged(a.msg, false)
+
+	if s.deadLetters == nil {
+		return
+	}
+
+	s.storeDeadLetter(a, "retry_canceled", "service stopped before retry")
+}
+
 func (s *Service) BulkIndexStats() esutil.BulkIndexerStats {
 	if s.bi == nil {
 		return esutil.BulkIndexerStats{}
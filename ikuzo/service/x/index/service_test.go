@@ -17,11 +17,16 @@ package index
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"strconv"
+	"sync"
 	"sync/atomic"
+	"testing"
 	"time"
 
 	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
 	"github.com/matryer/is"
 	"github.com/nats-io/stan.go"
 )
@@ -116,3 +121,197 @@ L:
 	err = svc.Shutdown(ctx)
 	is.NoErr(err)
 }
+
+type mockBulkIndexer struct {
+	rw    sync.Mutex
+	items []esutil.BulkIndexerItem
+	// failures are returned for the first added items
+	failures []esutil.BulkIndexerResponseItem
+	closed   bool
+	// closeDelay keeps Close busy like a flush of the remaining items
+	closeDelay time.Duration
+	// addedAfterClose counts the items that are added after Close, which
+	// panics with the esutil.BulkIndexer
+	addedAfterClose int
+}
+
+func (bi *mockBulkIndexer) Add(ctx context.Context, item esutil.BulkIndexerItem) error {
+	bi.rw.Lock()
+	if bi.closed {
+		bi.addedAfterClose++
+	}
+
+	bi.items = append(bi.items, item)
+
+	var failure *esutil.BulkIndexerResponseItem
+	if len(bi.failures) > 0 {
+		failure = &bi.failures[0]
+		bi.failures = bi.failures[1:]
+	}
+	bi.rw.Unlock()
+
+	if failure != nil {
+		item.OnFailure(ctx, item, *failure, nil)
+		return nil
+	}
+
+	item.OnSuccess(ctx, item, esutil.BulkIndexerResponseItem{})
+
+	return nil
+}
+
+func (bi *mockBulkIndexer) Close(ctx context.Context) error {
+	bi.rw.Lock()
+	bi.closed = true
+	bi.rw.Unlock()
+
+	time.Sleep(bi.closeDelay)
+
+	return nil
+}
+
+func (bi *mockBulkIndexer) Stats() esutil.BulkIndexerStats {
+	return esutil.BulkIndexerStats{}
+}
+
+func TestService_flushHook(t *testing.T) {
+	is := is.New(t)
+
+	var flushed []string
+
+	bi := &mockBulkIndexer{}
+
+	svc, err := NewService(
+		SetBulkIndexer(bi, true),
+		SetFlushHook(func(orgID, datasetID string) {
+			flushed = append(flushed, orgID+"/"+datasetID)
+		}),
+	)
+	is.NoErr(err)
+
+	err = svc.Publish(
+		context.Background(),
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "1"},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "3"},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec2", RecordID: "2", Deleted: true},
+	)
+	is.NoErr(err)
+
+	is.Equal(len(bi.items), 3)
+	is.Equal(bi.items[2].Action, "delete")
+	is.Equal(len(flushed), 0) // not called before the end of the flush
+	is.Equal(svc.Metrics().Index.Successful, uint64(3))
+
+	svc.FlushEnd(context.Background())
+	is.Equal(flushed, []string{"hub3/spec1", "hub3/spec2"}) // once per dataset
+
+	svc.FlushEnd(context.Background())
+	is.Equal(len(flushed), 2)
+
+	svc.RunFlushHooks("hub3", "spec3")
+	is.Equal(flushed, []string{"hub3/spec1", "hub3/spec2", "hub3/spec3"})
+}
+
+type mockIndexRouter struct{}
+
+func (mockIndexRouter) IndexName(ctx context.Context, m *domainpb.IndexMessage) (string, error) {
+	return m.GetIndexName() + "-" + m.GetOrganisationID(), nil
+}
+
+func TestService_indexRouter(t *testing.T) {
+	is := is.New(t)
+
+	bi := &mockBulkIndexer{}
+
+	svc, err := NewService(
+		SetBulkIndexer(bi, true),
+		SetIndexRouter(mockIndexRouter{}),
+	)
+	is.NoErr(err)
+
+	err = svc.Publish(
+		context.Background(),
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", IndexName: "hub3v2", RecordID: "1"},
+		&domainpb.IndexMessage{OrganisationID: "demo", DatasetID: "spec2", IndexName: "hub3v2", RecordID: "2"},
+	)
+	is.NoErr(err)
+
+	is.Equal(len(bi.items), 2)
+	is.Equal(bi.items[0].Index, "hub3v2-hub3")
+	is.Equal(bi.items[1].Index, "hub3v2-demo")
+}
+
+func TestService_update(t *testing.T) {
+	is := is.New(t)
+
+	bi := &mockBulkIndexer{}
+
+	svc, err := NewService(SetBulkIndexer(bi, true))
+	is.NoErr(err)
+
+	err = svc.Publish(
+		context.Background(),
+		&domainpb.IndexMessage{
+			OrganisationID: "hub3",
+			DatasetID:      "spec1",
+			IndexName:      "hub3v2",
+			RecordID:       "1",
+			Update:         true,
+			Source:         []byte(`{"doc":{"meta":{"revision":2}},"upsert":{"meta":{"revision":2}}}`),
+		},
+	)
+	is.NoErr(err)
+
+	is.Equal(len(bi.items), 1)
+	is.Equal(bi.items[0].Action, "update")
+
+	body, err := ioutil.ReadAll(bi.items[0].Body)
+	is.NoErr(err)
+	is.Equal(string(body), `{"doc":{"meta":{"revision":2}},"upsert":{"meta":{"revision":2}}}`)
+}
+
+type memoryContentHashStore struct {
+	hashes map[string]string
+}
+
+func (m *memoryContentHashStore) Put(ctx context.Context, orgID, datasetID string, hashes map[string]string) error {
+	for hubID, hash := range hashes {
+		if hash == "" {
+			delete(m.hashes, hubID)
+			continue
+		}
+
+		m.hashes[hubID] = hash
+	}
+
+	return nil
+}
+
+func TestService_contentHashes(t *testing.T) {
+	is := is.New(t)
+
+	bi := &mockBulkIndexer{
+		failures: []esutil.BulkIndexerResponseItem{failure(http.StatusBadRequest, "mapper_parsing_exception", "failed to parse")},
+	}
+	store := &memoryContentHashStore{hashes: map[string]string{"1": "old", "3": "old"}}
+
+	svc, err := NewService(SetBulkIndexer(bi, true), SetContentHashStore(store))
+	is.NoErr(err)
+
+	err = svc.Publish(
+		context.Background(),
+		// the v1 message of record 1 fails, so its v2 message does not store the hash
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "1", IndexName: "hub3v1", ContentHash: "abc"},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "1", IndexName: "hub3v2", ContentHash: "abc"},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "2", IndexName: "hub3v2", ContentHash: "def"},
+		&domainpb.IndexMessage{OrganisationID: "hub3", DatasetID: "spec1", RecordID: "3", IndexName: "hub3v2"},
+	)
+	is.NoErr(err)
+
+	// the content hashes are stored at the end of the flush
+	is.Equal(store.hashes, map[string]string{"1": "old", "3": "old"})
+
+	svc.FlushEnd(context.Background())
+
+	is.Equal(store.hashes, map[string]string{"2": "def", "3": "old"})
+}
@@ -13,10 +13,13 @@ import (
 
 type indexSuite struct {
 	suite.Suite
-	stanC testcontainers.Container
-	ip    string
-	port  nat.Port
-	ctx   context.Context
+	stanC  testcontainers.Container
+	ip     string
+	port   nat.Port
+	jsC    testcontainers.Container
+	jsIP   string
+	jsPort nat.Port
+	ctx    context.Context
 }
 
 func TestIndexSuite(t *testing.T) {
@@ -62,6 +65,23 @@ func (s *indexSuite) SetupSuite() {
 
 	s.port, err = s.stanC.MappedPort(s.ctx, "4222")
 	is.NoErr(err)
+
+	s.jsC, err = testcontainers.GenericContainer(s.ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "nats:2.2.6",
+			ExposedPorts: []string{"4222"},
+			WaitingFor:   wait.ForLog("Server is ready"),
+			Cmd:          []string{"-js"},
+		},
+		Started: true,
+	})
+	is.NoErr(err)
+
+	s.jsIP, err = s.jsC.Host(s.ctx)
+	is.NoErr(err)
+
+	s.jsPort, err = s.jsC.MappedPort(s.ctx, "4222")
+	is.NoErr(err)
 }
 
 // nolint:gocritic
@@ -69,4 +89,7 @@ func (s *indexSuite) TearDownSuite() {
 	is := is.New(s.T())
 	err := s.stanC.Terminate(s.ctx)
 	is.NoErr(err)
+
+	err = s.jsC.Terminate(s.ctx)
+	is.NoErr(err)
 }
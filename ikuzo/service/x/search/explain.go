@@ -0,0 +1,158 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+// Explanation describes how a query string is interpreted by the search
+// backend. It is used to debug surprising search results.
+type Explanation struct {
+	Query string `json:"query"`
+	// ParseError is set when the query could not be parsed by the QueryParser.
+	ParseError string `json:"parseError,omitempty"`
+	// Parsed is the QueryTerm tree with the analyzer output for each term.
+	// For ElasticSearch it is shown next to the Rewritten query.
+	Parsed *QueryExplanation `json:"parsed,omitempty"`
+	// ElasticQuery is the query source that is sent to ElasticSearch.
+	ElasticQuery interface{} `json:"elasticQuery,omitempty"`
+	// Rewritten contains the query as it is rewritten and executed by ElasticSearch.
+	Rewritten []*RewrittenQuery `json:"rewritten,omitempty"`
+	// Approximation is set when the scores of the hits are not computed by
+	// ElasticSearch, but approximated by the search backend.
+	Approximation string `json:"approximation,omitempty"`
+	// Hits contains the score explanation per hit.
+	Hits []*HitExplanation `json:"hits,omitempty"`
+	term *QueryTerm
+}
+
+// RewrittenQuery is the explanation of the ElasticSearch validate query API
+// for a single index.
+type RewrittenQuery struct {
+	Index       string `json:"index,omitempty"`
+	Valid       bool   `json:"valid"`
+	Explanation string `json:"explanation,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// QueryExplanation is the serializable representation of a QueryTerm.
+type QueryExplanation struct {
+	Type           string              `json:"type"`
+	Field          string              `json:"field,omitempty"`
+	Value          string              `json:"value,omitempty"`
+	Analyzed       string              `json:"analyzed,omitempty"`
+	PhoneticKey    string              `json:"phoneticKey,omitempty"`
+	Prohibited     bool                `json:"prohibited,omitempty"`
+	PrefixWildcard bool                `json:"prefixWildcard,omitempty"`
+	SuffixWildcard bool                `json:"suffixWildcard,omitempty"`
+	Boost          float64             `json:"boost,omitempty"`
+	Fuzzy          int                 `json:"fuzzy,omitempty"`
+	Slop           int                 `json:"slop,omitempty"`
+	Must           []*QueryExplanation `json:"must,omitempty"`
+	Should         []*QueryExplanation `json:"should,omitempty"`
+	MustNot        []*QueryExplanation `json:"mustNot,omitempty"`
+}
+
+// HitExplanation contains the score explanation of a single hit.
+type HitExplanation struct {
+	ID          string            `json:"id"`
+	Score       float64           `json:"score"`
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+}
+
+// ScoreExplanation has the same structure as the ElasticSearch score
+// explanation, so in-memory and ElasticSearch explanations are rendered the
+// same way. In-memory scores are only an approximation, see Explanation.Approximation.
+type ScoreExplanation struct {
+	Value       float64             `json:"value"`
+	Description string              `json:"description"`
+	Details     []*ScoreExplanation `json:"details,omitempty"`
+}
+
+// NewExplanation parses the query and returns its Explanation.
+// A parse error is reported in the Explanation and not returned as an error,
+// because it is part of what needs to be explained.
+func NewExplanation(query string, options ...QueryOption) (*Explanation, error) {
+	e := &Explanation{Query: query}
+
+	qp, err := NewQueryParser(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	qt, err := qp.Parse(query)
+	if err != nil {
+		e.ParseError = err.Error()
+		return e, nil
+	}
+
+	e.Parsed = ExplainQuery(qt)
+	e.term = qt
+
+	return e, nil
+}
+
+// QueryTerm returns the parsed QueryTerm. It is nil when the query could not be parsed.
+func (e *Explanation) QueryTerm() *QueryTerm {
+	return e.term
+}
+
+// ExplainQuery converts the QueryTerm tree to a QueryExplanation. Each term
+// is annotated with the output of the Analyzer.
+func ExplainQuery(qt *QueryTerm) *QueryExplanation {
+	if qt == nil {
+		return nil
+	}
+
+	qe := &QueryExplanation{
+		Type:           qt.Type().String(),
+		Field:          qt.Field,
+		Value:          qt.Value,
+		Prohibited:     qt.Prohibited,
+		PrefixWildcard: qt.PrefixWildcard,
+		SuffixWildcard: qt.SuffixWildcard,
+		Boost:          qt.Boost,
+		Fuzzy:          qt.Fuzzy,
+		Slop:           qt.Slop,
+	}
+
+	var a Analyzer
+
+	switch qt.Type() {
+	case BoolQuery:
+		qe.Must = explainClauses(qt.Must())
+		qe.Should = explainClauses(qt.Should())
+		qe.MustNot = explainClauses(qt.MustNot())
+	case PhraseQuery:
+		qe.Analyzed = a.TransformPhrase(qt.Value)
+	case PhoneticQuery:
+		qe.Analyzed = a.Transform(qt.Value)
+		qe.PhoneticKey = PhoneticKey(qt.Value)
+	default:
+		qe.Analyzed = a.Transform(qt.Value)
+	}
+
+	return qe
+}
+
+func explainClauses(clauses []*QueryTerm) []*QueryExplanation {
+	if len(clauses) == 0 {
+		return nil
+	}
+
+	explained := make([]*QueryExplanation, 0, len(clauses))
+	for _, clause := range clauses {
+		explained = append(explained, ExplainQuery(clause))
+	}
+
+	return explained
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/matryer/is"
+)
+
+func TestNewExplanation(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		options []QueryOption
+		want    *Explanation
+	}{
+		{
+			"single term",
+			"Privé",
+			nil,
+			&Explanation{
+				Query: "Privé",
+				Parsed: &QueryExplanation{
+					Type:   "BoolQuery",
+					Should: []*QueryExplanation{{Type: "TermQuery", Value: "prive", Analyzed: "prive"}},
+				},
+			},
+		},
+		{
+			"boolean query",
+			"jansen~ph AND \"Huis te Leiden\" NOT rotterdam",
+			nil,
+			&Explanation{
+				Query: "jansen~ph AND \"Huis te Leiden\" NOT rotterdam",
+				Parsed: &QueryExplanation{
+					Type: "BoolQuery",
+					Must: []*QueryExplanation{
+						{Type: "PhoneticQuery", Value: "jansen", Analyzed: "jansen", PhoneticKey: "JANSEN"},
+						{Type: "PhraseQuery", Value: "huis te leiden", Analyzed: "huis te leiden"},
+					},
+					MustNot: []*QueryExplanation{
+						{Type: "TermQuery", Value: "rotterdam", Analyzed: "rotterdam", Prohibited: true},
+					},
+				},
+			},
+		},
+		{
+			"modifiers",
+			"leid* batavia~1 amsterdam^2",
+			[]QueryOption{SetDefaultOperator(AndOperator)},
+			&Explanation{
+				Query: "leid* batavia~1 amsterdam^2",
+				Parsed: &QueryExplanation{
+					Type: "BoolQuery",
+					Must: []*QueryExplanation{
+						{Type: "WildCardQuery", Value: "leid", Analyzed: "leid", PrefixWildcard: true},
+						{Type: "FuzzyQuery", Value: "batavia", Analyzed: "batavia", Fuzzy: 1},
+						{Type: "TermQuery", Value: "amsterdam", Analyzed: "amsterdam", Boost: 2},
+					},
+				},
+			},
+		},
+		{
+			"parse error",
+			"jansen~xx",
+			nil,
+			&Explanation{
+				Query:      "jansen~xx",
+				ParseError: "unable to parse query input jansen~xx, due to; unknown fuzzy modifier xx",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			got, err := NewExplanation(tt.query, tt.options...)
+			is.NoErr(err)
+
+			if diff := cmp.Diff(tt.want, got, cmpopts.IgnoreUnexported(Explanation{})); diff != "" {
+				t.Errorf("NewExplanation() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+
+			is.Equal(got.QueryTerm() == nil, tt.want.ParseError != "")
+		})
+	}
+}
@@ -145,6 +145,11 @@ const (
 	// attempts to encode homophones with the same characters. More information can
 	// be found at http://en.wikipedia.org/wiki/Soundex.
 	Soundex
+
+	// DutchPhonetic computes a phonetic key that is tuned to the spelling
+	// variants of Dutch person and place names, e.g. Jansen, Janssen and Janszen
+	// or Meijer, Meyer and Meier all have the same key.
+	DutchPhonetic
 )
 
 func (pp PhoneticPreprocessor) String() string {
@@ -153,6 +158,7 @@ func (pp PhoneticPreprocessor) String() string {
 		"Nysiis",
 		"Phonex",
 		"Soundex",
+		"DutchPhonetic",
 	}[pp]
 }
 
@@ -168,6 +174,8 @@ func Transform(s1 string, pp PhoneticPreprocessor) (string, error) {
 		output = matchr.Soundex(s1)
 	case Phonex:
 		output = matchr.Phonex(s1)
+	case DutchPhonetic:
+		output = dutchPhonetic(s1)
 	default:
 		return "", fmt.Errorf("unknown phonetic preprocessor; %s", pp)
 	}
@@ -307,6 +307,16 @@ var metaphoneTests = []soundTests{
 	{"harper", "HRPR", false},
 }
 
+var dutchPhoneticTests = []soundTests{
+	{"Jansen", "JANSEN", false},
+	{"Janszen", "JANSEN", false},
+	{"Meijer", "MYER", false},
+	{"Smidt", "SMIT", false},
+	{"Bosch", "BOS", false},
+	{"1/2", "", false},
+	{"", "", false},
+}
+
 func TestTransform(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -333,6 +343,11 @@ func TestTransform(t *testing.T) {
 			Nysiis,
 			nysiisTests,
 		},
+		{
+			"dutch phonetic",
+			DutchPhonetic,
+			dutchPhoneticTests,
+		},
 		{
 			"unknown PhoneticPreprocessor",
 			100,
@@ -17,6 +17,8 @@ package search
 type Matches struct {
 	termFrequency map[string]int
 	termVectors   *Vectors
+	// boosts of the terms that are not scored with the default boost of 1
+	boosts map[string]float64
 }
 
 func NewMatches() *Matches {
@@ -30,6 +32,7 @@ func NewMatches() *Matches {
 func (m *Matches) Reset() {
 	m.termFrequency = make(map[string]int)
 	m.termVectors = NewVectors()
+	m.boosts = nil
 }
 
 func (m *Matches) AppendTerm(term string, tv *Vectors) {
@@ -41,6 +44,30 @@ func (m *Matches) AppendTerm(term string, tv *Vectors) {
 	m.mergeVectors(tv)
 }
 
+// AppendBoostedTerm appends the term like AppendTerm and scores it with the boost.
+func (m *Matches) AppendBoostedTerm(term string, tv *Vectors, boost float64) {
+	if tv.Size() == 0 {
+		return
+	}
+
+	m.AppendTerm(term, tv)
+
+	if m.boosts == nil {
+		m.boosts = make(map[string]float64)
+	}
+
+	m.boosts[term] = boost
+}
+
+// Boost returns the boost of the term. The default boost is 1.
+func (m *Matches) Boost(term string) float64 {
+	if boost, ok := m.boosts[term]; ok {
+		return boost
+	}
+
+	return 1
+}
+
 func (m *Matches) DocCount() int {
 	return m.termVectors.DocCount()
 }
@@ -60,6 +87,16 @@ func (m *Matches) Merge(matches *Matches) {
 		m.termFrequency[key] = count
 	}
 
+	for key, boost := range matches.boosts {
+		if boost > m.Boost(key) || m.boosts[key] == 0 {
+			if m.boosts == nil {
+				m.boosts = make(map[string]float64)
+			}
+
+			m.boosts[key] = boost
+		}
+	}
+
 	m.mergeVectors(matches.termVectors)
 }
 
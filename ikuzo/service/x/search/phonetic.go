@@ -0,0 +1,154 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// phoneticRule replaces a spelling variant with its phonetic code.
+// When atEnd is true the rule only applies at the end of a word.
+type phoneticRule struct {
+	pattern     string
+	replacement string
+	atEnd       bool
+}
+
+// dutchRules are ordered from the longest to the shortest pattern, so that
+// 'eij' is matched before 'ei' and 'sch' before 'ch'.
+//
+// The rules are geared towards matching spelling variants of Dutch person and
+// place names as they are found in civil registries and archival finding aids,
+// e.g. Jansen ~ Janssen ~ Janszen and Meijer ~ Meyer ~ Meier.
+var dutchRules = []phoneticRule{
+	{"SCH", "S", true},
+	{"EIJ", "Y", false},
+	{"SCH", "SG", false},
+	{"CK", "K", false},
+	{"DT", "T", false},
+	{"TH", "T", false},
+	{"PH", "F", false},
+	{"GH", "G", false},
+	{"CH", "G", false},
+	{"SZ", "S", false},
+	{"TZ", "S", false},
+	{"KS", "X", false},
+	{"IJ", "Y", false},
+	{"EY", "Y", false},
+	{"EI", "Y", false},
+	{"AY", "A", false},
+	{"AE", "A", false},
+	{"AU", "OU", false},
+	{"UE", "U", false},
+	{"D", "T", true},
+	{"Q", "K", false},
+	{"Z", "S", false},
+	{"V", "F", false},
+}
+
+// dutchPhonetic computes a phonetic key for Dutch names.
+//
+// The input is ASCII folded, stripped of everything but letters and
+// upper-cased before the spelling rules are applied. A 'C' followed by 'E', 'I'
+// or 'Y' sounds like an 'S', in all other cases it is coded as a 'K'. An 'H'
+// after a consonant is silent. Finally all repeated characters are collapsed,
+// so 'Janssen' and 'Jansen' share the same key.
+func dutchPhonetic(s string) string {
+	word := strings.Map(
+		func(r rune) rune {
+			if r <= unicode.MaxASCII && unicode.IsLetter(r) {
+				return unicode.ToUpper(r)
+			}
+
+			return -1
+		},
+		LuceneASCIIFolding(s),
+	)
+
+	var key strings.Builder
+
+	for i := 0; i < len(word); {
+		if code, size, ok := matchDutchRule(word, i); ok {
+			key.WriteString(code)
+
+			i += size
+
+			continue
+		}
+
+		switch c := word[i]; {
+		case c == 'C':
+			if i+1 < len(word) && strings.ContainsRune("EIY", rune(word[i+1])) {
+				key.WriteByte('S')
+			} else {
+				key.WriteByte('K')
+			}
+		case c == 'H' && i > 0 && !isVowel(word[i-1]):
+			// silent 'H' after a consonant
+		default:
+			key.WriteByte(c)
+		}
+
+		i++
+	}
+
+	return collapseRepeats(key.String())
+}
+
+func matchDutchRule(word string, pos int) (code string, size int, ok bool) {
+	for _, rule := range dutchRules {
+		if !strings.HasPrefix(word[pos:], rule.pattern) {
+			continue
+		}
+
+		if rule.atEnd && pos+len(rule.pattern) != len(word) {
+			continue
+		}
+
+		return rule.replacement, len(rule.pattern), true
+	}
+
+	return "", 0, false
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("AEIOUY", c) != -1
+}
+
+func collapseRepeats(s string) string {
+	var str strings.Builder
+
+	var prev rune
+
+	for _, r := range s {
+		if r == prev {
+			continue
+		}
+
+		str.WriteRune(r)
+
+		prev = r
+	}
+
+	return str.String()
+}
+
+// PhoneticKey returns the phonetic key of the term using the DutchPhonetic
+// preprocessor. It is used for both indexing and querying phonetic keys, so
+// an empty string is returned when the term contains no letters.
+func PhoneticKey(term string) string {
+	return dutchPhonetic(term)
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import "testing"
+
+func TestPhoneticKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []string
+		want     string
+	}{
+		{"double consonants", []string{"Jansen", "Janssen", "Janszen", "jansen"}, "JANSEN"},
+		{"ij and y", []string{"Meijer", "Meyer", "Meier", "Mijer"}, "MYER"},
+		{"final d and dt", []string{"Smit", "Smid", "Smidt"}, "SMIT"},
+		{"c as k", []string{"Cornelis", "Kornelis"}, "KORNELIS"},
+		{"c as s", []string{"Cecilia", "Sesilia"}, "SESILIA"},
+		{"ph as f", []string{"Philips", "Filips"}, "FILIPS"},
+		{"ae as aa", []string{"Maes", "Maas"}, "MAS"},
+		{"ch as g", []string{"Gerrits", "Cherrits"}, "GERITS"},
+		{"final sch", []string{"Bosch", "Bos"}, "BOS"},
+		{"ck as k", []string{"Hendrick", "Hendrik"}, "HENDRIK"},
+		{"diacritics", []string{"Hélène", "Helene"}, "HELENE"},
+		{"v as f", []string{"Vries", "Fries"}, "FRIES"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			for _, variant := range tt.variants {
+				if got := PhoneticKey(variant); got != tt.want {
+					t.Errorf("PhoneticKey() %s = %v, want %v", variant, got, tt.want)
+				}
+			}
+		})
+	}
+}
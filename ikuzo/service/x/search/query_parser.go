@@ -34,7 +34,8 @@ const (
 	OrOperator       Operator = "OR"
 	WildCardOperator Operator = "*"
 
-	fuzzinesDefault = 2
+	fuzzinesDefault  = 2
+	phoneticModifier = "ph"
 )
 
 type QueryType int
@@ -45,6 +46,7 @@ const (
 	PhraseQuery
 	TermQuery
 	WildCardQuery
+	PhoneticQuery
 )
 
 func (qt QueryType) String() string {
@@ -54,6 +56,7 @@ func (qt QueryType) String() string {
 		"PhraseQuery",
 		"TermQuery",
 		"WildCardQuery",
+		"PhoneticQuery",
 	}[qt]
 }
 
@@ -67,6 +70,7 @@ type QueryTerm struct {
 	Boost          float64
 	Fuzzy          int // fuzzy is for words
 	Slop           int // slop is for phrases
+	Phonetic       bool
 	mustClauses    []*QueryTerm
 	mustNotClauses []*QueryTerm
 	shouldClauses  []*QueryTerm
@@ -82,6 +86,8 @@ func (qt *QueryTerm) Type() QueryType {
 		return PhraseQuery
 	case qt.PrefixWildcard, qt.SuffixWildcard:
 		return WildCardQuery
+	case qt.Phonetic:
+		return PhoneticQuery
 	case qt.Fuzzy != 0:
 		return FuzzyQuery
 	}
@@ -160,6 +166,17 @@ func (qt *QueryTerm) setFuzziness(qp *QueryParser) error {
 		}
 
 		qt.Fuzzy = fuzzy
+	case unicode.IsLetter(r):
+		qp.s.Scan()
+		text := qp.tokenText()
+
+		if text != phoneticModifier {
+			return fmt.Errorf("unknown fuzzy modifier %s", text)
+		}
+
+		qt.Phonetic = !qt.Phrase
+
+		return nil
 	case unicode.IsSpace(r), r == scanner.EOF:
 		qt.Fuzzy = fuzzinesDefault
 	}
@@ -216,6 +233,7 @@ type QueryOption func(*QueryParser) error
 // '*' at the end of terms specifies prefix query: term*
 // '~N' at the end of terms specifies fuzzy query: term~1
 // '~N' at the end of phrases specifies near query: "term1 term2"~5
+// '~ph' at the end of terms specifies phonetic query: term~ph
 // '^N' at the end of terms specifies boost query: term~1.5
 // '^N' at the end of phrases specifies a boost query: "term1 term2"~2.4
 // '(' and ')' specifies precedence: token1 + (token2 | token3)
@@ -426,6 +444,9 @@ func (qp *QueryParser) runParser(q *QueryTerm, op Operator, qt *QueryTerm) error
 
 	if qt != nil && qt.Value != "" {
 		qp.appendQuery(q, op, qt)
+
+		// start with a clean QueryTerm so modifiers don't leak to the next term
+		qt = nil
 	}
 
 	// end of the group so return so the nested bool can be closed
@@ -757,6 +757,35 @@ func TestQueryParser_Parse(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"phonetic query",
+			args{"Jansen~ph"},
+			&QueryTerm{
+				shouldClauses: []*QueryTerm{
+					{Value: "jansen", Phonetic: true},
+				},
+			},
+			false,
+		},
+		{
+			"modifiers do not leak to the next term",
+			args{"Jansen~ph Smit~1 vries^2 bakker"},
+			&QueryTerm{
+				shouldClauses: []*QueryTerm{
+					{Value: "jansen", Phonetic: true},
+					{Value: "smit", Fuzzy: 1},
+					{Value: "vries", Boost: 2},
+					{Value: "bakker"},
+				},
+			},
+			false,
+		},
+		{
+			"bad phonetic modifier",
+			args{"Jansen~phonetic"},
+			nil,
+			true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1020,6 +1049,7 @@ func TestQueryType_String(t *testing.T) {
 		{"phrase query", PhraseQuery, "PhraseQuery"},
 		{"term query", TermQuery, "TermQuery"},
 		{"wildcard query", WildCardQuery, "WildCardQuery"},
+		{"phonetic query", PhoneticQuery, "PhoneticQuery"},
 	}
 
 	for _, tt := range tests {
@@ -1044,6 +1074,7 @@ func TestQueryTerm_Type(t *testing.T) {
 		Boost          float64
 		Fuzzy          int
 		Slop           int
+		Phonetic       bool
 		mustClauses    []*QueryTerm
 		mustNotClauses []*QueryTerm
 		shouldClauses  []*QueryTerm
@@ -1090,6 +1121,11 @@ func TestQueryTerm_Type(t *testing.T) {
 			fields{Value: "words", Fuzzy: 2},
 			FuzzyQuery,
 		},
+		{
+			"phonetic query",
+			fields{Value: "jansen", Phonetic: true},
+			PhoneticQuery,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1106,6 +1142,7 @@ func TestQueryTerm_Type(t *testing.T) {
 				Boost:          tt.fields.Boost,
 				Fuzzy:          tt.fields.Fuzzy,
 				Slop:           tt.fields.Slop,
+				Phonetic:       tt.fields.Phonetic,
 				mustClauses:    tt.fields.mustClauses,
 				mustNotClauses: tt.fields.mustNotClauses,
 				shouldClauses:  tt.fields.shouldClauses,
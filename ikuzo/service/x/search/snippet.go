@@ -0,0 +1,312 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// The defaults are the same as the ElasticSearch highlighter, so snippets from
+// the in-memory index and from ElasticSearch can be used interchangeably.
+const (
+	defaultFragmentSize      = 100
+	defaultNumberOfFragments = 5
+)
+
+// SnippetConfig configures the keyword-in-context fragments that are
+// extracted around the hits in a TokenStream.
+type SnippetConfig struct {
+	// FragmentSize is the target size of a fragment in characters.
+	FragmentSize int
+	// NumberOfFragments is the maximum number of fragments returned.
+	// When it is 0 the entire text is returned as a single highlighted fragment.
+	NumberOfFragments int
+	// MergeDistance is the maximum number of tokens between two fragments for
+	// them to be merged into a single fragment.
+	MergeDistance int
+	// SentenceAware prevents fragments from crossing sentence boundaries.
+	SentenceAware bool
+}
+
+type SnippetOption func(cfg *SnippetConfig)
+
+func NewSnippetConfig(options ...SnippetOption) *SnippetConfig {
+	cfg := &SnippetConfig{
+		FragmentSize:      defaultFragmentSize,
+		NumberOfFragments: defaultNumberOfFragments,
+	}
+
+	for _, option := range options {
+		option(cfg)
+	}
+
+	return cfg
+}
+
+func SetFragmentSize(size int) SnippetOption {
+	return func(cfg *SnippetConfig) {
+		cfg.FragmentSize = size
+	}
+}
+
+func SetNumberOfFragments(n int) SnippetOption {
+	return func(cfg *SnippetConfig) {
+		cfg.NumberOfFragments = n
+	}
+}
+
+func SetMergeDistance(distance int) SnippetOption {
+	return func(cfg *SnippetConfig) {
+		cfg.MergeDistance = distance
+	}
+}
+
+func SetSentenceAware() SnippetOption {
+	return func(cfg *SnippetConfig) {
+		cfg.SentenceAware = true
+	}
+}
+
+// Fragment is a highlighted keyword-in-context snippet.
+type Fragment struct {
+	DocID int
+	// Offset is the start offset of the fragment in the source text.
+	Offset int
+	// Hits is the number of highlighted tokens in the fragment.
+	Hits int
+	Text string
+}
+
+// window is an inclusive range of token indices.
+type window struct {
+	start int
+	end   int
+	hits  int
+}
+
+// Snippets returns the highlighted keyword-in-context fragments for the vectors.
+func (ts *TokenStream) Snippets(vectors *Vectors, cfg *SnippetConfig, tagLabel, emClass string) []string {
+	fragments := ts.Fragments(vectors, cfg, tagLabel, emClass)
+
+	snippets := make([]string, 0, len(fragments))
+	for _, f := range fragments {
+		snippets = append(snippets, f.Text)
+	}
+
+	return snippets
+}
+
+// Fragments returns the best scoring fragments for the vectors in document order.
+// Fragments are scored by the number of hits they contain.
+func (ts *TokenStream) Fragments(vectors *Vectors, cfg *SnippetConfig, tagLabel, emClass string) []Fragment {
+	if cfg == nil {
+		cfg = NewSnippetConfig()
+	}
+
+	hits := ts.hitPositions(vectors)
+	if len(hits) == 0 {
+		return []Fragment{}
+	}
+
+	if cfg.NumberOfFragments == 0 {
+		return []Fragment{
+			{
+				DocID: ts.tokens[0].DocID,
+				Hits:  len(hits),
+				Text:  ts.Highlight(vectors, tagLabel, emClass),
+			},
+		}
+	}
+
+	sizes := ts.prefixSizes()
+
+	windows := []window{}
+	for _, w := range clusterHits(hits, sizes, cfg.FragmentSize) {
+		windows = append(windows, ts.expandWindow(w, sizes, cfg))
+	}
+
+	fragments := []Fragment{}
+
+	for _, w := range mergeWindows(windows, cfg.MergeDistance) {
+		w = ts.trimWindow(w)
+		fragment := &TokenStream{tokens: ts.tokens[w.start : w.end+1]}
+
+		fragments = append(fragments, Fragment{
+			DocID:  ts.tokens[w.start].DocID,
+			Offset: ts.tokens[w.start].OffsetStart,
+			Hits:   w.hits,
+			Text:   fragment.Highlight(vectors, tagLabel, emClass),
+		})
+	}
+
+	return TopFragments(fragments, cfg.NumberOfFragments)
+}
+
+// TopFragments returns the n fragments with the most hits in document order.
+// All fragments are returned when n is 0.
+func TopFragments(fragments []Fragment, n int) []Fragment {
+	top := append([]Fragment{}, fragments...)
+
+	if n > 0 && len(top) > n {
+		sort.SliceStable(top, func(i, j int) bool {
+			return top[i].Hits > top[j].Hits
+		})
+
+		top = top[:n]
+	}
+
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].DocID != top[j].DocID {
+			return top[i].DocID < top[j].DocID
+		}
+
+		return top[i].Offset < top[j].Offset
+	})
+
+	return top
+}
+
+// hitPositions returns the indices of the tokens that are highlighted by the vectors.
+func (ts *TokenStream) hitPositions(vectors *Vectors) []int {
+	hits := []int{}
+
+	if vectors == nil {
+		return hits
+	}
+
+	for idx, token := range ts.tokens {
+		if token.isTermVector() && vectors.HasVector(token.GetTermVector()) {
+			hits = append(hits, idx)
+		}
+	}
+
+	return hits
+}
+
+// prefixSizes returns the cumulative size in characters of the tokens, so the
+// size of tokens[a:b] is sizes[b] - sizes[a].
+func (ts *TokenStream) prefixSizes() []int {
+	sizes := make([]int, len(ts.tokens)+1)
+
+	for idx, token := range ts.tokens {
+		size := utf8.RuneCountInString(token.RawText)
+		if token.TrailingSpace {
+			size++
+		}
+
+		sizes[idx+1] = sizes[idx] + size
+	}
+
+	return sizes
+}
+
+// clusterHits groups hits that fit together in a single fragment.
+func clusterHits(hits, sizes []int, fragmentSize int) []window {
+	clusters := []window{}
+	current := window{start: hits[0], end: hits[0], hits: 1}
+
+	for _, hit := range hits[1:] {
+		if sizes[hit+1]-sizes[current.start] <= fragmentSize {
+			current.end = hit
+			current.hits++
+
+			continue
+		}
+
+		clusters = append(clusters, current)
+		current = window{start: hit, end: hit, hits: 1}
+	}
+
+	return append(clusters, current)
+}
+
+// expandWindow adds context on both sides of the window until the fragment size
+// is reached. When the config is sentence aware the window does not expand
+// beyond the sentence the hits are in.
+func (ts *TokenStream) expandWindow(w window, sizes []int, cfg *SnippetConfig) window {
+	canLeft, canRight := true, true
+
+	for canLeft || canRight {
+		if canLeft {
+			canLeft = w.start > 0 &&
+				!(cfg.SentenceAware && isSentenceEnd(ts.tokens[w.start-1])) &&
+				sizes[w.end+1]-sizes[w.start-1] <= cfg.FragmentSize
+
+			if canLeft {
+				w.start--
+			}
+		}
+
+		if canRight {
+			canRight = w.end < len(ts.tokens)-1 &&
+				!(cfg.SentenceAware && isSentenceEnd(ts.tokens[w.end])) &&
+				sizes[w.end+2]-sizes[w.start] <= cfg.FragmentSize
+
+			if canRight {
+				w.end++
+			}
+		}
+	}
+
+	return w
+}
+
+// trimWindow removes leading punctuation and dangling tags from the window.
+func (ts *TokenStream) trimWindow(w window) window {
+	for w.start < w.end && ts.tokens[w.start].Ignored {
+		w.start++
+	}
+
+	for w.end > w.start && ts.tokens[w.end].Ignored && !ts.tokens[w.end].Punctuation {
+		w.end--
+	}
+
+	return w
+}
+
+// mergeWindows merges windows that overlap or are within distance tokens of each other.
+func mergeWindows(windows []window, distance int) []window {
+	merged := []window{}
+
+	for _, w := range windows {
+		if n := len(merged); n > 0 && w.start-merged[n-1].end-1 <= distance {
+			last := &merged[n-1]
+			if w.end > last.end {
+				last.end = w.end
+			}
+
+			last.hits += w.hits
+
+			continue
+		}
+
+		merged = append(merged, w)
+	}
+
+	return merged
+}
+
+// isSentenceEnd returns true when the token ends a sentence. Single letters
+// followed by a period are treated as initials and not as the end of a sentence.
+func isSentenceEnd(token Token) bool {
+	text := strings.TrimRight(token.RawText, "\"')]")
+	if !strings.HasSuffix(text, ".") && !strings.HasSuffix(text, "!") && !strings.HasSuffix(text, "?") {
+		return false
+	}
+
+	return token.Punctuation || utf8.RuneCountInString(text) > 2
+}
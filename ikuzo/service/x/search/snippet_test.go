@@ -0,0 +1,178 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const snippetText = "In 1850 verhuisde de familie Jansen naar Leiden. " +
+	"Daar werkte Pieter als timmerman bij de firma Smit en Zonen. " +
+	"In 1870 vertrok Jansen met zijn gezin naar Amsterdam, waar hij overleed. " +
+	"Zijn zoon Jan Jansen nam de zaak over."
+
+func snippetVectors(ts *TokenStream, terms ...string) *Vectors {
+	tv := NewVectors()
+
+	for _, token := range ts.Tokens() {
+		for _, term := range terms {
+			if token.Normal == term {
+				tv.AddVector(token.GetTermVector())
+			}
+		}
+	}
+
+	return tv
+}
+
+func TestTokenStream_Snippets(t *testing.T) {
+	type args struct {
+		text  string
+		terms []string
+		cfg   *SnippetConfig
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			"no hits",
+			args{snippetText, []string{"rotterdam"}, NewSnippetConfig()},
+			[]string{},
+		},
+		{
+			"default config",
+			args{snippetText, []string{"jansen"}, nil},
+			[]string{
+				"In 1850 verhuisde de familie <em>Jansen</em> naar Leiden. Daar werkte Pieter als timmerman bij de firma Smit",
+				"1870 vertrok <em>Jansen</em> met zijn gezin naar Amsterdam, waar hij overleed. Zijn zoon Jan <em>Jansen</em> nam de",
+			},
+		},
+		{
+			"fragment size",
+			args{snippetText, []string{"jansen"}, NewSnippetConfig(SetFragmentSize(40))},
+			[]string{
+				"de familie <em>Jansen</em> naar Leiden. Daar",
+				"In 1870 vertrok <em>Jansen</em> met zijn gezin",
+				"Zijn zoon Jan <em>Jansen</em> nam de zaak over.",
+			},
+		},
+		{
+			"sentence aware",
+			args{snippetText, []string{"jansen"}, NewSnippetConfig(SetFragmentSize(40), SetSentenceAware())},
+			[]string{
+				"de familie <em>Jansen</em> naar Leiden.",
+				"In 1870 vertrok <em>Jansen</em> met zijn gezin",
+				"Zijn zoon Jan <em>Jansen</em> nam de zaak over.",
+			},
+		},
+		{
+			"number of fragments",
+			args{snippetText, []string{"jansen"}, NewSnippetConfig(SetFragmentSize(40), SetNumberOfFragments(2))},
+			[]string{
+				"de familie <em>Jansen</em> naar Leiden. Daar",
+				"In 1870 vertrok <em>Jansen</em> met zijn gezin",
+			},
+		},
+		{
+			"best scoring fragments in document order",
+			args{snippetText, []string{"jansen", "zoon"}, NewSnippetConfig(SetFragmentSize(40), SetNumberOfFragments(1))},
+			[]string{
+				"overleed. Zijn <em>zoon</em> Jan <em>Jansen</em> nam de",
+			},
+		},
+		{
+			"merge nearby hits",
+			args{"Leiden en Delft en Gouda", []string{"leiden", "gouda"}, NewSnippetConfig(SetFragmentSize(6), SetMergeDistance(3))},
+			[]string{
+				"<em>Leiden</em> en Delft en <em>Gouda</em>",
+			},
+		},
+		{
+			"do not merge distant hits",
+			args{"Leiden en Delft en Gouda", []string{"leiden", "gouda"}, NewSnippetConfig(SetFragmentSize(6))},
+			[]string{
+				"<em>Leiden</em>",
+				"<em>Gouda</em>",
+			},
+		},
+		{
+			"entire text without fragments",
+			args{"Leiden en Delft en Gouda", []string{"gouda"}, NewSnippetConfig(SetNumberOfFragments(0))},
+			[]string{
+				"Leiden en Delft en <em>Gouda</em>",
+			},
+		},
+		{
+			"skip leading punctuation and tags",
+			args{"Leiden, <lb/> Delft en Gouda", []string{"delft"}, NewSnippetConfig(SetFragmentSize(16))},
+			[]string{
+				"<em>Delft</em> en",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ts := NewTokenizer().ParseString(tt.args.text, 1)
+
+			got := ts.Snippets(snippetVectors(ts, tt.args.terms...), tt.args.cfg, "em", "")
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("TokenStream.Snippets() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestTopFragments(t *testing.T) {
+	fragments := []Fragment{
+		{DocID: 1, Offset: 0, Hits: 1, Text: "a"},
+		{DocID: 1, Offset: 10, Hits: 3, Text: "b"},
+		{DocID: 2, Offset: 0, Hits: 2, Text: "c"},
+		{DocID: 3, Offset: 0, Hits: 3, Text: "d"},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []string
+	}{
+		{"all fragments", 0, []string{"a", "b", "c", "d"}},
+		{"more than available", 10, []string{"a", "b", "c", "d"}},
+		{"top two", 2, []string{"b", "d"}},
+		{"top three", 3, []string{"b", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, f := range TopFragments(fragments, tt.n) {
+				got = append(got, f.Text)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("TopFragments() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package viewconfig manages the DataSetConfig of each dataset.
+//
+// A DataSetConfig configures the facets, sort orders and result fields of the
+// search results of a dataset and the ordering and labels of the fields in
+// the detail view of its records. The DataSetConfig with ID '_default' applies
+// to all datasets of the organization without a DataSetConfig of their own.
+package viewconfig
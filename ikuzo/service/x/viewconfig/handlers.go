@@ -0,0 +1,142 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// Routes returns the CRUD endpoints of the Service.
+//
+//	GET    /{orgID}       list the DataSetConfigs of the organization
+//	GET    /{orgID}/{id}  get a DataSetConfig
+//	PUT    /{orgID}/{id}  create or replace a DataSetConfig
+//	DELETE /{orgID}/{id}  delete a DataSetConfig
+func (s *Service) Routes() chi.Router {
+	router := chi.NewRouter()
+
+	router.Get("/{orgID}", s.handleList)
+	router.Get("/{orgID}/{id}", s.handleGet)
+	router.Put("/{orgID}/{id}", s.handlePut)
+	router.Delete("/{orgID}/{id}", s.handleDelete)
+
+	return router
+}
+
+func (s *Service) handleList(w http.ResponseWriter, r *http.Request) {
+	cfgs, err := s.List(r.Context(), chi.URLParam(r, "orgID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]json.RawMessage, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		b, err := marshalOptions.Marshal(cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		items = append(items, b)
+	}
+
+	render.JSON(w, r, items)
+}
+
+func (s *Service) handleGet(w http.ResponseWriter, r *http.Request) {
+	cfg, err := s.Get(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "id"))
+	if err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	renderConfig(w, r, http.StatusOK, cfg)
+}
+
+func (s *Service) handlePut(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cfg fragments.DataSetConfig
+	if err := protojson.Unmarshal(body, &cfg); err != nil {
+		renderError(w, r, &fragments.ValidationError{Errors: []string{err.Error()}})
+		return
+	}
+
+	// the path is leading for the identity of the config
+	cfg.OrgID = chi.URLParam(r, "orgID")
+	cfg.ID = chi.URLParam(r, "id")
+
+	if len(cfg.GetSpec()) == 0 && cfg.GetID() != fragments.DefaultDataSetConfigID {
+		cfg.Spec = []string{cfg.GetID()}
+	}
+
+	if err := s.Put(r.Context(), &cfg); err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	renderConfig(w, r, http.StatusOK, &cfg)
+}
+
+func (s *Service) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.Delete(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "id")); err != nil {
+		renderError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func renderConfig(w http.ResponseWriter, r *http.Request, status int, cfg *fragments.DataSetConfig) {
+	b, err := marshalOptions.Marshal(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, err error) {
+	var ve *fragments.ValidationError
+
+	switch {
+	case errors.As(err, &ve):
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, ve)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewconfig
+
+// Option is a closure to configure the Service.
+// It is used in NewService.
+type Option func(*Service) error
+
+// SetStore sets the Store where the DataSetConfigs are persisted.
+func SetStore(store Store) Option {
+	return func(s *Service) error {
+		s.store = store
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoStore is returned when the Service is created without a Store.
+var ErrNoStore = errors.New("viewconfig store is required")
+
+// Service manages the DataSetConfigs and resolves which DataSetConfig applies to a dataset.
+// The DataSetConfigs of an organization are cached after the first lookup.
+// It is safe for concurrent use by multiple goroutines.
+type Service struct {
+	store Store
+	rw    sync.RWMutex
+	cache map[string][]*fragments.DataSetConfig
+	// generations is incremented by each invalidation of an organization,
+	// so a List that raced with a Put or Delete is not cached.
+	generations map[string]uint64
+}
+
+// NewService creates a Service.
+func NewService(options ...Option) (*Service, error) {
+	s := &Service{
+		cache:       map[string][]*fragments.DataSetConfig{},
+		generations: map[string]uint64{},
+	}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.store == nil {
+		return nil, ErrNoStore
+	}
+
+	return s, nil
+}
+
+// Put validates and stores the DataSetConfig.
+// Validation errors are returned as *fragments.ValidationError.
+func (s *Service) Put(ctx context.Context, cfg *fragments.DataSetConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if err := s.store.Put(ctx, cfg); err != nil {
+		return fmt.Errorf("unable to store dataset config %s; %w", cfg.GetID(), err)
+	}
+
+	s.invalidate(cfg.GetOrgID())
+
+	return nil
+}
+
+// Get returns the DataSetConfig or ErrNotFound.
+func (s *Service) Get(ctx context.Context, orgID, id string) (*fragments.DataSetConfig, error) {
+	return s.store.Get(ctx, orgID, id)
+}
+
+// List returns all DataSetConfigs of the organization.
+func (s *Service) List(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error) {
+	return s.store.List(ctx, orgID)
+}
+
+// Delete removes the DataSetConfig or returns ErrNotFound.
+func (s *Service) Delete(ctx context.Context, orgID, id string) error {
+	if err := s.store.Delete(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	s.invalidate(orgID)
+
+	return nil
+}
+
+// Lookup returns the DataSetConfig that applies to the dataset.
+//
+// The DataSetConfig with the spec as ID takes precedence over a DataSetConfig
+// that lists the spec. When neither exists the '_default' DataSetConfig of the
+// organization is returned. An empty spec only matches the '_default' config.
+func (s *Service) Lookup(ctx context.Context, orgID, spec string) (*fragments.DataSetConfig, bool) {
+	cfgs, err := s.orgConfigs(ctx, orgID)
+	if err != nil {
+		log.Error().Err(err).Str("orgID", orgID).Msg("unable to load dataset configs")
+		return nil, false
+	}
+
+	var listed, fallback *fragments.DataSetConfig
+
+	for _, cfg := range cfgs {
+		switch {
+		case !cfg.AppliesTo(spec):
+			continue
+		case spec != "" && cfg.GetID() == spec:
+			return cfg, true
+		case cfg.GetID() == fragments.DefaultDataSetConfigID:
+			fallback = cfg
+		case spec != "" && listed == nil:
+			listed = cfg
+		}
+	}
+
+	if listed != nil {
+		return listed, true
+	}
+
+	return fallback, fallback != nil
+}
+
+// Shutdown closes the Store.
+func (s *Service) Shutdown(ctx context.Context) error {
+	return s.store.Close()
+}
+
+func (s *Service) orgConfigs(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error) {
+	s.rw.RLock()
+	cfgs, ok := s.cache[orgID]
+	generation := s.generations[orgID]
+	s.rw.RUnlock()
+
+	if ok {
+		return cfgs, nil
+	}
+
+	cfgs, err := s.store.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.rw.Lock()
+	if s.generations[orgID] == generation {
+		s.cache[orgID] = cfgs
+	}
+	s.rw.Unlock()
+
+	return cfgs, nil
+}
+
+func (s *Service) invalidate(orgID string) {
+	s.rw.Lock()
+	delete(s.cache, orgID)
+	s.generations[orgID]++
+	s.rw.Unlock()
+}
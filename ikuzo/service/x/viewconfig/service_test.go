@@ -0,0 +1,227 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewconfig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/matryer/is"
+)
+
+// memoryStore is an in-memory Store for testing.
+type memoryStore struct {
+	sync.Mutex
+	cfgs map[string]*fragments.DataSetConfig
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{cfgs: map[string]*fragments.DataSetConfig{}}
+}
+
+func (m *memoryStore) Put(ctx context.Context, cfg *fragments.DataSetConfig) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.cfgs[cfg.GetOrgID()+"/"+cfg.GetID()] = cfg
+
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, orgID, id string) (*fragments.DataSetConfig, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	cfg, ok := m.cfgs[orgID+"/"+id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return cfg, nil
+}
+
+func (m *memoryStore) List(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	cfgs := []*fragments.DataSetConfig{}
+
+	for _, cfg := range m.cfgs {
+		if cfg.GetOrgID() == orgID {
+			cfgs = append(cfgs, cfg)
+		}
+	}
+
+	sort.Slice(cfgs, func(i, j int) bool { return cfgs[i].GetID() < cfgs[j].GetID() })
+
+	return cfgs, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, orgID, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.cfgs[orgID+"/"+id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.cfgs, orgID+"/"+id)
+
+	return nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+func TestNewService(t *testing.T) {
+	is := is.New(t)
+
+	_, err := NewService()
+	is.True(errors.Is(err, ErrNoStore))
+}
+
+func TestService_Lookup(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetStore(newMemoryStore()))
+	is.NoErr(err)
+
+	ctx := context.Background()
+
+	is.NoErr(svc.Put(ctx, &fragments.DataSetConfig{ID: "_default", OrgID: "hub3", ExcludeSpec: []string{"spec4"}}))
+	is.NoErr(svc.Put(ctx, &fragments.DataSetConfig{ID: "collection", OrgID: "hub3", Spec: []string{"spec1", "spec2"}}))
+
+	// cache the configs of the organization
+	cfg, ok := svc.Lookup(ctx, "hub3", "spec1")
+	is.True(ok)
+	is.Equal(cfg.GetID(), "collection")
+
+	// put invalidates the cache
+	is.NoErr(svc.Put(ctx, &fragments.DataSetConfig{ID: "spec1", OrgID: "hub3"}))
+
+	tests := []struct {
+		spec   string
+		wantID string
+		wantOK bool
+	}{
+		{"spec1", "spec1", true},
+		{"spec2", "collection", true},
+		{"spec3", "_default", true},
+		{"spec4", "", false},
+		{"", "_default", true},
+	}
+
+	for _, tt := range tests {
+		cfg, ok := svc.Lookup(ctx, "hub3", tt.spec)
+		is.Equal(ok, tt.wantOK)
+		is.Equal(cfg.GetID(), tt.wantID)
+	}
+
+	_, ok = svc.Lookup(ctx, "other", "spec1")
+	is.True(!ok)
+
+	is.NoErr(svc.Delete(ctx, "hub3", "spec1"))
+
+	cfg, ok = svc.Lookup(ctx, "hub3", "spec1")
+	is.True(ok)
+	is.Equal(cfg.GetID(), "collection")
+}
+
+// racingStore runs onList after reading the configs, to simulate a Put that
+// races with a List.
+type racingStore struct {
+	*memoryStore
+	onList func()
+}
+
+func (r *racingStore) List(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error) {
+	cfgs, err := r.memoryStore.List(ctx, orgID)
+
+	if r.onList != nil {
+		onList := r.onList
+		r.onList = nil
+		onList()
+	}
+
+	return cfgs, err
+}
+
+func TestService_LookupInvalidateDuringList(t *testing.T) {
+	is := is.New(t)
+
+	store := &racingStore{memoryStore: newMemoryStore()}
+
+	svc, err := NewService(SetStore(store))
+	is.NoErr(err)
+
+	ctx := context.Background()
+
+	is.NoErr(svc.Put(ctx, &fragments.DataSetConfig{ID: "_default", OrgID: "hub3"}))
+
+	store.onList = func() {
+		is.NoErr(svc.Put(ctx, &fragments.DataSetConfig{ID: "spec1", OrgID: "hub3"}))
+	}
+
+	// the stale list is returned, but not cached
+	cfg, ok := svc.Lookup(ctx, "hub3", "spec1")
+	is.True(ok)
+	is.Equal(cfg.GetID(), "_default")
+
+	cfg, ok = svc.Lookup(ctx, "hub3", "spec1")
+	is.True(ok)
+	is.Equal(cfg.GetID(), "spec1")
+}
+
+func TestService_handlePut(t *testing.T) {
+	is := is.New(t)
+
+	svc, err := NewService(SetStore(newMemoryStore()))
+	is.NoErr(err)
+
+	router := svc.Routes()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/hub3/spec1", strings.NewReader(`{"facets": [{"field": ""}]}`))
+	router.ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusBadRequest)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/hub3/spec1", strings.NewReader(`{"title": "Spec 1", "facets": [{"field": "dc_subject"}]}`))
+	router.ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusOK)
+
+	cfg, err := svc.Get(context.Background(), "hub3", "spec1")
+	is.NoErr(err)
+	is.Equal(cfg.GetTitle(), "Spec 1")
+	is.Equal(cfg.GetSpec(), []string{"spec1"})
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodDelete, "/hub3/spec1", nil)
+	router.ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusNoContent)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/hub3/spec1", nil)
+	router.ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusNotFound)
+}
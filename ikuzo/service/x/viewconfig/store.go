@@ -0,0 +1,39 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package viewconfig
+
+import (
+	"context"
+	"errors"
+
+	"github.com/delving/hub3/hub3/fragments"
+)
+
+// ErrNotFound is returned when the DataSetConfig does not exist.
+var ErrNotFound = errors.New("dataset config not found")
+
+// Store persists the DataSetConfigs.
+type Store interface {
+	// Put stores the DataSetConfig. An existing config with the same orgID and ID is replaced.
+	Put(ctx context.Context, cfg *fragments.DataSetConfig) error
+	// Get returns the DataSetConfig or ErrNotFound.
+	Get(ctx context.Context, orgID, id string) (*fragments.DataSetConfig, error)
+	// List returns all DataSetConfigs of the organization ordered by ID.
+	List(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error)
+	// Delete removes the DataSetConfig or returns ErrNotFound.
+	Delete(ctx context.Context, orgID, id string) error
+	// Close closes the Store.
+	Close() error
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/analytics"
+	bolt "go.etcd.io/bbolt"
+)
+
+var analyticsBucket = []byte("analytics")
+
+// AnalyticsStore is an embedded analytics.Store.
+//
+// Events are stored in a bucket per organization. The keys start with the
+// big-endian timestamp of the event so that time windows are range scans.
+type AnalyticsStore struct {
+	db *bolt.DB
+}
+
+var _ analytics.Store = (*AnalyticsStore)(nil)
+
+// NewAnalyticsStore opens or creates the AnalyticsStore at path.
+func NewAnalyticsStore(path string) (*AnalyticsStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create analytics directory; %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open analytics store %s; %w", path, err)
+	}
+
+	return &AnalyticsStore{db: db}, nil
+}
+
+// timeKey returns the key prefix for t.
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+
+	return key
+}
+
+// Put stores the events in a single transaction.
+func (s *AnalyticsStore) Put(ctx context.Context, events ...*analytics.Event) error {
+	values := make([][]byte, 0, len(events))
+
+	for _, e := range events {
+		v, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("unable to marshal event; %w", err)
+		}
+
+		values = append(values, v)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(analyticsBucket)
+		if err != nil {
+			return err
+		}
+
+		for idx, e := range events {
+			b, err := root.CreateBucketIfNotExists([]byte(e.OrgID))
+			if err != nil {
+				return fmt.Errorf("unable to create bucket for %s; %w", e.OrgID, err)
+			}
+
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			// the sequence makes keys of events with the same timestamp unique
+			key := make([]byte, 16)
+			copy(key, timeKey(e.Time))
+			binary.BigEndian.PutUint64(key[8:], seq)
+
+			if err := b.Put(key, values[idx]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Scan calls fn for each Event of the organization between from and until.
+func (s *AnalyticsStore) Scan(ctx context.Context, orgID string, from, until time.Time, fn func(e *analytics.Event) error) error {
+	end := timeKey(until)
+
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := orgBucket(tx, orgID)
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k[:8], end) < 0; k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			var e analytics.Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("unable to unmarshal event; %w", err)
+			}
+
+			if err := fn(&e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete removes the events of the organization recorded before t.
+func (s *AnalyticsStore) Delete(ctx context.Context, orgID string, before time.Time) (int, error) {
+	end := timeKey(before)
+
+	var removed int
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := orgBucket(tx, orgID)
+		if b == nil {
+			return nil
+		}
+
+		// collect the keys first; deleting while iterating skips keys
+		var keys [][]byte
+
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], end) < 0; k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		removed = len(keys)
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// OrgIDs returns the organizations that have recorded events.
+func (s *AnalyticsStore) OrgIDs(ctx context.Context) ([]string, error) {
+	var orgIDs []string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket(analyticsBucket)
+		if root == nil {
+			return nil
+		}
+
+		return root.ForEach(func(k, v []byte) error {
+			// nested buckets have a nil value
+			if v == nil {
+				orgIDs = append(orgIDs, string(k))
+			}
+
+			return nil
+		})
+	})
+
+	return orgIDs, err
+}
+
+// Close closes the underlying database.
+func (s *AnalyticsStore) Close() error {
+	return s.db.Close()
+}
+
+func orgBucket(tx *bolt.Tx, orgID string) *bolt.Bucket {
+	root := tx.Bucket(analyticsBucket)
+	if root == nil {
+		return nil
+	}
+
+	return root.Bucket([]byte(orgID))
+}
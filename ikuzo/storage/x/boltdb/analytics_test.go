@@ -0,0 +1,84 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/analytics"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func TestAnalyticsStore(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "analytics")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewAnalyticsStore(filepath.Join(dir, "db", "analytics.db"))
+	is.NoErr(err)
+
+	ctx := context.Background()
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	events := []*analytics.Event{
+		{OrgID: "hub3", Query: "old", Time: now.Add(-48 * time.Hour)},
+		{OrgID: "hub3", Query: "rembrandt", Hits: 10, Filters: []string{"dc_type:painting"}, Time: now.Add(-time.Hour)},
+		// same timestamp must not overwrite the previous event
+		{OrgID: "hub3", Query: "vermeer", Time: now.Add(-time.Hour)},
+		{OrgID: "other", Query: "rembrandt", Time: now.Add(-time.Hour)},
+	}
+
+	for _, e := range events {
+		is.NoErr(store.Put(ctx, e))
+	}
+
+	scan := func(orgID string, from, until time.Time) []*analytics.Event {
+		var got []*analytics.Event
+
+		is.NoErr(store.Scan(ctx, orgID, from, until, func(e *analytics.Event) error {
+			got = append(got, e)
+			return nil
+		}))
+
+		return got
+	}
+
+	got := scan("hub3", now.Add(-24*time.Hour), now)
+	if diff := cmp.Diff(events[1:3], got); diff != "" {
+		t.Errorf("Scan() mismatch (-want +got):\n%s", diff)
+	}
+
+	is.Equal(len(scan("unknown", now.Add(-24*time.Hour), now)), 0)
+
+	orgIDs, err := store.OrgIDs(ctx)
+	is.NoErr(err)
+	is.Equal(orgIDs, []string{"hub3", "other"})
+
+	removed, err := store.Delete(ctx, "hub3", now.Add(-24*time.Hour))
+	is.NoErr(err)
+	is.Equal(removed, 1)
+	is.Equal(len(scan("hub3", now.Add(-72*time.Hour), now)), 2)
+
+	is.NoErr(store.Close())
+}
@@ -0,0 +1,290 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/bulk"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	contentHashBucket     = []byte("contenthashes")
+	contentRevisionBucket = []byte("contentrevisions")
+)
+
+// ContentHashStore is an embedded bulk.ContentHashStore.
+//
+// The content hashes are stored in a bucket per dataset, nested in a bucket
+// per organization, with the hubID as key. The revisions of the records are
+// stored in the same layout in a separate bucket.
+type ContentHashStore struct {
+	db *bolt.DB
+}
+
+var _ bulk.ContentHashStore = (*ContentHashStore)(nil)
+
+// NewContentHashStore opens or creates the ContentHashStore at path.
+func NewContentHashStore(path string) (*ContentHashStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create content hash directory; %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open content hash store %s; %w", path, err)
+	}
+
+	return &ContentHashStore{db: db}, nil
+}
+
+// Get returns the content hash of the record or an empty string when the record is unknown.
+func (s *ContentHashStore) Get(ctx context.Context, orgID, datasetID, hubID string) (string, error) {
+	var hash string
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := datasetBucket(tx, contentHashBucket, orgID, datasetID)
+		if b == nil {
+			return nil
+		}
+
+		hash = string(b.Get([]byte(hubID)))
+
+		return nil
+	})
+
+	return hash, err
+}
+
+// Put stores the content hashes of the dataset in a single transaction. An
+// empty hash removes the content hash of the record.
+func (s *ContentHashStore) Put(ctx context.Context, orgID, datasetID string, hashes map[string]string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := createDatasetBucket(tx, contentHashBucket, orgID, datasetID)
+		if err != nil {
+			return err
+		}
+
+		for hubID, hash := range hashes {
+			if hash == "" {
+				if err := b.Delete([]byte(hubID)); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if err := b.Put([]byte(hubID), []byte(hash)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// PutRevision stores the revision of the dataset in which the records were
+// received. Skipped records were unchanged and not written to the index.
+func (s *ContentHashStore) PutRevision(ctx context.Context, orgID, datasetID string, revision int, records map[string]bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := createDatasetBucket(tx, contentRevisionBucket, orgID, datasetID)
+		if err != nil {
+			return err
+		}
+
+		for hubID, skipped := range records {
+			if err := b.Put([]byte(hubID), encodeRevision(revision, skipped)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ClearOrphans removes the content hashes and revisions of the records that
+// were not received in the revision and returns the hubIDs of the records
+// that were skipped in the revision.
+func (s *ContentHashStore) ClearOrphans(ctx context.Context, orgID, datasetID string, revision int) ([]string, error) {
+	skipped := []string{}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		current := map[string]bool{}
+
+		if b := datasetBucket(tx, contentRevisionBucket, orgID, datasetID); b != nil {
+			orphans := [][]byte{}
+
+			err := b.ForEach(func(k, v []byte) error {
+				rev, isSkipped := decodeRevision(v)
+				if rev != revision {
+					orphans = append(orphans, k)
+					return nil
+				}
+
+				current[string(k)] = true
+
+				if isSkipped {
+					skipped = append(skipped, string(k))
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			for _, k := range orphans {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+
+		b := datasetBucket(tx, contentHashBucket, orgID, datasetID)
+		if b == nil {
+			return nil
+		}
+
+		orphans := [][]byte{}
+
+		err := b.ForEach(func(k, v []byte) error {
+			if !current[string(k)] {
+				orphans = append(orphans, k)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range orphans {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return skipped, nil
+}
+
+// Delete removes all content hashes and revisions of the dataset.
+func (s *ContentHashStore) Delete(ctx context.Context, orgID, datasetID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{contentHashBucket, contentRevisionBucket} {
+			root := tx.Bucket(name)
+			if root == nil {
+				continue
+			}
+
+			org := root.Bucket([]byte(orgID))
+			if org == nil || org.Bucket([]byte(datasetID)) == nil {
+				continue
+			}
+
+			if err := org.DeleteBucket([]byte(datasetID)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeleteAll removes the content hashes and revisions of all datasets.
+func (s *ContentHashStore) DeleteAll(ctx context.Context) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{contentHashBucket, contentRevisionBucket} {
+			if tx.Bucket(name) == nil {
+				continue
+			}
+
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Close closes the underlying database.
+func (s *ContentHashStore) Close() error {
+	return s.db.Close()
+}
+
+func datasetBucket(tx *bolt.Tx, name []byte, orgID, datasetID string) *bolt.Bucket {
+	root := tx.Bucket(name)
+	if root == nil {
+		return nil
+	}
+
+	org := root.Bucket([]byte(orgID))
+	if org == nil {
+		return nil
+	}
+
+	return org.Bucket([]byte(datasetID))
+}
+
+func createDatasetBucket(tx *bolt.Tx, name []byte, orgID, datasetID string) (*bolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := root.CreateBucketIfNotExists([]byte(orgID))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create bucket for %s; %w", orgID, err)
+	}
+
+	b, err := org.CreateBucketIfNotExists([]byte(datasetID))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create bucket for %s/%s; %w", orgID, datasetID, err)
+	}
+
+	return b, nil
+}
+
+// encodeRevision encodes the revision followed by a byte that marks skipped records.
+func encodeRevision(revision int, skipped bool) []byte {
+	v := make([]byte, 9)
+	binary.BigEndian.PutUint64(v, uint64(revision))
+
+	if skipped {
+		v[8] = 1
+	}
+
+	return v
+}
+
+func decodeRevision(v []byte) (revision int, skipped bool) {
+	if len(v) != 9 {
+		return -1, false
+	}
+
+	return int(binary.BigEndian.Uint64(v)), v[8] == 1
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matryer/is"
+)
+
+func TestContentHashStore(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "contenthash")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewContentHashStore(filepath.Join(dir, "db", "contenthash.db"))
+	is.NoErr(err)
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	hash, err := store.Get(ctx, "hub3", "spec1", "hub3_spec1_1")
+	is.NoErr(err)
+	is.Equal(hash, "")
+
+	is.NoErr(store.Put(ctx, "hub3", "spec1", map[string]string{"hub3_spec1_1": "abc", "hub3_spec1_2": "def"}))
+	is.NoErr(store.Put(ctx, "hub3", "spec2", map[string]string{"hub3_spec1_1": "ghi"}))
+	is.NoErr(store.Put(ctx, "hub3", "spec1", map[string]string{"hub3_spec1_1": "xyz", "hub3_spec1_3": "uvw"}))
+
+	hash, err = store.Get(ctx, "hub3", "spec1", "hub3_spec1_1")
+	is.NoErr(err)
+	is.Equal(hash, "xyz")
+
+	// an empty hash removes the record
+	is.NoErr(store.Put(ctx, "hub3", "spec1", map[string]string{"hub3_spec1_3": ""}))
+
+	hash, err = store.Get(ctx, "hub3", "spec1", "hub3_spec1_3")
+	is.NoErr(err)
+	is.Equal(hash, "")
+
+	is.NoErr(store.Delete(ctx, "hub3", "spec1"))
+	is.NoErr(store.Delete(ctx, "unknown", "spec1"))
+
+	hash, err = store.Get(ctx, "hub3", "spec1", "hub3_spec1_2")
+	is.NoErr(err)
+	is.Equal(hash, "")
+
+	hash, err = store.Get(ctx, "hub3", "spec2", "hub3_spec1_1")
+	is.NoErr(err)
+	is.Equal(hash, "ghi")
+
+	is.NoErr(store.DeleteAll(ctx))
+	is.NoErr(store.DeleteAll(ctx))
+
+	hash, err = store.Get(ctx, "hub3", "spec2", "hub3_spec1_1")
+	is.NoErr(err)
+	is.Equal(hash, "")
+}
+
+func TestContentHashStore_ClearOrphans(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "contenthash")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewContentHashStore(filepath.Join(dir, "contenthash.db"))
+	is.NoErr(err)
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	is.NoErr(store.Put(ctx, "hub3", "spec1", map[string]string{"1": "abc", "2": "def", "3": "ghi", "4": "jkl"}))
+	is.NoErr(store.PutRevision(ctx, "hub3", "spec1", 1, map[string]bool{"1": false, "2": false, "3": false, "4": false}))
+
+	// 1 is skipped, 2 is indexed again and 3 and 4 are orphans
+	is.NoErr(store.PutRevision(ctx, "hub3", "spec1", 2, map[string]bool{"1": true, "2": false}))
+
+	skipped, err := store.ClearOrphans(ctx, "hub3", "spec1", 2)
+	is.NoErr(err)
+	is.Equal(skipped, []string{"1"})
+
+	for hubID, want := range map[string]string{"1": "abc", "2": "def", "3": "", "4": ""} {
+		hash, err := store.Get(ctx, "hub3", "spec1", hubID)
+		is.NoErr(err)
+		is.Equal(hash, want)
+	}
+
+	skipped, err = store.ClearOrphans(ctx, "hub3", "unknown", 2)
+	is.NoErr(err)
+	is.Equal(len(skipped), 0)
+
+	// the revisions are removed with the content hashes of the dataset
+	is.NoErr(store.Delete(ctx, "hub3", "spec1"))
+
+	skipped, err = store.ClearOrphans(ctx, "hub3", "spec1", 2)
+	is.NoErr(err)
+	is.Equal(len(skipped), 0)
+}
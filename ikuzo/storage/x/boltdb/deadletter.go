@@ -0,0 +1,193 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/delving/hub3/ikuzo/service/x/index"
+	bolt "go.etcd.io/bbolt"
+)
+
+var deadLetterBucket = []byte("deadletters")
+
+// DeadLetterStore is an embedded index.DeadLetterStore.
+//
+// The DeadLetters are stored as JSON in a bucket per dataset, nested in a
+// bucket per organization, with the ID of the DeadLetter as key.
+type DeadLetterStore struct {
+	db *bolt.DB
+}
+
+var _ index.DeadLetterStore = (*DeadLetterStore)(nil)
+
+// NewDeadLetterStore opens or creates the DeadLetterStore at path.
+func NewDeadLetterStore(path string) (*DeadLetterStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create deadletter directory; %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open deadletter store %s; %w", path, err)
+	}
+
+	return &DeadLetterStore{db: db}, nil
+}
+
+// Put stores the DeadLetter.
+func (s *DeadLetterStore) Put(ctx context.Context, dl *index.DeadLetter) error {
+	v, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("unable to marshal dead letter; %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(deadLetterBucket)
+		if err != nil {
+			return err
+		}
+
+		org, err := root.CreateBucketIfNotExists([]byte(dl.OrgID))
+		if err != nil {
+			return fmt.Errorf("unable to create bucket for %s; %w", dl.OrgID, err)
+		}
+
+		b, err := org.CreateBucketIfNotExists([]byte(dl.DatasetID))
+		if err != nil {
+			return fmt.Errorf("unable to create bucket for %s/%s; %w", dl.OrgID, dl.DatasetID, err)
+		}
+
+		return b.Put([]byte(dl.ID), v)
+	})
+}
+
+// Get returns the DeadLetter or index.ErrDeadLetterNotFound.
+func (s *DeadLetterStore) Get(ctx context.Context, orgID, datasetID, id string) (*index.DeadLetter, error) {
+	var dl *index.DeadLetter
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := deadLetterDatasetBucket(tx, orgID, datasetID)
+		if b == nil {
+			return index.ErrDeadLetterNotFound
+		}
+
+		v := b.Get([]byte(id))
+		if v == nil {
+			return index.ErrDeadLetterNotFound
+		}
+
+		var err error
+
+		dl, err = unmarshalDeadLetter(v)
+
+		return err
+	})
+
+	return dl, err
+}
+
+// List returns the DeadLetters of the organization or dataset ordered by dataset and ID.
+func (s *DeadLetterStore) List(ctx context.Context, orgID, datasetID string) ([]*index.DeadLetter, error) {
+	dls := []*index.DeadLetter{}
+
+	appendBucket := func(b *bolt.Bucket) error {
+		return b.ForEach(func(k, v []byte) error {
+			dl, err := unmarshalDeadLetter(v)
+			if err != nil {
+				return err
+			}
+
+			dls = append(dls, dl)
+
+			return nil
+		})
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if datasetID != "" {
+			b := deadLetterDatasetBucket(tx, orgID, datasetID)
+			if b == nil {
+				return nil
+			}
+
+			return appendBucket(b)
+		}
+
+		org := deadLetterOrgBucket(tx, orgID)
+		if org == nil {
+			return nil
+		}
+
+		return org.ForEach(func(k, v []byte) error {
+			if b := org.Bucket(k); b != nil {
+				return appendBucket(b)
+			}
+
+			return nil
+		})
+	})
+
+	return dls, err
+}
+
+// Delete removes the DeadLetter or returns index.ErrDeadLetterNotFound.
+func (s *DeadLetterStore) Delete(ctx context.Context, orgID, datasetID, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := deadLetterDatasetBucket(tx, orgID, datasetID)
+		if b == nil || b.Get([]byte(id)) == nil {
+			return index.ErrDeadLetterNotFound
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// Close closes the underlying database.
+func (s *DeadLetterStore) Close() error {
+	return s.db.Close()
+}
+
+func deadLetterOrgBucket(tx *bolt.Tx, orgID string) *bolt.Bucket {
+	root := tx.Bucket(deadLetterBucket)
+	if root == nil {
+		return nil
+	}
+
+	return root.Bucket([]byte(orgID))
+}
+
+func deadLetterDatasetBucket(tx *bolt.Tx, orgID, datasetID string) *bolt.Bucket {
+	org := deadLetterOrgBucket(tx, orgID)
+	if org == nil {
+		return nil
+	}
+
+	return org.Bucket([]byte(datasetID))
+}
+
+func unmarshalDeadLetter(v []byte) (*index.DeadLetter, error) {
+	var dl index.DeadLetter
+	if err := json.Unmarshal(v, &dl); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal dead letter; %w", err)
+	}
+
+	return &dl, nil
+}
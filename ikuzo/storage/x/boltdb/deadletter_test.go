@@ -0,0 +1,84 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/index"
+	"github.com/matryer/is"
+)
+
+func TestDeadLetterStore(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "deadletter")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewDeadLetterStore(filepath.Join(dir, "db", "deadletter.db"))
+	is.NoErr(err)
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	dl := &index.DeadLetter{
+		ID:        "1",
+		OrgID:     "hub3",
+		DatasetID: "spec1",
+		RecordID:  "hub3_spec1_1",
+		ErrorType: "mapper_parsing_exception",
+		Reason:    "failed to parse field [meta.revision]",
+		Attempts:  1,
+		Message:   []byte{0x0a, 0x04},
+	}
+
+	_, err = store.Get(ctx, "hub3", "spec1", "1")
+	is.True(errors.Is(err, index.ErrDeadLetterNotFound))
+
+	is.NoErr(store.Put(ctx, dl))
+	is.NoErr(store.Put(ctx, &index.DeadLetter{ID: "2", OrgID: "hub3", DatasetID: "spec2"}))
+	is.NoErr(store.Put(ctx, &index.DeadLetter{ID: "1", OrgID: "other", DatasetID: "spec1"}))
+
+	got, err := store.Get(ctx, "hub3", "spec1", "1")
+	is.NoErr(err)
+	is.Equal(got, dl)
+
+	dls, err := store.List(ctx, "hub3", "")
+	is.NoErr(err)
+	is.Equal(len(dls), 2)
+	is.Equal(dls[1].DatasetID, "spec2")
+
+	dls, err = store.List(ctx, "hub3", "spec1")
+	is.NoErr(err)
+	is.Equal(len(dls), 1)
+
+	dls, err = store.List(ctx, "unknown", "")
+	is.NoErr(err)
+	is.Equal(len(dls), 0)
+
+	is.NoErr(store.Delete(ctx, "hub3", "spec1", "1"))
+	is.True(errors.Is(store.Delete(ctx, "hub3", "spec1", "1"), index.ErrDeadLetterNotFound))
+
+	_, err = store.Get(ctx, "other", "spec1", "1")
+	is.NoErr(err)
+}
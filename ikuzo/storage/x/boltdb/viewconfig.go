@@ -0,0 +1,161 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/viewconfig"
+	proto "github.com/golang/protobuf/proto"
+	bolt "go.etcd.io/bbolt"
+)
+
+var viewConfigBucket = []byte("viewconfig")
+
+// ViewConfigStore is an embedded viewconfig.Store.
+//
+// The DataSetConfigs are stored as protobuf in a bucket per organization
+// with the ID of the config as key.
+type ViewConfigStore struct {
+	db *bolt.DB
+}
+
+var _ viewconfig.Store = (*ViewConfigStore)(nil)
+
+// NewViewConfigStore opens or creates the ViewConfigStore at path.
+func NewViewConfigStore(path string) (*ViewConfigStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("unable to create viewconfig directory; %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open viewconfig store %s; %w", path, err)
+	}
+
+	return &ViewConfigStore{db: db}, nil
+}
+
+// Put stores the DataSetConfig.
+func (s *ViewConfigStore) Put(ctx context.Context, cfg *fragments.DataSetConfig) error {
+	v, err := proto.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal dataset config; %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(viewConfigBucket)
+		if err != nil {
+			return err
+		}
+
+		b, err := root.CreateBucketIfNotExists([]byte(cfg.GetOrgID()))
+		if err != nil {
+			return fmt.Errorf("unable to create bucket for %s; %w", cfg.GetOrgID(), err)
+		}
+
+		return b.Put([]byte(cfg.GetID()), v)
+	})
+}
+
+// Get returns the DataSetConfig or viewconfig.ErrNotFound.
+func (s *ViewConfigStore) Get(ctx context.Context, orgID, id string) (*fragments.DataSetConfig, error) {
+	var cfg *fragments.DataSetConfig
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := viewConfigOrgBucket(tx, orgID)
+		if b == nil {
+			return viewconfig.ErrNotFound
+		}
+
+		v := b.Get([]byte(id))
+		if v == nil {
+			return viewconfig.ErrNotFound
+		}
+
+		var err error
+
+		cfg, err = unmarshalDataSetConfig(v)
+
+		return err
+	})
+
+	return cfg, err
+}
+
+// List returns the DataSetConfigs of the organization ordered by ID.
+func (s *ViewConfigStore) List(ctx context.Context, orgID string) ([]*fragments.DataSetConfig, error) {
+	cfgs := []*fragments.DataSetConfig{}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := viewConfigOrgBucket(tx, orgID)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			cfg, err := unmarshalDataSetConfig(v)
+			if err != nil {
+				return err
+			}
+
+			cfgs = append(cfgs, cfg)
+
+			return nil
+		})
+	})
+
+	return cfgs, err
+}
+
+// Delete removes the DataSetConfig or returns viewconfig.ErrNotFound.
+func (s *ViewConfigStore) Delete(ctx context.Context, orgID, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := viewConfigOrgBucket(tx, orgID)
+		if b == nil || b.Get([]byte(id)) == nil {
+			return viewconfig.ErrNotFound
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// Close closes the underlying database.
+func (s *ViewConfigStore) Close() error {
+	return s.db.Close()
+}
+
+func viewConfigOrgBucket(tx *bolt.Tx, orgID string) *bolt.Bucket {
+	root := tx.Bucket(viewConfigBucket)
+	if root == nil {
+		return nil
+	}
+
+	return root.Bucket([]byte(orgID))
+}
+
+func unmarshalDataSetConfig(v []byte) (*fragments.DataSetConfig, error) {
+	var cfg fragments.DataSetConfig
+	if err := proto.Unmarshal(v, &cfg); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal dataset config; %w", err)
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/delving/hub3/hub3/fragments"
+	"github.com/delving/hub3/ikuzo/service/x/viewconfig"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/matryer/is"
+)
+
+func TestViewConfigStore(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "viewconfig")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	store, err := NewViewConfigStore(filepath.Join(dir, "db", "viewconfig.db"))
+	is.NoErr(err)
+
+	defer store.Close()
+
+	ctx := context.Background()
+
+	cfg := &fragments.DataSetConfig{
+		ID:     "spec1",
+		OrgID:  "hub3",
+		Title:  "Spec 1",
+		Spec:   []string{"spec1"},
+		Facets: []*fragments.FacetField{{Field: "dc_subject", Size: 10}},
+	}
+
+	_, err = store.Get(ctx, "hub3", "spec1")
+	is.True(errors.Is(err, viewconfig.ErrNotFound))
+
+	is.NoErr(store.Put(ctx, cfg))
+	is.NoErr(store.Put(ctx, &fragments.DataSetConfig{ID: "_default", OrgID: "hub3"}))
+	is.NoErr(store.Put(ctx, &fragments.DataSetConfig{ID: "spec1", OrgID: "other"}))
+
+	got, err := store.Get(ctx, "hub3", "spec1")
+	is.NoErr(err)
+	is.True(proto.Equal(cfg, got))
+
+	cfgs, err := store.List(ctx, "hub3")
+	is.NoErr(err)
+	is.Equal(len(cfgs), 2)
+	is.Equal(cfgs[0].GetID(), "_default")
+
+	cfgs, err = store.List(ctx, "unknown")
+	is.NoErr(err)
+	is.Equal(len(cfgs), 0)
+
+	is.NoErr(store.Delete(ctx, "hub3", "spec1"))
+	is.True(errors.Is(store.Delete(ctx, "hub3", "spec1"), viewconfig.ErrNotFound))
+
+	_, err = store.Get(ctx, "other", "spec1")
+	is.NoErr(err)
+}
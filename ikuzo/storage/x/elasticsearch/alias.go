@@ -17,6 +17,7 @@ package elasticsearch
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/elastic/go-elasticsearch/v8"
@@ -48,7 +49,8 @@ func AliasCreate(es *elasticsearch.Client, alias, indexName string) error {
 
 // AliasGet returns the indexName for the given alias.
 //
-// When the alias is not found an ErrAliasNotFound error is returned.
+// When the alias is not found an ErrAliasNotFound error is returned. When the
+// alias refers to multiple indices an ErrAliasMultipleIndices error is returned.
 func AliasGet(es *elasticsearch.Client, alias string) (indexName string, err error) {
 	res, conErr := es.Indices.GetAlias(
 		es.Indices.GetAlias.WithName(alias),
@@ -63,20 +65,30 @@ func AliasGet(es *elasticsearch.Client, alias string) (indexName string, err err
 		return "", GetErrorType(res.Body).Error()
 	}
 
-	indexName = getIndexNameFromAlias(res.Body)
+	indexNames := getIndexNamesFromAlias(res.Body)
+	if len(indexNames) > 1 {
+		return "", fmt.Errorf("%w: %s refers to %s", ErrAliasMultipleIndices, alias, strings.Join(indexNames, ", "))
+	}
+
+	if len(indexNames) == 1 {
+		indexName = indexNames[0]
+	}
 
 	return indexName, nil
 }
 
-func getIndexNameFromAlias(r io.Reader) string {
+// getIndexNamesFromAlias returns the sorted names of the indices of the alias.
+func getIndexNamesFromAlias(r io.Reader) []string {
 	json := read(r)
 
-	// take the first index name for now
+	indexNames := []string{}
 	for k := range gjson.Parse(json).Map() {
-		return k
+		indexNames = append(indexNames, k)
 	}
 
-	return ""
+	sort.Strings(indexNames)
+
+	return indexNames
 }
 
 // AliasUpdate removes the alias if it exists from another index and creates a new one linked to indexName.
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/go-cmp/cmp"
 	"github.com/matryer/is"
 )
 
@@ -95,7 +96,7 @@ func (s *elasticSuite) TestAlias() {
 	is.True(errors.Is(err, ErrAliasNotFound))
 }
 
-func Test_getIndexNameFromAlias(t *testing.T) {
+func Test_getIndexNamesFromAlias(t *testing.T) {
 	type args struct {
 		r io.Reader
 	}
@@ -103,7 +104,7 @@ func Test_getIndexNameFromAlias(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want string
+		want []string
 	}{
 		{
 			"sample",
@@ -115,7 +116,17 @@ func Test_getIndexNameFromAlias(t *testing.T) {
 					}
 				}`,
 			)},
-			"logs_20302801",
+			[]string{"logs_20302801"},
+		},
+		{
+			"routed indices",
+			args{strings.NewReader(
+				`{
+					"hub3v2_20200101" : {"aliases" : {"hub3v2" : {}}},
+					"hub3v2-hub3_20200102" : {"aliases" : {"hub3v2" : {}, "hub3v2-hub3" : {}}}
+				}`,
+			)},
+			[]string{"hub3v2-hub3_20200102", "hub3v2_20200101"},
 		},
 	}
 
@@ -123,8 +134,9 @@ func Test_getIndexNameFromAlias(t *testing.T) {
 		tt := tt
 
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getIndexNameFromAlias(tt.args.r); got != tt.want {
-				t.Errorf("getIndexNameFromAlias() = %q, want %v", got, tt.want)
+			got := getIndexNamesFromAlias(tt.args.r)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("getIndexNamesFromAlias() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
@@ -31,6 +31,10 @@ var (
 	ErrIndexNotFound        = errors.New("index not found")
 	ErrIndexAlreadyCreated  = errors.New("index already created")
 	ErrIndexMappingNotValid = errors.New("parsing error in mapping")
+	// ErrAliasMultipleIndices is returned when an operation on a single index
+	// is requested for an alias that refers to multiple indices, e.g. the base
+	// alias of routed indices.
+	ErrAliasMultipleIndices = errors.New("alias refers to multiple indices")
 )
 
 type ErrorType struct {
@@ -0,0 +1,86 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// ExplainHits converts the ElasticSearch score explanations of the hits.
+// The search must be executed with Explain(true) for the explanations to be present.
+func ExplainHits(hits []*elastic.SearchHit) []*search.HitExplanation {
+	explanations := make([]*search.HitExplanation, 0, len(hits))
+
+	for _, hit := range hits {
+		he := &search.HitExplanation{
+			ID:          hit.Id,
+			Explanation: convertExplanation(hit.Explanation),
+		}
+
+		if hit.Score != nil {
+			he.Score = *hit.Score
+		}
+
+		explanations = append(explanations, he)
+	}
+
+	return explanations
+}
+
+// RewrittenQueries converts the explanations of the validate query API.
+// The query must be validated with explain or rewrite enabled for the
+// explanations to be present.
+func RewrittenQueries(resp *elastic.ValidateResponse) []*search.RewrittenQuery {
+	if resp == nil {
+		return nil
+	}
+
+	rewritten := make([]*search.RewrittenQuery, 0, len(resp.Explanations))
+
+	for _, e := range resp.Explanations {
+		fields, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		rq := &search.RewrittenQuery{}
+		rq.Index, _ = fields["index"].(string)
+		rq.Valid, _ = fields["valid"].(bool)
+		rq.Explanation, _ = fields["explanation"].(string)
+		rq.Error, _ = fields["error"].(string)
+
+		rewritten = append(rewritten, rq)
+	}
+
+	return rewritten
+}
+
+func convertExplanation(e *elastic.SearchExplanation) *search.ScoreExplanation {
+	if e == nil {
+		return nil
+	}
+
+	se := &search.ScoreExplanation{
+		Value:       e.Value,
+		Description: e.Description,
+	}
+
+	for idx := range e.Details {
+		se.Details = append(se.Details, convertExplanation(&e.Details[idx]))
+	}
+
+	return se
+}
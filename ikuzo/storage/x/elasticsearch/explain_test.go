@@ -0,0 +1,98 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/google/go-cmp/cmp"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func TestExplainHits(t *testing.T) {
+	score := 1.5
+
+	hits := []*elastic.SearchHit{
+		{
+			Id:    "hub3_1",
+			Score: &score,
+			Explanation: &elastic.SearchExplanation{
+				Value:       1.5,
+				Description: "sum of:",
+				Details: []elastic.SearchExplanation{
+					{Value: 1.5, Description: "weight(full_text:jansen in 0)"},
+				},
+			},
+		},
+		{
+			Id: "hub3_2",
+		},
+	}
+
+	want := []*search.HitExplanation{
+		{
+			ID:    "hub3_1",
+			Score: 1.5,
+			Explanation: &search.ScoreExplanation{
+				Value:       1.5,
+				Description: "sum of:",
+				Details: []*search.ScoreExplanation{
+					{Value: 1.5, Description: "weight(full_text:jansen in 0)"},
+				},
+			},
+		},
+		{
+			ID: "hub3_2",
+		},
+	}
+
+	got := ExplainHits(hits)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ExplainHits() = mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRewrittenQueries(t *testing.T) {
+	resp := &elastic.ValidateResponse{
+		Valid: false,
+		Explanations: []interface{}{
+			map[string]interface{}{
+				"index":       "hub3v2",
+				"valid":       true,
+				"explanation": "full_text:jansen",
+			},
+			map[string]interface{}{
+				"index": "hub3v2",
+				"valid": false,
+				"error": "failed to parse query",
+			},
+		},
+	}
+
+	want := []*search.RewrittenQuery{
+		{Index: "hub3v2", Valid: true, Explanation: "full_text:jansen"},
+		{Index: "hub3v2", Error: "failed to parse query"},
+	}
+
+	got := RewrittenQueries(resp)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("RewrittenQueries() = mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := RewrittenQueries(nil); got != nil {
+		t.Errorf("RewrittenQueries(nil) = %v; want nil", got)
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/delving/hub3/ikuzo/search"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// Names of the sub-aggregations of a facet aggregation.
+const (
+	aggFilter   = "filter"
+	aggValue    = "value"
+	aggDocs     = "docs"
+	aggMin      = "min"
+	aggMax      = "max"
+	aggCentroid = "centroid"
+	aggPivot    = "pivot"
+)
+
+// facetEntryFields maps the nested field of a search.FacetField to the field
+// of the resource entries in the v2 mapping.
+var facetEntryFields = map[string]string{
+	"@value":  "@value.keyword",
+	"@id":     "@id",
+	"date":    "isoDate",
+	"tags":    "tags",
+	"integer": "integer",
+	"latLong": "latLong",
+}
+
+// NewFacetAggregation returns the elastic.Aggregation for the search.FacetField.
+//
+// Facets on the resource entries are aggregated in a nested aggregation that is
+// filtered on the searchLabel. The facet counts are the number of documents,
+// not the number of resource entries, that contain the value.
+//
+// The pivot of a facet is aggregated for each bucket of the facet.
+func NewFacetAggregation(ff *search.FacetField) (elastic.Aggregation, error) {
+	pivot, err := newPivotAggregation(ff)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ff.IsNested() {
+		if ff.AggregationType() != "" {
+			return nil, fmt.Errorf("%s: %w", ff.Field, search.ErrUnsupportedFacet)
+		}
+
+		terms := newTermsAggregation(ff, ff.Path())
+		if pivot != nil {
+			terms = terms.SubAggregation(aggPivot, pivot)
+		}
+
+		return terms, nil
+	}
+
+	entryField, ok := facetEntryFields[ff.NestedField()]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", ff.Field, search.ErrUnsupportedFacet)
+	}
+
+	field := fmt.Sprintf("%s.%s", ff.Path(), entryField)
+
+	filter := elastic.NewFilterAggregation()
+
+	// when the field is the nested field, e.g. 'tags' or 'latLong', all the
+	// resource entries are aggregated regardless of their searchLabel.
+	if ff.Field == ff.NestedField() {
+		filter = filter.Filter(elastic.NewMatchAllQuery())
+	} else {
+		filter = filter.Filter(elastic.NewTermQuery(entriesSearchLabel, ff.Field))
+	}
+
+	docs := elastic.NewReverseNestedAggregation()
+	if pivot != nil {
+		docs = docs.SubAggregation(aggPivot, pivot)
+	}
+
+	switch ff.AggregationType() {
+	case "":
+		filter = filter.SubAggregation(aggValue, newTermsAggregation(ff, field).SubAggregation(aggDocs, docs))
+	case search.AggregationDateHistogram:
+		histogram := elastic.NewDateHistogramAggregation().
+			Field(field).
+			CalendarInterval("year").
+			Format("yyyy").
+			MinDocCount(1).
+			SubAggregation(aggDocs, docs)
+		filter = filter.SubAggregation(aggValue, histogram)
+	case search.AggregationDateMinMax:
+		filter = filter.
+			SubAggregation(aggMin, elastic.NewMinAggregation().Field(field).Format("yyyy-MM-dd")).
+			SubAggregation(aggMax, elastic.NewMaxAggregation().Field(field).Format("yyyy-MM-dd"))
+	case search.AggregationHistogram:
+		histogram := elastic.NewHistogramAggregation().
+			Field(field).
+			Interval(ff.Interval()).
+			MinDocCount(1).
+			SubAggregation(aggDocs, docs)
+		filter = filter.SubAggregation(aggValue, histogram)
+	case search.AggregationRange:
+		ranges := elastic.NewRangeAggregation().Field(field).SubAggregation(aggDocs, docs)
+		edges := ff.Ranges()
+
+		ranges = ranges.AddUnboundedFrom(edges[0])
+		for i := 1; i < len(edges); i++ {
+			ranges = ranges.AddRange(edges[i-1], edges[i])
+		}
+
+		filter = filter.SubAggregation(aggValue, ranges.AddUnboundedTo(edges[len(edges)-1]))
+	case search.AggregationGeoGrid:
+		grid := elastic.NewGeoHashGridAggregation().
+			Field(field).
+			Precision(ff.Precision()).
+			SubAggregation(aggDocs, docs).
+			SubAggregation(aggCentroid, elastic.NewGeoCentroidAggregation().Field(field))
+
+		if ff.Size() > 0 {
+			grid = grid.Size(ff.Size())
+		}
+
+		filter = filter.SubAggregation(aggValue, grid)
+	case search.AggregationGeoDistance:
+		origin := ff.Origin()
+		distance := elastic.NewGeoDistanceAggregation().
+			Field(field).
+			Point(fmt.Sprintf("%g,%g", origin.Lat, origin.Lon)).
+			Unit("km").
+			SubAggregation(aggDocs, docs)
+		edges := ff.Ranges()
+
+		distance = distance.AddUnboundedFrom(edges[0])
+		for i := 1; i < len(edges); i++ {
+			distance = distance.AddRange(edges[i-1], edges[i])
+		}
+
+		filter = filter.SubAggregation(aggValue, distance.AddUnboundedTo(edges[len(edges)-1]))
+	default:
+		return nil, fmt.Errorf("%s: %w", ff.Field, search.ErrUnsupportedFacet)
+	}
+
+	return elastic.NewNestedAggregation().
+		Path(ff.Path()).
+		SubAggregation(aggFilter, filter), nil
+}
+
+// newPivotAggregation returns the aggregation of the pivot of the facet or
+// nil when it has no pivot.
+func newPivotAggregation(ff *search.FacetField) (elastic.Aggregation, error) {
+	if ff.Pivot() == nil {
+		return nil, nil
+	}
+
+	if ff.AggregationType() != "" {
+		return nil, fmt.Errorf("pivot on %s: %w", ff.Field, search.ErrUnsupportedFacet)
+	}
+
+	return NewFacetAggregation(ff.Pivot())
+}
+
+func newTermsAggregation(ff *search.FacetField, field string) *elastic.TermsAggregation {
+	agg := elastic.NewTermsAggregation().Field(field)
+
+	// without a size the ElasticSearch default is used
+	if ff.Size() > 0 {
+		agg = agg.Size(ff.Size())
+	}
+
+	if ff.OrderByKey() {
+		return agg.OrderByKey(ff.SortAsc())
+	}
+
+	return agg.OrderByCount(ff.SortAsc()).OrderByKeyAsc()
+}
+
+// NewFacet returns the search.Facet from the aggregation with the name that
+// was created with NewFacetAggregation.
+func NewFacet(ff *search.FacetField, aggs elastic.Aggregations, name string) (*search.Facet, error) {
+	facet := &search.Facet{
+		Name:  ff.Field,
+		Field: ff.Field,
+		Type:  ff.AggregationType(),
+		Links: []*search.FacetLink{},
+	}
+
+	if !ff.IsNested() {
+		terms, ok := aggs.Terms(name)
+		if !ok {
+			return nil, fmt.Errorf("aggregation %s not found in response", name)
+		}
+
+		facet.OtherDocs = terms.SumOfOtherDocCount
+		facet.Total = facet.OtherDocs
+
+		for _, bucket := range terms.Buckets {
+			link := appendFacetLink(facet, bucketKey(bucket), bucket.DocCount, nil)
+
+			if err := setPivot(link, ff, bucket.Aggregations); err != nil {
+				return nil, err
+			}
+		}
+
+		return facet, nil
+	}
+
+	nested, ok := aggs.Nested(name)
+	if !ok {
+		return nil, fmt.Errorf("aggregation %s not found in response", name)
+	}
+
+	filter, ok := nested.Filter(aggFilter)
+	if !ok {
+		return nil, fmt.Errorf("aggregation %s.%s not found in response", name, aggFilter)
+	}
+
+	notFound := fmt.Errorf("aggregation %s.%s not found in response", name, aggValue)
+
+	switch ff.AggregationType() {
+	case search.AggregationDateHistogram:
+		histogram, ok := filter.DateHistogram(aggValue)
+		if !ok {
+			return nil, notFound
+		}
+
+		for _, bucket := range histogram.Buckets {
+			key := formatFloat(bucket.Key)
+			if bucket.KeyAsString != nil {
+				key = *bucket.KeyAsString
+			}
+
+			appendFacetLink(facet, key, bucket.DocCount, bucket.Aggregations)
+		}
+	case search.AggregationDateMinMax:
+		facet.Min = metricString(filter.Aggregations, aggMin)
+		facet.Max = metricString(filter.Aggregations, aggMax)
+	case search.AggregationHistogram:
+		histogram, ok := filter.Histogram(aggValue)
+		if !ok {
+			return nil, notFound
+		}
+
+		for _, bucket := range histogram.Buckets {
+			from, to := bucket.Key, bucket.Key+ff.Interval()
+
+			link := appendFacetLink(facet, formatFloat(bucket.Key), bucket.DocCount, bucket.Aggregations)
+			link.Range = &search.FacetRange{From: &from, To: &to}
+		}
+	case search.AggregationRange, search.AggregationGeoDistance:
+		ranges, ok := filter.Range(aggValue)
+		if !ok {
+			return nil, notFound
+		}
+
+		for _, bucket := range ranges.Buckets {
+			link := appendFacetLink(facet, bucket.Key, bucket.DocCount, bucket.Aggregations)
+			link.Range = &search.FacetRange{From: bucket.From, To: bucket.To}
+		}
+	case search.AggregationGeoGrid:
+		grid, ok := filter.GeoHash(aggValue)
+		if !ok {
+			return nil, notFound
+		}
+
+		for _, bucket := range grid.Buckets {
+			key := bucketKey(bucket)
+			link := appendFacetLink(facet, key, bucket.DocCount, bucket.Aggregations)
+			link.Cell = &search.GeoCell{Geohash: key}
+
+			if centroid, ok := bucket.GeoCentroid(aggCentroid); ok {
+				link.Cell.Centroid = search.GeoPoint{
+					Lat: centroid.Location.Latitude,
+					Lon: centroid.Location.Longitude,
+				}
+			}
+		}
+	default:
+		terms, ok := filter.Terms(aggValue)
+		if !ok {
+			return nil, notFound
+		}
+
+		facet.OtherDocs = terms.SumOfOtherDocCount
+		facet.Total = facet.OtherDocs
+
+		for _, bucket := range terms.Buckets {
+			link := appendFacetLink(facet, bucketKey(bucket), bucket.DocCount, bucket.Aggregations)
+
+			docs, ok := bucket.ReverseNested(aggDocs)
+			if !ok {
+				continue
+			}
+
+			if err := setPivot(link, ff, docs.Aggregations); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return facet, nil
+}
+
+// setPivot sets the pivot facet of the link when the facet has a pivot.
+func setPivot(link *search.FacetLink, ff *search.FacetField, aggs elastic.Aggregations) error {
+	if ff.Pivot() == nil {
+		return nil
+	}
+
+	pivot, err := NewFacet(ff.Pivot(), aggs, aggPivot)
+	if err != nil {
+		return err
+	}
+
+	link.Pivot = pivot
+
+	return nil
+}
+
+// appendFacetLink appends the link to the facet. For nested aggregations the
+// count is replaced by the number of documents from the reverse nested
+// aggregation.
+func appendFacetLink(facet *search.Facet, value string, count int64, aggs elastic.Aggregations) *search.FacetLink {
+	if docs, ok := aggs.ReverseNested(aggDocs); ok {
+		count = docs.DocCount
+	}
+
+	link := &search.FacetLink{
+		Value:         value,
+		DisplayString: value,
+		Count:         count,
+	}
+
+	facet.Total += count
+	facet.Links = append(facet.Links, link)
+
+	return link
+}
+
+func bucketKey(bucket *elastic.AggregationBucketKeyItem) string {
+	if bucket.KeyAsString != nil {
+		return *bucket.KeyAsString
+	}
+
+	return strings.TrimSpace(fmt.Sprint(bucket.Key))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// metricString returns the formatted value of a metric aggregation.
+func metricString(aggs elastic.Aggregations, name string) string {
+	raw, ok := aggs[name]
+	if !ok {
+		return ""
+	}
+
+	var metric struct {
+		ValueAsString string `json:"value_as_string"`
+	}
+
+	if err := json.Unmarshal(raw, &metric); err != nil {
+		return ""
+	}
+
+	return metric.ValueAsString
+}
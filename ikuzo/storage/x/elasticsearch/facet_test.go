@@ -0,0 +1,152 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package elasticsearch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestNewFacet(t *testing.T) {
+	tests := []struct {
+		name  string
+		facet string
+		aggs  string
+		want  *search.Facet
+	}{
+		{
+			"histogram",
+			"histogram.dc_extent:10",
+			`{"facet": {"doc_count": 9, "filter": {"doc_count": 5, "value": {"buckets": [
+				{"key": 0, "doc_count": 3, "docs": {"doc_count": 2}},
+				{"key": 20, "doc_count": 2, "docs": {"doc_count": 2}}
+			]}}}}`,
+			&search.Facet{
+				Name: "dc_extent", Field: "dc_extent", Type: "histogram", Total: 4,
+				Links: []*search.FacetLink{
+					{Value: "0", DisplayString: "0", Count: 2, Range: &search.FacetRange{From: float(0), To: float(10)}},
+					{Value: "20", DisplayString: "20", Count: 2, Range: &search.FacetRange{From: float(20), To: float(30)}},
+				},
+			},
+		},
+		{
+			"range",
+			"range.dc_extent:10",
+			`{"facet": {"doc_count": 9, "filter": {"doc_count": 5, "value": {"buckets": [
+				{"key": "*-10.0", "to": 10, "doc_count": 3, "docs": {"doc_count": 3}},
+				{"key": "10.0-*", "from": 10, "doc_count": 2, "docs": {"doc_count": 1}}
+			]}}}}`,
+			&search.Facet{
+				Name: "dc_extent", Field: "dc_extent", Type: "range", Total: 4,
+				Links: []*search.FacetLink{
+					{Value: "*-10.0", DisplayString: "*-10.0", Count: 3, Range: &search.FacetRange{To: float(10)}},
+					{Value: "10.0-*", DisplayString: "10.0-*", Count: 1, Range: &search.FacetRange{From: float(10)}},
+				},
+			},
+		},
+		{
+			"geogrid",
+			"geogrid.latLong:4",
+			`{"facet": {"doc_count": 9, "filter": {"doc_count": 5, "value": {"buckets": [
+				{"key": "u173", "doc_count": 4, "docs": {"doc_count": 3},
+				 "centroid": {"location": {"lat": 52.15, "lon": 4.48}, "count": 4}}
+			]}}}}`,
+			&search.Facet{
+				Name: "latLong", Field: "latLong", Type: "geogrid", Total: 3,
+				Links: []*search.FacetLink{
+					{
+						Value: "u173", DisplayString: "u173", Count: 3,
+						Cell: &search.GeoCell{Geohash: "u173", Centroid: search.GeoPoint{Lat: 52.15, Lon: 4.48}},
+					},
+				},
+			},
+		},
+		{
+			"pivot on meta field",
+			"meta.spec>dc_type",
+			`{"facet": {"sum_other_doc_count": 0, "buckets": [
+				{"key": "museum", "doc_count": 3, "pivot": {"doc_count": 6, "filter": {"doc_count": 3, "value": {
+					"sum_other_doc_count": 0,
+					"buckets": [{"key": "painting", "doc_count": 2, "docs": {"doc_count": 2}}]
+				}}}}
+			]}}`,
+			&search.Facet{
+				Name: "meta.spec", Field: "meta.spec", Total: 3,
+				Links: []*search.FacetLink{
+					{
+						Value: "museum", DisplayString: "museum", Count: 3,
+						Pivot: &search.Facet{
+							Name: "dc_type", Field: "dc_type", Total: 2,
+							Links: []*search.FacetLink{{Value: "painting", DisplayString: "painting", Count: 2}},
+						},
+					},
+				},
+			},
+		},
+		{
+			"pivot on nested field",
+			"dc_type>meta.spec",
+			`{"facet": {"doc_count": 9, "filter": {"doc_count": 5, "value": {"sum_other_doc_count": 0, "buckets": [
+				{"key": "painting", "doc_count": 2, "docs": {"doc_count": 2, "pivot": {
+					"sum_other_doc_count": 0,
+					"buckets": [{"key": "museum", "doc_count": 2}]
+				}}}
+			]}}}}`,
+			&search.Facet{
+				Name: "dc_type", Field: "dc_type", Total: 2,
+				Links: []*search.FacetLink{
+					{
+						Value: "painting", DisplayString: "painting", Count: 2,
+						Pivot: &search.Facet{
+							Name: "meta.spec", Field: "meta.spec", Total: 2,
+							Links: []*search.FacetLink{{Value: "museum", DisplayString: "museum", Count: 2}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			req := &search.Request{}
+			is.NoErr(req.AddFacet(tt.facet))
+
+			var aggs elastic.Aggregations
+			is.NoErr(json.Unmarshal([]byte(tt.aggs), &aggs))
+
+			got, err := NewFacet(req.Facets[0], aggs, "facet")
+			is.NoErr(err)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("NewFacet() %s mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
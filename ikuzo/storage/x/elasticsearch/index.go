@@ -35,7 +35,11 @@ import (
 func IndexCreate(es *elasticsearch.Client, alias, mapping string, withAlias bool) (indexName string, err error) {
 	if withAlias {
 		storedIndexName, aliasErr := AliasGet(es, alias)
-		if aliasErr != nil && !errors.Is(aliasErr, ErrAliasNotFound) {
+
+		switch {
+		case errors.Is(aliasErr, ErrAliasMultipleIndices):
+			return "", ErrIndexAlreadyCreated
+		case aliasErr != nil && !errors.Is(aliasErr, ErrAliasNotFound):
 			return "", aliasErr
 		}
 
@@ -44,7 +48,7 @@ func IndexCreate(es *elasticsearch.Client, alias, mapping string, withAlias bool
 		}
 	}
 
-	indexName = fmt.Sprintf("%s_%s", alias, time.Now().Format("20060102150405.999"))
+	indexName = newIndexName(alias)
 
 	res, err := es.Indices.Create(
 		indexName,
@@ -71,6 +75,11 @@ func IndexCreate(es *elasticsearch.Client, alias, mapping string, withAlias bool
 	return indexName, nil
 }
 
+// newIndexName returns the name of a new index for the alias.
+func newIndexName(alias string) string {
+	return fmt.Sprintf("%s_%s", alias, time.Now().Format("20060102150405.999"))
+}
+
 // IndexDelete delete the index from ElasticSearch.
 //
 // If the error does not exist an ErrIndexNotExist error is returned.
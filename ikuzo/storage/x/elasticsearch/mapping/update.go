@@ -25,8 +25,8 @@ import (
 // this should prevent changes to the mapping that are not reflected in the update.
 // this is needed for all mappings that have strict fields.
 const (
-	v2MappingSha       = "332a55b29c0b868d"
-	v2UpdateMappingSha = "9b20cec1c69f8b7b"
+	v2MappingSha       = "feca2ae2cdf82512"
+	v2UpdateMappingSha = "490e27594357c2f6"
 	fragmentMappingSha = "7607ca7737d17e4a"
 )
 
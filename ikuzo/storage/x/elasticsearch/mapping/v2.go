@@ -127,6 +127,10 @@ var v2Mapping = `{
 				},
 				"recordType": {"type": "short"},
 				"full_text": {"type": "text"},
+				"full_text_nl": {"type": "text", "analyzer": "dutch"},
+				"full_text_en": {"type": "text", "analyzer": "english"},
+				"full_text_de": {"type": "text", "analyzer": "german"},
+				"full_text_fr": {"type": "text", "analyzer": "french"},
 				"resources": {
 					"type": "nested",
 					"properties": {
@@ -176,7 +180,17 @@ var v2Mapping = `{
 								},
 								"intRange": {"type": "integer_range"},
 								"float": {"type": "float"},
-								"latLong": {"type": "geo_point"}
+								"latLong": {"type": "geo_point"},
+								"phonetic": {"type": "keyword", "ignore_above": 256},
+								"i18n": {
+									"type": "object",
+									"properties": {
+										"nl": {"type": "text", "analyzer": "dutch", "copy_to": "full_text_nl"},
+										"en": {"type": "text", "analyzer": "english", "copy_to": "full_text_en"},
+										"de": {"type": "text", "analyzer": "german", "copy_to": "full_text_de"},
+										"fr": {"type": "text", "analyzer": "french", "copy_to": "full_text_fr"}
+									}
+								}
 							}
 						}
 					}
@@ -221,11 +235,25 @@ var v2MappingUpdate = `{
 				"properties": {
 					"intRange": {"type": "integer_range"},
 					"float": {"type": "float"},
-					"level": {"type": "integer"}
+					"level": {"type": "integer"},
+					"phonetic": {"type": "keyword", "ignore_above": 256},
+					"i18n": {
+						"type": "object",
+						"properties": {
+							"nl": {"type": "text", "analyzer": "dutch", "copy_to": "full_text_nl"},
+							"en": {"type": "text", "analyzer": "english", "copy_to": "full_text_en"},
+							"de": {"type": "text", "analyzer": "german", "copy_to": "full_text_de"},
+							"fr": {"type": "text", "analyzer": "french", "copy_to": "full_text_fr"}
+						}
+					}
 				}
 			}
 		}
-	}
+	},
+	"full_text_nl": {"type": "text", "analyzer": "dutch"},
+	"full_text_en": {"type": "text", "analyzer": "english"},
+	"full_text_de": {"type": "text", "analyzer": "german"},
+	"full_text_fr": {"type": "text", "analyzer": "french"}
   }
 }
 `
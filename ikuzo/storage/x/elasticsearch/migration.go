@@ -0,0 +1,652 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrBreakingMappingChange is returned when the live mapping of an index
+	// can only be migrated to the current mapping with a reindex.
+	ErrBreakingMappingChange = errors.New("breaking mapping change requires a reindex")
+	// ErrReindexRunning is returned when a reindex is already running for a mapping.
+	ErrReindexRunning = errors.New("reindex is already running")
+	// ErrUnknownMapping is returned when no MappingTarget is registered for a name.
+	ErrUnknownMapping = errors.New("unknown mapping")
+)
+
+// MappingDiff describes the differences between the current mapping and the
+// live mapping of an index.
+type MappingDiff struct {
+	// Additive contains the paths of the fields that are missing in the live mapping
+	Additive []string `json:"additive,omitempty"`
+	// Breaking contains a description of each field that is changed in the current mapping
+	Breaking []string `json:"breaking,omitempty"`
+	// update contains the properties for PUT _mapping to apply the additive changes
+	update map[string]interface{}
+}
+
+// IsAdditive returns true when all changes can be applied with PUT _mapping.
+func (d *MappingDiff) IsAdditive() bool {
+	return len(d.Breaking) == 0 && len(d.Additive) != 0
+}
+
+// HasChanges returns true when the live mapping differs from the current mapping.
+func (d *MappingDiff) HasChanges() bool {
+	return len(d.Breaking) != 0 || len(d.Additive) != 0
+}
+
+// merge adds the changes of the other MappingDiff that are not in the MappingDiff.
+// The update is not merged, because it applies to the index of the other MappingDiff.
+func (d *MappingDiff) merge(other *MappingDiff) {
+	d.Additive = appendMissing(d.Additive, other.Additive)
+	d.Breaking = appendMissing(d.Breaking, other.Breaking)
+}
+
+func appendMissing(values, other []string) []string {
+	seen := map[string]bool{}
+	for _, v := range values {
+		seen[v] = true
+	}
+
+	for _, v := range other {
+		if !seen[v] {
+			seen[v] = true
+
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// UpdateBody returns the PUT _mapping request body with the additive changes.
+func (d *MappingDiff) UpdateBody() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"properties": d.update})
+}
+
+// CompareMappings classifies the differences between the current and the live
+// mapping. Both are the parsed 'mappings' objects of an index.
+//
+// Fields that are missing in the live mapping are additive. Fields whose type
+// or parameters differ are breaking. Fields that are only present in the live
+// mapping, e.g. created by dynamic templates, are ignored.
+func CompareMappings(current, live map[string]interface{}) *MappingDiff {
+	d := &MappingDiff{
+		Additive: []string{},
+		Breaking: []string{},
+	}
+
+	d.update = compareProperties("", properties(current, "properties"), properties(live, "properties"), d)
+
+	return d
+}
+
+func properties(field map[string]interface{}, key string) map[string]interface{} {
+	props, _ := field[key].(map[string]interface{})
+	if props == nil {
+		return map[string]interface{}{}
+	}
+
+	return props
+}
+
+// compareProperties adds the differences of the properties to the MappingDiff
+// and returns the properties that must be put to apply the additive changes.
+func compareProperties(path string, current, live map[string]interface{}, d *MappingDiff) map[string]interface{} {
+	update := map[string]interface{}{}
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		field, _ := current[name].(map[string]interface{})
+
+		liveField, ok := live[name].(map[string]interface{})
+		if !ok {
+			d.Additive = append(d.Additive, fieldPath)
+			update[name] = field
+
+			continue
+		}
+
+		if currentType, liveType := fieldType(field), fieldType(liveField); currentType != liveType {
+			d.Breaking = append(d.Breaking, fmt.Sprintf("%s: type %s != %s", fieldPath, liveType, currentType))
+			continue
+		}
+
+		breaking := len(d.Breaking)
+
+		for _, param := range fieldParams(field, liveField) {
+			liveValue := paramValue(fieldType(field), param, liveField[param])
+			if currentValue := paramValue(fieldType(field), param, field[param]); liveValue != currentValue {
+				d.Breaking = append(d.Breaking, fmt.Sprintf("%s: %s %s != %s", fieldPath, param, liveValue, currentValue))
+			}
+		}
+
+		if len(d.Breaking) != breaking {
+			continue
+		}
+
+		fieldUpdate := map[string]interface{}{}
+
+		if props := compareProperties(fieldPath, properties(field, "properties"), properties(liveField, "properties"), d); len(props) != 0 {
+			fieldUpdate["properties"] = props
+		}
+
+		if fields := compareProperties(fieldPath, properties(field, "fields"), properties(liveField, "fields"), d); len(fields) != 0 {
+			// multi-fields can only be added with the full definition of the field
+			for param, value := range field {
+				if param != "properties" {
+					fieldUpdate[param] = value
+				}
+			}
+
+			fieldUpdate["fields"] = fields
+		}
+
+		if len(fieldUpdate) == 0 {
+			continue
+		}
+
+		if t, ok := field["type"]; ok {
+			fieldUpdate["type"] = t
+		}
+
+		update[name] = fieldUpdate
+	}
+
+	return update
+}
+
+// fieldType returns the type of the field. Object fields have no explicit type.
+func fieldType(field map[string]interface{}) string {
+	if t, ok := field["type"].(string); ok {
+		return t
+	}
+
+	return "object"
+}
+
+// fieldParams returns the sorted parameters of both fields without the type and sub-fields.
+func fieldParams(field, liveField map[string]interface{}) []string {
+	seen := map[string]bool{"type": true, "properties": true, "fields": true}
+	params := []string{}
+
+	for _, f := range []map[string]interface{}{field, liveField} {
+		for param := range f {
+			if !seen[param] {
+				seen[param] = true
+
+				params = append(params, param)
+			}
+		}
+	}
+
+	sort.Strings(params)
+
+	return params
+}
+
+// paramDefaults are the default values of the mapping parameters. ElasticSearch
+// leaves parameters that are set to their default out of the live mapping.
+var paramDefaults = map[string]string{
+	"coerce":                "true",
+	"doc_values":            "true",
+	"eager_global_ordinals": "false",
+	"enabled":               "true",
+	"ignore_malformed":      "false",
+	"include_in_parent":     "false",
+	"include_in_root":       "false",
+	"index":                 "true",
+	"store":                 "false",
+}
+
+// paramValue returns a normalized string representation of a parameter value,
+// because ElasticSearch returns booleans and single values in arrays in another
+// form and leaves out parameters that are set to their default.
+func paramValue(fieldType, param string, value interface{}) string {
+	if values, ok := value.([]interface{}); ok && len(values) == 1 {
+		value = values[0]
+	}
+
+	if value != nil {
+		return fmt.Sprint(value)
+	}
+
+	if param == "norms" {
+		// only text fields have norms by default
+		return fmt.Sprint(fieldType == "text")
+	}
+
+	if def, ok := paramDefaults[param]; ok {
+		return def
+	}
+
+	return "<none>"
+}
+
+// MappingTarget is an aliased index whose live mapping is migrated to the
+// mapping of the ReindexConfig.
+type MappingTarget struct {
+	// Name identifies the target, e.g. the index type
+	Name string
+	ReindexConfig
+}
+
+// MappingMigration is the migration status of a MappingTarget.
+type MappingMigration struct {
+	Name       string       `json:"name"`
+	Alias      string       `json:"alias"`
+	Index      string       `json:"index"`
+	Diff       *MappingDiff `json:"diff,omitempty"`
+	Applied    bool         `json:"applied"`
+	Reindexing bool         `json:"reindexing"`
+	Error      string       `json:"error,omitempty"`
+	Checked    time.Time    `json:"checked"`
+}
+
+// MappingMigrator detects differences between the current and live mappings
+// of the MappingTargets at startup.
+//
+// Additive changes are applied with PUT _mapping to each index of the alias.
+// Breaking changes make the migrator not ready until the index is rebuilt with
+// Reindex, which can be triggered from the admin routes. Reindex is refused for
+// an alias with multiple indices, e.g. the base alias of routed indices.
+type MappingMigrator struct {
+	es         *elasticsearch.Client
+	targets    map[string]MappingTarget
+	autoApply  bool
+	rw         sync.RWMutex
+	migrations map[string]*MappingMigration
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewMappingMigrator returns a MappingMigrator for the targets. When autoApply
+// is true additive changes are applied during Check.
+func NewMappingMigrator(es *elasticsearch.Client, autoApply bool, targets ...MappingTarget) *MappingMigrator {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	m := &MappingMigrator{
+		es:         es,
+		targets:    map[string]MappingTarget{},
+		autoApply:  autoApply,
+		migrations: map[string]*MappingMigration{},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	for _, t := range targets {
+		m.targets[t.Name] = t
+	}
+
+	return m
+}
+
+// Check compares the live mapping of each MappingTarget with its current
+// mapping and applies the additive changes when autoApply is enabled.
+func (m *MappingMigrator) Check(ctx context.Context) ([]*MappingMigration, error) {
+	names := make([]string, 0, len(m.targets))
+	for name := range m.targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	migrations := []*MappingMigration{}
+
+	for _, name := range names {
+		migration, err := m.check(ctx, m.targets[name])
+		if err != nil {
+			return migrations, err
+		}
+
+		migrations = append(migrations, migration)
+	}
+
+	return migrations, nil
+}
+
+func (m *MappingMigrator) check(ctx context.Context, target MappingTarget) (*MappingMigration, error) {
+	migration := &MappingMigration{
+		Name:    target.Name,
+		Alias:   target.Alias,
+		Checked: time.Now(),
+	}
+
+	defer m.setMigration(migration)
+
+	live, err := liveMappings(ctx, m.es, target.Alias)
+	if err != nil {
+		migration.Error = err.Error()
+		return migration, err
+	}
+
+	indexNames := make([]string, 0, len(live))
+	for indexName := range live {
+		indexNames = append(indexNames, indexName)
+	}
+
+	sort.Strings(indexNames)
+
+	migration.Index = strings.Join(indexNames, ",")
+
+	var current struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+
+	if err := json.Unmarshal([]byte(target.Mapping), &current); err != nil {
+		migration.Error = err.Error()
+		return migration, fmt.Errorf("unable to parse mapping for %s; %w", target.Name, err)
+	}
+
+	// the alias refers to multiple indices when the documents are routed
+	diffs := map[string]*MappingDiff{}
+	migration.Diff = &MappingDiff{Additive: []string{}, Breaking: []string{}}
+
+	for _, indexName := range indexNames {
+		diffs[indexName] = CompareMappings(current.Mappings, live[indexName])
+		migration.Diff.merge(diffs[indexName])
+	}
+
+	logger := log.With().Str("alias", target.Alias).Str("index", migration.Index).Logger()
+
+	switch {
+	case len(migration.Diff.Breaking) != 0:
+		logger.Warn().Strs("breaking", migration.Diff.Breaking).Strs("additive", migration.Diff.Additive).
+			Msg("mapping has breaking changes; reindex required")
+	case migration.Diff.IsAdditive() && m.autoApply:
+		for _, indexName := range indexNames {
+			if !diffs[indexName].IsAdditive() {
+				continue
+			}
+
+			if err := putMapping(ctx, m.es, indexName, diffs[indexName]); err != nil {
+				migration.Error = err.Error()
+				return migration, err
+			}
+		}
+
+		migration.Applied = true
+
+		logger.Info().Strs("additive", migration.Diff.Additive).Msg("applied additive mapping changes")
+	case migration.Diff.IsAdditive():
+		logger.Warn().Strs("additive", migration.Diff.Additive).Msg("additive mapping changes are not applied")
+	}
+
+	return migration, nil
+}
+
+func (m *MappingMigrator) setMigration(migration *MappingMigration) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	if previous, ok := m.migrations[migration.Name]; ok {
+		migration.Reindexing = previous.Reindexing
+	}
+
+	m.migrations[migration.Name] = migration
+}
+
+// Migrations returns the migration status of each MappingTarget.
+func (m *MappingMigrator) Migrations() []MappingMigration {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	migrations := make([]MappingMigration, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Name < migrations[j].Name })
+
+	return migrations
+}
+
+// Ready returns an error when a mapping could not be checked or has breaking
+// changes that are not migrated yet.
+func (m *MappingMigrator) Ready(ctx context.Context) error {
+	stale := []string{}
+
+	for _, migration := range m.Migrations() {
+		switch {
+		case migration.Error != "":
+			stale = append(stale, fmt.Sprintf("%s: %s", migration.Name, migration.Error))
+		case migration.Diff != nil && len(migration.Diff.Breaking) != 0:
+			stale = append(stale, fmt.Sprintf("%s: %s", migration.Name, strings.Join(migration.Diff.Breaking, "; ")))
+		}
+	}
+
+	if len(stale) != 0 {
+		return fmt.Errorf("%w: %s", ErrBreakingMappingChange, strings.Join(stale, ", "))
+	}
+
+	return nil
+}
+
+// Reindex starts a reindex of the MappingTarget in the background. When it
+// succeeds the mapping is checked again.
+func (m *MappingMigrator) Reindex(name string, keepOldIndex bool) error {
+	target, ok := m.targets[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownMapping, name)
+	}
+
+	m.rw.Lock()
+
+	migration, ok := m.migrations[name]
+	if !ok {
+		migration = &MappingMigration{Name: name, Alias: target.Alias}
+		m.migrations[name] = migration
+	}
+
+	if migration.Reindexing {
+		m.rw.Unlock()
+		return fmt.Errorf("%w: %s", ErrReindexRunning, name)
+	}
+
+	migration.Reindexing = true
+
+	m.rw.Unlock()
+
+	// routed indices can only be reindexed separately
+	if _, err := AliasGet(m.es, target.Alias); errors.Is(err, ErrAliasMultipleIndices) {
+		m.rw.Lock()
+		migration.Reindexing = false
+		m.rw.Unlock()
+
+		return err
+	}
+
+	cfg := target.ReindexConfig
+	cfg.KeepOldIndex = keepOldIndex
+
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+
+		result, err := Reindex(m.ctx, m.es, cfg)
+
+		m.rw.Lock()
+		m.migrations[name].Reindexing = false
+		m.rw.Unlock()
+
+		if err != nil {
+			log.Error().Err(err).Str("alias", cfg.Alias).Msg("unable to reindex for mapping migration")
+
+			m.rw.Lock()
+			m.migrations[name].Error = err.Error()
+			m.rw.Unlock()
+
+			return
+		}
+
+		log.Info().Str("alias", result.Alias).Str("newIndex", result.NewIndex).Msg("reindexed for mapping migration")
+
+		if _, err := m.check(m.ctx, target); err != nil {
+			log.Error().Err(err).Str("alias", cfg.Alias).Msg("unable to check mapping after reindex")
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown cancels the running reindex tasks and waits for them to stop.
+func (m *MappingMigrator) Shutdown(ctx context.Context) error {
+	m.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Routes returns the admin routes of the MappingMigrator.
+func (m *MappingMigrator) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", m.handleList)
+	r.Post("/{name}/reindex", m.handleReindex)
+
+	return r
+}
+
+func (m *MappingMigrator) handleList(w http.ResponseWriter, r *http.Request) {
+	status := http.StatusOK
+	if err := m.Ready(r.Context()); err != nil {
+		status = http.StatusServiceUnavailable
+	}
+
+	render.Status(r, status)
+	render.JSON(w, r, m.Migrations())
+}
+
+func (m *MappingMigrator) handleReindex(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	keep := r.URL.Query().Get("keep") == "true"
+
+	err := m.Reindex(name, keep)
+
+	switch {
+	case errors.Is(err, ErrUnknownMapping):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrReindexRunning), errors.Is(err, ErrAliasMultipleIndices):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, m.Migrations())
+}
+
+// liveMappings returns the 'mappings' object of each index of the alias.
+func liveMappings(ctx context.Context, es *elasticsearch.Client, alias string) (map[string]map[string]interface{}, error) {
+	res, err := es.Indices.GetMapping(
+		es.Indices.GetMapping.WithContext(ctx),
+		es.Indices.GetMapping.WithIndex(alias),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get mapping for %s; %w", alias, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, GetErrorType(res.Body).Error()
+	}
+
+	var indices map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("unable to decode mapping for %s; %w", alias, err)
+	}
+
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("no mapping found for %s", alias)
+	}
+
+	mappings := map[string]map[string]interface{}{}
+	for indexName, index := range indices {
+		mappings[indexName] = index.Mappings
+	}
+
+	return mappings, nil
+}
+
+func putMapping(ctx context.Context, es *elasticsearch.Client, indexName string, d *MappingDiff) error {
+	body, err := d.UpdateBody()
+	if err != nil {
+		return fmt.Errorf("unable to encode mapping update; %w", err)
+	}
+
+	res, err := es.Indices.PutMapping(
+		[]string{indexName},
+		bytes.NewReader(body),
+		es.Indices.PutMapping.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to put mapping for %s; %w", indexName, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/mapping"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func parseMapping(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("unable to parse mapping: %s", err)
+	}
+
+	return m
+}
+
+func TestCompareMappings(t *testing.T) {
+	current := `{"properties": {
+		"meta": {"dynamic": "strict", "properties": {
+			"spec": {"type": "keyword"},
+			"tags": {"type": "keyword"}
+		}},
+		"title": {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+		"summary": {"type": "text", "analyzer": "dutch"},
+		"entries": {"type": "nested", "properties": {"value": {"type": "keyword"}}}
+	}}`
+
+	tests := []struct {
+		name       string
+		live       string
+		additive   []string
+		breaking   []string
+		updateBody string
+	}{
+		{
+			"equal",
+			`{"properties": {
+				"meta": {"dynamic": "strict", "properties": {"spec": {"type": "keyword"}, "tags": {"type": "keyword"}}},
+				"title": {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+				"summary": {"type": "text", "analyzer": "dutch"},
+				"entries": {"type": "nested", "properties": {"value": {"type": "keyword"}}},
+				"dynamic_field": {"type": "keyword"}
+			}}`,
+			[]string{},
+			[]string{},
+			`{"properties":{}}`,
+		},
+		{
+			"additive",
+			`{"properties": {
+				"meta": {"dynamic": "strict", "properties": {"spec": {"type": "keyword"}}},
+				"title": {"type": "text"},
+				"summary": {"type": "text", "analyzer": "dutch"},
+				"entries": {"type": "nested", "properties": {}}
+			}}`,
+			[]string{"entries.value", "meta.tags", "title.raw"},
+			[]string{},
+			`{"properties":{` +
+				`"entries":{"properties":{"value":{"type":"keyword"}},"type":"nested"},` +
+				`"meta":{"properties":{"tags":{"type":"keyword"}}},` +
+				`"title":{"fields":{"raw":{"ignore_above":256,"type":"keyword"}},"type":"text"}}}`,
+		},
+		{
+			"breaking",
+			`{"properties": {
+				"meta": {"dynamic": "strict", "properties": {"spec": {"type": "text"}, "tags": {"type": "keyword"}}},
+				"title": {"type": "text", "fields": {"raw": {"type": "keyword", "ignore_above": 256}}},
+				"summary": {"type": "text"},
+				"entries": {"properties": {"value": {"type": "keyword"}}}
+			}}`,
+			[]string{},
+			[]string{"entries: type object != nested", "meta.spec: type text != keyword", "summary: analyzer <none> != dutch"},
+			`{"properties":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareMappings(parseMapping(t, current), parseMapping(t, tt.live))
+
+			if diff := cmp.Diff(tt.additive, got.Additive); diff != "" {
+				t.Errorf("CompareMappings() additive mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.breaking, got.Breaking); diff != "" {
+				t.Errorf("CompareMappings() breaking mismatch (-want +got):\n%s", diff)
+			}
+
+			body, err := got.UpdateBody()
+			if err != nil {
+				t.Fatalf("MappingDiff.UpdateBody() unexpected error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.updateBody, string(body)); diff != "" {
+				t.Errorf("MappingDiff.UpdateBody() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCompareMappings_v2(t *testing.T) {
+	is := is.New(t)
+
+	v2 := parseMapping(t, mapping.V2ESMapping(1, 0))["mappings"].(map[string]interface{})
+
+	d := CompareMappings(v2, v2)
+	is.True(!d.HasChanges())
+
+	// the live mapping as returned by ElasticSearch leaves out the defaults
+	d = CompareMappings(v2, withoutDefaults(parseMapping(t, mapping.V2ESMapping(1, 0))["mappings"].(map[string]interface{})))
+	is.True(!d.HasChanges())
+}
+
+// withoutDefaults removes the parameters that ElasticSearch leaves out of the
+// live mapping, because they are set to their default.
+func withoutDefaults(field map[string]interface{}) map[string]interface{} {
+	for param, value := range field {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			withoutDefaults(v)
+		case bool:
+			if fmt.Sprint(v) == paramValue(fieldType(field), param, nil) {
+				delete(field, param)
+			}
+		}
+	}
+
+	return field
+}
+
+func TestCompareMappings_defaults(t *testing.T) {
+	current := `{"properties": {
+		"rawContent": {"type": "text", "store": false, "norms": true},
+		"id": {"type": "keyword", "index": true, "doc_values": true, "norms": false},
+		"stored": {"type": "keyword", "store": true}
+	}}`
+
+	// ElasticSearch leaves parameters with their default value out of GET _mapping
+	live := `{"properties": {
+		"rawContent": {"type": "text"},
+		"id": {"type": "keyword"},
+		"stored": {"type": "keyword"}
+	}}`
+
+	got := CompareMappings(parseMapping(t, current), parseMapping(t, live))
+
+	if diff := cmp.Diff([]string{"stored: store false != true"}, got.Breaking); diff != "" {
+		t.Errorf("CompareMappings() breaking mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMappingMigrator_Ready(t *testing.T) {
+	is := is.New(t)
+
+	m := NewMappingMigrator(nil, true)
+	is.NoErr(m.Ready(context.Background()))
+
+	m.setMigration(&MappingMigration{Name: "v2", Diff: &MappingDiff{Additive: []string{"meta.tags"}}, Applied: true})
+	is.NoErr(m.Ready(context.Background()))
+
+	m.setMigration(&MappingMigration{Name: "v1", Diff: &MappingDiff{Breaking: []string{"meta.spec: type text != keyword"}}})
+
+	err := m.Ready(context.Background())
+	is.True(errors.Is(err, ErrBreakingMappingChange))
+}
+
+func TestMappingMigrator_handleReindex(t *testing.T) {
+	is := is.New(t)
+
+	m := NewMappingMigrator(nil, true, MappingTarget{Name: "v2"})
+	m.setMigration(&MappingMigration{Name: "v2", Reindexing: true})
+
+	tests := []struct {
+		name       string
+		path       string
+		statusCode int
+	}{
+		{"unknown mapping", "/v1/reindex", http.StatusNotFound},
+		{"running reindex", "/v2/reindex", http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		m.Routes().ServeHTTP(w, req)
+		is.Equal(w.Code, tt.statusCode)
+	}
+}
@@ -17,14 +17,19 @@ package elasticsearch
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/OneOfOne/xxhash"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
 	"github.com/go-chi/chi"
 	"github.com/mailgun/groupcache"
 	"github.com/rs/zerolog/hlog"
@@ -35,14 +40,60 @@ type esCtxKey int
 
 var esKey esCtxKey
 
+// defaultRefreshInterval is the default refresh_interval of ElasticSearch.
+const defaultRefreshInterval = time.Second
+
 type Proxy struct {
-	es    *elasticsearch.Client
-	group *groupcache.Group
+	es          *elasticsearch.Client
+	group       *groupcache.Group
+	policy      *ProxyPolicy
+	router      *IndexRouter
+	generations cacheGenerations
+	// refreshInterval is the time after which flushed writes are searchable
+	refreshInterval time.Duration
 }
 
-func NewProxy(es *elasticsearch.Client) (*Proxy, error) {
+// ProxyOption is a closure to configure the Proxy.
+type ProxyOption func(*Proxy) error
+
+// SetProxyPolicy sets the ProxyPolicy that is applied to each proxy request.
+func SetProxyPolicy(policy *ProxyPolicy) ProxyOption {
+	return func(p *Proxy) error {
+		p.policy = policy
+		return nil
+	}
+}
+
+// SetProxyIndexRouter sets the IndexRouter that rewrites requests for a base
+// alias to the alias of the organization of the caller.
+func SetProxyIndexRouter(router *IndexRouter) ProxyOption {
+	return func(p *Proxy) error {
+		p.router = router
+		return nil
+	}
+}
+
+// SetProxyRefreshInterval sets the refresh_interval of the indices. The cache
+// is invalidated again after the refresh interval, so responses that were
+// cached before the flushed writes were searchable are not served.
+func SetProxyRefreshInterval(interval time.Duration) ProxyOption {
+	return func(p *Proxy) error {
+		p.refreshInterval = interval
+		return nil
+	}
+}
+
+func NewProxy(es *elasticsearch.Client, options ...ProxyOption) (*Proxy, error) {
 	p := &Proxy{
-		es: es,
+		es:              es,
+		generations:     cacheGenerations{orgs: map[string]uint64{}},
+		refreshInterval: defaultRefreshInterval,
+	}
+
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return nil, err
+		}
 	}
 
 	p.group = groupcache.NewGroup(
@@ -54,47 +105,168 @@ func NewProxy(es *elasticsearch.Client) (*Proxy, error) {
 	return p, nil
 }
 
-func requestKey(r *http.Request) string {
-	index := chi.URLParam(r, "index")
+// SearchError is the error response of ElasticSearch to a search request.
+// Error responses are not cached.
+type SearchError struct {
+	StatusCode int
+	Body       []byte
+}
 
-	var buf bytes.Buffer
+func (e *SearchError) Error() string {
+	return fmt.Sprintf("elasticsearch search error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// searchRequest is the search request that is executed when the response is not cached.
+type searchRequest struct {
+	index string
+	body  []byte
+}
+
+// cacheGenerations contains the cache generation of each organization.
+//
+// The generation is part of the cache key, so bumping it invalidates all
+// cached responses of the organization. The global generation is used for
+// requests without an organization and is bumped on each invalidation.
+type cacheGenerations struct {
+	rw     sync.RWMutex
+	global uint64
+	orgs   map[string]uint64
+}
+
+func (g *cacheGenerations) get(orgID string) uint64 {
+	g.rw.RLock()
+	defer g.rw.RUnlock()
 
+	if orgID == "" {
+		return g.global
+	}
+
+	return g.orgs[orgID]
+}
+
+func (g *cacheGenerations) bump(orgID string) {
+	g.rw.Lock()
+	defer g.rw.Unlock()
+
+	g.global++
+
+	if orgID != "" {
+		g.orgs[orgID]++
+	}
+}
+
+// Invalidate invalidates the cached responses of the organization. It is
+// called when the index.Service has flushed writes for a dataset of the organization.
+//
+// The flushed writes are only searchable after the next refresh, so the cache
+// is invalidated again after the refresh interval. Otherwise a search before
+// the refresh would cache the stale response under the new generation.
+func (p *Proxy) Invalidate(orgID, datasetID string) {
+	p.generations.bump(orgID)
+
+	if p.refreshInterval > 0 {
+		time.AfterFunc(p.refreshInterval, func() { p.generations.bump(orgID) })
+	}
+
+	log.Debug().Str("orgID", orgID).Str("datasetID", datasetID).Msg("invalidated es proxy cache")
+}
+
+// searchKey returns the cache key for the search request.
+func searchKey(index string, generation uint64, body []byte) string {
 	hash := xxhash.New64()
 	_, _ = hash.WriteString(index)
+	_, _ = hash.WriteString(strconv.FormatUint(generation, 10))
+	_, _ = hash.Write(body)
+
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// Search returns the ElasticSearch response for the search request body.
+// Responses are cached in the groupcache shared with the proxy routes.
+func (p *Proxy) Search(ctx context.Context, index string, body []byte) ([]byte, error) {
+	return p.search(ctx, index, "", body)
+}
+
+func (p *Proxy) search(ctx context.Context, index, orgID string, body []byte) ([]byte, error) {
+	key := searchKey(index, p.generations.get(orgID), body)
+
+	log.Info().Str("requestKey", key).Msg("")
+
+	var data []byte
+
+	ctx = context.WithValue(ctx, esKey, &searchRequest{index: index, body: body})
 
-	_, err := io.Copy(&buf, io.TeeReader(r.Body, hash))
+	if err := p.group.Get(ctx, key, groupcache.AllocatingByteSliceSink(&data)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Warn().
 			Str("method", r.Method).
 			Str("url", r.URL.String()).
 			Msg("unable to copy request body")
 
-		return ""
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
 	}
 
-	r.Body = ioutil.NopCloser(&buf)
+	index := chi.URLParam(r, "index")
 
-	return fmt.Sprintf("%016x", hash.Sum64())
-}
+	var orgID string
 
-func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	key := requestKey(r)
+	if p.policy != nil {
+		cp, policyErr := p.policy.Authorize(r, index)
+		if policyErr != nil {
+			log.Warn().Err(policyErr).Str("url", r.URL.String()).Msg("proxy request rejected by policy")
+			http.Error(w, policyErr.Error(), http.StatusForbidden)
 
-	log.Info().Str("requestKey", key).Msg("")
+			return
+		}
 
-	var data []byte
+		orgID = cp.OrgID
+
+		body, err = p.policy.FilterQuery(body, orgID)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrProxyForbidden) {
+				status = http.StatusForbidden
+			}
+
+			http.Error(w, err.Error(), status)
+
+			return
+		}
+	}
 
-	ctx := context.WithValue(r.Context(), esKey, r)
+	if p.router != nil {
+		index = p.routeIndex(index, orgID)
+	}
 
-	err := p.group.Get(ctx, key, groupcache.AllocatingByteSliceSink(&data))
+	data, err := p.search(r.Context(), index, orgID, body)
 	if err != nil {
-		if ctx.Done() != nil {
+		if r.Context().Err() != nil {
 			log.Debug().Err(err).Msg("request was canceled")
 			http.Error(w, err.Error(), http.StatusAccepted)
 
 			return
 		}
 
+		var searchErr *SearchError
+		if errors.As(err, &searchErr) {
+			// the ElasticSearch error is returned unchanged
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(searchErr.StatusCode)
+			_, _ = w.Write(searchErr.Body)
+
+			return
+		}
+
 		getErr := fmt.Errorf("error groupcache response: %s", err)
 		log.Warn().Err(getErr).Msg("")
 
@@ -114,26 +286,37 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (p *Proxy) retrieveFromElasticSearch(gctx groupcache.Context, id string, dest groupcache.Sink) error {
-	ctx := gctx.(context.Context)
-	r := ctx.Value(esKey).(*http.Request)
-
-	var body bytes.Buffer
+// routeIndex returns the comma-separated indices with each base alias replaced
+// by the alias of the organization.
+func (p *Proxy) routeIndex(indices, orgID string) string {
+	routed := strings.Split(indices, ",")
 
-	var queryBody bytes.Buffer
+	for i, index := range routed {
+		routed[i] = p.router.SearchAlias(index, orgID)
+	}
 
-	_, _ = io.Copy(&body, io.TeeReader(r.Body, &queryBody))
+	return strings.Join(routed, ",")
+}
 
-	index := chi.URLParam(r, "index")
+func (p *Proxy) retrieveFromElasticSearch(gctx groupcache.Context, id string, dest groupcache.Sink) error {
+	ctx := gctx.(context.Context)
+	sr := ctx.Value(esKey).(*searchRequest)
 
 	queryStart := time.Now()
 
-	res, err := p.es.Search(
+	options := []func(*esapi.SearchRequest){
 		p.es.Search.WithContext(ctx),
-		p.es.Search.WithIndex(index),
-		p.es.Search.WithBody(&body),
+		p.es.Search.WithIndex(sr.index),
+		p.es.Search.WithBody(bytes.NewReader(sr.body)),
 		p.es.Search.WithTrackTotalHits(true),
-	)
+	}
+
+	if p.router != nil {
+		// the routed alias does not exist before the organization has indexed records
+		options = append(options, p.es.Search.WithIgnoreUnavailable(true), p.es.Search.WithAllowNoIndices(true))
+	}
+
+	res, err := p.es.Search(options...)
 
 	queryEnd := time.Now()
 
@@ -143,32 +326,33 @@ func (p *Proxy) retrieveFromElasticSearch(gctx groupcache.Context, id string, de
 	}
 
 	defer res.Body.Close()
-	defer r.Body.Close()
-
-	var buf bytes.Buffer
 
 	if res.IsError() {
-		msg, _ := ioutil.ReadAll(res.Body)
+		msg, readErr := ioutil.ReadAll(res.Body)
+		if readErr != nil {
+			return readErr
+		}
+
 		log.Warn().RawJSON("error", msg).Msg("elasticsearch error message")
+
+		// errors are not cached
+		return &SearchError{StatusCode: res.StatusCode, Body: msg}
 	}
 
+	var buf bytes.Buffer
+
 	size, err := io.Copy(&buf, res.Body)
 	if err != nil {
 		return err
 	}
 
-	requestID, _ := hlog.IDFromRequest(r)
-
-	var query bytes.Buffer
-	if _, err := query.ReadFrom(&queryBody); err != nil {
-		log.Warn().Err(err).Msg("unable to read query body from request")
-	}
+	requestID, _ := hlog.IDFromCtx(ctx)
 
 	log.Info().
 		Int("status", res.StatusCode).
 		Int64("size", size).
 		Str("req_id", requestID.String()).
-		Str("query", query.String()).
+		Str("query", string(sr.body)).
 		Dur("duration", queryEnd.Sub(queryStart)).
 		Msg("elastic ead cluster search request")
 
@@ -0,0 +1,266 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	defaultCallerHeader = "X-API-Key"
+	defaultOrgIDKey     = "meta.orgID"
+)
+
+var (
+	// ErrProxyForbidden is returned when the caller is not allowed to make the proxy request.
+	ErrProxyForbidden = errors.New("proxy request is not allowed")
+	// ErrUnknownCaller is returned when the caller has no CallerPolicy and there is no default.
+	ErrUnknownCaller = errors.New("unknown caller")
+)
+
+// blockedEndpoints are the write and admin endpoints that are never proxied.
+var blockedEndpoints = map[string]bool{
+	"_bulk":            true,
+	"_delete_by_query": true,
+	"_update_by_query": true,
+	"_settings":        true,
+	"_mapping":         true,
+	"_update":          true,
+	"_create":          true,
+	"_reindex":         true,
+	"_close":           true,
+	"_open":            true,
+	"_alias":           true,
+	"_aliases":         true,
+}
+
+// allowedBodyKeys are the search request body keys that are proxied for a
+// caller with an organization. Other keys, like 'suggest', are not restricted
+// by the query and are rejected.
+var allowedBodyKeys = map[string]bool{
+	"query":               true,
+	"post_filter":         true,
+	"aggs":                true,
+	"aggregations":        true,
+	"from":                true,
+	"size":                true,
+	"sort":                true,
+	"_source":             true,
+	"fields":              true,
+	"docvalue_fields":     true,
+	"stored_fields":       true,
+	"highlight":           true,
+	"rescore":             true,
+	"collapse":            true,
+	"search_after":        true,
+	"track_total_hits":    true,
+	"track_scores":        true,
+	"min_score":           true,
+	"timeout":             true,
+	"terminate_after":     true,
+	"version":             true,
+	"seq_no_primary_term": true,
+	"explain":             true,
+}
+
+// blockedAggregations are the aggregations that use documents outside the
+// query, e.g. the background set of 'significant_terms'.
+var blockedAggregations = map[string]bool{
+	"global":            true,
+	"significant_terms": true,
+	"significant_text":  true,
+}
+
+// CallerPolicy restricts the proxy requests of a caller.
+type CallerPolicy struct {
+	// OrgID is added as a filter to each query of the caller
+	OrgID string
+	// Indices are the index patterns the caller is allowed to query, e.g. 'hub3v2*'
+	Indices []string
+}
+
+// allowsIndex returns true when each index of the comma-separated list matches an index pattern.
+func (cp *CallerPolicy) allowsIndex(indices string) bool {
+	if indices == "" {
+		return false
+	}
+
+	for _, index := range strings.Split(indices, ",") {
+		var allowed bool
+
+		for _, pattern := range cp.Indices {
+			if ok, _ := path.Match(pattern, index); ok {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ProxyPolicy is applied to each request of the ElasticSearch Proxy.
+//
+// Write and admin endpoints are blocked, the requested indices must match the
+// index patterns of the caller and the query is filtered on the organization
+// of the caller.
+type ProxyPolicy struct {
+	// CallerHeader is the header that identifies the caller. default: X-API-Key
+	CallerHeader string
+	// OrgIDKey is the field with the organization of each document. default: meta.orgID
+	OrgIDKey string
+	// Callers contains the CallerPolicy for each caller
+	Callers map[string]CallerPolicy
+	// Default is applied to callers without a CallerPolicy. When nil they are rejected.
+	Default *CallerPolicy
+}
+
+func (pp *ProxyPolicy) callerHeader() string {
+	if pp.CallerHeader == "" {
+		return defaultCallerHeader
+	}
+
+	return pp.CallerHeader
+}
+
+func (pp *ProxyPolicy) orgIDKey() string {
+	if pp.OrgIDKey == "" {
+		return defaultOrgIDKey
+	}
+
+	return pp.OrgIDKey
+}
+
+// Authorize returns the CallerPolicy of the request. An error is returned when
+// the request targets a blocked endpoint or an index that is not allowed.
+func (pp *ProxyPolicy) Authorize(r *http.Request, index string) (*CallerPolicy, error) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return nil, fmt.Errorf("%w: method %s", ErrProxyForbidden, r.Method)
+	}
+
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if blockedEndpoints[segment] {
+			return nil, fmt.Errorf("%w: endpoint %s", ErrProxyForbidden, segment)
+		}
+	}
+
+	cp, ok := pp.Callers[r.Header.Get(pp.callerHeader())]
+	if !ok {
+		if pp.Default == nil {
+			return nil, ErrUnknownCaller
+		}
+
+		cp = *pp.Default
+	}
+
+	if !cp.allowsIndex(index) {
+		return nil, fmt.Errorf("%w: index %s", ErrProxyForbidden, index)
+	}
+
+	return &cp, nil
+}
+
+// FilterQuery returns the search request body with the query restricted to the organization.
+//
+// An ErrProxyForbidden error is returned when the body contains a key or an
+// aggregation that is not restricted by the query.
+func (pp *ProxyPolicy) FilterQuery(body []byte, orgID string) ([]byte, error) {
+	if orgID == "" {
+		return body, nil
+	}
+
+	request := map[string]interface{}{}
+
+	if len(strings.TrimSpace(string(body))) != 0 {
+		if err := json.Unmarshal(body, &request); err != nil {
+			return nil, fmt.Errorf("unable to parse search request; %w", err)
+		}
+	}
+
+	for key := range request {
+		if !allowedBodyKeys[key] {
+			return nil, fmt.Errorf("%w: search request key %s", ErrProxyForbidden, key)
+		}
+	}
+
+	for _, key := range []string{"aggs", "aggregations"} {
+		if err := checkAggregations(request[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	query, ok := request["query"]
+	if !ok {
+		query = map[string]interface{}{"match_all": map[string]interface{}{}}
+	}
+
+	request["query"] = map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must": []interface{}{query},
+			"filter": []interface{}{
+				map[string]interface{}{
+					"term": map[string]interface{}{pp.orgIDKey(): orgID},
+				},
+			},
+		},
+	}
+
+	return json.Marshal(request)
+}
+
+// checkAggregations returns an ErrProxyForbidden error when the aggregations
+// or their sub-aggregations contain a blocked aggregation.
+func checkAggregations(aggs interface{}) error {
+	if aggs == nil {
+		return nil
+	}
+
+	named, ok := aggs.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%w: invalid aggregations", ErrProxyForbidden)
+	}
+
+	for name, agg := range named {
+		body, ok := agg.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: invalid aggregation %s", ErrProxyForbidden, name)
+		}
+
+		for aggType, sub := range body {
+			if blockedAggregations[aggType] {
+				return fmt.Errorf("%w: %s aggregation %s", ErrProxyForbidden, aggType, name)
+			}
+
+			if aggType != "aggs" && aggType != "aggregations" {
+				continue
+			}
+
+			if err := checkAggregations(sub); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
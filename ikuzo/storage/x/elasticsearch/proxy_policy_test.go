@@ -0,0 +1,227 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func TestProxyPolicy_Authorize(t *testing.T) {
+	policy := &ProxyPolicy{
+		Callers: map[string]CallerPolicy{
+			"secret": {OrgID: "org1", Indices: []string{"org1v2*", "shared"}},
+		},
+		Default: &CallerPolicy{OrgID: "hub3", Indices: []string{"hub3*"}},
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		caller    string
+		index     string
+		wantOrgID string
+		wantErr   error
+	}{
+		{"known caller", http.MethodPost, "/org1v2/_search", "secret", "org1v2", "org1", nil},
+		{"multiple indices", http.MethodGet, "/org1v2_frag,shared/_search", "secret", "org1v2_frag,shared", "org1", nil},
+		{"index not allowed", http.MethodPost, "/hub3v2/_search", "secret", "hub3v2", "", ErrProxyForbidden},
+		{"wildcard index", http.MethodPost, "/*/_search", "secret", "*", "", ErrProxyForbidden},
+		{"default caller", http.MethodPost, "/hub3v2/_search", "", "hub3v2", "hub3", nil},
+		{"delete by query", http.MethodPost, "/hub3v2/_delete_by_query", "", "hub3v2", "", ErrProxyForbidden},
+		{"bulk", http.MethodPost, "/hub3v2/_bulk", "", "hub3v2", "", ErrProxyForbidden},
+		{"settings", http.MethodGet, "/hub3v2/_settings/_search", "", "hub3v2", "", ErrProxyForbidden},
+		{"delete", http.MethodDelete, "/hub3v2/_search", "", "hub3v2", "", ErrProxyForbidden},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			if tt.caller != "" {
+				req.Header.Set("X-API-Key", tt.caller)
+			}
+
+			got, err := policy.Authorize(req, tt.index)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProxyPolicy.Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && got.OrgID != tt.wantOrgID {
+				t.Errorf("ProxyPolicy.Authorize() orgID = %s, want %s", got.OrgID, tt.wantOrgID)
+			}
+		})
+	}
+}
+
+func TestProxyPolicy_AuthorizeUnknownCaller(t *testing.T) {
+	is := is.New(t)
+
+	policy := &ProxyPolicy{CallerHeader: "X-Caller"}
+
+	req := httptest.NewRequest(http.MethodPost, "/hub3v2/_search", nil)
+	req.Header.Set("X-Caller", "unknown")
+
+	_, err := policy.Authorize(req, "hub3v2")
+	is.True(errors.Is(err, ErrUnknownCaller))
+}
+
+func TestProxyPolicy_FilterQuery(t *testing.T) {
+	policy := &ProxyPolicy{}
+
+	tests := []struct {
+		name    string
+		body    string
+		orgID   string
+		want    string
+		wantErr bool
+	}{
+		{
+			"no organization",
+			`{"query": {"match_all": {}}}`,
+			"",
+			`{"query": {"match_all": {}}}`,
+			false,
+		},
+		{
+			"empty body",
+			``,
+			"hub3",
+			`{"query":{"bool":{"filter":[{"term":{"meta.orgID":"hub3"}}],"must":[{"match_all":{}}]}}}`,
+			false,
+		},
+		{
+			"query with aggregations",
+			`{"size": 0, "query": {"term": {"meta.spec": "spec1"}}, "aggs": {"tags": {"terms": {"field": "meta.tags"}}}}`,
+			"hub3",
+			`{"aggs":{"tags":{"terms":{"field":"meta.tags"}}},` +
+				`"query":{"bool":{"filter":[{"term":{"meta.orgID":"hub3"}}],"must":[{"term":{"meta.spec":"spec1"}}]}},"size":0}`,
+			false,
+		},
+		{
+			"post_filter without query",
+			`{"post_filter": {"term": {"meta.spec": "spec1"}}}`,
+			"hub3",
+			`{"post_filter":{"term":{"meta.spec":"spec1"}},` +
+				`"query":{"bool":{"filter":[{"term":{"meta.orgID":"hub3"}}],"must":[{"match_all":{}}]}}}`,
+			false,
+		},
+		{
+			"global aggregation",
+			`{"aggs": {"all": {"global": {}, "aggs": {"orgs": {"terms": {"field": "meta.orgID"}}}}}}`,
+			"hub3",
+			``,
+			true,
+		},
+		{
+			"nested global aggregation",
+			`{"aggregations": {"tags": {"terms": {"field": "meta.tags"}, "aggs": {"all": {"global": {}}}}}}`,
+			"hub3",
+			``,
+			true,
+		},
+		{
+			"significant terms background",
+			`{"aggs": {"sig": {"significant_terms": {"field": "meta.tags"}}}}`,
+			"hub3",
+			``,
+			true,
+		},
+		{
+			"suggest",
+			`{"suggest": {"titles": {"text": "secret", "term": {"field": "title"}}}}`,
+			"hub3",
+			``,
+			true,
+		},
+		{
+			"unknown key",
+			`{"query": {"match_all": {}}, "pit": {"id": "abc"}}`,
+			"hub3",
+			``,
+			true,
+		},
+		{
+			"invalid body",
+			`{"query":`,
+			"hub3",
+			``,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.FilterQuery([]byte(tt.body), tt.orgID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProxyPolicy.FilterQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("ProxyPolicy.FilterQuery() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProxy_Invalidate(t *testing.T) {
+	is := is.New(t)
+
+	p := &Proxy{generations: cacheGenerations{orgs: map[string]uint64{}}}
+
+	body := []byte(`{"query": {"match_all": {}}}`)
+
+	org1 := searchKey("hub3v2", p.generations.get("org1"), body)
+	org2 := searchKey("hub3v2", p.generations.get("org2"), body)
+	global := searchKey("hub3v2", p.generations.get(""), body)
+
+	p.Invalidate("org1", "spec1")
+
+	is.True(org1 != searchKey("hub3v2", p.generations.get("org1"), body))
+	is.Equal(org2, searchKey("hub3v2", p.generations.get("org2"), body))
+	is.True(global != searchKey("hub3v2", p.generations.get(""), body))
+}
+
+func TestProxy_InvalidateAfterRefresh(t *testing.T) {
+	is := is.New(t)
+
+	p := &Proxy{
+		generations:     cacheGenerations{orgs: map[string]uint64{}},
+		refreshInterval: 10 * time.Millisecond,
+	}
+
+	p.Invalidate("org1", "spec1")
+
+	// the response that is cached before the refresh is invalidated again
+	is.Equal(p.generations.get("org1"), uint64(1))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.generations.get("org1") != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	is.Equal(p.generations.get("org1"), uint64(2))
+	is.Equal(p.generations.get("org2"), uint64(0))
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/matryer/is"
+)
+
+func TestProxy_ServeHTTPError(t *testing.T) {
+	is := is.New(t)
+
+	errorBody := `{"error":{"type":"parsing_exception","reason":"unknown query [mtch]"},"status":400}`
+
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(errorBody))
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	is.NoErr(err)
+
+	p, err := NewProxy(es)
+	is.NoErr(err)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hub3v2/_search", strings.NewReader(`{"query":{"mtch":{}}}`)))
+
+		// the ElasticSearch error is returned unchanged
+		is.Equal(w.Code, http.StatusBadRequest)
+		is.Equal(w.Body.String(), errorBody)
+		is.Equal(w.Header().Get("Content-Type"), "application/json")
+	}
+
+	// errors are not cached
+	is.Equal(atomic.LoadInt32(&requests), int32(2))
+}
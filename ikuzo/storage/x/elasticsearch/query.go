@@ -16,6 +16,7 @@ package elasticsearch
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/delving/hub3/ikuzo/service/x/search"
 	elastic "github.com/olivere/elastic/v7"
@@ -26,8 +27,45 @@ type QueryField struct {
 	Boost float64
 }
 
+// Locations of the resource entries in the v2 mapping.
+const (
+	entriesPath        = "resources.entries"
+	entriesValueField  = "resources.entries.@value"
+	entriesSearchLabel = "resources.entries.searchLabel"
+	entriesKeyword     = "resources.entries.@value.keyword"
+	entriesPhonetic    = "resources.entries.phonetic"
+)
+
+// FieldMapping maps a field in the query to its location in the index.
+type FieldMapping struct {
+	// Path is the nested path of the field. It is empty for fields that are
+	// not nested.
+	Path string
+	// Field is the name of the field in the index.
+	Field string
+	// SearchLabel restricts the nested resource entries to this searchLabel.
+	SearchLabel string
+	// Boost is applied when the query does not specify a boost.
+	Boost float64
+}
+
+// NewEntryFieldMapping returns the FieldMapping for the resource entries with
+// the searchLabel in the v2 mapping.
+func NewEntryFieldMapping(searchLabel string) FieldMapping {
+	return FieldMapping{
+		Path:        entriesPath,
+		Field:       entriesValueField,
+		SearchLabel: searchLabel,
+	}
+}
+
+// phoneticExactBoost is the boost given to exact matches in a phonetic query,
+// so that they are ranked above the phonetic variants.
+const phoneticExactBoost = 2.0
+
 type QueryBuilder struct {
 	defaultFields []QueryField
+	fieldMap      map[string]FieldMapping
 }
 
 func NewQueryBuilder(defaultFields ...QueryField) *QueryBuilder {
@@ -36,15 +74,66 @@ func NewQueryBuilder(defaultFields ...QueryField) *QueryBuilder {
 	}
 }
 
+// SetFieldMap sets the mapping from the fields in the query, e.g. 'dc_title:word',
+// to their location in the index.
+//
+// Fields that are not in the map are resolved as follows: fields that contain a
+// '.' are queried as is, e.g. 'meta.spec', all other fields are queried as the
+// searchLabel of the resource entries in the v2 mapping.
+func (qb *QueryBuilder) SetFieldMap(fields map[string]FieldMapping) {
+	qb.fieldMap = fields
+}
+
+// fieldMapping returns the FieldMapping for the field in the query.
+func (qb *QueryBuilder) fieldMapping(field string) FieldMapping {
+	if mapping, ok := qb.fieldMap[field]; ok {
+		return mapping
+	}
+
+	if strings.Contains(field, ".") {
+		return FieldMapping{Field: field}
+	}
+
+	return NewEntryFieldMapping(field)
+}
+
+// ParseQuery parses the query string with the search.QueryParser and returns
+// it as an elastic.Query. An empty query returns a match_all query.
+func (qb *QueryBuilder) ParseQuery(query string) (elastic.Query, error) {
+	if strings.TrimSpace(query) == "" {
+		return elastic.NewMatchAllQuery(), nil
+	}
+
+	qp, err := search.NewQueryParser()
+	if err != nil {
+		return nil, err
+	}
+
+	qt, err := qp.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return qb.NewElasticQuery(qt), nil
+}
+
 func (qb *QueryBuilder) NewElasticQuery(q *search.QueryTerm) elastic.Query {
 	if !q.IsBoolQuery() && q.Value == "" {
 		return elastic.NewMatchAllQuery()
 	}
 
 	if !q.IsBoolQuery() {
+		if q.Field != "" {
+			return qb.buildScopedQuery(q)
+		}
+
 		switch q.Type() {
 		case search.PhraseQuery:
 			return buildFieldQueries(q, qb.defaultFields, buildMatchPhraseQuery)
+		case search.WildCardQuery:
+			return buildFieldQueries(q, qb.defaultFields, buildWildcardQuery)
+		case search.PhoneticQuery:
+			return qb.buildPhoneticQuery(q)
 		default:
 			return buildFieldQueries(q, qb.defaultFields, buildMatchQuery)
 		}
@@ -67,6 +156,57 @@ func (qb *QueryBuilder) NewElasticQuery(q *search.QueryTerm) elastic.Query {
 	return bq
 }
 
+// buildPhoneticQuery matches the term exactly on the default fields and its
+// phonetic key on the phonetic keys of the resource entries in the v2 mapping.
+// The exact match is boosted, so exact matches are ranked above phonetic ones.
+func (qb *QueryBuilder) buildPhoneticQuery(q *search.QueryTerm) elastic.Query {
+	exact := *q
+	if exact.Boost == 0 {
+		exact.Boost = phoneticExactBoost
+	}
+
+	phonetic := *q
+	phonetic.Value = search.PhoneticKey(q.Value)
+
+	return elastic.NewBoolQuery().
+		Should(
+			buildFieldQueries(&exact, qb.defaultFields, buildMatchQuery),
+			elastic.NewNestedQuery(entriesPath, buildTermQuery(&phonetic, QueryField{Field: entriesPhonetic})),
+		)
+}
+
+// buildScopedQuery queries the field of the QueryTerm instead of the default
+// fields. Nested fields are wrapped in a nested query, that is restricted to
+// the searchLabel of the FieldMapping.
+func (qb *QueryBuilder) buildScopedQuery(q *search.QueryTerm) elastic.Query {
+	mapping := qb.fieldMapping(q.Field)
+	field := QueryField{Field: mapping.Field, Boost: mapping.Boost}
+
+	var esq elastic.Query
+
+	switch q.Type() {
+	case search.PhraseQuery:
+		esq = buildMatchPhraseQuery(q, field)
+	case search.WildCardQuery:
+		esq = buildWildcardQuery(q, field)
+	default:
+		esq = buildMatchQuery(q, field)
+	}
+
+	if mapping.SearchLabel != "" {
+		esq = elastic.NewBoolQuery().Must(
+			elastic.NewTermQuery(entriesSearchLabel, mapping.SearchLabel),
+			esq,
+		)
+	}
+
+	if mapping.Path != "" {
+		esq = elastic.NewNestedQuery(mapping.Path, esq)
+	}
+
+	return esq
+}
+
 type fieldQuery func(q *search.QueryTerm, field QueryField) elastic.Query
 
 func buildFieldQueries(q *search.QueryTerm, fields []QueryField, fn fieldQuery) elastic.Query {
@@ -101,11 +241,51 @@ func buildMatchQuery(q *search.QueryTerm, field QueryField) elastic.Query {
 	return esq
 }
 
+func buildTermQuery(q *search.QueryTerm, field QueryField) elastic.Query {
+	esq := elastic.NewTermQuery(field.Field, q.Value)
+
+	if field.Boost != 0 {
+		esq = esq.Boost(field.Boost)
+	}
+
+	return esq
+}
+
+// buildWildcardQuery translates a prefix 'term*' or suffix '*term' QueryTerm.
+func buildWildcardQuery(q *search.QueryTerm, field QueryField) elastic.Query {
+	value := q.Value
+
+	if q.PrefixWildcard {
+		value += "*"
+	}
+
+	if q.SuffixWildcard {
+		value = "*" + value
+	}
+
+	esq := elastic.NewWildcardQuery(field.Field, value)
+
+	if q.Boost != 0 {
+		esq = esq.Boost(q.Boost)
+	} else if field.Boost != 0 {
+		esq = esq.Boost(field.Boost)
+	}
+
+	return esq
+}
+
 func buildMatchPhraseQuery(q *search.QueryTerm, field QueryField) elastic.Query {
 	esq := elastic.NewMatchPhraseQuery(field.Field, q.Value)
 
-	if q.Fuzzy != 0 {
-		esq = esq.Slop(q.Fuzzy)
+	// the QueryParser sets the slop of phrases, but fuzziness is also accepted
+	// for QueryTerms that are created directly.
+	slop := q.Slop
+	if slop == 0 {
+		slop = q.Fuzzy
+	}
+
+	if slop != 0 {
+		esq = esq.Slop(slop)
 	}
 
 	if q.Boost != 0 {
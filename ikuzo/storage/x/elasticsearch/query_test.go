@@ -17,7 +17,11 @@ package elasticsearch
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/delving/hub3/ikuzo/service/x/search"
@@ -230,3 +234,118 @@ func TestNewElasticQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryBuilder_buildPhoneticQuery(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		name string
+		q    *search.QueryTerm
+		want string
+	}{
+		{
+			"exact match is boosted",
+			&search.QueryTerm{Value: "janssen", Phonetic: true},
+			`{"bool":{"should":[` +
+				`{"match":{"full_text":{"boost":2,"query":"janssen"}}},` +
+				`{"nested":{"path":"resources.entries","query":{"term":{"resources.entries.phonetic":"JANSEN"}}}}` +
+				`]}}`,
+		},
+		{
+			"user boost is kept",
+			&search.QueryTerm{Value: "meyer", Phonetic: true, Boost: 3},
+			`{"bool":{"should":[` +
+				`{"match":{"full_text":{"boost":3,"query":"meyer"}}},` +
+				`{"nested":{"path":"resources.entries","query":{"term":{"resources.entries.phonetic":"MYER"}}}}` +
+				`]}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder(QueryField{Field: "full_text"})
+
+			bqSource, err := qb.NewElasticQuery(tt.q).Source()
+			is.NoErr(err)
+
+			got, err := json.Marshal(bqSource)
+			is.NoErr(err)
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("buildPhoneticQuery(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// nolint:gocritic
+func TestQueryBuilder_golden(t *testing.T) {
+	is := is.New(t)
+
+	fieldMap := map[string]FieldMapping{
+		"title":  {Path: "resources.entries", Field: "resources.entries.@value", SearchLabel: "dc_title", Boost: 2},
+		"spec":   {Field: "meta.spec"},
+		"period": NewEntryFieldMapping("dcterms_temporal"),
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"term", "amsterdam"},
+		{"boost", "amsterdam^3 leiden"},
+		{"fuzzy", "batavia~1"},
+		{"fuzzy default", "batavia~"},
+		{"phrase slop", `"huis te leiden"~2`},
+		{"prefix wildcard", "leid*"},
+		{"suffix wildcard", "*dam"},
+		{"field mapped", "title:kaart"},
+		{"field mapped with boost", "title:kaart^4"},
+		{"field mapped phrase", `title:"kaart van leiden"~1`},
+		{"field mapped wildcard", "period:eeuw*"},
+		{"field mapped fuzzy", "title:kaard~1"},
+		{"field not nested", "spec:maps"},
+		{"field with path", "meta.tags:ead"},
+		{"field search label", "dc_subject:kaart"},
+		{"mixed", "title:kaart AND leid* NOT spec:maps"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			qp, err := search.NewQueryParser()
+			is.NoErr(err)
+
+			qt, err := qp.Parse(tt.query)
+			is.NoErr(err)
+
+			qb := NewQueryBuilder(QueryField{Field: "full_text"})
+			qb.SetFieldMap(fieldMap)
+
+			src, err := qb.NewElasticQuery(qt).Source()
+			is.NoErr(err)
+
+			got, err := json.MarshalIndent(src, "", "  ")
+			is.NoErr(err)
+
+			golden := filepath.Join("testdata", "query", strings.ReplaceAll(tt.name, " ", "_")+".json")
+
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, append(got, '\n'), 0o600)
+				is.NoErr(err)
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			is.NoErr(err)
+
+			if diff := cmp.Diff(string(want), string(got)+"\n"); diff != "" {
+				t.Errorf("NewElasticQuery(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+)
+
+// ErrReindexCountMismatch is returned when the document counts of the new
+// index do not match the counts of the old index after reindexing.
+var ErrReindexCountMismatch = errors.New("document counts do not match after reindex")
+
+const (
+	defaultReindexPollInterval = 5 * time.Second
+	compositePageSize          = 1000
+)
+
+// ReindexConfig configures a zero-downtime reindex of an aliased index.
+type ReindexConfig struct {
+	// Alias is the alias that is switched to the new index
+	Alias string
+	// Mapping is the mapping of the new index
+	Mapping string
+	// CountField is the keyword field with the dataset of each document,
+	// e.g. 'meta.spec'. When empty only the total number of documents is verified.
+	CountField string
+	// KeepOldIndex keeps the old index after the alias switch for rollback
+	KeepOldIndex bool
+	// PollInterval is the interval for reporting the progress. default: 5 seconds
+	PollInterval time.Duration
+	// Progress is called with the progress of the reindex task on each poll
+	Progress func(ReindexProgress)
+}
+
+// ReindexProgress is the progress of the ElasticSearch reindex task.
+type ReindexProgress struct {
+	Total            int64 `json:"total"`
+	Created          int64 `json:"created"`
+	Updated          int64 `json:"updated"`
+	Deleted          int64 `json:"deleted"`
+	Batches          int64 `json:"batches"`
+	VersionConflicts int64 `json:"versionConflicts"`
+	Completed        bool  `json:"completed"`
+}
+
+// Processed returns the number of processed documents.
+func (p ReindexProgress) Processed() int64 {
+	return p.Created + p.Updated + p.Deleted + p.VersionConflicts
+}
+
+// ReindexResult is the result of a successful reindex.
+type ReindexResult struct {
+	Alias    string           `json:"alias"`
+	OldIndex string           `json:"oldIndex"`
+	NewIndex string           `json:"newIndex"`
+	Total    int64            `json:"total"`
+	Counts   map[string]int64 `json:"counts,omitempty"`
+	Progress ReindexProgress  `json:"progress"`
+	Duration time.Duration    `json:"duration"`
+}
+
+// IndexCounts contains the number of documents of an index.
+type IndexCounts struct {
+	Total int64
+	// Datasets contains the number of documents per dataset
+	Datasets map[string]int64
+}
+
+// Diff returns a description of each difference between the counts.
+func (ic IndexCounts) Diff(other IndexCounts) []string {
+	diffs := []string{}
+
+	if ic.Total != other.Total {
+		diffs = append(diffs, fmt.Sprintf("total: %d != %d", ic.Total, other.Total))
+	}
+
+	keys := map[string]bool{}
+	for k := range ic.Datasets {
+		keys[k] = true
+	}
+
+	for k := range other.Datasets {
+		keys[k] = true
+	}
+
+	for k := range keys {
+		if ic.Datasets[k] != other.Datasets[k] {
+			diffs = append(diffs, fmt.Sprintf("%s: %d != %d", k, ic.Datasets[k], other.Datasets[k]))
+		}
+	}
+
+	sort.Strings(diffs)
+
+	return diffs
+}
+
+// Reindex copies the index of the alias into a new versioned index with the
+// mapping of the ReindexConfig and switches the alias atomically.
+//
+// The documents are copied with the ElasticSearch _reindex API. Writes to the
+// old index are blocked during the copy, so no writes are lost, and are
+// rejected with a cluster_block_exception until the alias is switched. The
+// alias is only switched when the document counts per dataset of both indices
+// are equal. When reindexing, the verification or switching the alias fails
+// the new index is deleted, the old index is writable again and the alias is
+// left untouched.
+func Reindex(ctx context.Context, es *elasticsearch.Client, cfg ReindexConfig) (*ReindexResult, error) {
+	start := time.Now()
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = defaultReindexPollInterval
+	}
+
+	oldIndex, err := AliasGet(es, cfg.Alias)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find index for alias %s; %w", cfg.Alias, err)
+	}
+
+	newIndex, err := IndexCreate(es, cfg.Alias, cfg.Mapping, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create new index for %s; %w", cfg.Alias, err)
+	}
+
+	result := &ReindexResult{
+		Alias:    cfg.Alias,
+		OldIndex: oldIndex,
+		NewIndex: newIndex,
+	}
+
+	log.Info().Str("alias", cfg.Alias).Str("oldIndex", oldIndex).Str("newIndex", newIndex).Msg("start reindex")
+
+	cleanup := func(cause error) error {
+		if err := IndexDelete(es, newIndex); err != nil {
+			log.Error().Err(err).Str("index", newIndex).Msg("unable to delete new index")
+		}
+
+		if err := blockWrites(es, oldIndex, false); err != nil {
+			log.Error().Err(err).Str("index", oldIndex).Msg("unable to remove write block")
+		}
+
+		return cause
+	}
+
+	if err := blockWrites(es, oldIndex, true); err != nil {
+		return nil, cleanup(err)
+	}
+
+	// writes from just before the block must be searchable to be copied
+	if err := refresh(ctx, es, oldIndex); err != nil {
+		return nil, cleanup(err)
+	}
+
+	result.Progress, err = reindexTask(ctx, es, oldIndex, newIndex, cfg)
+	if err != nil {
+		return nil, cleanup(err)
+	}
+
+	if err := refresh(ctx, es, newIndex); err != nil {
+		return nil, cleanup(err)
+	}
+
+	oldCounts, err := Counts(ctx, es, oldIndex, cfg.CountField)
+	if err != nil {
+		return nil, cleanup(err)
+	}
+
+	newCounts, err := Counts(ctx, es, newIndex, cfg.CountField)
+	if err != nil {
+		return nil, cleanup(err)
+	}
+
+	if diffs := oldCounts.Diff(newCounts); len(diffs) != 0 {
+		return nil, cleanup(fmt.Errorf("%w: %s", ErrReindexCountMismatch, strings.Join(diffs, "; ")))
+	}
+
+	result.Total = newCounts.Total
+	result.Counts = newCounts.Datasets
+
+	if switched, err := IndexSwitch(es, cfg.Alias, newIndex, !cfg.KeepOldIndex); err != nil {
+		err = fmt.Errorf("unable to switch alias %s to %s; %w", cfg.Alias, newIndex, err)
+
+		// the alias still refers to the old index
+		if switched == "" {
+			return nil, cleanup(err)
+		}
+
+		// the alias is switched, but the old index could not be deleted
+		if blockErr := blockWrites(es, oldIndex, false); blockErr != nil {
+			log.Error().Err(blockErr).Str("index", oldIndex).Msg("unable to remove write block")
+		}
+
+		return nil, err
+	}
+
+	if cfg.KeepOldIndex {
+		// the old index must be writable after a rollback
+		if err := blockWrites(es, oldIndex, false); err != nil {
+			log.Error().Err(err).Str("index", oldIndex).Msg("unable to remove write block")
+		}
+	}
+
+	result.Duration = time.Since(start)
+
+	log.Info().Str("alias", cfg.Alias).Str("newIndex", newIndex).Int64("total", result.Total).
+		Bool("keepOldIndex", cfg.KeepOldIndex).Msg("finished reindex")
+
+	return result, nil
+}
+
+// reindexTask starts the _reindex task and polls it until it is completed.
+// The task is cancelled when the context is done.
+func reindexTask(ctx context.Context, es *elasticsearch.Client, source, dest string, cfg ReindexConfig) (ReindexProgress, error) {
+	var progress ReindexProgress
+
+	res, err := es.Reindex(
+		strings.NewReader(fmt.Sprintf(`{"source": {"index": %q}, "dest": {"index": %q}}`, source, dest)),
+		es.Reindex.WithContext(ctx),
+		es.Reindex.WithWaitForCompletion(false),
+	)
+	if err != nil {
+		return progress, fmt.Errorf("unable to start reindex; %w", err)
+	}
+
+	body := read(res.Body)
+	res.Body.Close()
+
+	if res.IsError() {
+		return progress, GetErrorType(strings.NewReader(body)).Error()
+	}
+
+	taskID := gjson.Get(body, "task").String()
+	if taskID == "" {
+		return progress, fmt.Errorf("no task returned by reindex: %s", body)
+	}
+
+	ticker := time.NewTicker(cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancelTask(es, taskID)
+			return progress, ctx.Err()
+		case <-ticker.C:
+		}
+
+		res, err := es.Tasks.Get(taskID, es.Tasks.Get.WithContext(ctx))
+		if err != nil {
+			return progress, fmt.Errorf("unable to get reindex task %s; %w", taskID, err)
+		}
+
+		body := read(res.Body)
+		res.Body.Close()
+
+		if res.IsError() {
+			return progress, GetErrorType(strings.NewReader(body)).Error()
+		}
+
+		progress, err = parseReindexTask(body)
+
+		if cfg.Progress != nil {
+			cfg.Progress(progress)
+		}
+
+		if err != nil || progress.Completed {
+			return progress, err
+		}
+	}
+}
+
+// parseReindexTask parses the progress from the tasks API response.
+// An error is returned when the completed task has failures.
+func parseReindexTask(body string) (ReindexProgress, error) {
+	status := gjson.Get(body, "task.status")
+
+	progress := ReindexProgress{
+		Total:            status.Get("total").Int(),
+		Created:          status.Get("created").Int(),
+		Updated:          status.Get("updated").Int(),
+		Deleted:          status.Get("deleted").Int(),
+		Batches:          status.Get("batches").Int(),
+		VersionConflicts: status.Get("version_conflicts").Int(),
+		Completed:        gjson.Get(body, "completed").Bool(),
+	}
+
+	if !progress.Completed {
+		return progress, nil
+	}
+
+	if reason := gjson.Get(body, "error.reason"); reason.Exists() {
+		return progress, fmt.Errorf("reindex task failed: %s", reason.String())
+	}
+
+	if failures := gjson.Get(body, "response.failures").Array(); len(failures) != 0 {
+		return progress, fmt.Errorf("reindex task failed with %d failures; first: %s", len(failures), failures[0].Raw)
+	}
+
+	return progress, nil
+}
+
+func cancelTask(es *elasticsearch.Client, taskID string) {
+	res, err := es.Tasks.Cancel(es.Tasks.Cancel.WithTaskID(taskID))
+	if err != nil {
+		log.Error().Err(err).Str("task", taskID).Msg("unable to cancel reindex task")
+		return
+	}
+
+	res.Body.Close()
+}
+
+// blockWrites sets or removes the write block of the index.
+func blockWrites(es *elasticsearch.Client, indexName string, block bool) error {
+	res, err := es.Indices.PutSettings(
+		strings.NewReader(writeBlockSettings(block)),
+		es.Indices.PutSettings.WithIndex(indexName),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to update write block of %s; %w", indexName, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
+
+// writeBlockSettings returns the index settings for the write block. Removing
+// the block resets the setting to its default.
+func writeBlockSettings(block bool) string {
+	if block {
+		return `{"index": {"blocks.write": true}}`
+	}
+
+	return `{"index": {"blocks.write": null}}`
+}
+
+func refresh(ctx context.Context, es *elasticsearch.Client, indexName string) error {
+	res, err := es.Indices.Refresh(
+		es.Indices.Refresh.WithContext(ctx),
+		es.Indices.Refresh.WithIndex(indexName),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to refresh %s; %w", indexName, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
+
+// Counts returns the number of documents in the index. When the countField is
+// not empty the documents are also counted per value of the countField.
+func Counts(ctx context.Context, es *elasticsearch.Client, indexName, countField string) (IndexCounts, error) {
+	counts := IndexCounts{Datasets: map[string]int64{}}
+
+	var after string
+
+	for {
+		body := countsQuery(countField, after)
+
+		res, err := es.Search(
+			es.Search.WithContext(ctx),
+			es.Search.WithIndex(indexName),
+			es.Search.WithBody(strings.NewReader(body)),
+		)
+		if err != nil {
+			return counts, fmt.Errorf("unable to count documents in %s; %w", indexName, err)
+		}
+
+		json := read(res.Body)
+		res.Body.Close()
+
+		if res.IsError() {
+			return counts, GetErrorType(strings.NewReader(json)).Error()
+		}
+
+		after = parseCounts(json, &counts)
+		if after == "" {
+			return counts, nil
+		}
+	}
+}
+
+// countsQuery returns the search request that counts the documents per
+// countField with a composite aggregation starting after the given after_key.
+func countsQuery(countField, after string) string {
+	if countField == "" {
+		return `{"size": 0, "track_total_hits": true}`
+	}
+
+	afterKey := ""
+	if after != "" {
+		afterKey = fmt.Sprintf(`, "after": %s`, after)
+	}
+
+	return fmt.Sprintf(
+		`{"size": 0, "track_total_hits": true, "aggs": {"datasets": {"composite": {"size": %d, "sources": [{"dataset": {"terms": {"field": %q}}}]%s}}}}`,
+		compositePageSize,
+		countField,
+		afterKey,
+	)
+}
+
+// parseCounts adds the counts of the search response to the IndexCounts.
+// It returns the raw after_key of the next page or an empty string when all pages are read.
+func parseCounts(json string, counts *IndexCounts) string {
+	counts.Total = gjson.Get(json, "hits.total.value").Int()
+
+	buckets := gjson.Get(json, "aggregations.datasets.buckets").Array()
+	for _, b := range buckets {
+		counts.Datasets[b.Get("key.dataset").String()] += b.Get("doc_count").Int()
+	}
+
+	if len(buckets) < compositePageSize {
+		return ""
+	}
+
+	return gjson.Get(json, "aggregations.datasets.after_key").Raw
+}
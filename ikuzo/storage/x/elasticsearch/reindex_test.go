@@ -0,0 +1,303 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+// nolint:gocritic
+func (s *elasticSuite) TestReindex() {
+	is := is.New(s.T())
+
+	cfg := elasticsearch.Config{Addresses: []string{fmt.Sprintf("http://%s:%s", s.ip, s.port.Port())}}
+	es, err := elasticsearch.NewClient(cfg)
+	is.NoErr(err)
+
+	mapping := `{"mappings": {"properties": {"meta": {"properties": {"spec": {"type": "keyword"}}}}}}`
+
+	oldIndexName, err := IndexCreate(es, "hub3reindex", mapping, true)
+	is.NoErr(err)
+
+	for i, spec := range []string{"spec1", "spec1", "spec2"} {
+		res, indexErr := es.Index(
+			oldIndexName,
+			strings.NewReader(fmt.Sprintf(`{"meta": {"spec": %q}}`, spec)),
+			es.Index.WithDocumentID(fmt.Sprintf("%d", i)),
+			es.Index.WithRefresh("true"),
+		)
+		is.NoErr(indexErr)
+		res.Body.Close()
+	}
+
+	var progressCalled bool
+
+	result, err := Reindex(s.ctx, es, ReindexConfig{
+		Alias:        "hub3reindex",
+		Mapping:      mapping,
+		CountField:   "meta.spec",
+		KeepOldIndex: true,
+		PollInterval: 100 * time.Millisecond,
+		Progress:     func(ReindexProgress) { progressCalled = true },
+	})
+	is.NoErr(err)
+	is.True(progressCalled)
+	is.Equal(result.OldIndex, oldIndexName)
+	is.Equal(result.Total, int64(3))
+	is.Equal(result.Counts, map[string]int64{"spec1": 2, "spec2": 1})
+
+	indexName, err := AliasGet(es, "hub3reindex")
+	is.NoErr(err)
+	is.Equal(indexName, result.NewIndex)
+
+	// the old index is kept for rollback
+	is.NoErr(IndexExists(es, oldIndexName))
+
+	is.NoErr(IndexDelete(es, oldIndexName))
+	is.NoErr(IndexDelete(es, result.NewIndex))
+}
+
+func TestIndexCounts_Diff(t *testing.T) {
+	old := IndexCounts{Total: 3, Datasets: map[string]int64{"spec1": 2, "spec2": 1}}
+
+	tests := []struct {
+		name  string
+		other IndexCounts
+		want  []string
+	}{
+		{
+			"equal",
+			IndexCounts{Total: 3, Datasets: map[string]int64{"spec1": 2, "spec2": 1}},
+			[]string{},
+		},
+		{
+			"missing dataset",
+			IndexCounts{Total: 2, Datasets: map[string]int64{"spec1": 2}},
+			[]string{"spec2: 1 != 0", "total: 3 != 2"},
+		},
+		{
+			"extra dataset",
+			IndexCounts{Total: 3, Datasets: map[string]int64{"spec1": 1, "spec2": 1, "spec3": 1}},
+			[]string{"spec1: 2 != 1", "spec3: 0 != 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, old.Diff(tt.other)); diff != "" {
+				t.Errorf("IndexCounts.Diff() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseReindexTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    ReindexProgress
+		wantErr bool
+	}{
+		{
+			"running",
+			`{"completed": false, "task": {"status": {"total": 10, "created": 4, "batches": 1}}}`,
+			ReindexProgress{Total: 10, Created: 4, Batches: 1},
+			false,
+		},
+		{
+			"completed",
+			`{"completed": true, "task": {"status": {"total": 10, "created": 10, "batches": 2}}, "response": {"failures": []}}`,
+			ReindexProgress{Total: 10, Created: 10, Batches: 2, Completed: true},
+			false,
+		},
+		{
+			"failures",
+			`{"completed": true, "task": {"status": {"total": 10, "created": 9}}, "response": {"failures": [{"id": "1"}]}}`,
+			ReindexProgress{Total: 10, Created: 9, Completed: true},
+			true,
+		},
+		{
+			"error",
+			`{"completed": true, "task": {"status": {"total": 10}}, "error": {"reason": "boom"}}`,
+			ReindexProgress{Total: 10, Completed: true},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReindexTask(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReindexTask() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseReindexTask() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseCounts(t *testing.T) {
+	counts := IndexCounts{Datasets: map[string]int64{}}
+
+	after := parseCounts(
+		`{
+			"hits": {"total": {"value": 3}},
+			"aggregations": {"datasets": {
+				"after_key": {"dataset": "spec2"},
+				"buckets": [
+					{"key": {"dataset": "spec1"}, "doc_count": 2},
+					{"key": {"dataset": "spec2"}, "doc_count": 1}
+				]
+			}}
+		}`,
+		&counts,
+	)
+
+	if after != "" {
+		t.Errorf("parseCounts() after = %q, want no next page", after)
+	}
+
+	want := IndexCounts{Total: 3, Datasets: map[string]int64{"spec1": 2, "spec2": 1}}
+	if diff := cmp.Diff(want, counts); diff != "" {
+		t.Errorf("parseCounts() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_countsQuery(t *testing.T) {
+	if got := countsQuery("", ""); strings.Contains(got, "aggs") {
+		t.Errorf("countsQuery() without countField should not aggregate; got %s", got)
+	}
+
+	got := countsQuery("meta.spec", `{"dataset":"spec2"}`)
+	if !strings.Contains(got, `"field": "meta.spec"`) || !strings.Contains(got, `"after": {"dataset":"spec2"}`) {
+		t.Errorf("countsQuery() = %s", got)
+	}
+}
+
+func TestReindex_writeBlock(t *testing.T) {
+	is := is.New(t)
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/_alias/hub3v2":
+			_, _ = w.Write([]byte(`{"hub3v2_1": {"aliases": {"hub3v2": {}}}}`))
+		case r.URL.Path == "/_reindex":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"type":"illegal_argument_exception","reason":"bad mapping"},"status":400}`))
+		default:
+			_, _ = w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	is.NoErr(err)
+
+	_, err = Reindex(context.Background(), es, ReindexConfig{Alias: "hub3v2", Mapping: "{}"})
+	is.True(err != nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the old index is blocked for writes and refreshed before the copy and
+	// writable after the failure
+	is.Equal(len(requests), 7)
+	is.Equal(requests[2], `PUT /hub3v2_1/_settings {"index": {"blocks.write": true}}`)
+	is.Equal(requests[3], "POST /hub3v2_1/_refresh")
+	is.True(strings.HasPrefix(requests[4], "POST /_reindex"))
+	is.True(strings.HasPrefix(requests[5], "DELETE /hub3v2_"))
+	is.Equal(requests[6], `PUT /hub3v2_1/_settings {"index": {"blocks.write": null}}`)
+}
+
+func TestReindex_switchFailure(t *testing.T) {
+	is := is.New(t)
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/_alias/hub3v2":
+			_, _ = w.Write([]byte(`{"hub3v2_1": {"aliases": {"hub3v2": {}}}}`))
+		case r.URL.Path == "/_reindex":
+			_, _ = w.Write([]byte(`{"task": "node:1"}`))
+		case r.URL.Path == "/_tasks/node:1":
+			_, _ = w.Write([]byte(`{"completed": true, "task": {"status": {"total": 0}}, "response": {"failures": []}}`))
+		case strings.HasSuffix(r.URL.Path, "/_search"):
+			_, _ = w.Write([]byte(`{"hits": {"total": {"value": 0}}}`))
+		case r.URL.Path == "/_aliases":
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":{"type":"timeout_exception","reason":"timeout"},"status":500}`))
+		default:
+			_, _ = w.Write([]byte(`{"acknowledged": true}`))
+		}
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	is.NoErr(err)
+
+	_, err = Reindex(context.Background(), es, ReindexConfig{Alias: "hub3v2", Mapping: "{}", PollInterval: time.Millisecond})
+	is.True(err != nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the alias still refers to the old index, so it must be writable again
+	n := len(requests)
+	is.True(n > 2)
+	is.True(strings.HasPrefix(requests[n-2], "DELETE /hub3v2_"))
+	is.Equal(requests[n-1], "PUT /hub3v2_1/_settings")
+}
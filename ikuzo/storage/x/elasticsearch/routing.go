@@ -0,0 +1,253 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/rs/zerolog/log"
+)
+
+// RoutingStrategy determines in which index the documents of an organization are stored.
+type RoutingStrategy string
+
+const (
+	// RoutingShared stores the documents of all organizations in a single index.
+	RoutingShared RoutingStrategy = "shared"
+	// RoutingOrganization stores the documents of each organization in its own index.
+	RoutingOrganization RoutingStrategy = "organization"
+	// RoutingDataset stores the documents of each dataset of an organization in its own index.
+	RoutingDataset RoutingStrategy = "dataset"
+)
+
+// aliasSeparator separates the base alias, the organization and the dataset in a routed alias.
+const aliasSeparator = "-"
+
+// ErrUnknownRoutingStrategy is returned when the RoutingStrategy is not supported.
+var ErrUnknownRoutingStrategy = errors.New("unknown routing strategy")
+
+// ParseRoutingStrategy returns the RoutingStrategy. An empty string is RoutingShared.
+func ParseRoutingStrategy(s string) (RoutingStrategy, error) {
+	switch strategy := RoutingStrategy(strings.ToLower(s)); strategy {
+	case "":
+		return RoutingShared, nil
+	case RoutingShared, RoutingOrganization, RoutingDataset:
+		return strategy, nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrUnknownRoutingStrategy, s)
+}
+
+// unroutedComponent replaces an empty organization or dataset in a write
+// alias. It is never returned by aliasComponent.
+const unroutedComponent = "_none"
+
+// aliasComponent returns the organization or dataset identifier as a valid
+// part of an index alias. The characters [a-z0-9] are kept and each other
+// character is replaced by its hexadecimal code point between underscores,
+// e.g. 'a-b' becomes 'a_2d_b'. The result is unique for each identifier and
+// never contains the aliasSeparator.
+func aliasComponent(s string) string {
+	var sb strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		default:
+			fmt.Fprintf(&sb, "_%x_", r)
+		}
+	}
+
+	return sb.String()
+}
+
+// IndexRouter selects the index alias of documents and searches by RoutingStrategy.
+//
+// The index of a routed alias is created on first use with the mapping of its
+// base alias, e.g. 'hub3v2'. Each routed index is also added to the base alias
+// and, with RoutingDataset, to the alias of its organization, so queries on
+// these aliases include the routed documents. Because the base alias and the
+// organization aliases then refer to multiple indices, documents are only
+// written to routed aliases; documents without an organization or dataset are
+// written to a routed alias with the '_none' component. Operations on a single
+// index, like Reindex, IndexSwitch and SnapshotRestore, refuse these aliases.
+type IndexRouter struct {
+	es       *elasticsearch.Client
+	strategy RoutingStrategy
+	// mappings contains the mapping of each base alias
+	mappings map[string]string
+	rw       sync.RWMutex
+	created  map[string]bool
+	// locks serialize the creation of each routed alias
+	locks map[string]*sync.Mutex
+}
+
+// NewIndexRouter returns an IndexRouter for the base aliases and their mappings.
+func NewIndexRouter(es *elasticsearch.Client, strategy RoutingStrategy, mappings map[string]string) *IndexRouter {
+	return &IndexRouter{
+		es:       es,
+		strategy: strategy,
+		mappings: mappings,
+		created:  map[string]bool{},
+		locks:    map[string]*sync.Mutex{},
+	}
+}
+
+// Strategy returns the RoutingStrategy of the IndexRouter.
+func (ir *IndexRouter) Strategy() RoutingStrategy {
+	return ir.strategy
+}
+
+// isRouted returns true when documents in the base alias are routed.
+func (ir *IndexRouter) isRouted(base string) bool {
+	if ir.strategy == RoutingShared {
+		return false
+	}
+
+	_, ok := ir.mappings[base]
+
+	return ok
+}
+
+// WriteAlias returns the alias where the documents of the dataset are stored.
+func (ir *IndexRouter) WriteAlias(base, orgID, datasetID string) string {
+	if !ir.isRouted(base) {
+		return base
+	}
+
+	alias := base + aliasSeparator + writeComponent(orgID)
+
+	if ir.strategy == RoutingDataset {
+		alias += aliasSeparator + writeComponent(datasetID)
+	}
+
+	return alias
+}
+
+// writeComponent returns the aliasComponent of the identifier or the
+// unroutedComponent when it is empty.
+func writeComponent(s string) string {
+	if s == "" {
+		return unroutedComponent
+	}
+
+	return aliasComponent(s)
+}
+
+// SearchAlias returns the alias that contains all documents of the organization.
+// Without an orgID the base alias is returned.
+func (ir *IndexRouter) SearchAlias(base, orgID string) string {
+	if !ir.isRouted(base) || orgID == "" {
+		return base
+	}
+
+	return base + aliasSeparator + aliasComponent(orgID)
+}
+
+// readAliases returns the aliases that must include the index of the write alias.
+func (ir *IndexRouter) readAliases(base, orgID, writeAlias string) []string {
+	aliases := []string{base}
+
+	if orgAlias := ir.SearchAlias(base, orgID); orgAlias != base && orgAlias != writeAlias {
+		aliases = append(aliases, orgAlias)
+	}
+
+	return aliases
+}
+
+// IndexName returns the write alias of the IndexMessage and creates its index
+// when it does not exist. It is used by the index.Service for routing.
+func (ir *IndexRouter) IndexName(ctx context.Context, m *domainpb.IndexMessage) (string, error) {
+	base := m.GetIndexName()
+
+	alias := ir.WriteAlias(base, m.GetOrganisationID(), m.GetDatasetID())
+	if alias == base {
+		return base, nil
+	}
+
+	if err := ir.ensureAlias(base, m.GetOrganisationID(), alias); err != nil {
+		return "", err
+	}
+
+	return alias, nil
+}
+
+// ensureAlias creates the index of the routed alias when it does not exist.
+// Only the creation of the same alias is serialized, so the ElasticSearch
+// calls do not block the routing of other aliases.
+func (ir *IndexRouter) ensureAlias(base, orgID, alias string) error {
+	if ir.isCreated(alias) {
+		return nil
+	}
+
+	mu := ir.aliasLock(alias)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ir.isCreated(alias) {
+		return nil
+	}
+
+	indexName, err := IndexCreate(ir.es, alias, ir.mappings[base], true)
+
+	switch {
+	case errors.Is(err, ErrIndexAlreadyCreated):
+	case err != nil:
+		return fmt.Errorf("unable to create index for %s; %w", alias, err)
+	default:
+		for _, readAlias := range ir.readAliases(base, orgID, alias) {
+			if err := AliasCreate(ir.es, readAlias, indexName); err != nil {
+				return fmt.Errorf("unable to add %s to alias %s; %w", indexName, readAlias, err)
+			}
+		}
+
+		log.Info().Str("alias", alias).Str("index", indexName).Msg("created routed index")
+	}
+
+	ir.rw.Lock()
+	ir.created[alias] = true
+	ir.rw.Unlock()
+
+	return nil
+}
+
+func (ir *IndexRouter) isCreated(alias string) bool {
+	ir.rw.RLock()
+	defer ir.rw.RUnlock()
+
+	return ir.created[alias]
+}
+
+// aliasLock returns the lock for the creation of the alias.
+func (ir *IndexRouter) aliasLock(alias string) *sync.Mutex {
+	ir.rw.Lock()
+	defer ir.rw.Unlock()
+
+	mu, ok := ir.locks[alias]
+	if !ok {
+		mu = &sync.Mutex{}
+		ir.locks[alias] = mu
+	}
+
+	return mu
+}
@@ -0,0 +1,277 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/domain/domainpb"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func TestParseRoutingStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    RoutingStrategy
+		wantErr error
+	}{
+		{"empty", "", RoutingShared, nil},
+		{"shared", "shared", RoutingShared, nil},
+		{"organization", "Organization", RoutingOrganization, nil},
+		{"dataset", "dataset", RoutingDataset, nil},
+		{"unknown", "tenant", "", ErrUnknownRoutingStrategy},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRoutingStrategy(tt.s)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseRoutingStrategy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseRoutingStrategy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexRouter_aliases(t *testing.T) {
+	mappings := map[string]string{"hub3v2": "{}"}
+
+	tests := []struct {
+		name        string
+		strategy    RoutingStrategy
+		base        string
+		orgID       string
+		datasetID   string
+		writeAlias  string
+		searchAlias string
+		readAliases []string
+	}{
+		{"shared", RoutingShared, "hub3v2", "hub3", "spec", "hub3v2", "hub3v2", []string{"hub3v2"}},
+		{"organization", RoutingOrganization, "hub3v2", "hub3", "spec", "hub3v2-hub3", "hub3v2-hub3", []string{"hub3v2"}},
+		{"dataset", RoutingDataset, "hub3v2", "hub3", "spec", "hub3v2-hub3-spec", "hub3v2-hub3", []string{"hub3v2", "hub3v2-hub3"}},
+		{"escaped", RoutingDataset, "hub3v2", "Hub-3", "my spec.1", "hub3v2-_48_ub_2d_3-my_20_spec_2e_1", "hub3v2-_48_ub_2d_3", []string{"hub3v2", "hub3v2-_48_ub_2d_3"}},
+		{"no organization", RoutingOrganization, "hub3v2", "", "spec", "hub3v2-_none", "hub3v2", []string{"hub3v2"}},
+		{"no organization by dataset", RoutingDataset, "hub3v2", "", "spec", "hub3v2-_none-spec", "hub3v2", []string{"hub3v2"}},
+		{"no dataset", RoutingDataset, "hub3v2", "hub3", "", "hub3v2-hub3-_none", "hub3v2-hub3", []string{"hub3v2", "hub3v2-hub3"}},
+		{"unknown base alias", RoutingOrganization, "hub3v1", "hub3", "spec", "hub3v1", "hub3v1", []string{"hub3v1"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ir := NewIndexRouter(nil, tt.strategy, mappings)
+
+			writeAlias := ir.WriteAlias(tt.base, tt.orgID, tt.datasetID)
+			if writeAlias != tt.writeAlias {
+				t.Errorf("IndexRouter.WriteAlias() = %v, want %v", writeAlias, tt.writeAlias)
+			}
+
+			if got := ir.SearchAlias(tt.base, tt.orgID); got != tt.searchAlias {
+				t.Errorf("IndexRouter.SearchAlias() = %v, want %v", got, tt.searchAlias)
+			}
+
+			got := ir.readAliases(tt.base, tt.orgID, writeAlias)
+			if diff := cmp.Diff(tt.readAliases, got); diff != "" {
+				t.Errorf("IndexRouter.readAliases() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_aliasComponent(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"hub3", "hub3"},
+		{"a-b", "a_2d_b"},
+		{"a_b", "a_5f_b"},
+		{"A", "_41_"},
+		{"_none", "_5f_none"},
+		{"é", "_e9_"},
+	}
+
+	seen := map[string]string{}
+
+	for _, tt := range tests {
+		got := aliasComponent(tt.s)
+		if got != tt.want {
+			t.Errorf("aliasComponent(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+
+		if other, ok := seen[got]; ok {
+			t.Errorf("aliasComponent(%q) = aliasComponent(%q) = %q", tt.s, other, got)
+		}
+
+		seen[got] = tt.s
+	}
+}
+
+func TestIndexRouter_IndexName(t *testing.T) {
+	is := is.New(t)
+
+	ir := NewIndexRouter(nil, RoutingOrganization, map[string]string{"hub3v2": "{}"})
+	ir.created["hub3v2-hub3"] = true
+
+	got, err := ir.IndexName(context.Background(), &domainpb.IndexMessage{IndexName: "hub3v2", OrganisationID: "hub3"})
+	is.NoErr(err)
+	is.Equal(got, "hub3v2-hub3")
+
+	got, err = ir.IndexName(context.Background(), &domainpb.IndexMessage{IndexName: "hub3v2_frag", OrganisationID: "hub3"})
+	is.NoErr(err)
+	is.Equal(got, "hub3v2_frag")
+}
+
+// fakeAliasES is a minimal ElasticSearch for index, alias and mapping requests.
+type fakeAliasES struct {
+	mu sync.Mutex
+	// aliases contains the aliases of each index
+	aliases map[string][]string
+	// mappingUpdates contains the indices of each PUT _mapping request
+	mappingUpdates []string
+}
+
+func (f *fakeAliasES) indices(alias string) []string {
+	indices := []string{}
+
+	for index, aliases := range f.aliases {
+		for _, a := range aliases {
+			if a == alias {
+				indices = append(indices, index)
+			}
+		}
+	}
+
+	return indices
+}
+
+func (f *fakeAliasES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "_alias":
+		indices := f.indices(parts[1])
+		if len(indices) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `{"error": "alias [%s] missing", "status": 404}`, parts[1])
+
+			return
+		}
+
+		resp := map[string]interface{}{}
+		for _, index := range indices {
+			resp[index] = map[string]interface{}{"aliases": map[string]interface{}{parts[1]: map[string]interface{}{}}}
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "_mapping":
+		resp := map[string]interface{}{}
+		for _, index := range f.indices(parts[0]) {
+			resp[index] = map[string]interface{}{"mappings": map[string]interface{}{}}
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	case r.Method == http.MethodPut && len(parts) == 1:
+		f.aliases[parts[0]] = []string{}
+
+		fmt.Fprint(w, `{"acknowledged": true}`)
+	case r.Method == http.MethodPut && len(parts) == 3 && parts[1] == "_aliases":
+		f.aliases[parts[0]] = append(f.aliases[parts[0]], parts[2])
+
+		fmt.Fprint(w, `{"acknowledged": true}`)
+	case r.Method == http.MethodPut && len(parts) == 2 && parts[1] == "_mapping":
+		f.mappingUpdates = append(f.mappingUpdates, parts[0])
+
+		fmt.Fprint(w, `{"acknowledged": true}`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error": {"type": "unexpected_request", "reason": "%s %s"}}`, r.Method, r.URL.Path)
+	}
+}
+
+func TestIndexRouter_routedBaseAlias(t *testing.T) {
+	is := is.New(t)
+
+	fake := &fakeAliasES{aliases: map[string][]string{"hub3v2_1": {"hub3v2"}}}
+
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{Addresses: []string{srv.URL}})
+	is.NoErr(err)
+
+	mapping := `{"mappings": {"properties": {"meta": {"properties": {"spec": {"type": "keyword"}}}}}}`
+
+	ir := NewIndexRouter(es, RoutingOrganization, map[string]string{"hub3v2": mapping})
+
+	alias, err := ir.IndexName(context.Background(), &domainpb.IndexMessage{IndexName: "hub3v2", OrganisationID: "hub3"})
+	is.NoErr(err)
+	is.Equal(alias, "hub3v2-hub3")
+
+	// the routed index is added to the base alias
+	is.Equal(len(fake.indices("hub3v2")), 2)
+	is.Equal(len(fake.indices("hub3v2-hub3")), 1)
+
+	_, err = AliasGet(es, "hub3v2")
+	is.True(errors.Is(err, ErrAliasMultipleIndices))
+
+	// operations on a single index refuse the base alias
+	_, err = Reindex(context.Background(), es, ReindexConfig{Alias: "hub3v2", Mapping: mapping})
+	is.True(errors.Is(err, ErrAliasMultipleIndices))
+
+	_, err = IndexSwitch(es, "hub3v2", "hub3v2_2", false)
+	is.True(errors.Is(err, ErrAliasMultipleIndices))
+
+	m := NewMappingMigrator(es, true, MappingTarget{Name: "v2", ReindexConfig: ReindexConfig{Alias: "hub3v2", Mapping: mapping}})
+
+	err = m.Reindex("v2", false)
+	is.True(errors.Is(err, ErrAliasMultipleIndices))
+
+	// additive changes are applied to each index of the base alias
+	migrations, err := m.Check(context.Background())
+	is.NoErr(err)
+	is.Equal(len(migrations), 1)
+	is.True(migrations[0].Applied)
+	is.Equal(migrations[0].Diff.Additive, []string{"meta"})
+
+	want := fake.indices("hub3v2")
+	sort.Strings(want)
+	sort.Strings(fake.mappingUpdates)
+	is.Equal(fake.mappingUpdates, want)
+}
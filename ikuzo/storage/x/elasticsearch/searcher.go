@@ -0,0 +1,266 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/elastic/go-elasticsearch/v8"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+// defaultField is the full-text field of the v2 mapping.
+const defaultField = "full_text"
+
+// Searcher is a search.Searcher for indexes with the v2 mapping.
+type Searcher struct {
+	es     *elasticsearch.Client
+	index  string
+	qb     *QueryBuilder
+	router *IndexRouter
+}
+
+// SearcherOption is a closure to configure the Searcher.
+type SearcherOption func(*Searcher) error
+
+// SetSearcherIndexRouter sets the IndexRouter that selects the alias of the
+// organization of each search.Request.
+func SetSearcherIndexRouter(router *IndexRouter) SearcherOption {
+	return func(s *Searcher) error {
+		s.router = router
+		return nil
+	}
+}
+
+// NewSearcher returns a Searcher that queries the index. The index can also
+// be an alias.
+func NewSearcher(es *elasticsearch.Client, index string, options ...SearcherOption) (*Searcher, error) {
+	if es == nil {
+		return nil, errors.New("elasticsearch.Client cannot be nil")
+	}
+
+	if index == "" {
+		return nil, errors.New("index name cannot be empty")
+	}
+
+	s := &Searcher{
+		es:    es,
+		index: index,
+		qb:    NewQueryBuilder(QueryField{Field: defaultField}),
+	}
+
+	for _, option := range options {
+		if err := option(s); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// searchIndex returns the index or alias that contains the documents of the organization.
+func (s *Searcher) searchIndex(orgID string) string {
+	if s.router == nil {
+		return s.index
+	}
+
+	return s.router.SearchAlias(s.index, orgID)
+}
+
+// Search executes the search.Request against the index.
+func (s *Searcher) Search(ctx context.Context, req *search.Request) (*search.Response, error) {
+	source, err := s.searchSource(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := source.Source()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create search source; %w", err)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal search source; %w", err)
+	}
+
+	res, err := s.es.Search(
+		s.es.Search.WithContext(ctx),
+		s.es.Search.WithIndex(s.searchIndex(req.OrgID)),
+		s.es.Search.WithBody(bytes.NewReader(b)),
+		// the routed alias does not exist before the organization has indexed records
+		s.es.Search.WithIgnoreUnavailable(true),
+		s.es.Search.WithAllowNoIndices(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		et := GetErrorType(res.Body)
+		return nil, fmt.Errorf("search request failed: %s; %w", et.Reason, et.Error())
+	}
+
+	var result elastic.SearchResult
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to decode search response; %w", err)
+	}
+
+	return newSearchResponse(req, &result)
+}
+
+// searchSource returns the elastic.SearchSource for the search.Request.
+func (s *Searcher) searchSource(req *search.Request) (*elastic.SearchSource, error) {
+	query, err := s.qb.ParseQuery(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	bq := elastic.NewBoolQuery().Must(query)
+
+	if req.OrgID != "" {
+		bq = bq.Filter(elastic.NewTermQuery("meta.orgID", req.OrgID))
+	}
+
+	fields, values := req.FilterGroups()
+	for _, field := range fields {
+		bq = bq.Filter(s.filterQuery(field, values[field]))
+	}
+
+	source := elastic.NewSearchSource().
+		Query(bq).
+		From(req.Start()).
+		Size(req.Size).
+		TrackTotalHits(true)
+
+	sorts := req.Sort
+	if len(sorts) == 0 {
+		sorts = []search.Sort{{Field: search.SortScore, Desc: true}}
+	}
+
+	for _, sort := range sorts {
+		source = source.SortBy(s.sorter(sort))
+	}
+
+	// tie-breaker for a stable order of the hits
+	source = source.SortBy(elastic.NewFieldSort(search.SortID).Asc())
+
+	for idx, ff := range req.Facets {
+		agg, err := NewFacetAggregation(ff)
+		if err != nil {
+			return nil, err
+		}
+
+		source = source.Aggregation(strconv.Itoa(idx), agg)
+	}
+
+	return source, nil
+}
+
+// filterQuery returns a query that matches any of the values of the field.
+func (s *Searcher) filterQuery(field string, values []string) elastic.Query {
+	terms := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		terms = append(terms, value)
+	}
+
+	mapping := s.qb.fieldMapping(field)
+	if mapping.Path == "" {
+		return elastic.NewTermsQuery(mapping.Field, terms...)
+	}
+
+	return elastic.NewNestedQuery(
+		mapping.Path,
+		elastic.NewBoolQuery().Must(
+			elastic.NewTermQuery(entriesSearchLabel, mapping.SearchLabel),
+			elastic.NewTermsQuery(keywordField(mapping.Field), terms...),
+		),
+	)
+}
+
+func (s *Searcher) sorter(sort search.Sort) elastic.Sorter {
+	if sort.Field == search.SortScore {
+		return elastic.NewScoreSort().Order(!sort.Desc)
+	}
+
+	mapping := s.qb.fieldMapping(sort.Field)
+	if mapping.Path == "" {
+		return elastic.NewFieldSort(mapping.Field).Order(!sort.Desc)
+	}
+
+	return elastic.NewFieldSort(keywordField(mapping.Field)).
+		Order(!sort.Desc).
+		Nested(
+			elastic.NewNestedSort(mapping.Path).
+				Filter(elastic.NewTermQuery(entriesSearchLabel, mapping.SearchLabel)),
+		)
+}
+
+// keywordField returns the keyword version of the field for exact matching
+// and sorting.
+func keywordField(field string) string {
+	if field == entriesValueField {
+		return entriesKeyword
+	}
+
+	return field
+}
+
+func newSearchResponse(req *search.Request, result *elastic.SearchResult) (*search.Response, error) {
+	resp := &search.Response{
+		Pager: search.ScrollPager{
+			Cursor: int32(req.Start()),
+			Total:  result.TotalHits(),
+			Rows:   int32(req.Size),
+		},
+		Query:  req.Query,
+		Hits:   []*search.Hit{},
+		Facets: make([]*search.Facet, 0, len(req.Facets)),
+	}
+
+	if result.Hits != nil {
+		for _, hit := range result.Hits.Hits {
+			h := &search.Hit{
+				ID:     hit.Id,
+				Source: hit.Source,
+			}
+
+			if hit.Score != nil {
+				h.Score = *hit.Score
+			}
+
+			resp.Hits = append(resp.Hits, h)
+		}
+	}
+
+	for idx, ff := range req.Facets {
+		facet, err := NewFacet(ff, result.Aggregations, strconv.Itoa(idx))
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Facets = append(resp.Facets, facet)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,257 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/delving/hub3/ikuzo/search/searchtest"
+	"github.com/delving/hub3/ikuzo/storage/x/elasticsearch/mapping"
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+	elastic "github.com/olivere/elastic/v7"
+)
+
+func newTestRequest(t *testing.T, params string, facets ...string) *search.Request {
+	is := is.New(t)
+
+	req := &search.Request{Page: 1, Size: 10}
+
+	if params != "" {
+		values, err := url.ParseQuery(params)
+		is.NoErr(err)
+
+		req, err = search.NewRequest(values)
+		is.NoErr(err)
+	}
+
+	for _, facet := range facets {
+		is.NoErr(req.AddFacet(facet))
+	}
+
+	// defaults that are normally set by the search.Service
+	if req.Page == 0 {
+		req.Page = 1
+	}
+
+	if req.Size == 0 {
+		req.Size = 10
+	}
+
+	return req
+}
+
+// nolint:gocritic
+func TestSearcher_golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		params string
+		facets []string
+	}{
+		{"match all", "", nil},
+		{"query with paging", "q=leiden&page=3&rows=20", nil},
+		{"filters", "qf=dc_type:painting&qf=dc_type:drawing&qf[]=meta.spec:museum", nil},
+		{"sort", "sort=-dc_date,meta.spec", nil},
+		{"facets", "", []string{"dc_type~10", "^meta.spec@", "tags", "id.dc_creator", "datehistogram.dc_date", "dateminmax.dc_date"}},
+		{"numeric facets", "", []string{"histogram.dc_extent:10", "range.dc_extent:10,100"}},
+		{"geo facets", "", []string{"geogrid.latLong:4~100", "geodistance.wgs84_pos_lat_long:52.16,4.49:5,25"}},
+		{"pivot facets", "", []string{"meta.spec>dc_type~5", "dc_type>meta.spec"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+
+			s, err := NewSearcher(&elasticsearch.Client{}, "hub3v2")
+			is.NoErr(err)
+
+			source, err := s.searchSource(newTestRequest(t, tt.params, tt.facets...))
+			is.NoErr(err)
+
+			src, err := source.Source()
+			is.NoErr(err)
+
+			got, err := json.MarshalIndent(src, "", "  ")
+			is.NoErr(err)
+
+			golden := filepath.Join("testdata", "search", strings.ReplaceAll(tt.name, " ", "_")+".json")
+
+			if *updateGolden {
+				err = ioutil.WriteFile(golden, append(got, '\n'), 0o600)
+				is.NoErr(err)
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			is.NoErr(err)
+
+			if diff := cmp.Diff(string(want), string(got)+"\n"); diff != "" {
+				t.Errorf("searchSource(); %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+const testSearchResult = `{
+  "hits": {
+    "total": {"value": 12, "relation": "eq"},
+    "hits": [
+      {"_id": "hub3_museum_1", "_score": 1.5, "_source": {"meta": {"hubID": "hub3_museum_1"}}}
+    ]
+  },
+  "aggregations": {
+    "0": {
+      "doc_count": 30,
+      "filter": {
+        "doc_count": 14,
+        "value": {
+          "sum_other_doc_count": 2,
+          "buckets": [
+            {"key": "painting", "doc_count": 8, "docs": {"doc_count": 6}},
+            {"key": "drawing", "doc_count": 4, "docs": {"doc_count": 4}}
+          ]
+        }
+      }
+    },
+    "1": {
+      "sum_other_doc_count": 0,
+      "buckets": [
+        {"key": "museum", "doc_count": 9},
+        {"key": "archive", "doc_count": 3}
+      ]
+    },
+    "2": {
+      "doc_count": 30,
+      "filter": {
+        "doc_count": 10,
+        "min": {"value": -10413792000000, "value_as_string": "1640-01-01"},
+        "max": {"value": -8520336000000, "value_as_string": "1700-01-01"}
+      }
+    }
+  }
+}`
+
+func TestNewSearchResponse(t *testing.T) {
+	is := is.New(t)
+
+	req := newTestRequest(t, "page=2&rows=1", "dc_type", "meta.spec", "dateminmax.dc_date")
+
+	var result elastic.SearchResult
+	is.NoErr(json.Unmarshal([]byte(testSearchResult), &result))
+
+	got, err := newSearchResponse(req, &result)
+	is.NoErr(err)
+
+	want := &search.Response{
+		Pager: search.ScrollPager{Cursor: 1, Total: 12, Rows: 1},
+		Hits: []*search.Hit{
+			{ID: "hub3_museum_1", Score: 1.5, Source: json.RawMessage(`{"meta": {"hubID": "hub3_museum_1"}}`)},
+		},
+		Facets: []*search.Facet{
+			{
+				Name: "dc_type", Field: "dc_type", Total: 12, OtherDocs: 2,
+				Links: []*search.FacetLink{
+					{Value: "painting", DisplayString: "painting", Count: 6},
+					{Value: "drawing", DisplayString: "drawing", Count: 4},
+				},
+			},
+			{
+				Name: "meta.spec", Field: "meta.spec", Total: 12,
+				Links: []*search.FacetLink{
+					{Value: "museum", DisplayString: "museum", Count: 9},
+					{Value: "archive", DisplayString: "archive", Count: 3},
+				},
+			},
+			{
+				Name: "dc_date", Field: "dc_date", Type: "dateminmax",
+				Min: "1640-01-01", Max: "1700-01-01", Links: []*search.FacetLink{},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("newSearchResponse() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// nolint:gocritic
+func (s *elasticSuite) TestSearcherConformance() {
+	is := is.New(s.T())
+
+	cfg := elasticsearch.Config{Addresses: []string{fmt.Sprintf("http://%s:%s", s.ip, s.port.Port())}}
+	es, err := elasticsearch.NewClient(cfg)
+	is.NoErr(err)
+
+	searchtest.RunSuite(s.T(), func(t *testing.T, docs []*search.Document) search.Searcher {
+		name, err := IndexCreate(es, "hub3searcher", mapping.V2ESMapping(1, 0), true)
+		is.NoErr(err)
+
+		for _, doc := range docs {
+			b, err := json.Marshal(v2Document(doc))
+			is.NoErr(err)
+
+			res, err := es.Index(name, bytes.NewReader(b), es.Index.WithDocumentID(doc.ID))
+			is.NoErr(err)
+			is.True(!res.IsError())
+			res.Body.Close()
+		}
+
+		res, err := es.Indices.Refresh(es.Indices.Refresh.WithIndex(name))
+		is.NoErr(err)
+		res.Body.Close()
+
+		searcher, err := NewSearcher(es, "hub3searcher")
+		is.NoErr(err)
+
+		return searcher
+	})
+}
+
+// v2Document returns the document in the v2 mapping.
+func v2Document(doc *search.Document) map[string]interface{} {
+	entries := []map[string]string{}
+
+	for label, values := range doc.Fields {
+		for _, value := range values {
+			entries = append(entries, map[string]string{
+				"searchLabel": label,
+				"@value":      value,
+			})
+		}
+	}
+
+	return map[string]interface{}{
+		"meta": map[string]interface{}{
+			"hubID": doc.ID,
+			"orgID": doc.OrgID,
+			"spec":  doc.Spec,
+			"tags":  doc.Tags,
+		},
+		"resources": []map[string]interface{}{
+			{"entries": entries},
+		},
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/tidwall/gjson"
+)
+
+// snapshotPrefix is the prefix of the snapshots that are created and pruned by ikuzo.
+const snapshotPrefix = "ikuzo-"
+
+var (
+	// ErrSnapshotNotFound is returned when the snapshot does not exist in the repository.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrSnapshotIndexNotFound is returned when the snapshot contains no index for the alias.
+	ErrSnapshotIndexNotFound = errors.New("snapshot contains no index for alias")
+	// ErrSnapshotAliasNotAllowed is returned when the alias is not included in the snapshots.
+	ErrSnapshotAliasNotAllowed = errors.New("alias is not included in the snapshots")
+)
+
+// Snapshot is an ElasticSearch snapshot in a snapshot repository.
+type Snapshot struct {
+	Name     string    `json:"name"`
+	State    string    `json:"state"`
+	Indices  []string  `json:"indices"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end,omitempty"`
+	Failures []string  `json:"failures,omitempty"`
+}
+
+// IsManaged returns true when the snapshot is created by ikuzo.
+func (s *Snapshot) IsManaged() bool {
+	return strings.HasPrefix(s.Name, snapshotPrefix)
+}
+
+// aliasIndex returns the index of the snapshot that was created for the alias.
+func (s *Snapshot) aliasIndex(alias string) (string, bool) {
+	re := regexp.MustCompile(`^` + regexp.QuoteMeta(alias) + `_\d{14}(\.\d+)?$`)
+
+	for _, index := range s.Indices {
+		if index == alias || re.MatchString(index) {
+			return index, true
+		}
+	}
+
+	return "", false
+}
+
+// newSnapshotName returns the name of a managed snapshot created at t.
+func newSnapshotName(t time.Time) string {
+	return snapshotPrefix + t.UTC().Format("20060102-150405")
+}
+
+// SnapshotRepositoryCreate registers a shared filesystem snapshot repository.
+// The location must be listed in the 'path.repo' setting of each ElasticSearch node.
+func SnapshotRepositoryCreate(ctx context.Context, es *elasticsearch.Client, repository, location string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"type":     "fs",
+		"settings": map[string]interface{}{"location": location},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := es.Snapshot.CreateRepository(
+		repository,
+		strings.NewReader(string(body)),
+		es.Snapshot.CreateRepository.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot repository %s; %w", repository, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
+
+// SnapshotCreate starts a snapshot of the indices of the aliases. It does not
+// wait for the snapshot to complete; the progress is reported by SnapshotList.
+func SnapshotCreate(ctx context.Context, es *elasticsearch.Client, repository, snapshot string, aliases []string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"indices":              strings.Join(aliases, ","),
+		"ignore_unavailable":   true,
+		"include_global_state": false,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := es.Snapshot.Create(
+		repository,
+		snapshot,
+		es.Snapshot.Create.WithContext(ctx),
+		es.Snapshot.Create.WithBody(strings.NewReader(string(body))),
+		es.Snapshot.Create.WithWaitForCompletion(false),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot %s; %w", snapshot, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
+
+// SnapshotList returns the snapshots of the repository sorted by start time.
+func SnapshotList(ctx context.Context, es *elasticsearch.Client, repository string) ([]Snapshot, error) {
+	res, err := es.Snapshot.Get(
+		repository,
+		[]string{"_all"},
+		es.Snapshot.Get.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list snapshots of %s; %w", repository, err)
+	}
+
+	body := read(res.Body)
+	res.Body.Close()
+
+	if res.IsError() {
+		return nil, GetErrorType(strings.NewReader(body)).Error()
+	}
+
+	return parseSnapshots(body), nil
+}
+
+// parseSnapshots parses the response of the get snapshot API.
+func parseSnapshots(body string) []Snapshot {
+	snapshots := []Snapshot{}
+
+	for _, s := range gjson.Get(body, "snapshots").Array() {
+		snapshot := Snapshot{
+			Name:  s.Get("snapshot").String(),
+			State: s.Get("state").String(),
+			Start: time.Unix(0, s.Get("start_time_in_millis").Int()*int64(time.Millisecond)).UTC(),
+		}
+
+		if end := s.Get("end_time_in_millis").Int(); end != 0 {
+			snapshot.End = time.Unix(0, end*int64(time.Millisecond)).UTC()
+		}
+
+		for _, index := range s.Get("indices").Array() {
+			snapshot.Indices = append(snapshot.Indices, index.String())
+		}
+
+		for _, f := range s.Get("failures").Array() {
+			snapshot.Failures = append(snapshot.Failures, fmt.Sprintf("%s: %s", f.Get("index").String(), f.Get("reason").String()))
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Start.Before(snapshots[j].Start) })
+
+	return snapshots
+}
+
+// SnapshotDelete deletes the snapshot from the repository.
+func SnapshotDelete(ctx context.Context, es *elasticsearch.Client, repository, snapshot string) error {
+	res, err := es.Snapshot.Delete(
+		repository,
+		snapshot,
+		es.Snapshot.Delete.WithContext(ctx),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to delete snapshot %s; %w", snapshot, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return GetErrorType(res.Body).Error()
+	}
+
+	return nil
+}
+
+// SnapshotRestore restores the index of the alias from the snapshot into a
+// new index and switches the alias to it. The current index is kept, so the
+// alias can be switched back. It returns the name of the new index.
+func SnapshotRestore(ctx context.Context, es *elasticsearch.Client, repository, snapshot, alias string) (string, error) {
+	snapshots, err := SnapshotList(ctx, es, repository)
+	if err != nil {
+		return "", err
+	}
+
+	var found *Snapshot
+
+	for i := range snapshots {
+		if snapshots[i].Name == snapshot {
+			found = &snapshots[i]
+			break
+		}
+	}
+
+	if found == nil {
+		return "", fmt.Errorf("%w: %s", ErrSnapshotNotFound, snapshot)
+	}
+
+	index, ok := found.aliasIndex(alias)
+	if !ok {
+		return "", fmt.Errorf("%w: %s in %s", ErrSnapshotIndexNotFound, alias, snapshot)
+	}
+
+	indexName := newIndexName(alias)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"indices":              index,
+		"include_aliases":      false,
+		"include_global_state": false,
+		"rename_pattern":       "(.+)",
+		"rename_replacement":   indexName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	res, err := es.Snapshot.Restore(
+		repository,
+		snapshot,
+		es.Snapshot.Restore.WithContext(ctx),
+		es.Snapshot.Restore.WithBody(strings.NewReader(string(body))),
+		es.Snapshot.Restore.WithWaitForCompletion(true),
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to restore snapshot %s; %w", snapshot, err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return "", GetErrorType(res.Body).Error()
+	}
+
+	if _, err := IndexSwitch(es, alias, indexName, false); err != nil {
+		return indexName, fmt.Errorf("unable to switch alias %s to restored index %s; %w", alias, indexName, err)
+	}
+
+	return indexName, nil
+}
+
+// RetentionPolicy determines which managed snapshots are pruned.
+// The most recent successful snapshot is never pruned.
+type RetentionPolicy struct {
+	// MaxCount is the maximum number of managed snapshots. 0 is unlimited.
+	MaxCount int
+	// MaxAge is the maximum age of managed snapshots. 0 is unlimited.
+	MaxAge time.Duration
+}
+
+// Expired returns the managed snapshots that are outside the RetentionPolicy at time now.
+func (rp RetentionPolicy) Expired(snapshots []Snapshot, now time.Time) []Snapshot {
+	managed := []Snapshot{}
+
+	for _, s := range snapshots {
+		if s.IsManaged() {
+			managed = append(managed, s)
+		}
+	}
+
+	// newest first
+	sort.Slice(managed, func(i, j int) bool { return managed[i].Start.After(managed[j].Start) })
+
+	var (
+		expired     = []Snapshot{}
+		keptSuccess bool
+	)
+
+	for i, s := range managed {
+		keep := (rp.MaxCount == 0 || i < rp.MaxCount) &&
+			(rp.MaxAge == 0 || now.Sub(s.Start) <= rp.MaxAge)
+
+		if s.State == "IN_PROGRESS" {
+			keep = true
+		}
+
+		if !keep && s.State == "SUCCESS" && !keptSuccess {
+			keep = true
+		}
+
+		if keep && s.State == "SUCCESS" {
+			keptSuccess = true
+		}
+
+		if !keep {
+			expired = append(expired, s)
+		}
+	}
+
+	return expired
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func Test_parseSnapshots(t *testing.T) {
+	is := is.New(t)
+
+	body := `{"snapshots": [
+		{
+			"snapshot": "ikuzo-20200802-120000",
+			"state": "SUCCESS",
+			"indices": ["hub3v2_20200801120000.123"],
+			"start_time_in_millis": 1596369600000,
+			"end_time_in_millis": 1596369660000,
+			"failures": []
+		},
+		{
+			"snapshot": "ikuzo-20200801-120000",
+			"state": "PARTIAL",
+			"indices": ["hub3v1_20200801120000"],
+			"start_time_in_millis": 1596283200000,
+			"failures": [{"index": "hub3v1_20200801120000", "reason": "shard failed"}]
+		}
+	]}`
+
+	got := parseSnapshots(body)
+
+	want := []Snapshot{
+		{
+			Name:     "ikuzo-20200801-120000",
+			State:    "PARTIAL",
+			Indices:  []string{"hub3v1_20200801120000"},
+			Start:    time.Date(2020, 8, 1, 12, 0, 0, 0, time.UTC),
+			Failures: []string{"hub3v1_20200801120000: shard failed"},
+		},
+		{
+			Name:    "ikuzo-20200802-120000",
+			State:   "SUCCESS",
+			Indices: []string{"hub3v2_20200801120000.123"},
+			Start:   time.Date(2020, 8, 2, 12, 0, 0, 0, time.UTC),
+			End:     time.Date(2020, 8, 2, 12, 1, 0, 0, time.UTC),
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseSnapshots() mismatch (-want +got):\n%s", diff)
+	}
+
+	is.Equal(newSnapshotName(time.Date(2020, 8, 2, 12, 0, 0, 0, time.UTC)), "ikuzo-20200802-120000")
+}
+
+func TestSnapshot_aliasIndex(t *testing.T) {
+	s := &Snapshot{Indices: []string{"hub3v2_frag_20200801120000", "hub3v2_20200801120000.5", "other"}}
+
+	tests := []struct {
+		alias string
+		want  string
+		found bool
+	}{
+		{"hub3v2", "hub3v2_20200801120000.5", true},
+		{"hub3v2_frag", "hub3v2_frag_20200801120000", true},
+		{"other", "other", true},
+		{"hub3v1", "", false},
+	}
+
+	for _, tt := range tests {
+		got, found := s.aliasIndex(tt.alias)
+		if got != tt.want || found != tt.found {
+			t.Errorf("Snapshot.aliasIndex(%s) = %s, %v; want %s, %v", tt.alias, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestRetentionPolicy_Expired(t *testing.T) {
+	now := time.Date(2020, 8, 10, 0, 0, 0, 0, time.UTC)
+
+	snapshot := func(name, state string, days int) Snapshot {
+		return Snapshot{Name: name, State: state, Start: now.AddDate(0, 0, -days)}
+	}
+
+	snapshots := []Snapshot{
+		snapshot("ikuzo-1", "SUCCESS", 9),
+		snapshot("ikuzo-2", "SUCCESS", 5),
+		snapshot("manual", "SUCCESS", 30),
+		snapshot("ikuzo-3", "FAILED", 2),
+		snapshot("ikuzo-4", "IN_PROGRESS", 0),
+	}
+
+	names := func(snapshots []Snapshot) []string {
+		n := []string{}
+		for _, s := range snapshots {
+			n = append(n, s.Name)
+		}
+
+		return n
+	}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"unlimited", RetentionPolicy{}, []string{}},
+		{"max count", RetentionPolicy{MaxCount: 2}, []string{"ikuzo-1"}},
+		{"max age", RetentionPolicy{MaxAge: 7 * 24 * time.Hour}, []string{"ikuzo-1"}},
+		{"keeps newest success", RetentionPolicy{MaxAge: 24 * time.Hour}, []string{"ikuzo-3", "ikuzo-1"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, names(tt.policy.Expired(snapshots, now))); diff != "" {
+				t.Errorf("RetentionPolicy.Expired() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSnapshotter_handleRestore(t *testing.T) {
+	is := is.New(t)
+
+	s := &Snapshotter{cfg: SnapshotConfig{Aliases: []string{"hub3v2"}}}
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"missing alias", "/ikuzo-20200802-120000/restore"},
+		{"unknown alias", "/ikuzo-20200802-120000/restore?alias=other"},
+		{"routed alias", "/ikuzo-20200802-120000/restore?alias=hub3v2-hub3"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, tt.url, nil)
+		w := httptest.NewRecorder()
+
+		s.Routes().ServeHTTP(w, req)
+		is.Equal(w.Code, http.StatusBadRequest) // tt.name
+	}
+}
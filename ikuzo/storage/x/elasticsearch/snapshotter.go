@@ -0,0 +1,272 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog/log"
+)
+
+// SnapshotConfig configures the Snapshotter.
+type SnapshotConfig struct {
+	// Repository is the name of the snapshot repository
+	Repository string
+	// Location is the path of the shared filesystem repository. When empty
+	// the repository must already be registered.
+	Location string
+	// Aliases are the index aliases that are included in each snapshot and can
+	// be restored. The indices of routed aliases belong to their base alias, so
+	// they are included in each snapshot, but they cannot be restored separately.
+	Aliases []string
+	// Interval between scheduled snapshots. When 0 no snapshots are scheduled.
+	Interval time.Duration
+	// Retention determines which snapshots are pruned after each scheduled snapshot
+	Retention RetentionPolicy
+}
+
+// Snapshotter creates, restores and prunes snapshots of the ikuzo index aliases.
+type Snapshotter struct {
+	es     *elasticsearch.Client
+	cfg    SnapshotConfig
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	now    func() time.Time
+}
+
+// NewSnapshotter registers the snapshot repository and starts the snapshot
+// schedule. The Snapshotter must be stopped with Shutdown.
+func NewSnapshotter(ctx context.Context, es *elasticsearch.Client, cfg SnapshotConfig) (*Snapshotter, error) {
+	if cfg.Repository == "" {
+		return nil, fmt.Errorf("snapshot repository is required")
+	}
+
+	s := &Snapshotter{
+		es:     es,
+		cfg:    cfg,
+		cancel: func() {},
+		now:    time.Now,
+	}
+
+	if cfg.Location != "" {
+		if err := SnapshotRepositoryCreate(ctx, es, cfg.Repository, cfg.Location); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Interval > 0 {
+		runCtx, cancel := context.WithCancel(context.Background())
+		s.cancel = cancel
+
+		s.wg.Add(1)
+
+		go s.run(runCtx)
+	}
+
+	return s, nil
+}
+
+func (s *Snapshotter) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			name, err := s.Create(ctx)
+			if err != nil {
+				log.Error().Err(err).Str("repository", s.cfg.Repository).Msg("unable to create scheduled snapshot")
+				continue
+			}
+
+			log.Info().Str("repository", s.cfg.Repository).Str("snapshot", name).Msg("created scheduled snapshot")
+
+			if _, err := s.Prune(ctx); err != nil {
+				log.Error().Err(err).Str("repository", s.cfg.Repository).Msg("unable to prune snapshots")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Create starts a snapshot of the aliases and returns its name.
+func (s *Snapshotter) Create(ctx context.Context) (string, error) {
+	name := newSnapshotName(s.now())
+
+	if err := SnapshotCreate(ctx, s.es, s.cfg.Repository, name, s.cfg.Aliases); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// List returns the snapshots in the repository.
+func (s *Snapshotter) List(ctx context.Context) ([]Snapshot, error) {
+	return SnapshotList(ctx, s.es, s.cfg.Repository)
+}
+
+// Restore restores the index of the alias from the snapshot and switches the alias to it.
+// Only the Aliases of the SnapshotConfig can be restored.
+func (s *Snapshotter) Restore(ctx context.Context, snapshot, alias string) (string, error) {
+	if !s.allowsAlias(alias) {
+		return "", fmt.Errorf("%w: %s", ErrSnapshotAliasNotAllowed, alias)
+	}
+
+	return SnapshotRestore(ctx, s.es, s.cfg.Repository, snapshot, alias)
+}
+
+func (s *Snapshotter) allowsAlias(alias string) bool {
+	for _, a := range s.cfg.Aliases {
+		if a == alias {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delete deletes the snapshot.
+func (s *Snapshotter) Delete(ctx context.Context, snapshot string) error {
+	return SnapshotDelete(ctx, s.es, s.cfg.Repository, snapshot)
+}
+
+// Prune deletes the snapshots that are outside the RetentionPolicy and returns their names.
+func (s *Snapshotter) Prune(ctx context.Context) ([]string, error) {
+	snapshots, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+
+	for _, snapshot := range s.cfg.Retention.Expired(snapshots, s.now()) {
+		if err := s.Delete(ctx, snapshot.Name); err != nil {
+			return deleted, err
+		}
+
+		deleted = append(deleted, snapshot.Name)
+
+		log.Info().Str("repository", s.cfg.Repository).Str("snapshot", snapshot.Name).Msg("pruned snapshot")
+	}
+
+	return deleted, nil
+}
+
+// Shutdown stops the snapshot schedule.
+func (s *Snapshotter) Shutdown(ctx context.Context) error {
+	s.cancel()
+
+	done := make(chan struct{})
+
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Routes returns the admin routes of the Snapshotter.
+func (s *Snapshotter) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", s.handleList)
+	r.Post("/", s.handleCreate)
+	r.Post("/_prune", s.handlePrune)
+	r.Delete("/{name}", s.handleDelete)
+	r.Post("/{name}/restore", s.handleRestore)
+
+	return r
+}
+
+func (s *Snapshotter) handleList(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := s.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, snapshots)
+}
+
+func (s *Snapshotter) handleCreate(w http.ResponseWriter, r *http.Request) {
+	name, err := s.Create(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]string{"snapshot": name})
+}
+
+func (s *Snapshotter) handlePrune(w http.ResponseWriter, r *http.Request) {
+	deleted, err := s.Prune(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, map[string][]string{"deleted": deleted})
+}
+
+func (s *Snapshotter) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if err := s.Delete(r.Context(), chi.URLParam(r, "name")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Snapshotter) handleRestore(w http.ResponseWriter, r *http.Request) {
+	alias := r.URL.Query().Get("alias")
+	if alias == "" {
+		http.Error(w, "alias is required", http.StatusBadRequest)
+		return
+	}
+
+	indexName, err := s.Restore(r.Context(), chi.URLParam(r, "name"), alias)
+
+	switch {
+	case errors.Is(err, ErrSnapshotAliasNotAllowed):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, ErrSnapshotNotFound), errors.Is(err, ErrSnapshotIndexNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.JSON(w, r, map[string]string{"alias": alias, "index": indexName})
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+)
+
+// ScoreApproximation labels the scores of the TextIndex explanations. They
+// approximate the ElasticSearch relevance and are not comparable to its scores.
+const ScoreApproximation = "approximate TF-IDF scores of the in-memory index; not comparable to ElasticSearch scores"
+
+// Explain returns the score explanation for each document that matches the query,
+// ordered by descending score.
+//
+// Documents are scored with an approximation of the classic TF-IDF similarity. Each matched term
+// contributes sqrt(termFreq) * idf * boost, where idf = 1 + ln(docCount/(docFreq+1)).
+func (ti *TextIndex) Explain(query *search.QueryTerm) ([]*search.HitExplanation, error) {
+	hits, err := ti.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	docCount := len(ti.Docs)
+	explanations := map[int]*search.HitExplanation{}
+
+	terms := make([]string, 0, hits.TermCount())
+	for term := range hits.TermFrequency() {
+		terms = append(terms, term)
+	}
+
+	sort.Strings(terms)
+
+	for _, term := range terms {
+		tv, ok := ti.lookup(term)
+		if !ok {
+			continue
+		}
+
+		idf := 1 + math.Log(float64(docCount)/float64(tv.DocCount()+1))
+		boost := hits.Boost(term)
+
+		for docID, freq := range matchedFrequencies(tv, hits.Vectors()) {
+			tf := math.Sqrt(float64(freq))
+			weight := tf * idf * boost
+
+			hit, ok := explanations[docID]
+			if !ok {
+				hit = &search.HitExplanation{
+					ID: strconv.Itoa(docID),
+					Explanation: &search.ScoreExplanation{
+						Description: "approximate TF-IDF score, sum of:",
+					},
+				}
+				explanations[docID] = hit
+			}
+
+			details := []*search.ScoreExplanation{
+				{Value: tf, Description: fmt.Sprintf("tf, computed as sqrt(freq) from: freq=%d", freq)},
+				{
+					Value: idf,
+					Description: fmt.Sprintf(
+						"idf, computed as 1 + ln(docCount/(docFreq+1)) from: docFreq=%d, docCount=%d",
+						tv.DocCount(), docCount,
+					),
+				},
+			}
+
+			if boost != 1 {
+				details = append(details, &search.ScoreExplanation{Value: boost, Description: "boost"})
+			}
+
+			hit.Score += weight
+			hit.Explanation.Value = hit.Score
+			hit.Explanation.Details = append(hit.Explanation.Details, &search.ScoreExplanation{
+				Value:       weight,
+				Description: fmt.Sprintf("weight(%s in %d), product of:", term, docID),
+				Details:     details,
+			})
+		}
+	}
+
+	docIDs := make([]int, 0, len(explanations))
+	for docID := range explanations {
+		docIDs = append(docIDs, docID)
+	}
+
+	sort.Ints(docIDs)
+
+	ranked := make([]*search.HitExplanation, 0, len(docIDs))
+	for _, docID := range docIDs {
+		ranked = append(ranked, explanations[docID])
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked, nil
+}
+
+// matchedFrequencies returns per document the number of term vectors that
+// are part of the matched vectors.
+func matchedFrequencies(tv, matched *search.Vectors) map[int]int {
+	freqs := map[int]int{}
+
+	for vector := range tv.Locations {
+		if matched.HasVector(vector) {
+			freqs[vector.DocID]++
+		}
+	}
+
+	return freqs
+}
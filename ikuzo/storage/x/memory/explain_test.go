@@ -0,0 +1,98 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/matryer/is"
+)
+
+func TestTextIndex_Explain(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+
+	for _, text := range []string{
+		"Jansen te Leiden",
+		"Jansen en Jansen te Delft",
+		"Smit te Leiden",
+	} {
+		err := ti.AppendString(text)
+		is.NoErr(err)
+	}
+
+	qp, err := search.NewQueryParser()
+	is.NoErr(err)
+
+	query, err := qp.Parse("jansen")
+	is.NoErr(err)
+
+	hits, err := ti.Explain(query)
+	is.NoErr(err)
+	is.Equal(len(hits), 2)
+
+	// the document with the highest term frequency is ranked first
+	is.Equal(hits[0].ID, "2")
+	is.Equal(hits[1].ID, "1")
+	is.True(hits[0].Score > hits[1].Score)
+
+	explanation := hits[0].Explanation
+	is.Equal(explanation.Value, hits[0].Score)
+	is.Equal(explanation.Description, "approximate TF-IDF score, sum of:")
+	is.Equal(len(explanation.Details), 1)
+	is.Equal(explanation.Details[0].Description, "weight(jansen in 2), product of:")
+	is.Equal(explanation.Details[0].Details[0].Description, "tf, computed as sqrt(freq) from: freq=2")
+
+	query, err = qp.Parse("rotterdam")
+	is.NoErr(err)
+
+	_, err = ti.Explain(query)
+	is.True(errors.Is(err, ErrSearchNoMatch))
+}
+
+func TestTextIndex_ExplainPhonetic(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+
+	for _, text := range []string{
+		"Janssen te Leiden",
+		"Jansen te Delft",
+	} {
+		err := ti.AppendString(text)
+		is.NoErr(err)
+	}
+
+	qp, err := search.NewQueryParser()
+	is.NoErr(err)
+
+	query, err := qp.Parse("jansen~ph")
+	is.NoErr(err)
+
+	hits, err := ti.Explain(query)
+	is.NoErr(err)
+	is.Equal(len(hits), 2)
+
+	// the exact match is ranked above the match that only sounds alike
+	is.Equal(hits[0].ID, "2")
+	is.True(hits[0].Score > hits[1].Score)
+
+	details := hits[0].Explanation.Details[0].Details
+	is.Equal(details[len(details)-1].Value, phoneticExactBoost)
+}
@@ -19,8 +19,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/delving/hub3/ikuzo/service/x/search"
 )
@@ -29,6 +33,10 @@ var (
 	ErrSearchNoMatch = errors.New("the search query does not match the index")
 )
 
+// phoneticExactBoost is the boost of the exact term in a phonetic query, so
+// exact matches rank above the terms that only sound alike.
+const phoneticExactBoost = 2.0
+
 // TestIndex is a single document full-text index.
 // This means that all data you append to it will have its position incremented
 // and appends to the known state. It is not replaced. To reset the index to
@@ -38,19 +46,140 @@ type TextIndex struct {
 	a        search.Analyzer
 	DocCount int
 	Docs     map[int]bool
+	// Phonetic maps the phonetic key to the terms in the index that share it.
+	Phonetic map[string][]string
+	// segments are read-only on-disk TextIndex segments.
+	segments []*Segment
+	// segmentsPhonetic maps the phonetic keys to the terms in the segments.
+	// It is built once by the first phonetic query after the segments are added.
+	segmentsPhonetic map[string][]string
+	phoneticOnce     *sync.Once
 }
 
 func NewTextIndex() *TextIndex {
 	return &TextIndex{
-		Terms: make(map[string]*search.Vectors),
-		Docs:  make(map[int]bool),
+		Terms:    make(map[string]*search.Vectors),
+		Docs:     make(map[int]bool),
+		Phonetic: make(map[string][]string),
 	}
 }
 
 func (ti *TextIndex) reset() {
 	ti.Terms = make(map[string]*search.Vectors)
 	ti.Docs = make(map[int]bool)
+	ti.Phonetic = make(map[string][]string)
 	ti.DocCount = 0
+
+	_ = ti.Close()
+}
+
+// AddSegment adds a read-only Segment to the TextIndex. Terms in the Segment
+// are searched together with the terms that are appended to the TextIndex.
+func (ti *TextIndex) AddSegment(s *Segment) {
+	if ti.Docs == nil {
+		ti.Docs = make(map[int]bool)
+	}
+
+	for _, docID := range s.Docs() {
+		ti.Docs[docID] = true
+
+		if docID > ti.DocCount {
+			ti.DocCount = docID
+		}
+	}
+
+	ti.segments = append(ti.segments, s)
+
+	// the phonetic keys are only computed when a phonetic query is run
+	ti.segmentsPhonetic = nil
+	ti.phoneticOnce = &sync.Once{}
+}
+
+// segmentPhoneticTerms returns the terms in the segments that share the phonetic key.
+func (ti *TextIndex) segmentPhoneticTerms(key string) []string {
+	if len(ti.segments) == 0 {
+		return nil
+	}
+
+	ti.phoneticOnce.Do(func() {
+		phonetic := make(map[string][]string)
+
+		for _, s := range ti.segments {
+			s.Terms(func(term string) bool {
+				if key := search.PhoneticKey(term); key != "" {
+					phonetic[key] = append(phonetic[key], term)
+				}
+
+				return true
+			})
+		}
+
+		ti.segmentsPhonetic = phonetic
+	})
+
+	return ti.segmentsPhonetic[key]
+}
+
+// Close releases all the segments of the TextIndex.
+func (ti *TextIndex) Close() error {
+	var err error
+
+	for _, s := range ti.segments {
+		if closeErr := s.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	ti.segments = nil
+	ti.segmentsPhonetic = nil
+	ti.phoneticOnce = nil
+
+	return err
+}
+
+// lookup returns the Vectors for the term from both the appended terms and
+// the segments.
+func (ti *TextIndex) lookup(term string) (*search.Vectors, bool) {
+	tv, ok := ti.Terms[term]
+	if len(ti.segments) == 0 {
+		return tv, ok
+	}
+
+	stv := lookupSegments(ti.segments, term)
+	if stv == nil {
+		return tv, ok
+	}
+
+	if ok {
+		stv.Merge(tv)
+	}
+
+	return stv, true
+}
+
+// forEachTerm calls fn once for each unique term in the TextIndex.
+func (ti *TextIndex) forEachTerm(fn func(term string)) {
+	for term := range ti.Terms {
+		fn(term)
+	}
+
+	if len(ti.segments) == 0 {
+		return
+	}
+
+	seen := map[string]bool{}
+
+	for _, s := range ti.segments {
+		s.Terms(func(term string) bool {
+			if _, ok := ti.Terms[term]; !ok && !seen[term] {
+				seen[term] = true
+
+				fn(term)
+			}
+
+			return true
+		})
+	}
 }
 
 func (ti *TextIndex) setDocID(docID ...int) int {
@@ -123,11 +252,26 @@ func (ti *TextIndex) setTermVector(term string, pos int) {
 	if !ok {
 		tv = search.NewVectors()
 		ti.Terms[term] = tv
+
+		ti.addPhoneticKey(term)
 	}
 
 	tv.Add(ti.DocCount, pos)
 }
 
+func (ti *TextIndex) addPhoneticKey(term string) {
+	key := search.PhoneticKey(term)
+	if key == "" {
+		return
+	}
+
+	if ti.Phonetic == nil {
+		ti.Phonetic = make(map[string][]string)
+	}
+
+	ti.Phonetic[key] = append(ti.Phonetic[key], term)
+}
+
 func (ti *TextIndex) addTerm(word string, pos int) error {
 	if word == "" {
 		return fmt.Errorf("cannot index empty string")
@@ -152,6 +296,8 @@ func (ti *TextIndex) match(qt *search.QueryTerm, hits *search.Matches) bool {
 		return ti.matchPhrase(qt, hits)
 	case search.FuzzyQuery:
 		return ti.matchFuzzy(qt, hits)
+	case search.PhoneticQuery:
+		return ti.matchPhonetic(qt, hits)
 	default:
 		// search.TermQuery is the default
 		return ti.matchTerm(qt, hits)
@@ -166,7 +312,7 @@ func (ti *TextIndex) matchPhrase(qt *search.QueryTerm, hits *search.Matches) boo
 	words := strings.Fields(qt.Value)
 
 	if len(words) == 1 {
-		term, ok := ti.Terms[qt.Value]
+		term, ok := ti.lookup(qt.Value)
 		if !ok {
 			return false
 		}
@@ -179,7 +325,7 @@ func (ti *TextIndex) matchPhrase(qt *search.QueryTerm, hits *search.Matches) boo
 	var previousTerm string
 
 	for idx, word := range words {
-		term, ok := ti.Terms[word]
+		term, ok := ti.lookup(word)
 		if !ok {
 			return false
 		}
@@ -283,18 +429,68 @@ func sortAndCountPhrases(words []string, phrases map[search.Vector]string) map[s
 func (ti *TextIndex) matchFuzzy(qt *search.QueryTerm, hits *search.Matches) bool {
 	var hasMatch bool
 
-	for k, tv := range ti.Terms {
+	ti.forEachTerm(func(k string) {
 		ok, _ := search.IsFuzzyMatch(k, qt.Value, float64(qt.Fuzzy), search.Levenshtein)
 		if ok {
-			hasMatch = true
+			if tv, found := ti.lookup(k); found {
+				hasMatch = true
 
-			hits.AppendTerm(k, tv)
+				hits.AppendTerm(k, tv)
+			}
 		}
-	}
+	})
 
 	return hasMatch
 }
 
+// matchPhonetic matches all terms that sound like the query term, including
+// the exact term itself. The exact term is boosted with phoneticExactBoost.
+//
+// The phonetic keys are computed when terms are appended or the TextIndex is
+// decoded. The phonetic keys of the segments are computed by the first phonetic
+// query, so opening the segments does not compute them.
+func (ti *TextIndex) matchPhonetic(qt *search.QueryTerm, hits *search.Matches) bool {
+	key := search.PhoneticKey(qt.Value)
+	if key == "" {
+		return ti.matchTerm(qt, hits)
+	}
+
+	terms := append([]string{}, ti.Phonetic[key]...)
+	terms = append(terms, ti.segmentPhoneticTerms(key)...)
+
+	if qt.Prohibited {
+		return len(terms) == 0
+	}
+
+	for _, term := range terms {
+		tv, ok := ti.lookup(term)
+		if !ok {
+			continue
+		}
+
+		if term == qt.Value {
+			hits.AppendBoostedTerm(term, tv, phoneticExactBoost)
+			continue
+		}
+
+		hits.AppendTerm(term, tv)
+	}
+
+	return len(terms) != 0
+}
+
+// buildPhoneticKeys computes the phonetic keys of all terms when they were
+// not stored, e.g. for indexes that were encoded before they were added.
+func (ti *TextIndex) buildPhoneticKeys() {
+	if len(ti.Phonetic) != 0 {
+		return
+	}
+
+	for term := range ti.Terms {
+		ti.addPhoneticKey(term)
+	}
+}
+
 func (ti *TextIndex) matchWildcard(qt *search.QueryTerm, hits *search.Matches) bool {
 	var matcher func(s, prefix string) bool
 
@@ -307,19 +503,21 @@ func (ti *TextIndex) matchWildcard(qt *search.QueryTerm, hits *search.Matches) b
 
 	var hasMatch bool
 
-	for k, tv := range ti.Terms {
+	ti.forEachTerm(func(k string) {
 		if matcher(k, qt.Value) {
-			hasMatch = true
+			if tv, found := ti.lookup(k); found {
+				hasMatch = true
 
-			hits.AppendTerm(k, tv)
+				hits.AppendTerm(k, tv)
+			}
 		}
-	}
+	})
 
 	return hasMatch
 }
 
 func (ti *TextIndex) matchTerm(qt *search.QueryTerm, hits *search.Matches) bool {
-	term, ok := ti.Terms[qt.Value]
+	term, ok := ti.lookup(qt.Value)
 	if ok && qt.Prohibited {
 		return false
 	}
@@ -444,6 +642,127 @@ func (ti *TextIndex) Encode(w io.Writer) error {
 	return nil
 }
 
+// WriteSegment writes the TextIndex, including its segments, as a single
+// Segment to w.
+func (ti *TextIndex) WriteSegment(w io.Writer) error {
+	terms := []string{}
+
+	ti.forEachTerm(func(term string) {
+		terms = append(terms, term)
+	})
+
+	return writeSegment(w, ti.Docs, terms, func(term string) *search.Vectors {
+		tv, _ := ti.lookup(term)
+		return tv
+	})
+}
+
+// WriteSegmentFile writes the TextIndex as a Segment to path.
+//
+// The Segment is written and synced to a temporary file first, which then
+// replaces path atomically. Segments that are memory mapped from path keep
+// reading the file they were opened from.
+func (ti *TextIndex) WriteSegmentFile(path string) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+
+	if err := ti.WriteSegment(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+
+		return fmt.Errorf("unable to write segment %s; %w", path, err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// OpenTextIndex opens the TextIndex stored at path.
+// Segment files are memory mapped and their terms are only decoded when they
+// are queried. The TextIndex must be closed to release the memory map.
+//
+// For backwards compatibility TextIndex files in the GOB format are decoded
+// into memory. They can be converted with MigrateTextIndex.
+func OpenTextIndex(path string) (*TextIndex, error) {
+	isSegment, err := isSegmentFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isSegment {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return DecodeTextIndex(f)
+	}
+
+	s, err := OpenSegment(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ti := NewTextIndex()
+	ti.AddSegment(s)
+
+	return ti, nil
+}
+
+// MigrateTextIndex rewrites the TextIndex stored at src as a Segment at dst.
+// src can be either a GOB encoded TextIndex or a Segment. dst is replaced
+// atomically, see WriteSegmentFile.
+func MigrateTextIndex(src, dst string) error {
+	ti, err := OpenTextIndex(src)
+	if err != nil {
+		return fmt.Errorf("unable to open TextIndex %s; %w", src, err)
+	}
+	defer ti.Close()
+
+	return ti.WriteSegmentFile(dst)
+}
+
+func isSegmentFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, len(segmentMagic))
+
+	if _, err := io.ReadFull(f, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return IsSegment(header), nil
+}
+
 func DecodeTextIndex(r io.Reader) (*TextIndex, error) {
 	var ti TextIndex
 
@@ -454,5 +773,7 @@ func DecodeTextIndex(r io.Reader) (*TextIndex, error) {
 		return nil, err
 	}
 
+	ti.buildPhoneticKeys()
+
 	return &ti, nil
 }
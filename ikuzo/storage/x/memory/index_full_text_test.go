@@ -524,6 +524,39 @@ func TestTextIndex_matchCustom(t *testing.T) {
 				{"kwartier", []testVector{{DocID: 1, Location: 3}}},
 			}),
 		},
+		{
+			"phonetic query",
+			fields{"Jansen Janssen Janszen Smit"},
+			args{
+				&search.QueryTerm{Value: "jansen", Phonetic: true},
+				search.NewMatches(),
+			},
+			ti.matchPhonetic,
+			true,
+			func() *search.Matches {
+				m := createMatches([]termVector{
+					{"janssen", []testVector{{DocID: 1, Location: 2}}},
+					{"janszen", []testVector{{DocID: 1, Location: 3}}},
+				})
+
+				tv := search.NewVectors()
+				tv.AddVector(search.Vector{DocID: 1, Location: 1})
+				m.AppendBoostedTerm("jansen", tv, phoneticExactBoost)
+
+				return m
+			}(),
+		},
+		{
+			"prohibited phonetic query",
+			fields{"Janssen Smit"},
+			args{
+				&search.QueryTerm{Value: "jansen", Phonetic: true, Prohibited: true},
+				search.NewMatches(),
+			},
+			ti.matchPhonetic,
+			false,
+			search.NewMatches(),
+		},
 		{
 			"fuzzy query",
 			fields{"batauia"},
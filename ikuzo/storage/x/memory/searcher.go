@@ -0,0 +1,433 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/delving/hub3/ikuzo/search"
+)
+
+// Searcher is a search.Searcher for search.Documents that are kept in memory.
+//
+// Each document is indexed in its own TextIndex, so that the boolean clauses
+// of the query are evaluated per document. Hits are scored with the same
+// TF-IDF similarity as TextIndex.Explain. Filters, facets and sorting are
+// applied to the documents directly. Only term facets on resource entries and 'meta.'
+// fields are supported.
+//
+// Searcher is safe for concurrent use.
+type Searcher struct {
+	rw      sync.RWMutex
+	docs    []*search.Document
+	indexes []*TextIndex
+	// docFreq is the number of documents that contain the term
+	docFreq map[string]int
+}
+
+type scoredDoc struct {
+	doc   *search.Document
+	score float64
+}
+
+// NewSearcher returns a Searcher with the documents added to it.
+func NewSearcher(docs ...*search.Document) (*Searcher, error) {
+	s := &Searcher{
+		docFreq: map[string]int{},
+	}
+
+	if err := s.Add(docs...); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Add adds documents to the Searcher.
+func (s *Searcher) Add(docs ...*search.Document) error {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+	for _, doc := range docs {
+		ti := NewTextIndex()
+
+		if err := ti.AppendString(documentText(doc)); err != nil {
+			return fmt.Errorf("unable to index document %s; %w", doc.ID, err)
+		}
+
+		ti.forEachTerm(func(term string) {
+			s.docFreq[term]++
+		})
+
+		s.docs = append(s.docs, doc)
+		s.indexes = append(s.indexes, ti)
+	}
+
+	return nil
+}
+
+// Search executes the search.Request against the documents in the Searcher.
+func (s *Searcher) Search(ctx context.Context, req *search.Request) (*search.Response, error) {
+	s.rw.RLock()
+	defer s.rw.RUnlock()
+
+	scores, err := s.scores(req.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []*scoredDoc{}
+
+	for idx, doc := range s.docs {
+		score := 1.0
+
+		if scores != nil {
+			var ok bool
+			if score, ok = scores[idx]; !ok {
+				continue
+			}
+		}
+
+		if !matchFilters(doc, req) {
+			continue
+		}
+
+		matched = append(matched, &scoredDoc{doc: doc, score: score})
+	}
+
+	facets := make([]*search.Facet, 0, len(req.Facets))
+
+	for _, ff := range req.Facets {
+		facet, err := aggregate(ff, matched)
+		if err != nil {
+			return nil, err
+		}
+
+		facets = append(facets, facet)
+	}
+
+	sortDocs(matched, req.Sort)
+
+	resp := &search.Response{
+		Pager: search.ScrollPager{
+			Cursor: int32(req.Start()),
+			Total:  int64(len(matched)),
+			Rows:   int32(req.Size),
+		},
+		Query:  req.Query,
+		Hits:   []*search.Hit{},
+		Facets: facets,
+	}
+
+	start := req.Start()
+	if start > len(matched) {
+		start = len(matched)
+	}
+
+	end := start + req.Size
+	if end > len(matched) || req.Size <= 0 {
+		end = len(matched)
+	}
+
+	for _, sd := range matched[start:end] {
+		source, err := json.Marshal(sd.doc)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Hits = append(resp.Hits, &search.Hit{
+			ID:     sd.doc.ID,
+			Score:  sd.score,
+			Source: source,
+		})
+	}
+
+	return resp, nil
+}
+
+// scores returns the score for each document index that matches the query.
+// When the query is empty nil is returned which means all documents match.
+func (s *Searcher) scores(query string) (map[int]float64, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	tq, err := NewTextQueryFromString(query)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := map[int]float64{}
+	docCount := float64(len(s.docs))
+
+	for idx, ti := range s.indexes {
+		hits, err := ti.Search(tq.q)
+		if err != nil {
+			if errors.Is(err, ErrSearchNoMatch) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		if hits.DocCount() == 0 {
+			continue
+		}
+
+		var score float64
+
+		for term, freq := range hits.TermFrequency() {
+			idf := 1 + math.Log(docCount/float64(s.docFreq[term]+1))
+			score += math.Sqrt(float64(freq)) * idf * hits.Boost(term)
+		}
+
+		scores[idx] = score
+	}
+
+	return scores, nil
+}
+
+// documentText returns all field values of the document as a single text.
+func documentText(doc *search.Document) string {
+	fields := make([]string, 0, len(doc.Fields))
+	for field := range doc.Fields {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	var sb strings.Builder
+
+	for _, field := range fields {
+		for _, value := range doc.Fields[field] {
+			sb.WriteString(value)
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func matchFilters(doc *search.Document, req *search.Request) bool {
+	if req.OrgID != "" && doc.OrgID != req.OrgID {
+		return false
+	}
+
+	fields, values := req.FilterGroups()
+
+	for _, field := range fields {
+		if !containsAny(doc.Values(field), values[field]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// aggregate returns the term facet for the FacetField. Each document is
+// counted once per distinct value. The pivot facet is aggregated over the
+// documents of each value.
+func aggregate(ff *search.FacetField, docs []*scoredDoc) (*search.Facet, error) {
+	var values func(doc *search.Document) []string
+
+	switch {
+	case strings.HasPrefix(ff.Path(), "meta.") && ff.AggregationType() == "":
+		values = func(doc *search.Document) []string {
+			return doc.MetaValues(ff.Path())
+		}
+	case ff.IsNested() && ff.NestedField() == "@value" && ff.AggregationType() == "":
+		values = func(doc *search.Document) []string {
+			return doc.Fields[ff.Field]
+		}
+	default:
+		return nil, fmt.Errorf("%s: %w", ff.Field, search.ErrUnsupportedFacet)
+	}
+
+	counts := map[string]int64{}
+
+	for _, sd := range docs {
+		seen := map[string]bool{}
+
+		for _, value := range values(sd.doc) {
+			if !seen[value] {
+				seen[value] = true
+				counts[value]++
+			}
+		}
+	}
+
+	facet := &search.Facet{
+		Name:  ff.Field,
+		Field: ff.Field,
+		Links: []*search.FacetLink{},
+	}
+
+	for value, count := range counts {
+		facet.Total += count
+		facet.Links = append(facet.Links, &search.FacetLink{
+			Value:         value,
+			DisplayString: value,
+			Count:         count,
+		})
+	}
+
+	sortFacetLinks(ff, facet.Links)
+
+	if ff.Size() > 0 && len(facet.Links) > ff.Size() {
+		for _, link := range facet.Links[ff.Size():] {
+			facet.OtherDocs += link.Count
+		}
+
+		facet.Links = facet.Links[:ff.Size()]
+	}
+
+	if ff.Pivot() == nil {
+		return facet, nil
+	}
+
+	for _, link := range facet.Links {
+		selected := []*scoredDoc{}
+
+		for _, sd := range docs {
+			if containsAny(values(sd.doc), []string{link.Value}) {
+				selected = append(selected, sd)
+			}
+		}
+
+		pivot, err := aggregate(ff.Pivot(), selected)
+		if err != nil {
+			return nil, err
+		}
+
+		link.Pivot = pivot
+	}
+
+	return facet, nil
+}
+
+// sortFacetLinks sorts the links in the same order as an ElasticSearch
+// terms aggregation. Links with the same count are sorted by value.
+func sortFacetLinks(ff *search.FacetField, links []*search.FacetLink) {
+	sort.Slice(links, func(i, j int) bool {
+		a, b := links[i], links[j]
+
+		if ff.OrderByKey() {
+			if ff.SortAsc() {
+				return a.Value < b.Value
+			}
+
+			return a.Value > b.Value
+		}
+
+		if a.Count != b.Count {
+			if ff.SortAsc() {
+				return a.Count < b.Count
+			}
+
+			return a.Count > b.Count
+		}
+
+		return a.Value < b.Value
+	})
+}
+
+// sortDocs sorts the documents on the sort fields. Ties are sorted on the
+// document ID. Documents without a value for the sort field are sorted last.
+func sortDocs(docs []*scoredDoc, sorts []search.Sort) {
+	if len(sorts) == 0 {
+		sorts = []search.Sort{{Field: search.SortScore, Desc: true}}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, s := range sorts {
+			if cmp := compareDocs(docs[i], docs[j], s); cmp != 0 {
+				return cmp < 0
+			}
+		}
+
+		return docs[i].doc.ID < docs[j].doc.ID
+	})
+}
+
+// compareDocs returns a negative number when a is sorted before b, a
+// positive number when b is sorted before a, and 0 when they are equal.
+func compareDocs(a, b *scoredDoc, s search.Sort) int {
+	direction := 1
+	if s.Desc {
+		direction = -1
+	}
+
+	if s.Field == search.SortScore {
+		switch {
+		case a.score < b.score:
+			return -direction
+		case a.score > b.score:
+			return direction
+		}
+
+		return 0
+	}
+
+	av, aok := sortValue(a.doc.Values(s.Field), s.Desc)
+	bv, bok := sortValue(b.doc.Values(s.Field), s.Desc)
+
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return 1
+	case !bok:
+		return -1
+	}
+
+	return strings.Compare(av, bv) * direction
+}
+
+// sortValue returns the lowest value for ascending and the highest value for
+// descending sorts.
+func sortValue(values []string, desc bool) (string, bool) {
+	if len(values) == 0 {
+		return "", false
+	}
+
+	value := values[0]
+
+	for _, v := range values[1:] {
+		if (desc && v > value) || (!desc && v < value) {
+			value = v
+		}
+	}
+
+	return value, true
+}
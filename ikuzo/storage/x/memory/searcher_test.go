@@ -0,0 +1,50 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/search"
+	"github.com/delving/hub3/ikuzo/search/searchtest"
+	"github.com/matryer/is"
+)
+
+func TestSearcher_conformance(t *testing.T) {
+	searchtest.RunSuite(t, func(t *testing.T, docs []*search.Document) search.Searcher {
+		is := is.New(t)
+
+		s, err := NewSearcher(docs...)
+		is.NoErr(err)
+
+		return s
+	})
+}
+
+func TestSearcher_unsupportedFacet(t *testing.T) {
+	is := is.New(t)
+
+	s, err := NewSearcher(searchtest.Documents()...)
+	is.NoErr(err)
+
+	req := &search.Request{Page: 1, Size: 10}
+	is.NoErr(req.AddFacet("datehistogram.dc_date"))
+
+	_, err = s.Search(context.Background(), req)
+	is.True(errors.Is(err, search.ErrUnsupportedFacet))
+}
@@ -0,0 +1,580 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+)
+
+// The segment file layout is:
+//
+//	magic (8 bytes) | version (uint32)
+//	docs:       uvarint count, followed by count uvarint docIDs
+//	postings:   per term: uvarint nrDocs, followed by per doc
+//	            uvarint docID, uvarint nrPositions, delta encoded uvarint positions
+//	dictionary: per term: uvarint termLength, term, uint64 postings offset
+//	index:      per term: uint64 dictionary offset, sorted by term
+//	footer:     uint64 docs offset | uint64 postings offset | uint64 dictionary offset |
+//	            uint64 index offset | uint32 docCount | uint32 termCount | magic (8 bytes)
+//
+// All fixed size integers are little endian. The index makes it possible to
+// binary search the dictionary without decoding it, so terms can be looked up
+// lazily from a memory mapped file.
+const (
+	segmentVersion    = uint32(1)
+	segmentHeaderSize = 12
+	segmentFooterSize = 48
+)
+
+var segmentMagic = []byte("HUB3SEG\x00")
+
+var (
+	ErrInvalidSegment     = errors.New("invalid TextIndex segment")
+	ErrUnsupportedSegment = errors.New("unsupported TextIndex segment version")
+)
+
+// Segment is an immutable on-disk representation of a TextIndex.
+// The postings of the terms are decoded on demand, so opening a Segment only
+// validates the layout of its documents and dictionary.
+type Segment struct {
+	data           []byte
+	closer         func() error
+	docCount       int
+	termCount      int
+	docsOffset     uint64
+	postingsOffset uint64
+	dictOffset     uint64
+	idxOffset      uint64
+}
+
+// IsSegment returns true when b starts with the segment magic bytes.
+func IsSegment(b []byte) bool {
+	return bytes.HasPrefix(b, segmentMagic)
+}
+
+// NewSegment creates a Segment from its binary representation.
+func NewSegment(data []byte) (*Segment, error) {
+	if len(data) < segmentHeaderSize+segmentFooterSize || !IsSegment(data) {
+		return nil, ErrInvalidSegment
+	}
+
+	if version := binary.LittleEndian.Uint32(data[len(segmentMagic):]); version != segmentVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSegment, version)
+	}
+
+	footer := data[len(data)-segmentFooterSize:]
+	if !bytes.Equal(footer[40:], segmentMagic) {
+		return nil, ErrInvalidSegment
+	}
+
+	s := &Segment{
+		data:           data,
+		docsOffset:     binary.LittleEndian.Uint64(footer[0:]),
+		postingsOffset: binary.LittleEndian.Uint64(footer[8:]),
+		dictOffset:     binary.LittleEndian.Uint64(footer[16:]),
+		idxOffset:      binary.LittleEndian.Uint64(footer[24:]),
+		docCount:       int(binary.LittleEndian.Uint32(footer[32:])),
+		termCount:      int(binary.LittleEndian.Uint32(footer[36:])),
+	}
+
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// validate checks that the sections of the Segment are in order and within
+// the data, and that all documents and dictionary entries can be decoded.
+// The postings are checked when they are decoded.
+func (s *Segment) validate() error {
+	end := uint64(len(s.data) - segmentFooterSize)
+
+	if s.docsOffset != segmentHeaderSize ||
+		s.postingsOffset < s.docsOffset ||
+		s.dictOffset < s.postingsOffset ||
+		s.idxOffset < s.dictOffset ||
+		s.idxOffset > end ||
+		(end-s.idxOffset)/8 != uint64(s.termCount) || (end-s.idxOffset)%8 != 0 {
+		return fmt.Errorf("%w: section offsets out of range", ErrInvalidSegment)
+	}
+
+	docs, err := s.decodeDocs()
+	if err != nil {
+		return err
+	}
+
+	if len(docs) != s.docCount {
+		return fmt.Errorf("%w: expected %d documents, got %d", ErrInvalidSegment, s.docCount, len(docs))
+	}
+
+	var previous []byte
+
+	for n := 0; n < s.termCount; n++ {
+		term, _, err := s.readTerm(n)
+		if err != nil {
+			return err
+		}
+
+		if n > 0 && bytes.Compare(previous, term) >= 0 {
+			return fmt.Errorf("%w: dictionary is not sorted", ErrInvalidSegment)
+		}
+
+		previous = term
+	}
+
+	return nil
+}
+
+// OpenSegment memory maps the segment file at path.
+// The Segment must be closed to release the mapping.
+func OpenSegment(path string) (*Segment, error) {
+	data, closer, err := mapFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to map segment %s; %w", path, err)
+	}
+
+	s, err := NewSegment(data)
+	if err != nil {
+		_ = closer()
+		return nil, err
+	}
+
+	s.closer = closer
+
+	return s, nil
+}
+
+// Close releases the underlying memory map.
+func (s *Segment) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+
+	err := s.closer()
+	s.closer = nil
+	s.data = nil
+
+	return err
+}
+
+// DocCount returns the number of documents in the Segment.
+func (s *Segment) DocCount() int {
+	return s.docCount
+}
+
+// TermCount returns the number of unique terms in the Segment.
+func (s *Segment) TermCount() int {
+	return s.termCount
+}
+
+// Docs returns the document identifiers stored in the Segment.
+// The documents are validated when the Segment is created.
+func (s *Segment) Docs() []int {
+	docs, _ := s.decodeDocs()
+	return docs
+}
+
+func (s *Segment) decodeDocs() ([]int, error) {
+	r := bytes.NewReader(s.data[s.docsOffset:s.postingsOffset])
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to read document count; %s", ErrInvalidSegment, err)
+	}
+
+	// each docID takes at least one byte
+	if count > uint64(r.Len()) {
+		return nil, fmt.Errorf("%w: document count %d out of range", ErrInvalidSegment, count)
+	}
+
+	docs := make([]int, 0, count)
+
+	for i := uint64(0); i < count; i++ {
+		docID, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unable to read document; %s", ErrInvalidSegment, err)
+		}
+
+		docs = append(docs, int(docID))
+	}
+
+	return docs, nil
+}
+
+// term returns the term and postings offset of the nth dictionary entry.
+// The dictionary is validated when the Segment is created.
+func (s *Segment) term(n int) (term []byte, postings uint64) {
+	term, postings, _ = s.readTerm(n)
+	return term, postings
+}
+
+// readTerm decodes the nth dictionary entry and checks that it is within the
+// dictionary and points to the postings.
+func (s *Segment) readTerm(n int) (term []byte, postings uint64, err error) {
+	if n < 0 || n >= s.termCount {
+		return nil, 0, fmt.Errorf("%w: term %d out of range", ErrInvalidSegment, n)
+	}
+
+	entry := binary.LittleEndian.Uint64(s.data[s.idxOffset+uint64(n)*8:])
+	if entry < s.dictOffset || entry >= s.idxOffset {
+		return nil, 0, fmt.Errorf("%w: dictionary entry %d out of range", ErrInvalidSegment, n)
+	}
+
+	buf := s.data[entry:s.idxOffset]
+
+	size, read := binary.Uvarint(buf)
+	if read <= 0 || size > uint64(len(buf)-read) || uint64(len(buf)-read)-size < 8 {
+		return nil, 0, fmt.Errorf("%w: dictionary entry %d is truncated", ErrInvalidSegment, n)
+	}
+
+	buf = buf[read:]
+
+	postings = binary.LittleEndian.Uint64(buf[size:])
+	if postings < s.postingsOffset || postings >= s.dictOffset {
+		return nil, 0, fmt.Errorf("%w: postings of dictionary entry %d out of range", ErrInvalidSegment, n)
+	}
+
+	return buf[:size], postings, nil
+}
+
+// Terms calls fn for each term in the Segment in sorted order.
+// Iteration stops when fn returns false.
+func (s *Segment) Terms(fn func(term string) bool) {
+	for i := 0; i < s.termCount; i++ {
+		term, _ := s.term(i)
+		if !fn(string(term)) {
+			return
+		}
+	}
+}
+
+// Lookup returns the Vectors for the term.
+func (s *Segment) Lookup(term string) (*search.Vectors, bool) {
+	target := []byte(term)
+
+	i := sort.Search(s.termCount, func(i int) bool {
+		t, _ := s.term(i)
+		return bytes.Compare(t, target) >= 0
+	})
+
+	if i >= s.termCount {
+		return nil, false
+	}
+
+	t, offset := s.term(i)
+	if !bytes.Equal(t, target) {
+		return nil, false
+	}
+
+	tv, err := s.decodePostings(offset)
+	if err != nil {
+		return nil, false
+	}
+
+	return tv, true
+}
+
+func (s *Segment) decodePostings(offset uint64) (*search.Vectors, error) {
+	if offset < s.postingsOffset || offset >= s.dictOffset {
+		return nil, fmt.Errorf("%w: postings offset %d out of range", ErrInvalidSegment, offset)
+	}
+
+	tv := search.NewVectors()
+
+	r := bytes.NewReader(s.data[offset:s.dictOffset])
+
+	nrDocs, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// each doc takes at least two bytes: docID and nrPositions
+	if nrDocs > uint64(r.Len()/2) {
+		return nil, fmt.Errorf("%w: postings document count %d out of range", ErrInvalidSegment, nrDocs)
+	}
+
+	for i := uint64(0); i < nrDocs; i++ {
+		docID, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		nrPositions, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// each position takes at least one byte
+		if nrPositions > uint64(r.Len()) {
+			return nil, fmt.Errorf("%w: postings position count %d out of range", ErrInvalidSegment, nrPositions)
+		}
+
+		var pos uint64
+
+		for j := uint64(0); j < nrPositions; j++ {
+			delta, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+
+			pos += delta
+
+			tv.Add(int(docID), int(pos))
+		}
+	}
+
+	return tv, nil
+}
+
+// MergeSegments writes a single Segment to w that contains all documents
+// and terms of the given segments.
+func MergeSegments(w io.Writer, segments ...*Segment) error {
+	docs := map[int]bool{}
+
+	for _, s := range segments {
+		for _, docID := range s.Docs() {
+			docs[docID] = true
+		}
+	}
+
+	terms := []string{}
+	seen := map[string]bool{}
+
+	for _, s := range segments {
+		s.Terms(func(term string) bool {
+			if !seen[term] {
+				seen[term] = true
+
+				terms = append(terms, term)
+			}
+
+			return true
+		})
+	}
+
+	return writeSegment(w, docs, terms, func(term string) *search.Vectors {
+		return lookupSegments(segments, term)
+	})
+}
+
+// lookupSegments returns the merged Vectors for term from all segments.
+func lookupSegments(segments []*Segment, term string) *search.Vectors {
+	var merged *search.Vectors
+
+	for _, s := range segments {
+		tv, ok := s.Lookup(term)
+		if !ok {
+			continue
+		}
+
+		if merged == nil {
+			merged = tv
+			continue
+		}
+
+		merged.Merge(tv)
+	}
+
+	return merged
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n uint64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += uint64(n)
+
+	return n, err
+}
+
+func (cw *countingWriter) uvarint(v uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	_, err := cw.Write(buf[:binary.PutUvarint(buf, v)])
+
+	return err
+}
+
+func (cw *countingWriter) uint64(v uint64) error {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, v)
+	_, err := cw.Write(buf)
+
+	return err
+}
+
+func (cw *countingWriter) uint32(v uint32) error {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, v)
+	_, err := cw.Write(buf)
+
+	return err
+}
+
+// writeSegment writes the segment for the terms. The lookup function must
+// return the Vectors for each term.
+func writeSegment(w io.Writer, docs map[int]bool, terms []string, lookup func(term string) *search.Vectors) error {
+	sort.Strings(terms)
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	if _, err := cw.Write(segmentMagic); err != nil {
+		return err
+	}
+
+	if err := cw.uint32(segmentVersion); err != nil {
+		return err
+	}
+
+	docsOffset := cw.n
+
+	if err := writeDocs(cw, docs); err != nil {
+		return err
+	}
+
+	postingsOffset := cw.n
+	postings := make([]uint64, len(terms))
+
+	for idx, term := range terms {
+		postings[idx] = cw.n
+
+		if err := writePostings(cw, lookup(term)); err != nil {
+			return fmt.Errorf("unable to write postings for %s; %w", term, err)
+		}
+	}
+
+	dictOffset := cw.n
+	entries := make([]uint64, len(terms))
+
+	for idx, term := range terms {
+		entries[idx] = cw.n
+
+		if err := cw.uvarint(uint64(len(term))); err != nil {
+			return err
+		}
+
+		if _, err := cw.Write([]byte(term)); err != nil {
+			return err
+		}
+
+		if err := cw.uint64(postings[idx]); err != nil {
+			return err
+		}
+	}
+
+	idxOffset := cw.n
+
+	for _, entry := range entries {
+		if err := cw.uint64(entry); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range []uint64{docsOffset, postingsOffset, dictOffset, idxOffset} {
+		if err := cw.uint64(v); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range []int{len(docs), len(terms)} {
+		if err := cw.uint32(uint32(v)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := cw.Write(segmentMagic); err != nil {
+		return err
+	}
+
+	return cw.w.Flush()
+}
+
+func writeDocs(cw *countingWriter, docs map[int]bool) error {
+	ids := make([]int, 0, len(docs))
+	for docID := range docs {
+		ids = append(ids, docID)
+	}
+
+	sort.Ints(ids)
+
+	if err := cw.uvarint(uint64(len(ids))); err != nil {
+		return err
+	}
+
+	for _, docID := range ids {
+		if err := cw.uvarint(uint64(docID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writePostings(cw *countingWriter, tv *search.Vectors) error {
+	positions := map[int][]int{}
+
+	if tv != nil {
+		for vector := range tv.Locations {
+			positions[vector.DocID] = append(positions[vector.DocID], vector.Location)
+		}
+	}
+
+	docs := make([]int, 0, len(positions))
+	for docID := range positions {
+		docs = append(docs, docID)
+	}
+
+	sort.Ints(docs)
+
+	if err := cw.uvarint(uint64(len(docs))); err != nil {
+		return err
+	}
+
+	for _, docID := range docs {
+		locations := positions[docID]
+		sort.Ints(locations)
+
+		if err := cw.uvarint(uint64(docID)); err != nil {
+			return err
+		}
+
+		if err := cw.uvarint(uint64(len(locations))); err != nil {
+			return err
+		}
+
+		var prev int
+
+		for _, pos := range locations {
+			if err := cw.uvarint(uint64(pos - prev)); err != nil {
+				return err
+			}
+
+			prev = pos
+		}
+	}
+
+	return nil
+}
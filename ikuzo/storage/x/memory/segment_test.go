@@ -0,0 +1,399 @@
+// Copyright 2020 Delving B.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// nolint:gocritic
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/delving/hub3/ikuzo/service/x/search"
+	"github.com/google/go-cmp/cmp"
+	"github.com/matryer/is"
+)
+
+func newTestSegment(t *testing.T, texts ...string) *Segment {
+	t.Helper()
+
+	is := is.New(t)
+
+	ti := NewTextIndex()
+
+	for _, text := range texts {
+		err := ti.AppendString(text)
+		is.NoErr(err)
+	}
+
+	var buf bytes.Buffer
+
+	err := ti.WriteSegment(&buf)
+	is.NoErr(err)
+
+	s, err := NewSegment(buf.Bytes())
+	is.NoErr(err)
+
+	return s
+}
+
+func TestSegment_Lookup(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+	err := ti.AppendString("One two three. One two.")
+	is.NoErr(err)
+	err = ti.AppendString("three four", 5)
+	is.NoErr(err)
+
+	var buf bytes.Buffer
+
+	err = ti.WriteSegment(&buf)
+	is.NoErr(err)
+
+	s, err := NewSegment(buf.Bytes())
+	is.NoErr(err)
+
+	is.Equal(s.DocCount(), 2)
+	is.Equal(s.TermCount(), 4)
+	is.Equal(s.Docs(), []int{1, 5})
+
+	for term, want := range ti.Terms {
+		got, ok := s.Lookup(term)
+		is.True(ok)
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Segment.Lookup() %s = mismatch (-want +got):\n%s", term, diff)
+		}
+	}
+
+	_, ok := s.Lookup("five")
+	is.True(!ok)
+
+	terms := []string{}
+
+	s.Terms(func(term string) bool {
+		terms = append(terms, term)
+		return true
+	})
+
+	is.Equal(terms, []string{"four", "one", "three", "two"})
+}
+
+func TestNewSegment(t *testing.T) {
+	is := is.New(t)
+
+	s := newTestSegment(t, "one")
+
+	// corrupt returns a copy of the segment with the byte at offset replaced
+	corrupt := func(offset int, b byte) []byte {
+		data := append([]byte{}, s.data...)
+		data[offset] = b
+
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"valid segment", s.data, nil},
+		{"empty data", []byte{}, ErrInvalidSegment},
+		{"gob data", bytes.Repeat([]byte("x"), 100), ErrInvalidSegment},
+		{"truncated segment", s.data[:len(s.data)-1], ErrInvalidSegment},
+		{"document count out of range", corrupt(12, 100), ErrInvalidSegment},
+		{"dictionary entry out of range", corrupt(30, 0), ErrInvalidSegment},
+		{"term length out of range", corrupt(18, 100), ErrInvalidSegment},
+		{"postings out of range", corrupt(22, 200), ErrInvalidSegment},
+		{"index offset out of range", corrupt(len(s.data)-24, 0xff), ErrInvalidSegment},
+		{
+			"unsupported version",
+			append(append([]byte{}, segmentMagic...), append([]byte{2, 0, 0, 0}, s.data[12:]...)...),
+			ErrUnsupportedSegment,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSegment(tt.data)
+			is.True(errors.Is(err, tt.wantErr))
+		})
+	}
+}
+
+func TestSegment_corrupted(t *testing.T) {
+	s := newTestSegment(t, "one two three. one two", "three four")
+
+	// corrupted segments must either be rejected or be read without panicking
+	for offset := range s.data {
+		data := append([]byte{}, s.data...)
+		data[offset] ^= 0xff
+
+		corrupted, err := NewSegment(data)
+		if err != nil {
+			continue
+		}
+
+		corrupted.Docs()
+
+		corrupted.Terms(func(term string) bool {
+			corrupted.Lookup(term)
+			return true
+		})
+	}
+}
+
+func TestTextIndex_searchSegment(t *testing.T) {
+	is := is.New(t)
+
+	for _, tt := range searchTests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			ti := NewTextIndex()
+
+			for _, txt := range tt.args.text {
+				err := ti.AppendString(txt)
+				is.NoErr(err)
+			}
+
+			var buf bytes.Buffer
+
+			err := ti.WriteSegment(&buf)
+			is.NoErr(err)
+
+			s, err := NewSegment(buf.Bytes())
+			is.NoErr(err)
+
+			segmentIndex := NewTextIndex()
+			segmentIndex.AddSegment(s)
+
+			queryParser, err := search.NewQueryParser()
+			is.NoErr(err)
+
+			query, err := queryParser.Parse(tt.args.query)
+			is.NoErr(err)
+
+			got, err := segmentIndex.Search(query)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TextIndex.search() segment %s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got.TermFrequency()); diff != "" {
+				t.Errorf("TextIndex.search() segment %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestTextIndex_segmentPhoneticTerms(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+	ti.AddSegment(newTestSegment(t, "Jan Janssen en Piet Meijer"))
+	ti.AddSegment(newTestSegment(t, "Klaas Jansen"))
+
+	// the phonetic keys are not computed when the segments are added
+	is.Equal(ti.segmentsPhonetic, nil)
+
+	hits := search.NewMatches()
+	is.True(ti.matchPhonetic(&search.QueryTerm{Value: "jansen", Phonetic: true}, hits))
+	is.Equal(ti.segmentPhoneticTerms(search.PhoneticKey("jansen")), []string{"janssen", "jansen"})
+
+	is.NoErr(ti.Close())
+	is.Equal(ti.segmentPhoneticTerms(search.PhoneticKey("jansen")), nil)
+}
+
+func TestMergeSegments(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+	err := ti.AppendString("Jansen woonde in Leiden", 1)
+	is.NoErr(err)
+	first := newTestSegmentFromIndex(t, ti)
+
+	ti = NewTextIndex()
+	err = ti.AppendString("Janssen verhuisde naar Leiden", 2)
+	is.NoErr(err)
+	second := newTestSegmentFromIndex(t, ti)
+
+	var buf bytes.Buffer
+
+	err = MergeSegments(&buf, first, second)
+	is.NoErr(err)
+
+	merged, err := NewSegment(buf.Bytes())
+	is.NoErr(err)
+
+	is.Equal(merged.Docs(), []int{1, 2})
+	is.Equal(merged.TermCount(), 7)
+
+	leiden, ok := merged.Lookup("leiden")
+	is.True(ok)
+	is.Equal(leiden.DocCount(), 2)
+
+	mergedIndex := NewTextIndex()
+	mergedIndex.AddSegment(merged)
+
+	hits := search.NewMatches()
+	is.True(mergedIndex.match(&search.QueryTerm{Value: "jansen", Phonetic: true}, hits))
+	is.Equal(hits.TermFrequency(), map[string]int{"jansen": 1, "janssen": 1})
+}
+
+func newTestSegmentFromIndex(t *testing.T, ti *TextIndex) *Segment {
+	t.Helper()
+
+	is := is.New(t)
+
+	var buf bytes.Buffer
+
+	err := ti.WriteSegment(&buf)
+	is.NoErr(err)
+
+	s, err := NewSegment(buf.Bytes())
+	is.NoErr(err)
+
+	return s
+}
+
+func TestTextIndex_appendToSegment(t *testing.T) {
+	is := is.New(t)
+
+	ti := NewTextIndex()
+	ti.AddSegment(newTestSegment(t, "one two"))
+
+	is.True(ti.hasDocID(1))
+	is.Equal(ti.DocCount, 1)
+
+	err := ti.AppendString("two three")
+	is.NoErr(err)
+	is.True(ti.hasDocID(2))
+
+	two, ok := ti.lookup("two")
+	is.True(ok)
+	is.Equal(two.DocCount(), 2)
+
+	var buf bytes.Buffer
+
+	err = ti.WriteSegment(&buf)
+	is.NoErr(err)
+
+	s, err := NewSegment(buf.Bytes())
+	is.NoErr(err)
+	is.Equal(s.TermCount(), 3)
+	is.Equal(s.Docs(), []int{1, 2})
+}
+
+func TestMigrateTextIndex(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "textindex")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	ti := NewTextIndex()
+	err = ti.AppendString("One two three. One two. One two three")
+	is.NoErr(err)
+
+	var buf bytes.Buffer
+
+	err = ti.Encode(&buf)
+	is.NoErr(err)
+
+	gobPath := filepath.Join(dir, "index.gob")
+	err = ioutil.WriteFile(gobPath, buf.Bytes(), os.ModePerm)
+	is.NoErr(err)
+
+	// gob files can still be opened
+	gobIndex, err := OpenTextIndex(gobPath)
+	is.NoErr(err)
+	is.Equal(gobIndex.size(), 3)
+	is.Equal(len(gobIndex.segments), 0)
+
+	segmentPath := filepath.Join(dir, "index.seg")
+	err = MigrateTextIndex(gobPath, segmentPath)
+	is.NoErr(err)
+
+	segmentIndex, err := OpenTextIndex(segmentPath)
+	is.NoErr(err)
+
+	defer segmentIndex.Close()
+
+	is.Equal(len(segmentIndex.segments), 1)
+	is.Equal(segmentIndex.size(), 0)
+
+	for term, want := range ti.Terms {
+		got, ok := segmentIndex.lookup(term)
+		is.True(ok)
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("MigrateTextIndex() %s = mismatch (-want +got):\n%s", term, diff)
+		}
+	}
+
+	is.NoErr(segmentIndex.Close())
+	is.Equal(len(segmentIndex.segments), 0)
+}
+
+func TestTextIndex_WriteSegmentFile(t *testing.T) {
+	is := is.New(t)
+
+	dir, err := ioutil.TempDir("", "textindex")
+	is.NoErr(err)
+
+	defer os.RemoveAll(dir)
+
+	segmentPath := filepath.Join(dir, "index.seg")
+
+	ti := NewTextIndex()
+	err = ti.AppendString("One two three")
+	is.NoErr(err)
+	is.NoErr(ti.WriteSegmentFile(segmentPath))
+
+	opened, err := OpenTextIndex(segmentPath)
+	is.NoErr(err)
+
+	defer opened.Close()
+
+	// replacing the segment does not change the memory mapped segment
+	replacement := NewTextIndex()
+	err = replacement.AppendString("four")
+	is.NoErr(err)
+	is.NoErr(replacement.WriteSegmentFile(segmentPath))
+
+	_, ok := opened.lookup("three")
+	is.True(ok)
+
+	reopened, err := OpenTextIndex(segmentPath)
+	is.NoErr(err)
+
+	defer reopened.Close()
+
+	_, ok = reopened.lookup("three")
+	is.True(!ok)
+	_, ok = reopened.lookup("four")
+	is.True(ok)
+
+	files, err := ioutil.ReadDir(dir)
+	is.NoErr(err)
+	is.Equal(len(files), 1) // no temporary files are left behind
+}
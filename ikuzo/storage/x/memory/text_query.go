@@ -32,6 +32,8 @@ type TextQuery struct {
 	Hits         *search.Matches
 	EmStartTag   string
 	EmStyleClass string
+	// SnippetConfig enables keyword-in-context snippets when it is not nil.
+	SnippetConfig *search.SnippetConfig
 }
 
 func NewTextQuery(q *search.QueryTerm) *TextQuery {
@@ -102,6 +104,26 @@ func (tq *TextQuery) hightlightWithVectors(text string, docID int, vectors *sear
 	return tokens.Highlight(vectors, tq.EmStartTag, tq.EmStyleClass)
 }
 
+// Snippets returns the keyword-in-context fragments for the hits in text.
+// The default SnippetConfig is used when none is set on the TextQuery.
+func (tq *TextQuery) Snippets(text string, docID int) ([]string, bool) {
+	if !tq.ti.hasDocID(docID) || tq.Hits == nil {
+		return []string{}, false
+	}
+
+	cfg := tq.SnippetConfig
+	if cfg == nil {
+		cfg = search.NewSnippetConfig()
+	}
+
+	tok := search.NewTokenizer()
+	tokens := tok.ParseString(text, docID)
+
+	snippets := tokens.Snippets(tq.Hits.Vectors(), cfg, tq.EmStartTag, tq.EmStyleClass)
+
+	return snippets, len(snippets) != 0
+}
+
 func (tq *TextQuery) SetTextIndex(ti *TextIndex) {
 	tq.ti = ti
 }
@@ -153,3 +153,69 @@ func Test_hightlightWithVectors(t *testing.T) {
 		})
 	}
 }
+
+func TestTextQuery_Snippets(t *testing.T) {
+	is := is.New(t)
+
+	type fields struct {
+		q   string
+		cfg *search.SnippetConfig
+	}
+
+	tests := []struct {
+		name     string
+		fields   fields
+		text     string
+		want     []string
+		wantHits bool
+	}{
+		{
+			"no hits",
+			fields{"one", nil},
+			"not 1",
+			[]string{},
+			false,
+		},
+		{
+			"default config",
+			fields{"one", nil},
+			"only one",
+			[]string{"only <em class=\"dchl\">one</em>"},
+			true,
+		},
+		{
+			"fragment size",
+			fields{"leiden", search.NewSnippetConfig(search.SetFragmentSize(20))},
+			"Hij verhuisde in 1850 naar Leiden en later naar Delft",
+			[]string{"1850 naar <em class=\"dchl\">Leiden</em> en"},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			tq, err := NewTextQueryFromString(tt.fields.q)
+			is.NoErr(err)
+
+			tq.SnippetConfig = tt.fields.cfg
+			id := tq.ti.setDocID()
+
+			err = tq.AppendString(tt.text, id)
+			is.NoErr(err)
+
+			_, err = tq.PerformSearch()
+			is.NoErr(err)
+
+			got, gotHits := tq.Snippets(tt.text, id)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("TextQuery.Snippets() %s = mismatch (-want +got):\n%s", tt.name, diff)
+			}
+
+			if gotHits != tt.wantHits {
+				t.Errorf("TextQuery.Snippets() %s = gotHits %v, want %v", tt.name, gotHits, tt.wantHits)
+			}
+		})
+	}
+}